@@ -9,11 +9,11 @@ require (
 	github.com/sirupsen/logrus v1.9.3
 	github.com/telekom-mms/tnd v0.7.0
 	github.com/vishvananda/netlink v1.3.1
+	github.com/vishvananda/netns v0.0.5
 	golang.org/x/sys v0.38.0
 )
 
 require (
-	github.com/vishvananda/netns v0.0.5 // indirect
 	golang.org/x/mod v0.30.0 // indirect
 	golang.org/x/net v0.47.0 // indirect
 	golang.org/x/sync v0.18.0 // indirect
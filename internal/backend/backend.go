@@ -0,0 +1,46 @@
+// Package backend contains the backends that change the network
+// configuration for traffic policing and the VPN setup.
+package backend
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// DNS are the DNS settings of the VPN device.
+type DNS struct {
+	Protocols []string
+	Servers   []string
+	Domains   []string
+}
+
+// Backend is the interface of a backend that changes the network
+// configuration.
+type Backend interface {
+	// traffic policing
+	SetFilterRules(ctx context.Context, config *daemoncfg.Config) error
+	UnsetFilterRules(ctx context.Context, config *daemoncfg.Config) error
+	SetAllowedDevices(ctx context.Context, config *daemoncfg.Config, devices []string) error
+	SetAllowedHosts(ctx context.Context, config *daemoncfg.Config, ips []netip.Prefix) error
+	SetAllowedPorts(ctx context.Context, config *daemoncfg.Config, ports []uint16) error
+	CleanupFilterRules(ctx context.Context, config *daemoncfg.Config) error
+
+	// vpn setup
+	SetupVPN(ctx context.Context, config *daemoncfg.Config) error
+	TeardownVPN(ctx context.Context, config *daemoncfg.Config) error
+	SetExcludes(ctx context.Context, config *daemoncfg.Config, prefixes []netip.Prefix) error
+	SetDNS(ctx context.Context, config *daemoncfg.Config) error
+	GetDNS(ctx context.Context, config *daemoncfg.Config) (*DNS, error)
+	CleanupVPN(ctx context.Context, config *daemoncfg.Config) error
+	ResetVPN(ctx context.Context, config *daemoncfg.Config) error
+}
+
+// New returns the backend selected in config.
+func New(config *daemoncfg.Config) Backend {
+	if config.CommandLists.Backend == daemoncfg.CommandListsBackendNative {
+		return &Native{}
+	}
+	return &Template{}
+}
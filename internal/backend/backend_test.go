@@ -0,0 +1,20 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// TestNew tests New.
+func TestNew(t *testing.T) {
+	config := daemoncfg.NewConfig()
+	if _, ok := New(config).(*Template); !ok {
+		t.Error("default backend should be template backend")
+	}
+
+	config.CommandLists.Backend = daemoncfg.CommandListsBackendNative
+	if _, ok := New(config).(*Native); !ok {
+		t.Error("backend should be native backend")
+	}
+}
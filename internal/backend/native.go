@@ -0,0 +1,673 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/netnsexec"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// filterTable is the nftables table of the traffic policing filter
+	// rules.
+	filterTable = "oc-daemon-filter"
+
+	// srcValidMark is the sysctl file that enables the use of the firewall
+	// mark in the reverse path filter.
+	srcValidMark = "/proc/sys/net/ipv4/conf/all/src_valid_mark"
+)
+
+// inNetNS runs f in the network namespace in config if it is enabled,
+// otherwise f runs in the current network namespace. Sockets created by f
+// stay in the network namespace.
+var inNetNS = func(config *daemoncfg.NetNS, f func() error) error {
+	if !config.Enabled {
+		return f()
+	}
+	return netnsexec.Run(config.Name, f)
+}
+
+// ipNet returns prefix as IPNet, the host bits of the address are kept.
+func ipNet(prefix netip.Prefix) *net.IPNet {
+	return &net.IPNet{
+		IP:   prefix.Addr().AsSlice(),
+		Mask: net.CIDRMask(prefix.Bits(), prefix.Addr().BitLen()),
+	}
+}
+
+// defaultRoutes are the default routes of the address families.
+var defaultRoutes = map[int]*net.IPNet{
+	netlink.FAMILY_V4: ipNet(netip.MustParsePrefix("0.0.0.0/0")),
+	netlink.FAMILY_V6: ipNet(netip.MustParsePrefix("::/0")),
+}
+
+// splitRouting are the split routing settings as numbers.
+type splitRouting struct {
+	table int
+	prio1 int
+	prio2 int
+	mark  uint32
+}
+
+// getSplitRouting returns the split routing settings in config as numbers.
+func getSplitRouting(config *daemoncfg.SplitRouting) (*splitRouting, error) {
+	table, err := strconv.ParseUint(config.RoutingTable, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid routing table: %w", err)
+	}
+	prio1, err := strconv.ParseUint(config.RulePriority1, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule priority: %w", err)
+	}
+	prio2, err := strconv.ParseUint(config.RulePriority2, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule priority: %w", err)
+	}
+	mark, err := strconv.ParseUint(config.FirewallMark, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid firewall mark: %w", err)
+	}
+	return &splitRouting{
+		table: int(table),
+		prio1: int(prio1),
+		prio2: int(prio2),
+		mark:  uint32(mark),
+	}, nil
+}
+
+// loadRuleset loads the nftables ruleset in the template with name for the
+// command list. The rulesets are only loaded during setup, so they are
+// still loaded with nft and can be customized in the templates.
+func loadRuleset(ctx context.Context, config *daemoncfg.Config, list, name string) error {
+	ruleset, err := cmdtmpl.ExecuteTemplate(name, config)
+	if err != nil {
+		return fmt.Errorf("could not execute template %s: %w", name, err)
+	}
+	c := &cmdtmpl.Cmd{
+		List:      list,
+		Cmd:       config.Executables.Nft,
+		Args:      []string{"-f", "-"},
+		Stdin:     ruleset,
+		OnFailure: cmdtmpl.GetOnFailure(list),
+	}
+	if list == cmdtmpl.VPNSetupSetup && config.NetNS.Enabled {
+		// split routing rules are in the network namespace
+		c.Args = append([]string{"netns", "exec", config.NetNS.Name, c.Cmd}, c.Args...)
+		c.Cmd = config.Executables.IP
+	}
+	if stdout, stderr, err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return &cmdtmpl.CmdError{
+			List:   list,
+			Cmd:    c.Cmd,
+			Args:   c.Args,
+			Stdin:  c.Stdin,
+			Stdout: string(stdout),
+			Stderr: string(stderr),
+			Err:    err,
+		}
+	}
+	return nil
+}
+
+// step is a step of the native backend that changes the network
+// configuration.
+type step struct {
+	// name is the name of the step in failures
+	name string
+
+	// run applies the change
+	run func() error
+
+	// undo reverts the applied change
+	undo func(ctx context.Context) error
+
+	// journal are the commands that revert the applied change after a
+	// failed shutdown
+	journal []*cmdtmpl.Cmd
+}
+
+// undoCmd returns the command of list that reverts a step in the journal. If
+// netns is set, the command runs in the network namespace in config if it is
+// enabled.
+func undoCmd(config *daemoncfg.Config, list string, netns bool, cmd string, args ...string) *cmdtmpl.Cmd {
+	if netns && config.NetNS.Enabled {
+		args = append([]string{"netns", "exec", config.NetNS.Name, cmd}, args...)
+		cmd = config.Executables.IP
+	}
+	return &cmdtmpl.Cmd{List: list, Cmd: cmd, Args: args}
+}
+
+// runSteps runs steps with the failure mode of the command list identified
+// by list and returns the errors of all failed steps. If ctx contains a
+// journal, the commands that revert the applied steps are added to it.
+func runSteps(ctx context.Context, list string, steps []*step) error {
+	onFailure := cmdtmpl.GetOnFailure(list)
+	j := cmdtmpl.JournalFromContext(ctx)
+	var errs []error
+	var applied []*step
+	for _, s := range steps {
+		if err := s.run(); err != nil {
+			// failed commands report their failures themselves
+			errs = append(errs, err)
+			var cmdErr *cmdtmpl.CmdError
+			if !errors.As(err, &cmdErr) {
+				cmdtmpl.SendFailure(&cmdtmpl.Failure{
+					List:      list,
+					Command:   s.name,
+					Stderr:    err.Error(),
+					OnFailure: onFailure,
+				})
+			}
+			switch onFailure {
+			case cmdtmpl.OnFailureAbort:
+				return errors.Join(errs...)
+			case cmdtmpl.OnFailureRollback:
+				errs = append(errs, rollbackSteps(ctx, applied)...)
+				return errors.Join(errs...)
+			}
+			continue
+		}
+
+		// step applied, remember it for rollback
+		applied = append(applied, s)
+		if j == nil {
+			continue
+		}
+		for _, c := range s.journal {
+			if err := j.Add(c); err != nil {
+				errs = append(errs, fmt.Errorf("could not add undo command to journal: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rollbackSteps reverts the applied steps in reverse order and returns the
+// errors of all failed reverts. It is not canceled with ctx, so the applied
+// steps are always reverted. Reverted steps are removed from the journal in
+// ctx.
+func rollbackSteps(ctx context.Context, applied []*step) []error {
+	ctx = context.WithoutCancel(ctx)
+	j := cmdtmpl.JournalFromContext(ctx)
+	var errs []error
+	for _, s := range slices.Backward(applied) {
+		if err := s.undo(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("could not roll back: %w", err))
+			continue
+		}
+
+		// change reverted, remove it from journal
+		if j == nil {
+			continue
+		}
+		for _, c := range s.journal {
+			if err := j.Remove(c); err != nil {
+				errs = append(errs, fmt.Errorf("could not remove undo command from journal: %w", err))
+			}
+		}
+	}
+	return errs
+}
+
+// Native is the backend that changes the network configuration with
+// netlink and the D-Bus API of systemd-resolved.
+type Native struct{}
+
+// SetFilterRules sets the filter rules.
+func (n *Native) SetFilterRules(ctx context.Context, config *daemoncfg.Config) error {
+	list := cmdtmpl.TrafPolSetFilterRules
+	return runSteps(ctx, list, []*step{
+		{
+			name: "load filter rules",
+			run: func() error {
+				return loadRuleset(ctx, config, list, "TrafPolRules")
+			},
+			undo: func(ctx context.Context) error {
+				return n.UnsetFilterRules(ctx, config)
+			},
+			journal: []*cmdtmpl.Cmd{
+				undoCmd(config, list, false, config.Executables.Nft,
+					"-f", "-", "delete", "table", "inet", filterTable),
+			},
+		},
+	})
+}
+
+// UnsetFilterRules unsets the filter rules.
+func (n *Native) UnsetFilterRules(_ context.Context, _ *daemoncfg.Config) error {
+	b := &nftBatch{}
+	b.deleteTable(filterTable)
+	return b.send()
+}
+
+// SetAllowedDevices sets devices as allowed devices.
+func (n *Native) SetAllowedDevices(_ context.Context, _ *daemoncfg.Config, devices []string) error {
+	b := &nftBatch{}
+	b.flushSet(filterTable, "allowdevs")
+	b.addElements(filterTable, "allowdevs", nftIfnameElems(devices))
+	return b.send()
+}
+
+// SetAllowedHosts sets ips as allowed hosts.
+func (n *Native) SetAllowedHosts(_ context.Context, _ *daemoncfg.Config, ips []netip.Prefix) error {
+	var ips4, ips6 []netip.Prefix
+	for _, ip := range ips {
+		if ip.Addr().Is4() {
+			ips4 = append(ips4, ip)
+			continue
+		}
+		ips6 = append(ips6, ip)
+	}
+	b := &nftBatch{}
+	b.flushSet(filterTable, "allowhosts4")
+	b.flushSet(filterTable, "allowhosts6")
+	b.addElements(filterTable, "allowhosts4", nftIntervalElems(ips4))
+	b.addElements(filterTable, "allowhosts6", nftIntervalElems(ips6))
+	return b.send()
+}
+
+// SetAllowedPorts sets ports as allowed ports.
+func (n *Native) SetAllowedPorts(_ context.Context, _ *daemoncfg.Config, ports []uint16) error {
+	b := &nftBatch{}
+	b.flushSet(filterTable, "allowports")
+	b.addElements(filterTable, "allowports", nftPortElems(ports))
+	return b.send()
+}
+
+// CleanupFilterRules cleans up the filter rules after a failed shutdown.
+func (n *Native) CleanupFilterRules(ctx context.Context, config *daemoncfg.Config) error {
+	return n.UnsetFilterRules(ctx, config)
+}
+
+// setupDevice sets the MTU and addresses of the VPN device and sets it up.
+func setupDevice(config *daemoncfg.VPNConfig) error {
+	link, err := netlink.LinkByName(config.Device.Name)
+	if err != nil {
+		return fmt.Errorf("could not get device: %w", err)
+	}
+	var errs []error
+	if err := netlink.LinkSetMTU(link, config.Device.MTU); err != nil {
+		errs = append(errs, fmt.Errorf("could not set device mtu: %w", err))
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		errs = append(errs, fmt.Errorf("could not set device up: %w", err))
+	}
+	for _, prefix := range []netip.Prefix{config.IPv4, config.IPv6} {
+		if !prefix.IsValid() {
+			continue
+		}
+		if err := netlink.AddrAdd(link, &netlink.Addr{IPNet: ipNet(prefix)}); err != nil {
+			errs = append(errs, fmt.Errorf("could not add address %s: %w", prefix, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resetDevice removes the addresses of the VPN device and sets it down.
+func resetDevice(config *daemoncfg.VPNConfig) error {
+	link, err := netlink.LinkByName(config.Device.Name)
+	if err != nil {
+		return fmt.Errorf("could not get device: %w", err)
+	}
+	var errs []error
+	for _, prefix := range []netip.Prefix{config.IPv4, config.IPv6} {
+		if !prefix.IsValid() {
+			continue
+		}
+		if err := netlink.AddrDel(link, &netlink.Addr{IPNet: ipNet(prefix)}); err != nil {
+			errs = append(errs, fmt.Errorf("could not delete address %s: %w", prefix, err))
+		}
+	}
+	if err := netlink.LinkSetDown(link); err != nil {
+		errs = append(errs, fmt.Errorf("could not set device down: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// deviceUndoCmds returns the commands that revert setupDevice in the
+// journal.
+func deviceUndoCmds(config *daemoncfg.Config) []*cmdtmpl.Cmd {
+	list, ip := cmdtmpl.VPNSetupSetup, config.Executables.IP
+	device := config.VPNConfig.Device.Name
+	cmds := []*cmdtmpl.Cmd{
+		undoCmd(config, list, true, ip, "link", "set", device, "down"),
+	}
+	for _, prefix := range []netip.Prefix{config.VPNConfig.IPv4, config.VPNConfig.IPv6} {
+		if !prefix.IsValid() {
+			continue
+		}
+		cmds = append(cmds, undoCmd(config, list, true, ip,
+			"address", "delete", prefix.String(), "dev", device))
+	}
+	return cmds
+}
+
+// setupRouting sets up the split routing routes and rules of the VPN
+// device.
+func setupRouting(config *daemoncfg.Config) error {
+	sr, err := getSplitRouting(config.SplitRouting)
+	if err != nil {
+		return err
+	}
+	link, err := netlink.LinkByName(config.VPNConfig.Device.Name)
+	if err != nil {
+		return fmt.Errorf("could not get device: %w", err)
+	}
+	var errs []error
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		// default route over the VPN device in routing table
+		if err := netlink.RouteAdd(&netlink.Route{
+			LinkIndex: link.Attrs().Index,
+			Dst:       defaultRoutes[family],
+			Table:     sr.table,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("could not add default route: %w", err))
+		}
+
+		// traffic from the VPN device uses the main routing table
+		iif := netlink.NewRule()
+		iif.Family = family
+		iif.IifName = config.VPNConfig.Device.Name
+		iif.Table = unix.RT_TABLE_MAIN
+		iif.Priority = sr.prio1
+		if err := netlink.RuleAdd(iif); err != nil {
+			errs = append(errs, fmt.Errorf("could not add device rule: %w", err))
+		}
+
+		// traffic without firewall mark uses the routing table
+		fwmark := netlink.NewRule()
+		fwmark.Family = family
+		fwmark.Mark = sr.mark
+		fwmark.Invert = true
+		fwmark.Table = sr.table
+		fwmark.Priority = sr.prio2
+		if err := netlink.RuleAdd(fwmark); err != nil {
+			errs = append(errs, fmt.Errorf("could not add firewall mark rule: %w", err))
+		}
+	}
+	if err := os.WriteFile(srcValidMark, []byte("1"), 0644); err != nil {
+		errs = append(errs, fmt.Errorf("could not set src_valid_mark: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// teardownRouting tears down the split routing rules of the VPN device and
+// the VPN device.
+func teardownRouting(config *daemoncfg.Config) error {
+	sr, err := getSplitRouting(config.SplitRouting)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	if link, err := netlink.LinkByName(config.VPNConfig.Device.Name); err != nil {
+		errs = append(errs, fmt.Errorf("could not get device: %w", err))
+	} else if err := netlink.LinkSetDown(link); err != nil {
+		errs = append(errs, fmt.Errorf("could not set device down: %w", err))
+	}
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		table := netlink.NewRule()
+		table.Family = family
+		table.Table = sr.table
+		if err := netlink.RuleDel(table); err != nil {
+			errs = append(errs, fmt.Errorf("could not delete firewall mark rule: %w", err))
+		}
+
+		iif := netlink.NewRule()
+		iif.Family = family
+		iif.IifName = config.VPNConfig.Device.Name
+		iif.Table = unix.RT_TABLE_MAIN
+		if err := netlink.RuleDel(iif); err != nil {
+			errs = append(errs, fmt.Errorf("could not delete device rule: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// routingUndoCmds returns the commands that revert setupRouting in the
+// journal.
+func routingUndoCmds(config *daemoncfg.Config) []*cmdtmpl.Cmd {
+	list, ip := cmdtmpl.VPNSetupSetup, config.Executables.IP
+	sr, device := config.SplitRouting, config.VPNConfig.Device.Name
+	var cmds []*cmdtmpl.Cmd
+	for _, family := range []struct{ flag, dst string }{
+		{"-4", "0.0.0.0/0"},
+		{"-6", "::/0"},
+	} {
+		cmds = append(cmds,
+			undoCmd(config, list, true, ip, family.flag, "route", "delete",
+				family.dst, "dev", device, "table", sr.RoutingTable),
+			undoCmd(config, list, true, ip, family.flag, "rule", "delete",
+				"pref", sr.RulePriority1),
+			undoCmd(config, list, true, ip, family.flag, "rule", "delete",
+				"pref", sr.RulePriority2))
+	}
+	return cmds
+}
+
+// cleanupRouting removes the split routing rules, routes and nftables table.
+func cleanupRouting(config *daemoncfg.Config) error {
+	errs := []error{deleteRouting(config)}
+	b := &nftBatch{}
+	b.deleteTable(config.SplitRouting.NftTable)
+	if err := b.send(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// deleteRouting removes the split routing rules and routes.
+func deleteRouting(config *daemoncfg.Config) error {
+	sr, err := getSplitRouting(config.SplitRouting)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		for _, prio := range []int{sr.prio1, sr.prio2} {
+			rule := netlink.NewRule()
+			rule.Family = family
+			rule.Priority = prio
+			if err := netlink.RuleDel(rule); err != nil {
+				errs = append(errs, fmt.Errorf("could not delete rule %d: %w", prio, err))
+			}
+		}
+	}
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		routes, err := netlink.RouteListFiltered(family,
+			&netlink.Route{Table: sr.table}, netlink.RT_FILTER_TABLE)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not list routes: %w", err))
+			continue
+		}
+		for _, r := range routes {
+			if err := netlink.RouteDel(&r); err != nil {
+				errs = append(errs, fmt.Errorf("could not delete route: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetupVPN sets up the device, routing and DNS configuration of the VPN
+// with the failure mode of the VPNSetupSetup command list.
+func (n *Native) SetupVPN(ctx context.Context, config *daemoncfg.Config) error {
+	list := cmdtmpl.VPNSetupSetup
+	steps := []*step{
+		{
+			name: "set up device",
+			run: func() error {
+				return inNetNS(config.NetNS, func() error {
+					return setupDevice(config.VPNConfig)
+				})
+			},
+			undo: func(context.Context) error {
+				return inNetNS(config.NetNS, func() error {
+					return resetDevice(config.VPNConfig)
+				})
+			},
+			journal: deviceUndoCmds(config),
+		},
+		{
+			name: "load split routing rules",
+			run: func() error {
+				return loadRuleset(ctx, config, list, "SplitRoutingRules")
+			},
+			undo: func(context.Context) error {
+				b := &nftBatch{}
+				b.deleteTable(config.SplitRouting.NftTable)
+				return inNetNS(config.NetNS, b.send)
+			},
+			journal: []*cmdtmpl.Cmd{
+				undoCmd(config, list, true, config.Executables.Nft,
+					"-f", "-", "delete", "table", "inet",
+					config.SplitRouting.NftTable),
+			},
+		},
+		{
+			name: "set up routing",
+			run: func() error {
+				return inNetNS(config.NetNS, func() error {
+					return setupRouting(config)
+				})
+			},
+			undo: func(context.Context) error {
+				return inNetNS(config.NetNS, func() error {
+					return deleteRouting(config)
+				})
+			},
+			journal: routingUndoCmds(config),
+		},
+	}
+	if !config.NetNS.Enabled {
+		// DNS settings are in the resolv.conf of the network namespace
+		device := config.VPNConfig.Device.Name
+		steps = append(steps, &step{
+			name: "set dns",
+			run: func() error {
+				if err := n.SetDNS(ctx, config); err != nil {
+					return err
+				}
+				return resolvedFlush(ctx)
+			},
+			undo: func(ctx context.Context) error {
+				return resolvedRevert(ctx, device)
+			},
+			journal: []*cmdtmpl.Cmd{
+				undoCmd(config, list, false, config.Executables.Resolvectl,
+					"revert", device),
+			},
+		})
+	}
+	return runSteps(ctx, list, steps)
+}
+
+// TeardownVPN tears down the device, routing and DNS configuration of the
+// VPN.
+func (n *Native) TeardownVPN(ctx context.Context, config *daemoncfg.Config) error {
+	errs := []error{
+		inNetNS(config.NetNS, func() error {
+			if err := teardownRouting(config); err != nil {
+				return err
+			}
+			b := &nftBatch{}
+			b.deleteTable(config.SplitRouting.NftTable)
+			return b.send()
+		}),
+	}
+	if !config.NetNS.Enabled {
+		errs = append(errs,
+			resolvedRevert(ctx, config.VPNConfig.Device.Name),
+			resolvedFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// SetExcludes sets prefixes as split excludes.
+func (n *Native) SetExcludes(_ context.Context, config *daemoncfg.Config, prefixes []netip.Prefix) error {
+	var excludes4, excludes6 []netip.Prefix
+	for _, p := range prefixes {
+		if p.Addr().Is6() {
+			excludes6 = append(excludes6, p)
+			continue
+		}
+		excludes4 = append(excludes4, p)
+	}
+	table := config.SplitRouting.NftTable
+	b := &nftBatch{}
+	b.flushSet(table, "excludes4")
+	b.flushSet(table, "excludes6")
+	b.addElements(table, "excludes4", nftIntervalElems(excludes4))
+	b.addElements(table, "excludes6", nftIntervalElems(excludes6))
+	return inNetNS(config.NetNS, b.send)
+}
+
+// SetDNS sets the DNS settings of the VPN device.
+func (n *Native) SetDNS(ctx context.Context, config *daemoncfg.Config) error {
+	if config.NetNS.Enabled {
+		return nil
+	}
+	return resolvedSetDNS(ctx, config.VPNConfig.Device.Name,
+		config.DNSProxy.Address, config.VPNConfig.DNS.DefaultDomain)
+}
+
+// GetDNS returns the DNS settings of the VPN device.
+func (n *Native) GetDNS(ctx context.Context, config *daemoncfg.Config) (*DNS, error) {
+	if config.NetNS.Enabled {
+		return &DNS{}, nil
+	}
+	return resolvedGetDNS(ctx, config.VPNConfig.Device.Name)
+}
+
+// CleanupVPN cleans up the configuration of the VPN after a failed
+// shutdown.
+func (n *Native) CleanupVPN(ctx context.Context, config *daemoncfg.Config) error {
+	var errs []error
+	if !config.NetNS.Enabled {
+		errs = append(errs, resolvedRevert(ctx, config.OpenConnect.VPNDevice))
+	}
+	errs = append(errs, inNetNS(config.NetNS, func() error {
+		var errs []error
+		if link, err := netlink.LinkByName(config.OpenConnect.VPNDevice); err != nil {
+			errs = append(errs, fmt.Errorf("could not get device: %w", err))
+		} else if err := netlink.LinkDel(link); err != nil {
+			errs = append(errs, fmt.Errorf("could not delete device: %w", err))
+		}
+		return errors.Join(append(errs, cleanupRouting(config))...)
+	}))
+	return errors.Join(errs...)
+}
+
+// ResetVPN resets the DNS, address and routing configuration of the VPN and
+// keeps the VPN device.
+func (n *Native) ResetVPN(ctx context.Context, config *daemoncfg.Config) error {
+	var errs []error
+	if !config.NetNS.Enabled {
+		errs = append(errs, resolvedRevert(ctx, config.VPNConfig.Device.Name))
+	}
+	errs = append(errs, inNetNS(config.NetNS, func() error {
+		var errs []error
+		if link, err := netlink.LinkByName(config.VPNConfig.Device.Name); err != nil {
+			errs = append(errs, fmt.Errorf("could not get device: %w", err))
+		} else if addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL); err != nil {
+			errs = append(errs, fmt.Errorf("could not list addresses: %w", err))
+		} else {
+			for _, a := range addrs {
+				if err := netlink.AddrDel(link, &a); err != nil {
+					errs = append(errs, fmt.Errorf("could not delete address: %w", err))
+				}
+			}
+		}
+		return errors.Join(append(errs, cleanupRouting(config))...)
+	}))
+	return errors.Join(errs...)
+}
@@ -0,0 +1,236 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// TestIPNet tests ipNet.
+func TestIPNet(t *testing.T) {
+	for _, prefix := range []string{
+		"192.168.1.0/24",
+		"10.0.0.1/32",
+		"2001:db8::/64",
+		"::/0",
+	} {
+		if got := ipNet(netip.MustParsePrefix(prefix)).String(); got != prefix {
+			t.Errorf("got %s, want %s", got, prefix)
+		}
+	}
+}
+
+// TestGetSplitRouting tests getSplitRouting.
+func TestGetSplitRouting(t *testing.T) {
+	// valid config
+	got, err := getSplitRouting(daemoncfg.NewSplitRouting())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &splitRouting{table: 42111, prio1: 2111, prio2: 2112, mark: 42111}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// invalid configs
+	for _, f := range []func(*daemoncfg.SplitRouting){
+		func(c *daemoncfg.SplitRouting) { c.RoutingTable = "invalid" },
+		func(c *daemoncfg.SplitRouting) { c.RulePriority1 = "invalid" },
+		func(c *daemoncfg.SplitRouting) { c.RulePriority2 = "70000" },
+		func(c *daemoncfg.SplitRouting) { c.FirewallMark = "-1" },
+	} {
+		config := daemoncfg.NewSplitRouting()
+		f(config)
+		if _, err := getSplitRouting(config); err == nil {
+			t.Errorf("invalid config %v should return error", config)
+		}
+	}
+}
+
+// TestLoadRuleset tests loadRuleset.
+func TestLoadRuleset(t *testing.T) {
+	oldRunCmd := cmdtmpl.RunCmd
+	defer func() { cmdtmpl.RunCmd = oldRunCmd }()
+
+	var got []string
+	cmdtmpl.RunCmd = func(_ context.Context, cmd string, s string, arg ...string) ([]byte, []byte, error) {
+		got = append([]string{cmd}, arg...)
+		if !strings.Contains(s, "table inet oc-daemon-routing {") {
+			t.Errorf("got invalid ruleset %q", s)
+		}
+		return nil, nil, nil
+	}
+
+	ctx := context.Background()
+	config := daemoncfg.NewConfig()
+
+	// without network namespace
+	if err := loadRuleset(ctx, config, cmdtmpl.VPNSetupSetup, "SplitRoutingRules"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"nft", "-f", "-"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// with network namespace
+	config.NetNS.Enabled = true
+	if err := loadRuleset(ctx, config, cmdtmpl.VPNSetupSetup, "SplitRoutingRules"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ip", "netns", "exec", config.NetNS.Name, "nft", "-f", "-"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// failed command
+	cmdtmpl.RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
+		return nil, nil, errors.New("test error")
+	}
+	err := loadRuleset(ctx, config, cmdtmpl.VPNSetupSetup, "SplitRoutingRules")
+	var cmdErr *cmdtmpl.CmdError
+	if !errors.As(err, &cmdErr) {
+		t.Errorf("got invalid error %v", err)
+	}
+
+	// invalid template
+	if err := loadRuleset(ctx, config, cmdtmpl.VPNSetupSetup, "does not exist"); err == nil {
+		t.Error("invalid template should return error")
+	}
+}
+
+// testJournal is a journal for testing.
+type testJournal struct {
+	undo []string
+}
+
+func (j *testJournal) Add(undo *cmdtmpl.Cmd) error {
+	j.undo = append(j.undo, strings.Join(append([]string{undo.Cmd}, undo.Args...), " "))
+	return nil
+}
+
+func (j *testJournal) Remove(undo *cmdtmpl.Cmd) error {
+	j.undo = slices.DeleteFunc(j.undo, func(s string) bool {
+		return s == strings.Join(append([]string{undo.Cmd}, undo.Args...), " ")
+	})
+	return nil
+}
+
+// TestRunSteps tests runSteps.
+func TestRunSteps(t *testing.T) {
+	defer cmdtmpl.Reset()
+
+	// drop failures of previous tests
+	for len(cmdtmpl.Failures()) > 0 {
+		<-cmdtmpl.Failures()
+	}
+
+	var run, undone []string
+	getStep := func(name string, fail bool) *step {
+		return &step{
+			name: name,
+			run: func() error {
+				run = append(run, name)
+				if fail {
+					return errors.New("test error")
+				}
+				return nil
+			},
+			undo: func(context.Context) error {
+				undone = append(undone, name)
+				return nil
+			},
+			journal: []*cmdtmpl.Cmd{{Cmd: "undo", Args: []string{name}}},
+		}
+	}
+	steps := []*step{
+		getStep("1", false),
+		getStep("2", true),
+		getStep("3", false),
+	}
+
+	for _, test := range []struct {
+		onFailure string
+		run       []string
+		undone    []string
+		journal   []string
+	}{
+		{"", []string{"1", "2", "3"}, nil, []string{"undo 1", "undo 3"}},
+		{cmdtmpl.OnFailureContinue, []string{"1", "2", "3"}, nil, []string{"undo 1", "undo 3"}},
+		{cmdtmpl.OnFailureAbort, []string{"1", "2"}, nil, []string{"undo 1"}},
+		{cmdtmpl.OnFailureRollback, []string{"1", "2"}, []string{"1"}, []string{}},
+	} {
+		cl := *cmdtmpl.CommandLists[cmdtmpl.VPNSetupSetup]
+		cl.OnFailure = test.onFailure
+		cmdtmpl.CommandLists[cmdtmpl.VPNSetupSetup] = &cl
+
+		run, undone = nil, nil
+		j := &testJournal{}
+		ctx := cmdtmpl.WithJournal(context.Background(), j)
+		if err := runSteps(ctx, cmdtmpl.VPNSetupSetup, steps); err == nil {
+			t.Errorf("%q: failed step should return error", test.onFailure)
+		}
+		select {
+		case f := <-cmdtmpl.Failures():
+			if f.List != cmdtmpl.VPNSetupSetup || f.Command != "2" ||
+				f.OnFailure != test.onFailure {
+				t.Errorf("%q: got invalid failure %v", test.onFailure, f)
+			}
+		default:
+			t.Errorf("%q: failed step should be reported", test.onFailure)
+		}
+		if !reflect.DeepEqual(run, test.run) ||
+			!reflect.DeepEqual(undone, test.undone) ||
+			!reflect.DeepEqual(j.undo, test.journal) {
+			t.Errorf("%q: got %v, %v, %v, want %v, %v, %v", test.onFailure,
+				run, undone, j.undo, test.run, test.undone, test.journal)
+		}
+	}
+}
+
+// TestNativeUndoCmds tests that the journal commands of the native backend
+// match the undo commands of the VPNSetupSetup command list.
+func TestNativeUndoCmds(t *testing.T) {
+	for _, netns := range []bool{false, true} {
+		config := daemoncfg.NewConfig()
+		config.NetNS.Enabled = netns
+		config.VPNConfig.Device.Name = "oc-daemon-tun0"
+		config.VPNConfig.IPv4 = netip.MustParsePrefix("192.168.0.123/24")
+		config.VPNConfig.IPv6 = netip.MustParsePrefix("2001:db8::1/64")
+
+		cmds, err := cmdtmpl.GetCmds(cmdtmpl.VPNSetupSetup, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []string
+		for _, c := range cmds {
+			if c.Undo != nil {
+				want = append(want, strings.Join(append([]string{c.Undo.Cmd}, c.Undo.Args...), " "))
+			}
+		}
+
+		var got []string
+		native := append(deviceUndoCmds(config), routingUndoCmds(config)...)
+		native = append(native, undoCmd(config, cmdtmpl.VPNSetupSetup, true,
+			"nft", "-f", "-", "delete", "table", "inet", config.SplitRouting.NftTable))
+		if !netns {
+			native = append(native, undoCmd(config, cmdtmpl.VPNSetupSetup, false,
+				"resolvectl", "revert", "oc-daemon-tun0"))
+		}
+		for _, c := range native {
+			got = append(got, strings.Join(append([]string{c.Cmd}, c.Args...), " "))
+		}
+
+		slices.Sort(got)
+		slices.Sort(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("netns %t: got %q, want %q", netns, got, want)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package backend
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// nftMaxElems is the maximum number of set elements in one message.
+	nftMaxElems = 256
+
+	// nftRecvBufferSize is the size of the buffer for netlink replies.
+	nftRecvBufferSize = 64 * 1024
+
+	// nfgenmsgLen is the length of the nfnetlink header.
+	nfgenmsgLen = 4
+
+	// nfnetlinkV0 is the version of the nfnetlink header.
+	nfnetlinkV0 = 0
+)
+
+// nftTimeout is the timeout for replies of the kernel.
+var nftTimeout = 5 * time.Second
+
+// nlAttr returns the netlink attribute with typ and data.
+func nlAttr(typ uint16, data []byte) []byte {
+	l := unix.SizeofNlAttr + len(data)
+	b := make([]byte, (l+unix.NLA_ALIGNTO-1)&^(unix.NLA_ALIGNTO-1))
+	binary.NativeEndian.PutUint16(b[0:2], uint16(l))
+	binary.NativeEndian.PutUint16(b[2:4], typ)
+	copy(b[unix.SizeofNlAttr:], data)
+	return b
+}
+
+// nlNested returns the nested netlink attribute with typ and attrs.
+func nlNested(typ uint16, attrs ...[]byte) []byte {
+	return nlAttr(typ|unix.NLA_F_NESTED, slices.Concat(attrs...))
+}
+
+// nlString returns s as zero terminated string.
+func nlString(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+// nftElem is an element of an nftables set.
+type nftElem struct {
+	key   []byte
+	flags uint32
+}
+
+// nftIfnameElems returns the set elements for the interface names.
+func nftIfnameElems(names []string) []*nftElem {
+	elems := []*nftElem{}
+	for _, n := range names {
+		key := make([]byte, unix.IFNAMSIZ)
+		copy(key, n)
+		elems = append(elems, &nftElem{key: key})
+	}
+	return elems
+}
+
+// nftPortElems returns the set elements for the ports.
+func nftPortElems(ports []uint16) []*nftElem {
+	elems := []*nftElem{}
+	for _, p := range ports {
+		elems = append(elems, &nftElem{key: binary.BigEndian.AppendUint16(nil, p)})
+	}
+	return elems
+}
+
+// nftIntervalElems returns the set elements for the prefixes in a set with
+// interval flag. Each prefix consists of an element with its first address
+// and an element with the address after its last address that ends the
+// interval.
+func nftIntervalElems(prefixes []netip.Prefix) []*nftElem {
+	elems := []*nftElem{}
+	for _, p := range prefixes {
+		p = p.Masked()
+		elems = append(elems, &nftElem{key: p.Addr().AsSlice()})
+
+		// get last address in prefix, the end of the interval is the
+		// next address unless the prefix reaches the end of the
+		// address space
+		b := p.Addr().AsSlice()
+		for i := p.Bits(); i < len(b)*8; i++ {
+			b[i/8] |= 0x80 >> (i % 8)
+		}
+		last, _ := netip.AddrFromSlice(b)
+		if end := last.Next(); end.IsValid() {
+			elems = append(elems, &nftElem{
+				key:   end.AsSlice(),
+				flags: unix.NFT_SET_ELEM_INTERVAL_END,
+			})
+		}
+	}
+	return elems
+}
+
+// nftMsg is an nftables netlink message in a batch.
+type nftMsg struct {
+	desc  string
+	typ   uint16
+	flags uint16
+	attrs []byte
+}
+
+// nftBatch is a batch of nftables netlink messages, the kernel applies all
+// messages in the batch or none.
+type nftBatch struct {
+	msgs []*nftMsg
+}
+
+// add adds a message with nftables message type typ, netlink flags and
+// attributes attrs to the batch, desc describes the message in errors.
+func (b *nftBatch) add(desc string, typ, flags uint16, attrs ...[]byte) {
+	b.msgs = append(b.msgs, &nftMsg{
+		desc:  desc,
+		typ:   unix.NFNL_SUBSYS_NFTABLES<<8 | typ,
+		flags: unix.NLM_F_REQUEST | unix.NLM_F_ACK | flags,
+		attrs: slices.Concat(attrs...),
+	})
+}
+
+// deleteTable adds the deletion of the inet table to the batch.
+func (b *nftBatch) deleteTable(table string) {
+	b.add("delete table inet "+table, unix.NFT_MSG_DELTABLE, 0,
+		nlAttr(unix.NFTA_TABLE_NAME, nlString(table)))
+}
+
+// flushSet adds the removal of all elements in set of the inet table to the
+// batch.
+func (b *nftBatch) flushSet(table, set string) {
+	b.add("flush set inet "+table+" "+set, unix.NFT_MSG_DELSETELEM, 0,
+		nlAttr(unix.NFTA_SET_ELEM_LIST_TABLE, nlString(table)),
+		nlAttr(unix.NFTA_SET_ELEM_LIST_SET, nlString(set)))
+}
+
+// addElements adds elems to set of the inet table to the batch.
+func (b *nftBatch) addElements(table, set string, elems []*nftElem) {
+	for chunk := range slices.Chunk(elems, nftMaxElems) {
+		list := [][]byte{}
+		for _, e := range chunk {
+			attrs := [][]byte{
+				nlNested(unix.NFTA_SET_ELEM_KEY,
+					nlAttr(unix.NFTA_DATA_VALUE, e.key)),
+			}
+			if e.flags != 0 {
+				attrs = append(attrs, nlAttr(unix.NFTA_SET_ELEM_FLAGS,
+					binary.BigEndian.AppendUint32(nil, e.flags)))
+			}
+			list = append(list, nlNested(unix.NFTA_LIST_ELEM, attrs...))
+		}
+		b.add("add element inet "+table+" "+set, unix.NFT_MSG_NEWSETELEM, unix.NLM_F_CREATE,
+			nlAttr(unix.NFTA_SET_ELEM_LIST_TABLE, nlString(table)),
+			nlAttr(unix.NFTA_SET_ELEM_LIST_SET, nlString(set)),
+			nlNested(unix.NFTA_SET_ELEM_LIST_ELEMENTS, list...))
+	}
+}
+
+// nlMsg returns the netlink message with typ, flags, seq and nfgenmsg
+// header with family and resID followed by attrs.
+func nlMsg(typ, flags uint16, seq uint32, family uint8, resID uint16, attrs []byte) []byte {
+	l := unix.NLMSG_HDRLEN + nfgenmsgLen + len(attrs)
+	b := make([]byte, unix.NLMSG_HDRLEN+nfgenmsgLen, l)
+	binary.NativeEndian.PutUint32(b[0:4], uint32(l))
+	binary.NativeEndian.PutUint16(b[4:6], typ)
+	binary.NativeEndian.PutUint16(b[6:8], flags)
+	binary.NativeEndian.PutUint32(b[8:12], seq)
+	b[16] = family
+	b[17] = nfnetlinkV0
+	binary.BigEndian.PutUint16(b[18:20], resID)
+	return append(b, attrs...)
+}
+
+// serialize returns the batch as netlink messages, the sequence number of
+// the i-th message in the batch is i+1.
+func (b *nftBatch) serialize() []byte {
+	buf := nlMsg(unix.NFNL_MSG_BATCH_BEGIN, unix.NLM_F_REQUEST, 0,
+		unix.AF_UNSPEC, unix.NFNL_SUBSYS_NFTABLES, nil)
+	for i, m := range b.msgs {
+		buf = append(buf, nlMsg(m.typ, m.flags, uint32(i+1),
+			unix.NFPROTO_INET, 0, m.attrs)...)
+	}
+	return append(buf, nlMsg(unix.NFNL_MSG_BATCH_END, unix.NLM_F_REQUEST,
+		uint32(len(b.msgs)+1), unix.AF_UNSPEC, unix.NFNL_SUBSYS_NFTABLES, nil)...)
+}
+
+// parseAcks parses the acknowledgements in the netlink replies in buf and
+// returns the number of acknowledged messages and the errors of failed
+// messages.
+func (b *nftBatch) parseAcks(buf []byte) (int, []error) {
+	msgs, err := syscall.ParseNetlinkMessage(buf)
+	if err != nil {
+		return 0, []error{err}
+	}
+	acks := 0
+	var errs []error
+	for _, m := range msgs {
+		if m.Header.Type != unix.NLMSG_ERROR {
+			continue
+		}
+		acks++
+		if len(m.Data) < 4+unix.NLMSG_HDRLEN {
+			errs = append(errs, errors.New("invalid netlink error message"))
+			continue
+		}
+		errno := int32(binary.NativeEndian.Uint32(m.Data[0:4]))
+		if errno == 0 {
+			continue
+		}
+		desc := "unknown message"
+		if seq := int(binary.NativeEndian.Uint32(m.Data[12:16])); seq > 0 && seq <= len(b.msgs) {
+			desc = b.msgs[seq-1].desc
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", desc, syscall.Errno(-errno)))
+	}
+	return acks, errs
+}
+
+// send sends the batch to the kernel and waits until all messages are
+// acknowledged.
+func (b *nftBatch) send() error {
+	if len(b.msgs) == 0 {
+		return nil
+	}
+
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_NETFILTER)
+	if err != nil {
+		return fmt.Errorf("could not create nftables netlink socket: %w", err)
+	}
+	defer func() { _ = unix.Close(fd) }()
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("could not bind nftables netlink socket: %w", err)
+	}
+	tv := unix.NsecToTimeval(nftTimeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		return fmt.Errorf("could not set timeout of nftables netlink socket: %w", err)
+	}
+
+	// send batch, large batches with many set elements may not fit in
+	// the default send buffer
+	buf := b.serialize()
+	_ = unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_SNDBUFFORCE, len(buf))
+	if err := unix.Sendto(fd, buf, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("could not send nftables netlink messages: %w", err)
+	}
+
+	// wait for acknowledgements
+	acks := 0
+	var errs []error
+	rbuf := make([]byte, nftRecvBufferSize)
+	for acks < len(b.msgs) {
+		n, _, err := unix.Recvfrom(fd, rbuf, 0)
+		if err != nil {
+			return errors.Join(append(errs,
+				fmt.Errorf("could not receive nftables netlink replies: %w", err))...)
+		}
+		a, e := b.parseAcks(rbuf[:n])
+		acks += a
+		errs = append(errs, e...)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// TestNLAttr tests nlAttr.
+func TestNLAttr(t *testing.T) {
+	b := nlAttr(1, []byte{1, 2, 3, 4, 5})
+	if len(b) != 12 {
+		t.Errorf("got length %d, want 12", len(b))
+	}
+	if l := binary.NativeEndian.Uint16(b[0:2]); l != 9 {
+		t.Errorf("got attribute length %d, want 9", l)
+	}
+	if typ := binary.NativeEndian.Uint16(b[2:4]); typ != 1 {
+		t.Errorf("got attribute type %d, want 1", typ)
+	}
+
+	n := nlNested(2, b)
+	if typ := binary.NativeEndian.Uint16(n[2:4]); typ != 2|unix.NLA_F_NESTED {
+		t.Errorf("got nested attribute type %d", typ)
+	}
+}
+
+// TestNftElems tests the set element functions.
+func TestNftElems(t *testing.T) {
+	// interface names
+	ifnames := nftIfnameElems([]string{"eth0"})
+	if len(ifnames) != 1 || len(ifnames[0].key) != unix.IFNAMSIZ ||
+		string(ifnames[0].key[:5]) != "eth0\x00" {
+		t.Errorf("got invalid interface name elements %v", ifnames)
+	}
+
+	// ports
+	ports := nftPortElems([]uint16{443})
+	if len(ports) != 1 || !reflect.DeepEqual(ports[0].key, []byte{1, 187}) {
+		t.Errorf("got invalid port elements %v", ports)
+	}
+
+	// intervals
+	for _, test := range []struct {
+		prefix string
+		want   []*nftElem
+	}{
+		{"192.168.1.5/24", []*nftElem{
+			{key: []byte{192, 168, 1, 0}},
+			{key: []byte{192, 168, 2, 0}, flags: unix.NFT_SET_ELEM_INTERVAL_END},
+		}},
+		{"10.0.0.1/32", []*nftElem{
+			{key: []byte{10, 0, 0, 1}},
+			{key: []byte{10, 0, 0, 2}, flags: unix.NFT_SET_ELEM_INTERVAL_END},
+		}},
+		{"255.255.255.0/24", []*nftElem{
+			{key: []byte{255, 255, 255, 0}},
+		}},
+		{"::/0", []*nftElem{
+			{key: make([]byte, 16)},
+		}},
+	} {
+		got := nftIntervalElems([]netip.Prefix{netip.MustParsePrefix(test.prefix)})
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.prefix, got, test.want)
+		}
+	}
+}
+
+// TestNftBatchSerialize tests serialize of nftBatch.
+func TestNftBatchSerialize(t *testing.T) {
+	b := &nftBatch{}
+	b.flushSet("table", "set")
+	b.addElements("table", "set", nftPortElems(make([]uint16, nftMaxElems+1)))
+	if len(b.msgs) != 3 {
+		t.Fatalf("got %d messages, want 3", len(b.msgs))
+	}
+
+	msgs, err := syscall.ParseNetlinkMessage(b.serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 5 {
+		t.Fatalf("got %d netlink messages, want 5", len(msgs))
+	}
+	if msgs[0].Header.Type != unix.NFNL_MSG_BATCH_BEGIN ||
+		msgs[4].Header.Type != unix.NFNL_MSG_BATCH_END {
+		t.Error("batch should be framed by batch begin and end")
+	}
+	for i, m := range msgs[1:4] {
+		if m.Header.Seq != uint32(i+1) {
+			t.Errorf("got sequence number %d, want %d", m.Header.Seq, i+1)
+		}
+		if m.Header.Flags&unix.NLM_F_ACK == 0 {
+			t.Error("message should request acknowledgement")
+		}
+		if m.Data[0] != unix.NFPROTO_INET {
+			t.Errorf("got family %d, want inet", m.Data[0])
+		}
+	}
+	if msgs[2].Header.Type != unix.NFNL_SUBSYS_NFTABLES<<8|unix.NFT_MSG_NEWSETELEM {
+		t.Errorf("got invalid message type %d", msgs[2].Header.Type)
+	}
+}
+
+// TestNftBatchParseAcks tests parseAcks of nftBatch.
+func TestNftBatchParseAcks(t *testing.T) {
+	b := &nftBatch{}
+	b.deleteTable("table")
+	b.flushSet("table", "set")
+
+	ack := func(seq uint32, errno int32) []byte {
+		buf := make([]byte, unix.NLMSG_HDRLEN+4+unix.NLMSG_HDRLEN)
+		binary.NativeEndian.PutUint32(buf[0:4], uint32(len(buf)))
+		binary.NativeEndian.PutUint16(buf[4:6], unix.NLMSG_ERROR)
+		binary.NativeEndian.PutUint32(buf[16:20], uint32(errno))
+		binary.NativeEndian.PutUint32(buf[28:32], seq)
+		return buf
+	}
+
+	// successful messages
+	acks, errs := b.parseAcks(append(ack(1, 0), ack(2, 0)...))
+	if acks != 2 || len(errs) != 0 {
+		t.Errorf("got %d acks, errors %v", acks, errs)
+	}
+
+	// failed message
+	acks, errs = b.parseAcks(ack(2, -int32(syscall.ENOENT)))
+	if acks != 1 || len(errs) != 1 {
+		t.Fatalf("got %d acks, errors %v", acks, errs)
+	}
+	if !errors.Is(errs[0], syscall.ENOENT) {
+		t.Errorf("got invalid error %v", errs[0])
+	}
+	want := "flush set inet table set: " + syscall.ENOENT.Error()
+	if errs[0].Error() != want {
+		t.Errorf("got %q, want %q", errs[0], want)
+	}
+
+	// truncated error message
+	buf := ack(1, 0)[:unix.NLMSG_HDRLEN+4]
+	binary.NativeEndian.PutUint32(buf[0:4], uint32(len(buf)))
+	if _, errs := b.parseAcks(buf); len(errs) != 1 {
+		t.Errorf("got errors %v, want 1 error", errs)
+	}
+}
@@ -0,0 +1,196 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// object path, destination, interfaces of systemd-resolved.
+	resolvedPath    = "/org/freedesktop/resolve1"
+	resolvedDest    = "org.freedesktop.resolve1"
+	resolvedManager = resolvedDest + ".Manager"
+	resolvedLink    = resolvedDest + ".Link"
+)
+
+// resolvedAddress is a DNS server address in the D-Bus API of
+// systemd-resolved.
+type resolvedAddress struct {
+	Family  int32
+	Address []byte
+	Port    uint16
+	Name    string
+}
+
+// resolvedDomain is a DNS domain in the D-Bus API of systemd-resolved.
+type resolvedDomain struct {
+	Domain      string
+	RoutingOnly bool
+}
+
+// dbusSystemBus is dbus.SystemBus for testing.
+var dbusSystemBus = dbus.SystemBus
+
+// resolvedCall calls method of systemd-resolved with args.
+func resolvedCall(ctx context.Context, method string, args ...any) *dbus.Call {
+	conn, err := dbusSystemBus()
+	if err != nil {
+		return &dbus.Call{Err: err}
+	}
+	return conn.Object(resolvedDest, resolvedPath).
+		CallWithContext(ctx, resolvedManager+"."+method, 0, args...)
+}
+
+// resolvedLinkIndex returns the interface index of the device with name.
+func resolvedLinkIndex(name string) (int32, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return 0, err
+	}
+	return int32(iface.Index), nil
+}
+
+// resolvedAddresses returns the DNS server addresses for the DNS server
+// with address and port in server.
+func resolvedAddresses(server string) ([]resolvedAddress, error) {
+	addrPort, err := netip.ParseAddrPort(server)
+	if err != nil {
+		return nil, err
+	}
+	family := int32(unix.AF_INET)
+	if addrPort.Addr().Is6() {
+		family = unix.AF_INET6
+	}
+	return []resolvedAddress{{
+		Family:  family,
+		Address: addrPort.Addr().AsSlice(),
+		Port:    addrPort.Port(),
+	}}, nil
+}
+
+// resolvedDomains returns the DNS domains for the space separated domains.
+// The routing only domain "." is always added, so all DNS queries are sent
+// over the VPN device.
+func resolvedDomains(domains string) []resolvedDomain {
+	d := []resolvedDomain{}
+	for _, domain := range strings.Fields(domains) {
+		d = append(d, resolvedDomain{Domain: domain})
+	}
+	return append(d, resolvedDomain{Domain: ".", RoutingOnly: true})
+}
+
+// resolvedDNS returns the DNS settings for the DNS server addresses,
+// domains and default route setting in the format of resolvectl status.
+func resolvedDNS(addresses []resolvedAddress, domains []resolvedDomain, defaultRoute bool) *DNS {
+	dns := &DNS{Protocols: []string{"-DefaultRoute"}}
+	if defaultRoute {
+		dns.Protocols = []string{"+DefaultRoute"}
+	}
+	for _, a := range addresses {
+		addr, ok := netip.AddrFromSlice(a.Address)
+		if !ok {
+			continue
+		}
+		if a.Port == 0 {
+			dns.Servers = append(dns.Servers, addr.String())
+			continue
+		}
+		dns.Servers = append(dns.Servers, netip.AddrPortFrom(addr, a.Port).String())
+	}
+	for _, d := range domains {
+		if d.RoutingOnly {
+			dns.Domains = append(dns.Domains, "~"+d.Domain)
+			continue
+		}
+		dns.Domains = append(dns.Domains, d.Domain)
+	}
+	return dns
+}
+
+// resolvedSetDNS sets the DNS server, domains and default route of the
+// device with name.
+func resolvedSetDNS(ctx context.Context, name, server, domains string) error {
+	index, err := resolvedLinkIndex(name)
+	if err != nil {
+		return err
+	}
+	addresses, err := resolvedAddresses(server)
+	if err != nil {
+		return err
+	}
+	if err := resolvedCall(ctx, "SetLinkDNSEx", index, addresses).Err; err != nil {
+		return fmt.Errorf("could not set DNS servers: %w", err)
+	}
+	if err := resolvedCall(ctx, "SetLinkDomains", index, resolvedDomains(domains)).Err; err != nil {
+		return fmt.Errorf("could not set DNS domains: %w", err)
+	}
+	if err := resolvedCall(ctx, "SetLinkDefaultRoute", index, true).Err; err != nil {
+		return fmt.Errorf("could not set DNS default route: %w", err)
+	}
+	return nil
+}
+
+// resolvedGetDNS returns the DNS settings of the device with name.
+func resolvedGetDNS(ctx context.Context, name string) (*DNS, error) {
+	index, err := resolvedLinkIndex(name)
+	if err != nil {
+		return nil, err
+	}
+	var path dbus.ObjectPath
+	if err := resolvedCall(ctx, "GetLink", index).Store(&path); err != nil {
+		return nil, fmt.Errorf("could not get link: %w", err)
+	}
+	conn, err := dbusSystemBus()
+	if err != nil {
+		return nil, err
+	}
+	link := conn.Object(resolvedDest, path)
+
+	var addresses []resolvedAddress
+	var domains []resolvedDomain
+	var defaultRoute bool
+	for prop, value := range map[string]any{
+		"DNSEx":        &addresses,
+		"Domains":      &domains,
+		"DefaultRoute": &defaultRoute,
+	} {
+		v, err := link.GetProperty(resolvedLink + "." + prop)
+		if err != nil {
+			return nil, fmt.Errorf("could not get property %s: %w", prop, err)
+		}
+		if err := v.Store(value); err != nil {
+			return nil, fmt.Errorf("could not parse property %s: %w", prop, err)
+		}
+	}
+	return resolvedDNS(addresses, domains, defaultRoute), nil
+}
+
+// resolvedRevert reverts the DNS settings of the device with name.
+func resolvedRevert(ctx context.Context, name string) error {
+	index, err := resolvedLinkIndex(name)
+	if err != nil {
+		return err
+	}
+	if err := resolvedCall(ctx, "RevertLink", index).Err; err != nil {
+		return fmt.Errorf("could not revert DNS settings: %w", err)
+	}
+	return nil
+}
+
+// resolvedFlush flushes the DNS caches and resets the learnt server
+// features.
+func resolvedFlush(ctx context.Context) error {
+	if err := resolvedCall(ctx, "FlushCaches").Err; err != nil {
+		return fmt.Errorf("could not flush DNS caches: %w", err)
+	}
+	if err := resolvedCall(ctx, "ResetServerFeatures").Err; err != nil {
+		return fmt.Errorf("could not reset DNS server features: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// TestResolvedAddresses tests resolvedAddresses.
+func TestResolvedAddresses(t *testing.T) {
+	// invalid server
+	if _, err := resolvedAddresses("127.0.0.1"); err == nil {
+		t.Error("server without port should return error")
+	}
+
+	// ipv4 and ipv6 servers
+	for server, want := range map[string]resolvedAddress{
+		"127.0.0.1:4253": {Family: unix.AF_INET, Address: []byte{127, 0, 0, 1}, Port: 4253},
+		"[::1]:53": {
+			Family:  unix.AF_INET6,
+			Address: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+			Port:    53,
+		},
+	} {
+		got, err := resolvedAddresses(server)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, []resolvedAddress{want}) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+// TestResolvedDomains tests resolvedDomains.
+func TestResolvedDomains(t *testing.T) {
+	got := resolvedDomains("example.com  test.example.com")
+	want := []resolvedDomain{
+		{Domain: "example.com"},
+		{Domain: "test.example.com"},
+		{Domain: ".", RoutingOnly: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestResolvedDNS tests resolvedDNS.
+func TestResolvedDNS(t *testing.T) {
+	addresses := []resolvedAddress{
+		{Family: unix.AF_INET, Address: []byte{127, 0, 0, 1}, Port: 4253},
+		{Family: unix.AF_INET, Address: []byte{192, 168, 1, 1}},
+		{Family: unix.AF_INET, Address: []byte{1}},
+	}
+	domains := resolvedDomains("example.com")
+
+	got := resolvedDNS(addresses, domains, true)
+	want := &DNS{
+		Protocols: []string{"+DefaultRoute"},
+		Servers:   []string{"127.0.0.1:4253", "192.168.1.1"},
+		Domains:   []string{"example.com", "~."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got = resolvedDNS(nil, nil, false)
+	want = &DNS{Protocols: []string{"-DefaultRoute"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
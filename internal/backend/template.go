@@ -0,0 +1,159 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// Template is the backend that runs the command lists, it can be
+// customized with the command lists and templates files.
+type Template struct{}
+
+// SetFilterRules sets the filter rules.
+func (t *Template) SetFilterRules(ctx context.Context, config *daemoncfg.Config) error {
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.TrafPolSetFilterRules, config)
+}
+
+// UnsetFilterRules unsets the filter rules.
+func (t *Template) UnsetFilterRules(ctx context.Context, config *daemoncfg.Config) error {
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.TrafPolUnsetFilterRules, config)
+}
+
+// SetAllowedDevices sets devices as allowed devices.
+func (t *Template) SetAllowedDevices(ctx context.Context, config *daemoncfg.Config, devices []string) error {
+	data := &struct {
+		daemoncfg.Config
+		Devices []string
+	}{
+		Config:  *config,
+		Devices: devices,
+	}
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.TrafPolSetAllowedDevices, data)
+}
+
+// SetAllowedHosts sets ips as allowed hosts.
+func (t *Template) SetAllowedHosts(ctx context.Context, config *daemoncfg.Config, ips []netip.Prefix) error {
+	data := &struct {
+		daemoncfg.Config
+		AllowedIPs []netip.Prefix
+	}{
+		Config:     *config,
+		AllowedIPs: ips,
+	}
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.TrafPolSetAllowedHosts, data)
+}
+
+// SetAllowedPorts sets ports as allowed ports.
+func (t *Template) SetAllowedPorts(ctx context.Context, config *daemoncfg.Config, ports []uint16) error {
+	data := &struct {
+		daemoncfg.Config
+		Ports []uint16
+	}{
+		Config: *config,
+		Ports:  ports,
+	}
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.TrafPolSetAllowedPorts, data)
+}
+
+// CleanupFilterRules cleans up the filter rules after a failed shutdown.
+func (t *Template) CleanupFilterRules(ctx context.Context, config *daemoncfg.Config) error {
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.TrafPolCleanup, config)
+}
+
+// SetupVPN sets up the device, routing and DNS configuration of the VPN.
+func (t *Template) SetupVPN(ctx context.Context, config *daemoncfg.Config) error {
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.VPNSetupSetup, config)
+}
+
+// TeardownVPN tears down the device, routing and DNS configuration of the
+// VPN.
+func (t *Template) TeardownVPN(ctx context.Context, config *daemoncfg.Config) error {
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.VPNSetupTeardown, config)
+}
+
+// SetExcludes sets prefixes as split excludes.
+func (t *Template) SetExcludes(ctx context.Context, config *daemoncfg.Config, prefixes []netip.Prefix) error {
+	data := &struct {
+		daemoncfg.Config
+		Addresses []netip.Prefix
+	}{
+		Config:    *config,
+		Addresses: prefixes,
+	}
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.VPNSetupSetExcludes, data)
+}
+
+// SetDNS sets the DNS settings of the VPN device.
+func (t *Template) SetDNS(ctx context.Context, config *daemoncfg.Config) error {
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.VPNSetupSetDNS, config)
+}
+
+// GetDNS returns the DNS settings of the VPN device.
+func (t *Template) GetDNS(ctx context.Context, config *daemoncfg.Config) (*DNS, error) {
+	cmds, err := cmdtmpl.GetCmds(cmdtmpl.VPNSetupGetDNS, config)
+	if err != nil {
+		return nil, fmt.Errorf("could not get %s commands: %w", cmdtmpl.VPNSetupGetDNS, err)
+	}
+	var stdout []byte
+	for _, c := range cmds {
+		sout, serr, err := c.Run(ctx)
+		if err != nil {
+			return nil, &cmdtmpl.CmdError{
+				List:   cmdtmpl.VPNSetupGetDNS,
+				Cmd:    c.Cmd,
+				Args:   c.Args,
+				Stdin:  c.Stdin,
+				Stdout: string(sout),
+				Stderr: string(serr),
+				Err:    err,
+			}
+		}
+		// collect output
+		stdout = slices.Concat(stdout, sout)
+	}
+	return parseResolvectlStatus(string(stdout)), nil
+}
+
+// parseResolvectlStatus parses the DNS settings in the output of
+// resolvectl status.
+func parseResolvectlStatus(status string) *DNS {
+	dns := &DNS{}
+	for line := range strings.Lines(status) {
+		// try to find separator ":"
+		before, after, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+
+		// get fields after separator
+		f := strings.Fields(after)
+
+		switch before {
+		case "Protocols":
+			dns.Protocols = f
+		case "DNS Servers":
+			dns.Servers = f
+		case "DNS Domain":
+			dns.Domains = f
+		}
+	}
+	return dns
+}
+
+// CleanupVPN cleans up the configuration of the VPN after a failed
+// shutdown.
+func (t *Template) CleanupVPN(ctx context.Context, config *daemoncfg.Config) error {
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.VPNSetupCleanup, config)
+}
+
+// ResetVPN resets the DNS, address and routing configuration of the VPN and
+// keeps the VPN device.
+func (t *Template) ResetVPN(ctx context.Context, config *daemoncfg.Config) error {
+	return cmdtmpl.RunCmds(ctx, cmdtmpl.VPNSetupReset, config)
+}
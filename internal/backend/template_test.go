@@ -0,0 +1,136 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// TestTemplate tests the command lists run by Template.
+func TestTemplate(t *testing.T) {
+	oldRunCmd := cmdtmpl.RunCmd
+	defer func() { cmdtmpl.RunCmd = oldRunCmd }()
+
+	got := []string{}
+	cmdtmpl.RunCmd = func(_ context.Context, cmd string, s string, arg ...string) ([]byte, []byte, error) {
+		got = append(got, strings.TrimSpace(cmd+" "+strings.Join(arg, " ")+" "+s))
+		return nil, nil, nil
+	}
+
+	ctx := context.Background()
+	config := daemoncfg.NewConfig()
+	config.VPNConfig.Device.Name = "tun0"
+	b := &Template{}
+	for _, f := range []func() error{
+		func() error { return b.UnsetFilterRules(ctx, config) },
+		func() error { return b.SetAllowedDevices(ctx, config, []string{"eth0"}) },
+		func() error {
+			return b.SetAllowedHosts(ctx, config, []netip.Prefix{
+				netip.MustParsePrefix("192.168.1.1/32"),
+			})
+		},
+		func() error { return b.SetAllowedPorts(ctx, config, []uint16{80}) },
+		func() error { return b.CleanupFilterRules(ctx, config) },
+		func() error {
+			return b.SetExcludes(ctx, config, []netip.Prefix{
+				netip.MustParsePrefix("2001:db8::1/128"),
+			})
+		},
+		func() error { return b.ResetVPN(ctx, config) },
+	} {
+		if err := f(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	want := []string{
+		"nft -f - delete table inet oc-daemon-filter",
+		"nft -f - flush set inet oc-daemon-filter allowdevs\n" +
+			"add element inet oc-daemon-filter allowdevs { eth0 }",
+		"nft -f - flush set inet oc-daemon-filter allowhosts4\n" +
+			"flush set inet oc-daemon-filter allowhosts6\n" +
+			"add element inet oc-daemon-filter allowhosts4 { 192.168.1.1/32 }",
+		"nft -f - flush set inet oc-daemon-filter allowports\n" +
+			"add element inet oc-daemon-filter allowports { 80 }",
+		"nft -f - delete table inet oc-daemon-filter",
+		"nft -f - flush set inet oc-daemon-routing excludes4\n" +
+			"flush set inet oc-daemon-routing excludes6\n" +
+			"add element inet oc-daemon-routing excludes6 { 2001:db8::1/128 }",
+		"resolvectl revert tun0",
+		"ip address flush dev tun0",
+		"ip -4 rule delete pref 2111",
+		"ip -4 rule delete pref 2112",
+		"ip -6 rule delete pref 2111",
+		"ip -6 rule delete pref 2112",
+		"ip -4 route flush table 42111",
+		"ip -6 route flush table 42111",
+		"nft -f - delete table inet oc-daemon-routing",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestTemplateErrors tests errors of failed commands in Template.
+func TestTemplateErrors(t *testing.T) {
+	oldRunCmd := cmdtmpl.RunCmd
+	defer func() { cmdtmpl.RunCmd = oldRunCmd }()
+
+	ctx := context.Background()
+	config := daemoncfg.NewConfig()
+	b := &Template{}
+
+	// failed commands
+	cmdtmpl.RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
+		return nil, []byte("stderr"), errors.New("test error")
+	}
+	err := b.CleanupVPN(ctx, config)
+	var cmdErr *cmdtmpl.CmdError
+	if !errors.As(err, &cmdErr) || cmdErr.List != cmdtmpl.VPNSetupCleanup {
+		t.Errorf("got invalid error %v", err)
+	}
+	if _, err := b.GetDNS(ctx, config); !errors.As(err, &cmdErr) {
+		t.Errorf("got invalid error %v", err)
+	}
+
+	// canceled commands
+	cmdtmpl.RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
+		return nil, nil, context.Canceled
+	}
+	if err := b.TeardownVPN(ctx, config); err != nil {
+		t.Errorf("canceled commands should not return error: %v", err)
+	}
+}
+
+// TestTemplateGetDNS tests GetDNS of Template.
+func TestTemplateGetDNS(t *testing.T) {
+	oldRunCmd := cmdtmpl.RunCmd
+	defer func() { cmdtmpl.RunCmd = oldRunCmd }()
+
+	cmdtmpl.RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
+		return []byte("Link 3 (tun0)\n" +
+			"    Current Scopes: DNS\n" +
+			"         Protocols: +DefaultRoute -LLMNR\n" +
+			"       DNS Servers: 127.0.0.1:4253\n" +
+			"        DNS Domain: example.com ~.\n"), nil, nil
+	}
+
+	got, err := (&Template{}).GetDNS(context.Background(), daemoncfg.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DNS{
+		Protocols: []string{"+DefaultRoute", "-LLMNR"},
+		Servers:   []string{"127.0.0.1:4253"},
+		Domains:   []string{"example.com", "~."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -2,11 +2,18 @@
 package client
 
 import (
+	jsonenc "encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
 	"github.com/telekom-mms/oc-daemon/pkg/client"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnhistory"
 	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
 	"github.com/telekom-mms/oc-daemon/pkg/xmlprofile"
 )
@@ -99,6 +106,42 @@ func disconnectVPN() error {
 	return nil
 }
 
+// remediateCaptivePortal opens the network for captive portal login.
+func remediateCaptivePortal() error {
+	// create client
+	c, err := clientNewClient(config)
+	if err != nil {
+		return fmt.Errorf("error creating client: %w", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	// remediate captive portal
+	err = c.RemediateCaptivePortal()
+	if err != nil {
+		return fmt.Errorf("error opening network for captive portal login: %w", err)
+	}
+
+	return nil
+}
+
+// getConnectionStatus returns the trusted network, connection and openconnect
+// state of the VPN connection in the config. Named VPN connections are not
+// affected by trusted networks.
+func getConnectionStatus(status *vpnstatus.Status) (trusted bool,
+	state vpnstatus.ConnectionState, running vpnstatus.OCRunning, err error) {
+	if config == nil || config.Connection == "" {
+		return status.TrustedNetwork.Trusted(), status.ConnectionState,
+			status.OCRunning, nil
+	}
+	conn := status.GetConnection(config.Connection)
+	if conn == nil {
+		return false, vpnstatus.ConnectionStateUnknown,
+			vpnstatus.OCRunningUnknown,
+			fmt.Errorf("unknown VPN connection %s", config.Connection)
+	}
+	return false, conn.ConnectionState, conn.OCRunning, nil
+}
+
 // reconnectVPN reconnects to the VPN.
 func reconnectVPN() error {
 	// create client
@@ -113,9 +156,13 @@ func reconnectVPN() error {
 	if err != nil {
 		return fmt.Errorf("error reconnecting to VPN: %w", err)
 	}
+	_, _, running, err := getConnectionStatus(status)
+	if err != nil {
+		return fmt.Errorf("error reconnecting to VPN: %w", err)
+	}
 
 	// disconnect if needed
-	if status.OCRunning.Running() {
+	if running.Running() {
 		// send disconnect request
 		if err := disconnectVPN(); err != nil {
 			return err
@@ -129,10 +176,14 @@ func reconnectVPN() error {
 		if err != nil {
 			return fmt.Errorf("error reconnecting to VPN: %w", err)
 		}
+		trusted, state, running, err := getConnectionStatus(status)
+		if err != nil {
+			return fmt.Errorf("error reconnecting to VPN: %w", err)
+		}
 
-		if !status.TrustedNetwork.Trusted() &&
-			status.ConnectionState.Disconnected() &&
-			!status.OCRunning.Running() {
+		if !trusted &&
+			state.Disconnected() &&
+			!running.Running() {
 			// authenticate and connect
 			return connectVPN()
 		}
@@ -165,6 +216,9 @@ func printStatus(status *vpnstatus.Status) error {
 	fmt.Printf("Connection State: %s\n", status.ConnectionState)
 	fmt.Printf("IP:               %s\n", status.IP)
 	fmt.Printf("Device:           %s\n", status.Device)
+	if status.NetNS != "" {
+		fmt.Printf("Network Namespace: %s\n", status.NetNS)
+	}
 	fmt.Printf("Current Server:   %s\n", status.Server)
 	fmt.Printf("Server IP:        %s\n", status.ServerIP)
 
@@ -181,6 +235,13 @@ func printStatus(status *vpnstatus.Status) error {
 	}
 
 	fmt.Printf("OC Running:       %s\n", status.OCRunning)
+	fmt.Printf("Disconnect:       %s\n", status.DisconnectPolicy)
+
+	fmt.Printf("Connections:\n")
+	for _, conn := range status.Connections {
+		fmt.Printf("  - %s: %s, %s, %s, %s\n", conn.Name,
+			conn.ConnectionState, conn.Server, conn.Device, conn.IP)
+	}
 
 	// verbose output
 	if !verbose {
@@ -193,6 +254,7 @@ func printStatus(status *vpnstatus.Status) error {
 	fmt.Printf("Captive Portal:   %s\n", status.CaptivePortal)
 	fmt.Printf("TND State:        %s\n", status.TNDState)
 	fmt.Printf("TND Servers:      %s\n", status.TNDServers)
+	fmt.Printf("TND Matches:      %s\n", status.TNDMatches)
 
 	if status.VPNConfig == nil {
 		fmt.Printf("VPN Config:\n")
@@ -200,6 +262,35 @@ func printStatus(status *vpnstatus.Status) error {
 		fmt.Printf("VPN Config:       %+v\n", *status.VPNConfig)
 	}
 
+	fmt.Printf("Reconnect Attempts: %d\n", status.ReconnectAttempts)
+	if status.ReconnectAt <= 0 {
+		fmt.Printf("Reconnect At:\n")
+	} else {
+		reconnectAt := time.Unix(status.ReconnectAt, 0)
+		fmt.Printf("Reconnect At:     %s\n", reconnectAt)
+	}
+	fmt.Printf("Reconnect Give Up Reason: %s\n", status.ReconnectGiveUpReason)
+
+	if status.IdleTimeoutAt <= 0 {
+		fmt.Printf("Idle Timeout At:\n")
+	} else {
+		idleTimeoutAt := time.Unix(status.IdleTimeoutAt, 0)
+		fmt.Printf("Idle Timeout At:  %s\n", idleTimeoutAt)
+	}
+
+	fmt.Printf("Resume Action:    %s\n", status.ResumeAction)
+	fmt.Printf("Resume Outcome:   %s\n", status.ResumeOutcome)
+
+	fmt.Printf("Connect Failure Policy: %s\n", status.ConnectFailureState)
+	fmt.Printf("Connect Failures: %d\n", status.ConnectFailures)
+
+	fmt.Printf("Network Policy Action:   %s\n", status.NetworkPolicyAction)
+	fmt.Printf("Network Policy Decision: %s\n", status.NetworkPolicyDecision)
+
+	if status.CommandListError != "" {
+		fmt.Printf("Command List Error: %s\n", status.CommandListError)
+	}
+
 	return nil
 }
 
@@ -240,6 +331,156 @@ func dumpState() error {
 	return nil
 }
 
+// printTrace prints the command trace on the command line.
+func printTrace(trace []*cmdtmpl.TraceEntry) error {
+	if json {
+		// print trace as json
+		j, err := jsonenc.Marshal(trace)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(j))
+		return nil
+	}
+
+	// print trace entries
+	for i, e := range trace {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Time:         %s\n", e.Time)
+		fmt.Printf("List:         %s\n", e.List)
+		fmt.Printf("Command:      %s\n", strings.Join(append([]string{e.Command}, e.Args...), " "))
+		fmt.Printf("Stdin Digest: %s\n", e.StdinDigest)
+		fmt.Printf("Exit Code:    %d\n", e.ExitCode)
+		fmt.Printf("Error:        %s\n", e.Error)
+		fmt.Printf("Duration:     %s\n", e.Duration)
+		fmt.Printf("Stderr:       %s\n", strings.TrimSpace(e.Stderr))
+	}
+
+	return nil
+}
+
+// getTrace gets the command trace from the internal state of the daemon and
+// prints the entries that match the trace filter.
+func getTrace() error {
+	// create client
+	c, err := clientNewClient(config)
+	if err != nil {
+		return fmt.Errorf("error creating client: %w", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	// get trace
+	state, err := c.DumpState()
+	if err != nil {
+		return fmt.Errorf("error getting state: %w", err)
+	}
+	s := struct{ CommandTrace []*cmdtmpl.TraceEntry }{}
+	if err := jsonenc.Unmarshal([]byte(state), &s); err != nil {
+		return fmt.Errorf("error parsing state: %w", err)
+	}
+
+	// print trace
+	return printTrace(cmdtmpl.FilterTrace(s.CommandTrace, traceList, traceCommand, traceFailed))
+}
+
+// execCommand is exec.Command for testing.
+var execCommand = exec.Command
+
+// execNetNS runs the command in args in the network namespace of the VPN
+// tunnel.
+func execNetNS(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no command to run in network namespace")
+	}
+
+	// create client
+	c, err := clientNewClient(config)
+	if err != nil {
+		return fmt.Errorf("error creating client: %w", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	// get status
+	status, err := c.Query()
+	if err != nil {
+		return fmt.Errorf("error getting status: %w", err)
+	}
+	if status.NetNS == "" {
+		return errors.New("VPN tunnel is not in a network namespace")
+	}
+
+	// run command in network namespace
+	parameters := append([]string{"netns", "exec", status.NetNS}, args...)
+	command := execCommand("ip", parameters...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	return command.Run()
+}
+
+// formatUnixTime returns the unix time t formatted as string or an empty
+// string if t is not set.
+func formatUnixTime(t int64) string {
+	if t <= 0 {
+		return ""
+	}
+	return time.Unix(t, 0).String()
+}
+
+// printHistory prints the connection history on the command line.
+func printHistory(history *vpnhistory.History) error {
+	if json {
+		// print history as json
+		j, err := history.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(j))
+		return nil
+	}
+
+	// print sessions
+	for i, s := range history.Sessions {
+		if i > 0 {
+			fmt.Println()
+		}
+		if s.Connection != "" {
+			fmt.Printf("Connection:       %s\n", s.Connection)
+		}
+		fmt.Printf("Server:           %s\n", s.Server)
+		fmt.Printf("Server IP:        %s\n", s.ServerIP)
+		fmt.Printf("IP:               %s\n", s.IP)
+		fmt.Printf("Started At:       %s\n", formatUnixTime(s.StartedAt))
+		fmt.Printf("Connected At:     %s\n", formatUnixTime(s.ConnectedAt))
+		fmt.Printf("Disconnected At:  %s\n", formatUnixTime(s.DisconnectedAt))
+		fmt.Printf("Disconnect Cause: %s\n", s.Cause)
+		fmt.Printf("Exit Code:        %d\n", s.ExitCode)
+	}
+
+	return nil
+}
+
+// getHistory gets the connection history from the daemon.
+func getHistory() error {
+	// create client
+	c, err := clientNewClient(config)
+	if err != nil {
+		return fmt.Errorf("error creating client: %w", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	// get history
+	history, err := c.GetHistory()
+	if err != nil {
+		return fmt.Errorf("error getting history: %w", err)
+	}
+
+	// print history
+	return printHistory(history)
+}
+
 // monitor subscribes to VPN status updates from the daemon and displays them.
 func monitor() error {
 	// create client
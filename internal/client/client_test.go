@@ -2,11 +2,14 @@ package client
 
 import (
 	"errors"
+	"os/exec"
+	"slices"
 	"testing"
 
 	"github.com/telekom-mms/oc-daemon/pkg/client"
 	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
 	"github.com/telekom-mms/oc-daemon/pkg/vpnconfig"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnhistory"
 	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
 )
 
@@ -16,9 +19,12 @@ type testClient struct {
 	status  *vpnstatus.Status
 	dumpErr error
 	dumpSta string
+	histErr error
+	history *vpnhistory.History
 	authErr error
 	connErr error
 	discErr error
+	remeErr error
 	subsErr error
 	subsCha chan *vpnstatus.Status
 }
@@ -32,10 +38,13 @@ func (t *testClient) GetLogin() *logininfo.LoginInfo             { return nil }
 func (t *testClient) Ping() error                                { return nil }
 func (t *testClient) Query() (*vpnstatus.Status, error)          { return t.status, t.querErr }
 func (t *testClient) Subscribe() (chan *vpnstatus.Status, error) { return t.subsCha, t.subsErr }
+func (t *testClient) Events() (chan *client.Event, error)        { return nil, nil }
 func (t *testClient) Authenticate() error                        { return t.authErr }
 func (t *testClient) Connect() error                             { return t.connErr }
 func (t *testClient) Disconnect() error                          { return t.discErr }
 func (t *testClient) DumpState() (string, error)                 { return t.dumpSta, t.dumpErr }
+func (t *testClient) GetHistory() (*vpnhistory.History, error)   { return t.history, t.histErr }
+func (t *testClient) RemediateCaptivePortal() error              { return t.remeErr }
 func (t *testClient) Close() error                               { return nil }
 
 // TestListServers tests listServers.
@@ -115,6 +124,38 @@ func TestDisconnectVPN(t *testing.T) {
 	}
 }
 
+// TestRemediateCaptivePortal tests remediateCaptivePortal.
+func TestRemediateCaptivePortal(t *testing.T) {
+	defer func() { clientNewClient = client.NewClient }()
+
+	// test with client error
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return nil, errors.New("test error")
+	}
+
+	if err := remediateCaptivePortal(); err == nil {
+		t.Error("client error should return error")
+	}
+
+	// test with remediate error
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return &testClient{remeErr: errors.New("test error")}, nil
+	}
+
+	if err := remediateCaptivePortal(); err == nil {
+		t.Error("remediate error should return error")
+	}
+
+	// test without error
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return &testClient{}, nil
+	}
+
+	if err := remediateCaptivePortal(); err != nil {
+		t.Error(err)
+	}
+}
+
 // TestReconnectVPN tests reconnectVPN.
 func TestReconnectVPN(t *testing.T) {
 	defer func() { clientNewClient = client.NewClient }()
@@ -139,6 +180,34 @@ func TestReconnectVPN(t *testing.T) {
 	if err := reconnectVPN(); err == nil {
 		t.Error("oc already running should return error")
 	}
+
+	// test with unknown named connection
+	config = client.NewConfig()
+	config.Connection = "lab"
+	defer func() { config = nil }()
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return &testClient{status: vpnstatus.New()}, nil
+	}
+
+	if err := reconnectVPN(); err == nil {
+		t.Error("unknown connection should return error")
+	}
+
+	// test with named connection not running in trusted network
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		status := vpnstatus.New()
+		status.TrustedNetwork = vpnstatus.TrustedNetworkTrusted
+		status.Connections = []*vpnstatus.Connection{{
+			Name:            "lab",
+			ConnectionState: vpnstatus.ConnectionStateDisconnected,
+			OCRunning:       vpnstatus.OCRunningNotRunning,
+		}}
+		return &testClient{status: status}, nil
+	}
+
+	if err := reconnectVPN(); err != nil {
+		t.Error(err)
+	}
 }
 
 // TestGetStatus tests getStatus.
@@ -240,6 +309,170 @@ func TestDumpState(t *testing.T) {
 	}
 }
 
+// TestExecNetNS tests execNetNS.
+func TestExecNetNS(t *testing.T) {
+	defer func() { clientNewClient = client.NewClient }()
+	defer func() { execCommand = exec.Command }()
+	var name string
+	var args []string
+	execCommand = func(n string, a ...string) *exec.Cmd {
+		name = n
+		args = a
+		return exec.Command("true")
+	}
+
+	// test without command
+	if err := execNetNS(nil); err == nil {
+		t.Error("missing command should return error")
+	}
+
+	// test with client error
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return nil, errors.New("test error")
+	}
+
+	if err := execNetNS([]string{"ping"}); err == nil {
+		t.Error("client error should return error")
+	}
+
+	// test with query error
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return &testClient{querErr: errors.New("test error")}, nil
+	}
+
+	if err := execNetNS([]string{"ping"}); err == nil {
+		t.Error("query error should return error")
+	}
+
+	// test without network namespace
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return &testClient{status: vpnstatus.New()}, nil
+	}
+
+	if err := execNetNS([]string{"ping"}); err == nil {
+		t.Error("missing network namespace should return error")
+	}
+
+	// test with network namespace
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		status := vpnstatus.New()
+		status.NetNS = "oc-daemon"
+		return &testClient{status: status}, nil
+	}
+
+	if err := execNetNS([]string{"ping", "-c", "1", "10.0.0.1"}); err != nil {
+		t.Error(err)
+	}
+	want := []string{"netns", "exec", "oc-daemon", "ping", "-c", "1", "10.0.0.1"}
+	if name != "ip" || !slices.Equal(args, want) {
+		t.Errorf("got %s %v, want ip %v", name, args, want)
+	}
+}
+
+// TestGetHistory tests getHistory.
+func TestGetHistory(t *testing.T) {
+	defer func() { clientNewClient = client.NewClient }()
+	defer func() { json = false }()
+
+	// test with client error
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return nil, errors.New("test error")
+	}
+
+	if err := getHistory(); err == nil {
+		t.Error("client error should return error")
+	}
+
+	// test with get history error
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return &testClient{histErr: errors.New("test error")}, nil
+	}
+
+	if err := getHistory(); err == nil {
+		t.Error("get history error should return error")
+	}
+
+	// test without error
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		history := vpnhistory.New()
+		history.Add(&vpnhistory.Session{
+			Server:         "test server",
+			ConnectedAt:    1,
+			DisconnectedAt: 2,
+			Cause:          vpnhistory.DisconnectCauseUser,
+		}, 10)
+		history.Add(&vpnhistory.Session{
+			Connection: "test",
+			Server:     "test server",
+			Cause:      vpnhistory.DisconnectCauseOpenConnectExit,
+		}, 10)
+		return &testClient{history: history}, nil
+	}
+
+	if err := getHistory(); err != nil {
+		t.Error(err)
+	}
+
+	// test with json output
+	json = true
+	if err := getHistory(); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestGetTrace tests getTrace.
+func TestGetTrace(t *testing.T) {
+	defer func() { clientNewClient = client.NewClient }()
+	defer func() { json = false }()
+	defer func() { traceFailed = false }()
+
+	// test with client error
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return nil, errors.New("test error")
+	}
+
+	if err := getTrace(); err == nil {
+		t.Error("client error should return error")
+	}
+
+	// test with dump state error
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return &testClient{dumpErr: errors.New("test error")}, nil
+	}
+
+	if err := getTrace(); err == nil {
+		t.Error("dump state error should return error")
+	}
+
+	// test with invalid state
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return &testClient{dumpSta: "invalid"}, nil
+	}
+
+	if err := getTrace(); err == nil {
+		t.Error("invalid state should return error")
+	}
+
+	// test without error
+	clientNewClient = func(*client.Config) (client.Client, error) {
+		return &testClient{dumpSta: `{"CommandTrace":[
+			{"List":"VPNSetupSetup","Command":"ip","Args":["link"],"ExitCode":0},
+			{"List":"VPNSetupSetup","Command":"nft","ExitCode":1,"Stderr":"error"}
+		]}`}, nil
+	}
+
+	if err := getTrace(); err != nil {
+		t.Error(err)
+	}
+
+	// test with filter and json output
+	traceFailed = true
+	json = true
+	if err := getTrace(); err != nil {
+		t.Error(err)
+	}
+}
+
 // TestMonitor tests monitor.
 func TestMonitor(t *testing.T) {
 	defer func() { clientNewClient = client.NewClient }()
@@ -24,6 +24,15 @@ var (
 
 	// json specifies whether output should be formatted as json.
 	json = false
+
+	// execArgs is the command of the exec subcommand.
+	execArgs []string
+
+	// traceList, traceCommand and traceFailed filter the command trace by
+	// command list name, command and failed commands.
+	traceList    = ""
+	traceCommand = ""
+	traceFailed  = false
 )
 
 // clientUserConfig is client.UserConfig for testing.
@@ -60,6 +69,17 @@ func setConfig(args []string) error {
 	monitorCmd.BoolVar(&verbose, "verbose", verbose, "set verbose output")
 	monitorCmd.BoolVar(&json, "json", json, "set json output")
 
+	// history subcommand
+	historyCmd := flag.NewFlagSet("history", flag.ContinueOnError)
+	historyCmd.BoolVar(&json, "json", json, "set json output")
+
+	// trace subcommand
+	traceCmd := flag.NewFlagSet("trace", flag.ContinueOnError)
+	traceCmd.StringVar(&traceList, "list", traceList, "only show commands of command list `name`")
+	traceCmd.StringVar(&traceCommand, "command", traceCommand, "only show `command`")
+	traceCmd.BoolVar(&traceFailed, "failed", traceFailed, "only show failed commands")
+	traceCmd.BoolVar(&json, "json", json, "set json output")
+
 	// define command line arguments
 	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	cfgFile := flags.String("config", "", "set config `file`")
@@ -75,6 +95,8 @@ func setConfig(args []string) error {
 	srv := flags.String("server", "", "set server `address`")
 	usr := flags.String("user", "", "set `username`")
 	group := flags.String("group", "", "set `usergroup`")
+	conn := flags.String("connection", "", "set `name` of additional VPN "+
+		"connection configured in the daemon")
 	sys := flags.Bool("system-settings", false, "use system settings "+
 		"instead of user configuration")
 	ver := flags.Bool("version", false, "print version")
@@ -103,8 +125,16 @@ func setConfig(args []string) error {
 		usage("        show VPN status\n")
 		usage("  monitor\n")
 		usage("        monitor VPN status updates\n")
+		usage("  history\n")
+		usage("        show VPN connection history\n")
+		usage("  trace\n")
+		usage("        show recently executed commands of the daemon\n")
+		usage("  remediate\n")
+		usage("        open network for captive portal login\n")
 		usage("  save\n")
 		usage("        save current settings to user configuration\n")
+		usage("  exec [--] command [args]\n")
+		usage("        run command in network namespace of VPN tunnel\n")
 		usage("\nExamples:\n")
 		usage("  %s connect\n", cmd)
 		usage("  %s disconnect\n", cmd)
@@ -115,7 +145,10 @@ func setConfig(args []string) error {
 		usage("  %s -server \"My SSL VPN Server\" save\n", cmd)
 		usage("  %s -user exampleuser connect\n", cmd)
 		usage("  %s -user $USER save\n", cmd)
+		usage("  %s -connection lab -server \"My Lab VPN Server\" connect\n", cmd)
 		usage("  %s -system-settings save\n", cmd)
+		usage("  %s exec -- ping -c 1 10.0.0.1\n", cmd)
+		usage("  %s trace -failed -list VPNSetupSetup\n", cmd)
 	}
 
 	// parse arguments
@@ -140,6 +173,19 @@ func setConfig(args []string) error {
 		if err := monitorCmd.Parse(args[2:]); err != nil {
 			return err
 		}
+	case "history":
+		if err := historyCmd.Parse(args[2:]); err != nil {
+			return err
+		}
+	case "trace":
+		if err := traceCmd.Parse(args[2:]); err != nil {
+			return err
+		}
+	case "exec":
+		execArgs = flags.Args()[1:]
+		if len(execArgs) > 0 && execArgs[0] == "--" {
+			execArgs = execArgs[1:]
+		}
 	}
 
 	// set command
@@ -209,6 +255,11 @@ func setConfig(args []string) error {
 		config.UserGroup = *group
 	}
 
+	// set vpn connection
+	if *conn != "" {
+		config.Connection = *conn
+	}
+
 	// reset to system settings
 	if *sys {
 		systemConfig := clientSystemConfig()
@@ -253,8 +304,16 @@ func run(args []string) error {
 		return dumpState()
 	case "monitor":
 		return monitor()
+	case "history":
+		return getHistory()
+	case "trace":
+		return getTrace()
+	case "remediate":
+		return remediateCaptivePortal()
 	case "save":
 		return saveConfig()
+	case "exec":
+		return execNetNS(execArgs)
 	default:
 		return fmt.Errorf("unknown command: %s", command)
 	}
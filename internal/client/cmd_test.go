@@ -5,6 +5,7 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/telekom-mms/oc-daemon/pkg/client"
@@ -32,6 +33,11 @@ func TestRun(t *testing.T) {
 		t.Errorf("help should return ErrHelp, got: %v", err)
 	}
 
+	// test with "trace -help"
+	if err := run([]string{"test", "trace", "-help"}); err != flag.ErrHelp {
+		t.Errorf("help should return ErrHelp, got: %v", err)
+	}
+
 	// not existing config with "-config"
 	dir := t.TempDir()
 	cfg := filepath.Join(dir, "config")
@@ -127,6 +133,7 @@ func TestRun(t *testing.T) {
 		"status",
 		"monitor",
 		"dumpstate",
+		"exec",
 	} {
 		if err := run([]string{"test",
 			"-cert", "cert-file",
@@ -142,4 +149,16 @@ func TestRun(t *testing.T) {
 			t.Errorf("command %s should return error, got: %v", cmd, err)
 		}
 	}
+	// exec command with arguments
+	if err := run([]string{"test",
+		"-cert", "cert-file",
+		"-key", "key-file",
+		"-server", "test-server",
+		"exec", "--", "ping", "-c", "1", "10.0.0.1",
+	}); err == nil || err == flag.ErrHelp {
+		t.Errorf("exec should return error, got: %v", err)
+	}
+	if !slices.Equal(execArgs, []string{"ping", "-c", "1", "10.0.0.1"}) {
+		t.Errorf("got invalid exec arguments %v", execArgs)
+	}
 }
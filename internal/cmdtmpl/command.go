@@ -5,24 +5,69 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/telekom-mms/oc-daemon/internal/metrics"
 )
 
 // Command consists of a command line to be executed and an optional Stdin to
-// be passed to the command on execution.
+// be passed to the command on execution. Undo and UndoStdin are the optional
+// command line and Stdin that revert the command when the command list is
+// rolled back. The command line is split into arguments like in a shell,
+// arguments can be quoted with single or double quotes and characters can be
+// escaped with backslashes. Timeout limits each attempt to run the command,
+// Retries is the number of times a failed command is retried, starting after
+// RetryBackoff and doubling it after each retry. Failures of commands with
+// IgnoreErrors are not reported.
 type Command struct {
-	Line  string
-	Stdin string
+	Line      string
+	Stdin     string
+	Undo      string `json:",omitempty"`
+	UndoStdin string `json:",omitempty"`
+
+	Timeout      time.Duration `json:",omitempty"`
+	Retries      int           `json:",omitempty"`
+	RetryBackoff time.Duration `json:",omitempty"`
+	IgnoreErrors bool          `json:",omitempty"`
+}
+
+// valid returns whether the settings of the command are valid.
+func (c *Command) valid() bool {
+	return c.Timeout >= 0 && c.Retries >= 0 && c.RetryBackoff >= 0
 }
 
+// Failure modes of command lists.
+const (
+	// OnFailureContinue runs the remaining commands after a failed
+	// command.
+	OnFailureContinue = "continue"
+
+	// OnFailureAbort stops running commands after a failed command.
+	OnFailureAbort = "abort"
+
+	// OnFailureRollback stops running commands after a failed command and
+	// runs the undo commands of the already applied commands in reverse
+	// order.
+	OnFailureRollback = "rollback"
+)
+
 // CommandList is a list of Commands.
 type CommandList struct {
-	Name     string
-	Commands []*Command
+	Name      string
+	OnFailure string `json:",omitempty"`
+	Commands  []*Command
 
 	template *template.Template
 }
@@ -200,10 +245,41 @@ table inet oc-daemon-filter {
 
                 # accept traffic on allowed devices
                 iifname @allowdevs oifname @allowdevs counter accept
+                {{if .NetNS.Enabled}}
+
+                # accept traffic from network namespace to allowed hosts
+                iifname {{.NetNS.HostDevice}} ip daddr @allowhosts4 counter accept
+                {{end}}
         }
 }
 {{end}}
 
+{{- /*********************************************************************/ -}}
+{{- /*********************************************************************/ -}}
+{{- /*********************************************************************/ -}}
+{{- /***                                                               ***/ -}}
+{{- /***                 Network Namespace Templates                   ***/ -}}
+{{- /***                                                               ***/ -}}
+{{- /*********************************************************************/ -}}
+{{- /*********************************************************************/ -}}
+{{- /*********************************************************************/ -}}
+
+{{- define "NetNSExec"}}
+{{- if .NetNS.Enabled}}{{.Executables.IP}} netns exec {{.NetNS.Name}} {{end}}
+{{- end}}
+
+{{- define "NetNSRules"}}
+table inet oc-daemon-netns {
+	chain postrouting {
+		type nat hook postrouting priority srcnat; policy accept;
+
+		# masquerade traffic from the network namespace to make sure
+		# the source IP matches the outgoing interface
+		ip saddr {{.NetNS.Address.Masked}} oifname != {{.NetNS.HostDevice}} counter masquerade
+	}
+}
+{{end}}
+
 {{- /*********************************************************************/ -}}
 {{- /*********************************************************************/ -}}
 {{- /*********************************************************************/ -}}
@@ -215,7 +291,7 @@ table inet oc-daemon-filter {
 {{- /*********************************************************************/ -}}
 
 {{- define "SplitRoutingRules"}}
-table inet oc-daemon-routing {
+table inet {{.SplitRouting.NftTable}} {
 	# set for ipv4 excludes
 	set excludes4 {
 		type ipv4_addr
@@ -332,8 +408,14 @@ table inet oc-daemon-routing {
 // LoadedTemplates are the templates loaded from file.
 var LoadedTemplates string
 
+// builtinTemplate is the parsed built-in template for the command lists.
+var builtinTemplate = template.Must(template.New("Template").Parse(DefaultTemplate))
+
 // defaultTemplate is the parsed default template for the command lists.
-var defaultTemplate = template.Must(template.New("Template").Parse(DefaultTemplate))
+var defaultTemplate = builtinTemplate
+
+// mutex protects the templates and command lists while they are reloaded.
+var mutex sync.RWMutex
 
 // Command list names.
 const (
@@ -350,8 +432,46 @@ const (
 	VPNSetupSetDNS      = "VPNSetupSetDNS"
 	VPNSetupGetDNS      = "VPNSetupGetDNS"
 	VPNSetupCleanup     = "VPNSetupCleanup"
+	VPNSetupReset       = "VPNSetupReset"
+
+	VPNSetupSetupNetNS    = "VPNSetupSetupNetNS"
+	VPNSetupTeardownNetNS = "VPNSetupTeardownNetNS"
+
+	HookConnected      = "HookConnected"
+	HookDisconnected   = "HookDisconnected"
+	HookTrustedNetwork = "HookTrustedNetwork"
+	HookCaptivePortal  = "HookCaptivePortal"
 )
 
+// validCommands returns whether all commands are valid.
+func validCommands(commands []*Command) bool {
+	for _, c := range commands {
+		if c == nil || !c.valid() {
+			return false
+		}
+	}
+	return true
+}
+
+// isHook returns whether name is the name of a hook command list.
+func isHook(name string) bool {
+	switch name {
+	case HookConnected, HookDisconnected, HookTrustedNetwork, HookCaptivePortal:
+		return true
+	}
+	return false
+}
+
+// validOnFailure returns whether onFailure is a valid failure mode, empty is
+// the default failure mode continue.
+func validOnFailure(onFailure string) bool {
+	switch onFailure {
+	case "", OnFailureContinue, OnFailureAbort, OnFailureRollback:
+		return true
+	}
+	return false
+}
+
 // CommandLists contains all command lists.
 var CommandLists = map[string]*CommandList{
 
@@ -363,7 +483,8 @@ var CommandLists = map[string]*CommandList{
 	TrafPolSetFilterRules: {
 		Name: TrafPolSetFilterRules,
 		Commands: []*Command{
-			{Line: "{{.Executables.Nft}} -f -", Stdin: `{{template "TrafPolRules" .}}`},
+			{Line: "{{.Executables.Nft}} -f -", Stdin: `{{template "TrafPolRules" .}}`,
+				Undo: "{{.Executables.Nft}} -f - delete table inet oc-daemon-filter"},
 		},
 		template: defaultTemplate,
 	},
@@ -443,30 +564,41 @@ add element inet oc-daemon-filter allowports { {{.}} }
 			// - set mtu on device
 			// - set device up
 			// - set ipv4 and ipv6 addresses on device
-			{Line: "{{.Executables.IP}} link set {{.VPNConfig.Device.Name}} mtu {{.VPNConfig.Device.MTU}}"},
-			{Line: "{{.Executables.IP}} link set {{.VPNConfig.Device.Name}} up"},
-			{Line: "{{if .VPNConfig.IPv4.IsValid}}{{.Executables.IP}} address add {{.VPNConfig.IPv4}} dev {{.VPNConfig.Device.Name}}{{end}}"},
-			{Line: "{{if .VPNConfig.IPv6.IsValid}}{{.Executables.IP}} address add {{.VPNConfig.IPv6}} dev {{.VPNConfig.Device.Name}}{{end}}"},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} link set {{.VPNConfig.Device.Name}} mtu {{.VPNConfig.Device.MTU}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} link set {{.VPNConfig.Device.Name}} up`,
+				Undo: `{{template "NetNSExec" .}}{{.Executables.IP}} link set {{.VPNConfig.Device.Name}} down`},
+			{Line: `{{if .VPNConfig.IPv4.IsValid}}{{template "NetNSExec" .}}{{.Executables.IP}} address add {{.VPNConfig.IPv4}} dev {{.VPNConfig.Device.Name}}{{end}}`,
+				Undo: `{{if .VPNConfig.IPv4.IsValid}}{{template "NetNSExec" .}}{{.Executables.IP}} address delete {{.VPNConfig.IPv4}} dev {{.VPNConfig.Device.Name}}{{end}}`},
+			{Line: `{{if .VPNConfig.IPv6.IsValid}}{{template "NetNSExec" .}}{{.Executables.IP}} address add {{.VPNConfig.IPv6}} dev {{.VPNConfig.Device.Name}}{{end}}`,
+				Undo: `{{if .VPNConfig.IPv6.IsValid}}{{template "NetNSExec" .}}{{.Executables.IP}} address delete {{.VPNConfig.IPv6}} dev {{.VPNConfig.Device.Name}}{{end}}`},
 			// Routing setup
-			{Line: "{{.Executables.Nft}} -f -", Stdin: `{{template "SplitRoutingRules" .}}`},
-			{Line: "{{.Executables.IP}} -4 route add 0.0.0.0/0 dev {{.VPNConfig.Device.Name}} table {{.SplitRouting.RoutingTable}}"},
-			{Line: "{{.Executables.IP}} -4 rule add iif {{.VPNConfig.Device.Name}} table main pref {{.SplitRouting.RulePriority1}}"},
-			{Line: "{{.Executables.IP}} -4 rule add not fwmark {{.SplitRouting.FirewallMark}} table {{.SplitRouting.RoutingTable}} pref {{.SplitRouting.RulePriority2}}"},
-			{Line: "{{.Executables.Sysctl}} -q net.ipv4.conf.all.src_valid_mark=1"},
-			{Line: "{{.Executables.IP}} -6 route add ::/0 dev {{.VPNConfig.Device.Name}} table {{.SplitRouting.RoutingTable}}"},
-			{Line: "{{.Executables.IP}} -6 rule add iif {{.VPNConfig.Device.Name}} table main pref {{.SplitRouting.RulePriority1}}"},
-			{Line: "{{.Executables.IP}} -6 rule add not fwmark {{.SplitRouting.FirewallMark}} table {{.SplitRouting.RoutingTable}} pref {{.SplitRouting.RulePriority2}}"},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.Nft}} -f -`, Stdin: `{{template "SplitRoutingRules" .}}`,
+				Undo: `{{template "NetNSExec" .}}{{.Executables.Nft}} -f - delete table inet {{.SplitRouting.NftTable}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 route add 0.0.0.0/0 dev {{.VPNConfig.Device.Name}} table {{.SplitRouting.RoutingTable}}`,
+				Undo: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 route delete 0.0.0.0/0 dev {{.VPNConfig.Device.Name}} table {{.SplitRouting.RoutingTable}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 rule add iif {{.VPNConfig.Device.Name}} table main pref {{.SplitRouting.RulePriority1}}`,
+				Undo: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 rule delete pref {{.SplitRouting.RulePriority1}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 rule add not fwmark {{.SplitRouting.FirewallMark}} table {{.SplitRouting.RoutingTable}} pref {{.SplitRouting.RulePriority2}}`,
+				Undo: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 rule delete pref {{.SplitRouting.RulePriority2}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.Sysctl}} -q net.ipv4.conf.all.src_valid_mark=1`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 route add ::/0 dev {{.VPNConfig.Device.Name}} table {{.SplitRouting.RoutingTable}}`,
+				Undo: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 route delete ::/0 dev {{.VPNConfig.Device.Name}} table {{.SplitRouting.RoutingTable}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 rule add iif {{.VPNConfig.Device.Name}} table main pref {{.SplitRouting.RulePriority1}}`,
+				Undo: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 rule delete pref {{.SplitRouting.RulePriority1}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 rule add not fwmark {{.SplitRouting.FirewallMark}} table {{.SplitRouting.RoutingTable}} pref {{.SplitRouting.RulePriority2}}`,
+				Undo: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 rule delete pref {{.SplitRouting.RulePriority2}}`},
 			// DNS setup:
 			// - set DNS Proxy
 			// - set Domains
 			// - set default DNS route
 			// - flush caches
 			// - reset server features
-			{Line: "{{.Executables.Resolvectl}} dns {{.VPNConfig.Device.Name}} {{.DNSProxy.Address}}"},
-			{Line: "{{.Executables.Resolvectl}} domain {{.VPNConfig.Device.Name}} {{.VPNConfig.DNS.DefaultDomain}} ~."},
-			{Line: "{{.Executables.Resolvectl}} default-route {{.VPNConfig.Device.Name}} yes"},
-			{Line: "{{.Executables.Resolvectl}} flush-caches"},
-			{Line: "{{.Executables.Resolvectl}} reset-server-features"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} dns {{.VPNConfig.Device.Name}} {{.DNSProxy.Address}}{{end}}",
+				Undo: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} revert {{.VPNConfig.Device.Name}}{{end}}"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} domain {{.VPNConfig.Device.Name}} {{.VPNConfig.DNS.DefaultDomain}} ~.{{end}}"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} default-route {{.VPNConfig.Device.Name}} yes{{end}}"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} flush-caches{{end}}"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} reset-server-features{{end}}"},
 		},
 		template: defaultTemplate,
 	},
@@ -476,17 +608,17 @@ add element inet oc-daemon-filter allowports { {{.}} }
 		Name: VPNSetupTeardown,
 		Commands: []*Command{
 			// Device teardown
-			{Line: "{{.Executables.IP}} link set {{.VPNConfig.Device.Name}} down"},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} link set {{.VPNConfig.Device.Name}} down`},
 			// Routing teardown
-			{Line: "{{.Executables.IP}} -4 rule delete table {{.SplitRouting.RoutingTable}}"},
-			{Line: "{{.Executables.IP}} -4 rule delete iif {{.VPNConfig.Device.Name}} table main"},
-			{Line: "{{.Executables.IP}} -6 rule delete table {{.SplitRouting.RoutingTable}}"},
-			{Line: "{{.Executables.IP}} -6 rule delete iif {{.VPNConfig.Device.Name}} table main"},
-			{Line: "{{.Executables.Nft}} -f - delete table inet oc-daemon-routing"},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 rule delete table {{.SplitRouting.RoutingTable}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 rule delete iif {{.VPNConfig.Device.Name}} table main`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 rule delete table {{.SplitRouting.RoutingTable}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 rule delete iif {{.VPNConfig.Device.Name}} table main`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.Nft}} -f - delete table inet {{.SplitRouting.NftTable}}`},
 			// DNS teardown
-			{Line: "{{.Executables.Resolvectl}} revert {{.VPNConfig.Device.Name}}"},
-			{Line: "{{.Executables.Resolvectl}} flush-caches"},
-			{Line: "{{.Executables.Resolvectl}} reset-server-features"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} revert {{.VPNConfig.Device.Name}}{{end}}"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} flush-caches{{end}}"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} reset-server-features{{end}}"},
 		},
 		template: defaultTemplate,
 	},
@@ -497,14 +629,14 @@ add element inet oc-daemon-filter allowports { {{.}} }
 		Commands: []*Command{
 			// flush existing entries
 			// add entries
-			{Line: "{{.Executables.Nft}} -f -",
-				Stdin: `flush set inet oc-daemon-routing excludes4
-flush set inet oc-daemon-routing excludes6
+			{Line: `{{template "NetNSExec" .}}{{.Executables.Nft}} -f -`,
+				Stdin: `flush set inet {{.SplitRouting.NftTable}} excludes4
+flush set inet {{.SplitRouting.NftTable}} excludes6
 {{range .Addresses -}}
 {{if .Addr.Is6 -}}
-add element inet oc-daemon-routing excludes6 { {{.}} }
+add element inet {{$.SplitRouting.NftTable}} excludes6 { {{.}} }
 {{else -}}
-add element inet oc-daemon-routing excludes4 { {{.}} }
+add element inet {{$.SplitRouting.NftTable}} excludes4 { {{.}} }
 {{end -}}
 {{end}}`},
 		},
@@ -515,9 +647,9 @@ add element inet oc-daemon-routing excludes4 { {{.}} }
 	VPNSetupSetDNS: {
 		Name: VPNSetupSetDNS,
 		Commands: []*Command{
-			{Line: "{{.Executables.Resolvectl}} dns {{.VPNConfig.Device.Name}} {{.DNSProxy.Address}}"},
-			{Line: "{{.Executables.Resolvectl}} domain {{.VPNConfig.Device.Name}} {{.VPNConfig.DNS.DefaultDomain}} ~."},
-			{Line: "{{.Executables.Resolvectl}} default-route {{.VPNConfig.Device}} yes"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} dns {{.VPNConfig.Device.Name}} {{.DNSProxy.Address}}{{end}}"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} domain {{.VPNConfig.Device.Name}} {{.VPNConfig.DNS.DefaultDomain}} ~.{{end}}"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} default-route {{.VPNConfig.Device.Name}} yes{{end}}"},
 		},
 		template: defaultTemplate,
 	},
@@ -526,7 +658,7 @@ add element inet oc-daemon-routing excludes4 { {{.}} }
 	VPNSetupGetDNS: {
 		Name: VPNSetupGetDNS,
 		Commands: []*Command{
-			{Line: "{{.Executables.Resolvectl}} status {{.VPNConfig.Device.Name}} --no-pager"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} status {{.VPNConfig.Device.Name}} --no-pager{{end}}"},
 		},
 		template: defaultTemplate,
 	},
@@ -536,53 +668,139 @@ add element inet oc-daemon-routing excludes4 { {{.}} }
 		Name: VPNSetupCleanup,
 		Commands: []*Command{
 			// DNS cleanup
-			{Line: "{{.Executables.Resolvectl}} revert {{.OpenConnect.VPNDevice}}"},
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} revert {{.OpenConnect.VPNDevice}}{{end}}"},
 			// Device cleanup
-			{Line: "{{.Executables.IP}} link delete {{.OpenConnect.VPNDevice}}"},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} link delete {{.OpenConnect.VPNDevice}}`},
 			// Routing cleanup
-			{Line: "{{.Executables.IP}} -4 rule delete pref {{.SplitRouting.RulePriority1}}"},
-			{Line: "{{.Executables.IP}} -4 rule delete pref {{.SplitRouting.RulePriority2}}"},
-			{Line: "{{.Executables.IP}} -6 rule delete pref {{.SplitRouting.RulePriority1}}"},
-			{Line: "{{.Executables.IP}} -6 rule delete pref {{.SplitRouting.RulePriority2}}"},
-			{Line: "{{.Executables.IP}} -4 route flush table {{.SplitRouting.RoutingTable}}"},
-			{Line: "{{.Executables.IP}} -6 route flush table {{.SplitRouting.RoutingTable}}"},
-			{Line: "{{.Executables.Nft}} -f - delete table inet oc-daemon-routing"},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 rule delete pref {{.SplitRouting.RulePriority1}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 rule delete pref {{.SplitRouting.RulePriority2}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 rule delete pref {{.SplitRouting.RulePriority1}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 rule delete pref {{.SplitRouting.RulePriority2}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 route flush table {{.SplitRouting.RoutingTable}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 route flush table {{.SplitRouting.RoutingTable}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.Nft}} -f - delete table inet {{.SplitRouting.NftTable}}`},
+		},
+		template: defaultTemplate,
+	},
+
+	// Reset VPN, keeps the VPN device of a running openconnect process
+	VPNSetupReset: {
+		Name: VPNSetupReset,
+		Commands: []*Command{
+			// DNS reset
+			{Line: "{{if not .NetNS.Enabled}}{{.Executables.Resolvectl}} revert {{.VPNConfig.Device.Name}}{{end}}"},
+			// Device reset
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} address flush dev {{.VPNConfig.Device.Name}}`},
+			// Routing reset
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 rule delete pref {{.SplitRouting.RulePriority1}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 rule delete pref {{.SplitRouting.RulePriority2}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 rule delete pref {{.SplitRouting.RulePriority1}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 rule delete pref {{.SplitRouting.RulePriority2}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -4 route flush table {{.SplitRouting.RoutingTable}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.IP}} -6 route flush table {{.SplitRouting.RoutingTable}}`},
+			{Line: `{{template "NetNSExec" .}}{{.Executables.Nft}} -f - delete table inet {{.SplitRouting.NftTable}}`},
+		},
+		template: defaultTemplate,
+	},
+
+	// Setup Network Namespace
+	VPNSetupSetupNetNS: {
+		Name: VPNSetupSetupNetNS,
+		Commands: []*Command{
+			// Namespace setup:
+			// - add network namespace
+			// - set loopback device up
+			{Line: "{{.Executables.IP}} netns add {{.NetNS.Name}}"},
+			{Line: "{{.Executables.IP}} -n {{.NetNS.Name}} link set lo up"},
+			// Device setup:
+			// - add veth devices between host and network namespace
+			// - set addresses on veth devices
+			// - set veth devices up
+			{Line: "{{.Executables.IP}} link add {{.NetNS.HostDevice}} type veth peer name {{.NetNS.Device}} netns {{.NetNS.Name}}"},
+			{Line: "{{.Executables.IP}} address add {{.NetNS.HostAddress}} dev {{.NetNS.HostDevice}}"},
+			{Line: "{{.Executables.IP}} -n {{.NetNS.Name}} address add {{.NetNS.Address}} dev {{.NetNS.Device}}"},
+			{Line: "{{.Executables.IP}} link set {{.NetNS.HostDevice}} up"},
+			{Line: "{{.Executables.IP}} -n {{.NetNS.Name}} link set {{.NetNS.Device}} up"},
+			// Routing setup:
+			// - set default route over host in network namespace
+			// - forward and masquerade traffic from network namespace
+			{Line: "{{.Executables.IP}} -n {{.NetNS.Name}} route add default via {{.NetNS.HostAddress.Addr}}"},
+			{Line: "{{.Executables.Sysctl}} -q net.ipv4.ip_forward=1"},
+			{Line: "{{.Executables.Nft}} -f -", Stdin: `{{template "NetNSRules" .}}`},
+		},
+		template: defaultTemplate,
+	},
+
+	// Teardown Network Namespace
+	VPNSetupTeardownNetNS: {
+		Name: VPNSetupTeardownNetNS,
+		Commands: []*Command{
+			{Line: "{{.Executables.Nft}} -f - delete table inet oc-daemon-netns"},
+			{Line: "{{.Executables.IP}} link delete {{.NetNS.HostDevice}}"},
+			{Line: "{{.Executables.IP}} netns delete {{.NetNS.Name}}"},
 		},
 		template: defaultTemplate,
 	},
 }
 
-// LoadTemplates loads the templates from file.
-func LoadTemplates(file string) error {
+// builtinCommandLists are the built-in command lists.
+var builtinCommandLists = maps.Clone(CommandLists)
+
+// Reset resets the templates and command lists to the built-in defaults.
+func Reset() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	LoadedTemplates = ""
+	defaultTemplate = builtinTemplate
+	CommandLists = maps.Clone(builtinCommandLists)
+}
+
+// parseTemplates reads and parses the templates in file.
+func parseTemplates(file string) (string, *template.Template, error) {
 	// read file contents
 	f, err := os.ReadFile(file)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
 	// parse file contents
 	s := string(f)
 	t, err := template.New("Template").Parse(s)
-	if err == nil {
-		// save loaded templates
-		LoadedTemplates = s
-		defaultTemplate = t
+	if err != nil {
+		return "", nil, err
 	}
-	return err
+	return s, t, nil
 }
 
-// LoadCommandLists loads the command lists from file.
-func LoadCommandLists(file string) error {
+// LoadTemplates loads the templates from file.
+func LoadTemplates(file string) error {
+	s, t, err := parseTemplates(file)
+	if err != nil {
+		return err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// save loaded templates
+	LoadedTemplates = s
+	defaultTemplate = t
+	return nil
+}
+
+// parseCommandLists reads, parses and checks the command lists in file.
+func parseCommandLists(file string) ([]*CommandList, error) {
 	// read file contents
 	f, err := os.ReadFile(file)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// parse entries in file
 	lists := []*CommandList{}
 	if err := json.Unmarshal(f, &lists); err != nil {
-		return err
+		return nil, err
 	}
 
 	// check entries in file
@@ -602,14 +820,45 @@ func LoadCommandLists(file string) error {
 		case VPNSetupSetDNS:
 		case VPNSetupGetDNS:
 		case VPNSetupCleanup:
+		case VPNSetupReset:
+
+		case VPNSetupSetupNetNS:
+		case VPNSetupTeardownNetNS:
+
+		case HookConnected:
+		case HookDisconnected:
+		case HookTrustedNetwork:
+		case HookCaptivePortal:
 
 		default:
 			// invalid name
-			return fmt.Errorf("invalid command list name %s", cl.Name)
+			return nil, fmt.Errorf("invalid command list name %s", cl.Name)
+		}
+
+		// check valid failure modes
+		if !validOnFailure(cl.OnFailure) {
+			return nil, fmt.Errorf("invalid failure mode %s in command list %s",
+				cl.OnFailure, cl.Name)
+		}
+
+		// check valid commands
+		if !validCommands(cl.Commands) {
+			return nil, fmt.Errorf("invalid command in command list %s", cl.Name)
 		}
 	}
+	return lists, nil
+}
+
+// LoadCommandLists loads the command lists from file.
+func LoadCommandLists(file string) error {
+	lists, err := parseCommandLists(file)
+	if err != nil {
+		return err
+	}
 
 	// entries in file valid, update command lists
+	mutex.Lock()
+	defer mutex.Unlock()
 	for _, cl := range lists {
 		cl.template = defaultTemplate
 		CommandLists[cl.Name] = cl
@@ -618,16 +867,223 @@ func LoadCommandLists(file string) error {
 	return nil
 }
 
+// parseHooks reads, parses, checks and merges the hook command lists in the
+// JSON files in dir.
+func parseHooks(dir string) (map[string]*CommandList, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	// parse and merge entries in files
+	hooks := map[string]*CommandList{}
+	for _, file := range files {
+		f, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		lists := []*CommandList{}
+		if err := json.Unmarshal(f, &lists); err != nil {
+			return nil, fmt.Errorf("could not parse hooks file %s: %w", file, err)
+		}
+		for _, cl := range lists {
+			if !isHook(cl.Name) {
+				return nil, fmt.Errorf("invalid hook name %s in file %s", cl.Name, file)
+			}
+			if !validOnFailure(cl.OnFailure) {
+				return nil, fmt.Errorf("invalid failure mode %s in file %s",
+					cl.OnFailure, file)
+			}
+			if !validCommands(cl.Commands) {
+				return nil, fmt.Errorf("invalid command in hook %s in file %s",
+					cl.Name, file)
+			}
+			h := hooks[cl.Name]
+			if h == nil {
+				h = &CommandList{Name: cl.Name}
+				hooks[cl.Name] = h
+			}
+			if h.OnFailure != "" && cl.OnFailure != "" && h.OnFailure != cl.OnFailure {
+				return nil, fmt.Errorf("conflicting failure mode of hook %s in file %s",
+					cl.Name, file)
+			}
+			if cl.OnFailure != "" {
+				h.OnFailure = cl.OnFailure
+			}
+			h.Commands = append(h.Commands, cl.Commands...)
+		}
+	}
+	return hooks, nil
+}
+
+// addHooks adds the hook command lists in hooks with template t to lists.
+// Commands of hooks already in lists are kept before the added commands.
+func addHooks(lists, hooks map[string]*CommandList, t *template.Template) {
+	for name, h := range hooks {
+		if cl := lists[name]; cl != nil {
+			h.Commands = append(slices.Clone(cl.Commands), h.Commands...)
+			if h.OnFailure == "" {
+				h.OnFailure = cl.OnFailure
+			}
+		}
+		h.template = t
+		lists[name] = h
+	}
+}
+
+// LoadHooks loads the hook command lists from the JSON files in dir. The
+// commands of a hook in multiple files are appended to the hook command list
+// in the order of the file names.
+func LoadHooks(dir string) error {
+	hooks, err := parseHooks(dir)
+	if err != nil {
+		return err
+	}
+
+	// entries in files valid, add them to hooks in command lists
+	mutex.Lock()
+	defer mutex.Unlock()
+	addHooks(CommandLists, hooks, defaultTemplate)
+
+	return nil
+}
+
+// Load loads the templates from templatesFile, the command lists from
+// listsFile and the hook command lists from the files in hooksDir and
+// replaces the current templates and command lists with them. Missing files
+// are replaced with the built-in defaults. If a file could not be loaded, the
+// current templates and command lists are kept.
+func Load(templatesFile, listsFile, hooksDir string) error {
+	loaded, t := "", builtinTemplate
+	s, tmpl, err := parseTemplates(templatesFile)
+	switch {
+	case err == nil:
+		loaded, t = s, tmpl
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("could not load command templates: %w", err)
+	}
+
+	lists := maps.Clone(builtinCommandLists)
+	cls, err := parseCommandLists(listsFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not load command lists: %w", err)
+	}
+	for _, cl := range cls {
+		cl.template = t
+		lists[cl.Name] = cl
+	}
+
+	hooks, err := parseHooks(hooksDir)
+	if err != nil {
+		return fmt.Errorf("could not load hooks: %w", err)
+	}
+	addHooks(lists, hooks, t)
+
+	// all files valid, replace templates and command lists
+	mutex.Lock()
+	defer mutex.Unlock()
+	LoadedTemplates = loaded
+	defaultTemplate = t
+	CommandLists = lists
+
+	return nil
+}
+
+// Get returns the templates loaded from file and a copy of the command
+// lists.
+func Get() (string, map[string]*CommandList) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return LoadedTemplates, maps.Clone(CommandLists)
+}
+
+// HasCommandList returns whether the command list identified by name exists,
+// e.g., whether a hook is configured.
+func HasCommandList(name string) bool {
+	return getCommandList(name) != nil
+}
+
+// ExecuteTemplate executes the template identified by name in the loaded
+// templates on data and returns the resulting output as string.
+func ExecuteTemplate(name string, data any) (string, error) {
+	mutex.RLock()
+	t := defaultTemplate
+	mutex.RUnlock()
+
+	buf := &bytes.Buffer{}
+	if err := t.ExecuteTemplate(buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 // getCommandList returns the command list identified by name.
 func getCommandList(name string) *CommandList {
+	mutex.RLock()
+	defer mutex.RUnlock()
 	return CommandLists[name]
 }
 
+// GetOnFailure returns the failure mode of the command list identified by
+// name, empty if the command list does not exist.
+func GetOnFailure(name string) string {
+	cl := getCommandList(name)
+	if cl == nil {
+		return ""
+	}
+	return cl.OnFailure
+}
+
 // Cmd is a command ready to run.
 type Cmd struct {
-	Cmd   string
-	Args  []string
-	Stdin string
+	List      string
+	Cmd       string
+	Args      []string
+	Stdin     string
+	OnFailure string `json:",omitempty"`
+	Undo      *Cmd   `json:",omitempty"`
+
+	Timeout      time.Duration `json:",omitempty"`
+	Retries      int           `json:",omitempty"`
+	RetryBackoff time.Duration `json:",omitempty"`
+	IgnoreErrors bool          `json:",omitempty"`
+}
+
+// Failure is a failed command, OnFailure is the failure mode of its command
+// list.
+type Failure struct {
+	List      string
+	Command   string
+	Stderr    string
+	OnFailure string
+}
+
+// failures is the channel for failed commands, failures are dropped if
+// nobody reads them.
+var failures = make(chan *Failure, 16)
+
+// Failures returns the channel for failed commands.
+func Failures() <-chan *Failure {
+	return failures
+}
+
+// SendFailure sends the failure f, e.g., of a backend that does not run
+// commands.
+func SendFailure(f *Failure) {
+	select {
+	case failures <- f:
+	default:
+	}
+}
+
+// sendFailure sends a failure of command c with stderr.
+func (c *Cmd) sendFailure(stderr []byte) {
+	SendFailure(&Failure{
+		List:      c.List,
+		Command:   strings.Join(append([]string{c.Cmd}, c.Args...), " "),
+		Stderr:    string(stderr),
+		OnFailure: c.OnFailure,
+	})
 }
 
 // RunCmd runs the cmd with args and sets stdin to s, returns stdout and stderr.
@@ -645,9 +1101,164 @@ var RunCmd = func(ctx context.Context, cmd string, s string, arg ...string) (std
 	return
 }
 
-// Run runs the command.
+// run runs the command once with its timeout.
+func (c *Cmd) run(ctx context.Context) (stdout, stderr []byte, err error) {
+	start := time.Now()
+	defer func() { commandTrace.add(newTraceEntry(c, start, stderr, err)) }()
+
+	if c.Timeout <= 0 {
+		return RunCmd(ctx, c.Cmd, c.Stdin, c.Args...)
+	}
+	cmdCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	stdout, stderr, err = RunCmd(cmdCtx, c.Cmd, c.Stdin, c.Args...)
+	if err != nil && ctx.Err() == nil && errors.Is(cmdCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s: %w", c.Timeout, err)
+	}
+	return
+}
+
+// Run runs the command, retries it if it failed and reports the failure
+// unless errors are ignored.
 func (c *Cmd) Run(ctx context.Context) (stdout, stderr []byte, err error) {
-	return RunCmd(ctx, c.Cmd, c.Stdin, c.Args...)
+	stdout, stderr, _, err = c.runIgnore(ctx)
+	return
+}
+
+// runIgnore runs the command like Run and also returns whether the command
+// failed but the failure was ignored.
+func (c *Cmd) runIgnore(ctx context.Context) (stdout, stderr []byte, ignored bool, err error) {
+	backoff := c.RetryBackoff
+	for retry := 0; ; retry++ {
+		stdout, stderr, err = c.run(ctx)
+		if err == nil || errors.Is(err, context.Canceled) ||
+			retry >= c.Retries || ctx.Err() != nil {
+			break
+		}
+
+		// wait before retrying
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		backoff *= 2
+	}
+
+	if err != nil && !errors.Is(err, context.Canceled) {
+		if c.IgnoreErrors {
+			return stdout, stderr, true, nil
+		}
+		metrics.CommandFailures.Inc()
+		c.sendFailure(stderr)
+	}
+	return
+}
+
+// splitLine splits the command line into its fields like a shell. Fields are
+// separated by whitespace, can be quoted with single or double quotes and
+// characters can be escaped with backslashes outside of single quotes.
+func splitLine(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			// escaped character, in double quotes only some characters
+			// can be escaped
+			if quote == '"' && !strings.ContainsRune(`"\$`+"`", r) {
+				field.WriteRune('\\')
+			}
+			field.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inField = true
+		case quote != 0:
+			// quoted character
+			if r == quote {
+				quote = 0
+				continue
+			}
+			field.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inField = true
+		case unicode.IsSpace(r):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if escaped {
+		return nil, errors.New("unterminated escape")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote %c", quote)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// getCmd returns the Cmd of the command list identified by name for the
+// command line and stdin templates executed on data, nil if the command line
+// is empty.
+func (cl *CommandList) getCmd(name, lineTmpl, stdinTmpl string, data any) (*Cmd, error) {
+	// execute template for command line
+	line, err := cl.executeTemplate(lineTmpl, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute template for command line: %w", err)
+	}
+
+	// execute template for stdin
+	stdin, err := cl.executeTemplate(stdinTmpl, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute template for stdin: %w", err)
+	}
+
+	// extract command from command line
+	fields, err := splitLine(line)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse command line: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	command := fields[0]
+
+	// extract arguments from command line
+	args := []string{}
+	if len(fields) > 1 {
+		args = fields[1:]
+	}
+	return &Cmd{
+		List:  name,
+		Cmd:   command,
+		Args:  args,
+		Stdin: stdin,
+	}, nil
+}
+
+// setOptions sets the timeout, retries and error handling of c from command.
+func (c *Cmd) setOptions(command *Command) {
+	c.Timeout = command.Timeout
+	c.Retries = command.Retries
+	c.RetryBackoff = command.RetryBackoff
+	c.IgnoreErrors = command.IgnoreErrors
 }
 
 // GetCmds returns a list of Cmds ready to run.
@@ -658,35 +1269,157 @@ func GetCmds(name string, data any) ([]*Cmd, error) {
 	}
 	var commands []*Cmd
 	for _, c := range cl.Commands {
-		// execute template for command line
-		line, err := cl.executeTemplate(c.Line, data)
+		cmd, err := cl.getCmd(name, c.Line, c.Stdin, data)
 		if err != nil {
-			return nil, fmt.Errorf("could not execute template for command line: %w", err)
+			return nil, err
+		}
+		if cmd == nil {
+			continue
 		}
+		cmd.OnFailure = cl.OnFailure
+		cmd.setOptions(c)
 
-		// execute template for stdin
-		stdin, err := cl.executeTemplate(c.Stdin, data)
+		// get undo command
+		undo, err := cl.getCmd(name, c.Undo, c.UndoStdin, data)
 		if err != nil {
-			return nil, fmt.Errorf("could not execute template for stdin: %w", err)
+			return nil, fmt.Errorf("could not get undo command: %w", err)
 		}
+		if undo != nil {
+			undo.setOptions(c)
+		}
+		cmd.Undo = undo
+
+		commands = append(commands, cmd)
+	}
+	return commands, nil
+}
+
+// CmdError is the error of a failed command of a command list.
+type CmdError struct {
+	List   string
+	Cmd    string
+	Args   []string
+	Stdin  string
+	Stdout string
+	Stderr string
+	Err    error
+}
 
-		// extract command from command line
-		fields := strings.Fields(line)
-		if len(fields) == 0 {
+// Error returns the error as string.
+func (e *CmdError) Error() string {
+	cmd := strings.Join(append([]string{e.Cmd}, e.Args...), " ")
+	return fmt.Sprintf("command %q of %s failed: %v: %s",
+		cmd, e.List, e.Err, strings.TrimSpace(e.Stderr))
+}
+
+// Unwrap returns the wrapped error.
+func (e *CmdError) Unwrap() error {
+	return e.Err
+}
+
+// newCmdError returns a new CmdError for the command c that failed with err,
+// stdout and stderr.
+func newCmdError(c *Cmd, stdout, stderr []byte, err error) *CmdError {
+	return &CmdError{
+		List:   c.List,
+		Cmd:    c.Cmd,
+		Args:   c.Args,
+		Stdin:  c.Stdin,
+		Stdout: string(stdout),
+		Stderr: string(stderr),
+		Err:    err,
+	}
+}
+
+// rollback runs the undo commands in reverse order and returns the errors of
+// all failed undo commands. It is not canceled with ctx, so the applied
+// commands are always reverted.
+func rollback(ctx context.Context, undo []*Cmd) []error {
+	ctx = context.WithoutCancel(ctx)
+	j := JournalFromContext(ctx)
+	var errs []error
+	for _, c := range slices.Backward(undo) {
+		if stdout, stderr, err := c.Run(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("could not roll back: %w",
+				newCmdError(c, stdout, stderr, err)))
 			continue
 		}
-		command := fields[0]
 
-		// extract arguments from command line
-		args := []string{}
-		if len(fields) > 1 {
-			args = fields[1:]
+		// change undone, remove it from journal
+		if j == nil {
+			continue
+		}
+		if err := j.Remove(c); err != nil {
+			errs = append(errs, fmt.Errorf("could not remove undo command from journal: %w", err))
 		}
-		commands = append(commands, &Cmd{
-			Cmd:   command,
-			Args:  args,
-			Stdin: stdin,
-		})
 	}
-	return commands, nil
+	return errs
+}
+
+// Journal records the undo commands of applied commands, e.g., to undo
+// them after a failed shutdown.
+type Journal interface {
+	Add(undo *Cmd) error
+	Remove(undo *Cmd) error
+}
+
+// journalKey is the context key of the journal.
+type journalKey struct{}
+
+// WithJournal returns a copy of ctx with the journal j. RunCmds adds the
+// undo commands of all commands it applied successfully with ctx to j.
+func WithJournal(ctx context.Context, j Journal) context.Context {
+	return context.WithValue(ctx, journalKey{}, j)
+}
+
+// JournalFromContext returns the journal in ctx, nil if there is none.
+func JournalFromContext(ctx context.Context) Journal {
+	j, _ := ctx.Value(journalKey{}).(Journal)
+	return j
+}
+
+// RunCmds runs the commands in the command list identified by name on data
+// with the failure mode of the command list and returns the errors of all
+// failed commands as CmdErrors. Commands canceled with ctx are not treated as
+// failed. If ctx contains a journal, the undo commands of all applied
+// commands are added to it.
+func RunCmds(ctx context.Context, name string, data any) error {
+	cmds, err := GetCmds(name, data)
+	if err != nil {
+		return fmt.Errorf("could not get %s commands: %w", name, err)
+	}
+	j := JournalFromContext(ctx)
+	var errs []error
+	var undo []*Cmd
+	for _, c := range cmds {
+		stdout, stderr, ignored, err := c.runIgnore(ctx)
+		if errors.Is(err, context.Canceled) || ignored {
+			// not applied, nothing to undo
+			continue
+		}
+		if err == nil {
+			// command applied, remember its undo command
+			if c.Undo == nil {
+				continue
+			}
+			undo = append(undo, c.Undo)
+			if j == nil {
+				continue
+			}
+			if err := j.Add(c.Undo); err != nil {
+				errs = append(errs, fmt.Errorf("could not add undo command to journal: %w", err))
+			}
+			continue
+		}
+
+		errs = append(errs, newCmdError(c, stdout, stderr, err))
+		switch c.OnFailure {
+		case OnFailureAbort:
+			return errors.Join(errs...)
+		case OnFailureRollback:
+			errs = append(errs, rollback(ctx, undo)...)
+			return errors.Join(errs...)
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -2,10 +2,15 @@ package cmdtmpl
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"text/template"
+	"time"
 
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
 )
@@ -20,6 +25,57 @@ func TestExecuteTemplateParseError(t *testing.T) {
 	}
 }
 
+// TestExecuteTemplate tests ExecuteTemplate.
+func TestExecuteTemplate(t *testing.T) {
+	// not existing template
+	if _, err := ExecuteTemplate("does not exist", nil); err == nil {
+		t.Error("not existing template should return error")
+	}
+
+	// existing template
+	config := daemoncfg.NewConfig()
+	got, err := ExecuteTemplate("SplitRoutingRules", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "table inet oc-daemon-routing {") {
+		t.Errorf("got invalid split routing rules: %s", got)
+	}
+}
+
+// TestReset tests Reset.
+func TestReset(t *testing.T) {
+	// load templates and command lists from files
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "command-lists.tmpl")
+	if err := os.WriteFile(tmpl, []byte("valid template"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	lists := filepath.Join(dir, "command-lists.json")
+	b := []byte(`[{"Name":"TrafPolCleanup","Commands":[{"Line":"echo TrafPol"}]}]`)
+	if err := os.WriteFile(lists, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadTemplates(tmpl); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadCommandLists(lists); err != nil {
+		t.Fatal(err)
+	}
+
+	// reset to built-in defaults
+	Reset()
+	if LoadedTemplates != "" {
+		t.Errorf("unexpected loaded templates: %s", LoadedTemplates)
+	}
+	if defaultTemplate != builtinTemplate {
+		t.Error("reset did not restore templates")
+	}
+	if CommandLists[TrafPolCleanup] != builtinCommandLists[TrafPolCleanup] {
+		t.Error("reset did not restore command lists")
+	}
+}
+
 // TestLoadTemplates tests LoadTemplates.
 func TestLoadTemplates(t *testing.T) {
 	dir := t.TempDir()
@@ -86,6 +142,22 @@ func TestLoadCommandLists(t *testing.T) {
 		t.Errorf("invalid command list name should return error")
 	}
 
+	b = []byte(`[{"Name":"TrafPolCleanup","OnFailure":"invalid"}]`)
+	if err := os.WriteFile(f, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadCommandLists(f); err == nil {
+		t.Errorf("invalid failure mode should return error")
+	}
+
+	b = []byte(`[{"Name":"TrafPolCleanup","Commands":[{"Line":"true","Retries":-1}]}]`)
+	if err := os.WriteFile(f, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadCommandLists(f); err == nil {
+		t.Errorf("invalid command should return error")
+	}
+
 	// valid file, update command lists
 	oldTrafPolCleanup := CommandLists[TrafPolCleanup].Commands
 	oldVPNSetupCleanup := CommandLists[VPNSetupCleanup].Commands
@@ -179,6 +251,9 @@ func TestGetCommandList(t *testing.T) {
 		"VPNSetupSetDNS",
 		"VPNSetupGetDNS",
 		"VPNSetupCleanup",
+		"VPNSetupReset",
+		"VPNSetupSetupNetNS",
+		"VPNSetupTeardownNetNS",
 	} {
 		cl := getCommandList(name)
 		if cl.Name != name {
@@ -187,6 +262,27 @@ func TestGetCommandList(t *testing.T) {
 	}
 }
 
+// TestGetOnFailure tests GetOnFailure.
+func TestGetOnFailure(t *testing.T) {
+	defer Reset()
+
+	// not existing
+	if got := GetOnFailure("DoesNotExist"); got != "" {
+		t.Errorf("got %q, want empty", got)
+	}
+
+	// existing, default and set failure mode
+	if got := GetOnFailure(VPNSetupSetup); got != "" {
+		t.Errorf("got %q, want empty", got)
+	}
+	cl := *CommandLists[VPNSetupSetup]
+	cl.OnFailure = OnFailureRollback
+	CommandLists[VPNSetupSetup] = &cl
+	if got := GetOnFailure(VPNSetupSetup); got != OnFailureRollback {
+		t.Errorf("got %q, want %q", got, OnFailureRollback)
+	}
+}
+
 // TestRunCmd tests RunCmd.
 func TestRunCmd(t *testing.T) {
 	ctx := context.Background()
@@ -235,6 +331,201 @@ func TestCmdRun(t *testing.T) {
 	}
 }
 
+// TestCmdRunFailures tests Run of Cmd, failures.
+func TestCmdRunFailures(t *testing.T) {
+	oldRunCmd := RunCmd
+	defer func() { RunCmd = oldRunCmd }()
+	RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
+		return nil, []byte("test stderr"), errors.New("test error")
+	}
+
+	// drain failures of other tests
+	for len(failures) > 0 {
+		<-failures
+	}
+
+	cmd := &Cmd{
+		List: "TestList",
+		Cmd:  "test",
+		Args: []string{"a", "b"},
+	}
+	if _, _, err := cmd.Run(context.Background()); err == nil {
+		t.Error("failed command should return error")
+	}
+	want := &Failure{
+		List:    "TestList",
+		Command: "test a b",
+		Stderr:  "test stderr",
+	}
+	got := <-Failures()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestCmdRunTimeout tests Run of Cmd with timeout.
+func TestCmdRunTimeout(t *testing.T) {
+	cmd := &Cmd{
+		Cmd:     "sleep",
+		Args:    []string{"10"},
+		Timeout: 10 * time.Millisecond,
+	}
+	if _, _, err := cmd.Run(context.Background()); err == nil {
+		t.Error("timed out command should return error")
+	}
+
+	// drain failures
+	for len(failures) > 0 {
+		<-failures
+	}
+}
+
+// TestCmdRunRetries tests Run of Cmd with retries and ignored errors.
+func TestCmdRunRetries(t *testing.T) {
+	oldRunCmd := RunCmd
+	defer func() { RunCmd = oldRunCmd }()
+	runs := 0
+	failRuns := 0
+	RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
+		runs++
+		if runs <= failRuns {
+			return nil, nil, errors.New("test error")
+		}
+		return nil, nil, nil
+	}
+
+	// drain failures of other tests
+	for len(failures) > 0 {
+		<-failures
+	}
+
+	for _, test := range []struct {
+		retries      int
+		ignoreErrors bool
+		failRuns     int
+		wantRuns     int
+		wantErr      bool
+	}{
+		{0, false, 0, 1, false},
+		{0, false, 1, 1, true},
+		{2, false, 2, 3, false},
+		{2, false, 3, 3, true},
+		{2, true, 3, 3, false},
+	} {
+		runs = 0
+		failRuns = test.failRuns
+		cmd := &Cmd{
+			Cmd:          "test",
+			Retries:      test.retries,
+			RetryBackoff: time.Millisecond,
+			IgnoreErrors: test.ignoreErrors,
+		}
+		_, _, err := cmd.Run(context.Background())
+		if (err != nil) != test.wantErr {
+			t.Errorf("got error %v, want error %t", err, test.wantErr)
+		}
+		if runs != test.wantRuns {
+			t.Errorf("got %d runs, want %d", runs, test.wantRuns)
+		}
+		if got := len(failures) > 0; got != test.wantErr {
+			t.Errorf("got failure %t, want %t", got, test.wantErr)
+		}
+		for len(failures) > 0 {
+			<-failures
+		}
+	}
+
+	// canceled context stops retries
+	runs = 0
+	failRuns = 10
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cmd := &Cmd{Cmd: "test", Retries: 5, RetryBackoff: time.Hour}
+	if _, _, err := cmd.Run(ctx); err == nil {
+		t.Error("failed command should return error")
+	}
+	if runs != 1 {
+		t.Errorf("got %d runs, want 1", runs)
+	}
+	for len(failures) > 0 {
+		<-failures
+	}
+}
+
+// TestSplitLine tests splitLine.
+func TestSplitLine(t *testing.T) {
+	for _, test := range []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"  ", nil},
+		{"ip link set tun0 up", []string{"ip", "link", "set", "tun0", "up"}},
+		{" a\tb\n c ", []string{"a", "b", "c"}},
+		{`a 'b c' "d e"`, []string{"a", "b c", "d e"}},
+		{`a '' ""`, []string{"a", "", ""}},
+		{`a'b'"c"d`, []string{"abcd"}},
+		{`a\ b \'c\"`, []string{"a b", `'c"`}},
+		{`'a\b "c"'`, []string{`a\b "c"`}},
+		{`"a\"b\\c\d 'e'"`, []string{`a"b\c\d 'e'`}},
+	} {
+		got, err := splitLine(test.line)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.line, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %q, want %q", test.line, got, test.want)
+		}
+	}
+
+	// invalid
+	for _, line := range []string{`a 'b`, `a "b`, `a\`} {
+		if _, err := splitLine(line); err == nil {
+			t.Errorf("%q: should return error", line)
+		}
+	}
+}
+
+// TestGetCmdsOptions tests GetCmds with quoting and command options.
+func TestGetCmdsOptions(t *testing.T) {
+	defer Reset()
+	cl := &CommandList{
+		Name: "TestList",
+		Commands: []*Command{{
+			Line:         `resolvectl domain {{.Device}} "{{.Domains}}"`,
+			Undo:         "resolvectl revert {{.Device}}",
+			Timeout:      time.Second,
+			Retries:      2,
+			RetryBackoff: time.Millisecond,
+			IgnoreErrors: true,
+		}},
+		template: template.New("TestList"),
+	}
+	CommandLists["TestList"] = cl
+
+	data := struct{ Device, Domains string }{"tun0", "a.example b.example"}
+	cmds, err := GetCmds("TestList", data)
+	if err != nil || len(cmds) != 1 {
+		t.Fatalf("got invalid command list: %v, %v", cmds, err)
+	}
+	want := []string{"domain", "tun0", "a.example b.example"}
+	if got := cmds[0].Args; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, c := range []*Cmd{cmds[0], cmds[0].Undo} {
+		if c.Timeout != time.Second || c.Retries != 2 ||
+			c.RetryBackoff != time.Millisecond || !c.IgnoreErrors {
+			t.Errorf("got invalid options in %v", c)
+		}
+	}
+
+	// invalid quoting
+	cl.Commands[0].Line = `echo "test`
+	if _, err := GetCmds("TestList", data); err == nil {
+		t.Error("invalid quoting should return error")
+	}
+}
+
 // TestGetCmds tets GetCmds.
 func TestGetCmds(t *testing.T) {
 	// not existing
@@ -259,6 +550,9 @@ func TestGetCmds(t *testing.T) {
 		"VPNSetupSetDNS",
 		"VPNSetupGetDNS",
 		"VPNSetupCleanup",
+		"VPNSetupReset",
+		"VPNSetupSetupNetNS",
+		"VPNSetupTeardownNetNS",
 	} {
 		if cmds, err := GetCmds(name, daemoncfg.NewConfig()); err != nil ||
 			len(cmds) == 0 {
@@ -282,3 +576,442 @@ func TestGetCmds(t *testing.T) {
 		}
 	}
 }
+
+// TestGetCmdsNetNS tests GetCmds with network namespace.
+func TestGetCmdsNetNS(t *testing.T) {
+	config := daemoncfg.NewConfig()
+	config.NetNS.Enabled = true
+	config.VPNConfig.Device.Name = "oc-daemon-tun0"
+
+	// vpn setup runs in network namespace without resolvectl
+	cmds, err := GetCmds(VPNSetupSetup, config)
+	if err != nil || len(cmds) == 0 {
+		t.Fatalf("got invalid command list: %v", err)
+	}
+	for _, c := range cmds {
+		if c.Cmd != "ip" ||
+			len(c.Args) < 3 ||
+			c.Args[0] != "netns" ||
+			c.Args[1] != "exec" ||
+			c.Args[2] != "oc-daemon" {
+			t.Errorf("command should run in network namespace: %s %v", c.Cmd, c.Args)
+		}
+	}
+
+	// dns settings are not checked
+	cmds, err = GetCmds(VPNSetupGetDNS, config)
+	if err != nil || len(cmds) != 0 {
+		t.Errorf("got invalid command list: %v, %v", cmds, err)
+	}
+
+	// traffic policing allows traffic from network namespace
+	cmds, err = GetCmds(TrafPolSetFilterRules, config)
+	if err != nil || len(cmds) != 1 {
+		t.Fatalf("got invalid command list: %v, %v", cmds, err)
+	}
+	if !strings.Contains(cmds[0].Stdin, "iifname oc-daemon-veth0 ip daddr @allowhosts4") {
+		t.Errorf("filter rules should allow network namespace: %s", cmds[0].Stdin)
+	}
+
+	// network namespace setup
+	cmds, err = GetCmds(VPNSetupSetupNetNS, config)
+	if err != nil || len(cmds) == 0 {
+		t.Fatalf("got invalid command list: %v", err)
+	}
+	last := cmds[len(cmds)-1]
+	if !strings.Contains(last.Stdin, "ip saddr 169.254.42.0/30 oifname != oc-daemon-veth0") {
+		t.Errorf("invalid network namespace rules: %s", last.Stdin)
+	}
+}
+
+// TestGetCmdsSplitRoutingUndo tests GetCmds with undo of split routing rules
+// in a non-default nftables table.
+func TestGetCmdsSplitRoutingUndo(t *testing.T) {
+	config := daemoncfg.NewConfig()
+	config.SplitRouting.NftTable = "oc-daemon-routing-test"
+	config.VPNConfig.Device.Name = "oc-daemon-tun0"
+
+	cmds, err := GetCmds(VPNSetupSetup, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cmds {
+		if !strings.Contains(c.Stdin, "table inet oc-daemon-routing-test") {
+			continue
+		}
+		if c.Undo == nil {
+			t.Fatal("split routing rules should have undo command")
+		}
+		want := []string{"-f", "-", "delete", "table", "inet", "oc-daemon-routing-test"}
+		if c.Undo.Cmd != "nft" || !reflect.DeepEqual(c.Undo.Args, want) {
+			t.Errorf("got %s %v, want nft %v", c.Undo.Cmd, c.Undo.Args, want)
+		}
+		return
+	}
+	t.Error("split routing rules not found")
+}
+
+// TestCmdError tests CmdError.
+func TestCmdError(t *testing.T) {
+	err := &CmdError{
+		List:   "TrafPolCleanup",
+		Cmd:    "nft",
+		Args:   []string{"-f", "-"},
+		Stderr: "stderr\n",
+		Err:    context.Canceled,
+	}
+	want := `command "nft -f -" of TrafPolCleanup failed: context canceled: stderr`
+	if err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Error("error should wrap context canceled")
+	}
+}
+
+// TestGetCmdsUndo tests GetCmds with undo commands.
+func TestGetCmdsUndo(t *testing.T) {
+	defer Reset()
+	CommandLists[TrafPolCleanup] = &CommandList{
+		Name:      TrafPolCleanup,
+		OnFailure: OnFailureRollback,
+		Commands: []*Command{
+			{Line: "echo 1", Undo: "echo undo {{.Verbose}}", UndoStdin: "stdin"},
+			{Line: "echo 2", Undo: "{{if .Verbose}}echo undo{{end}}"},
+			{Line: "echo 3", Undo: "{{.Invalid}}"},
+		},
+		template: defaultTemplate,
+	}
+
+	// invalid undo template
+	if _, err := GetCmds(TrafPolCleanup, daemoncfg.NewConfig()); err == nil {
+		t.Error("invalid undo template should return error")
+	}
+
+	// valid undo templates
+	CommandLists[TrafPolCleanup].Commands = CommandLists[TrafPolCleanup].Commands[:2]
+	cmds, err := GetCmds(TrafPolCleanup, daemoncfg.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Cmd{
+		{
+			List:      TrafPolCleanup,
+			Cmd:       "echo",
+			Args:      []string{"1"},
+			OnFailure: OnFailureRollback,
+			Undo: &Cmd{
+				List:  TrafPolCleanup,
+				Cmd:   "echo",
+				Args:  []string{"undo", "false"},
+				Stdin: "stdin",
+			},
+		},
+		{
+			List:      TrafPolCleanup,
+			Cmd:       "echo",
+			Args:      []string{"2"},
+			OnFailure: OnFailureRollback,
+		},
+	}
+	if !reflect.DeepEqual(cmds, want) {
+		t.Errorf("got %v, want %v", cmds, want)
+	}
+}
+
+// TestRunCmds tests RunCmds.
+func TestRunCmds(t *testing.T) {
+	defer Reset()
+	oldRunCmd := RunCmd
+	defer func() { RunCmd = oldRunCmd }()
+
+	// not existing command list
+	if err := RunCmds(context.Background(), "does not exist", nil); err == nil {
+		t.Error("not existing command list should return error")
+	}
+
+	// record commands, fail command "fail"
+	var got []string
+	RunCmd = func(_ context.Context, cmd string, _ string, arg ...string) ([]byte, []byte, error) {
+		got = append(got, strings.Join(append([]string{cmd}, arg...), " "))
+		if cmd == "fail" {
+			return nil, []byte("stderr"), errors.New("test error")
+		}
+		if cmd == "cancel" {
+			return nil, nil, context.Canceled
+		}
+		return nil, nil, nil
+	}
+
+	for _, test := range []struct {
+		onFailure string
+		want      []string
+	}{
+		{"", []string{"cmd 1", "cancel 2", "fail 3", "cmd 4"}},
+		{OnFailureContinue, []string{"cmd 1", "cancel 2", "fail 3", "cmd 4"}},
+		{OnFailureAbort, []string{"cmd 1", "cancel 2", "fail 3"}},
+		{OnFailureRollback, []string{"cmd 1", "cancel 2", "fail 3", "undo 1"}},
+	} {
+		got = nil
+		CommandLists[TrafPolCleanup] = &CommandList{
+			Name:      TrafPolCleanup,
+			OnFailure: test.onFailure,
+			Commands: []*Command{
+				{Line: "cmd 1", Undo: "undo 1"},
+				{Line: "cancel 2", Undo: "undo 2"},
+				{Line: "fail 3", Undo: "undo 3"},
+				{Line: "cmd 4", Undo: "undo 4"},
+			},
+			template: defaultTemplate,
+		}
+		err := RunCmds(context.Background(), TrafPolCleanup, nil)
+		var cmdErr *CmdError
+		if !errors.As(err, &cmdErr) || cmdErr.Cmd != "fail" || cmdErr.Stderr != "stderr" {
+			t.Errorf("%s: got invalid error %v", test.onFailure, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.onFailure, got, test.want)
+		}
+	}
+
+	// failed rollback
+	CommandLists[TrafPolCleanup].Commands[0].Undo = "fail undo"
+	err := RunCmds(context.Background(), TrafPolCleanup, nil)
+	if err == nil || !strings.Contains(err.Error(), "could not roll back") {
+		t.Errorf("got invalid error %v", err)
+	}
+	// ignored failure is not rolled back
+	got = nil
+	CommandLists[TrafPolCleanup] = &CommandList{
+		Name:      TrafPolCleanup,
+		OnFailure: OnFailureRollback,
+		Commands: []*Command{
+			{Line: "cmd 1", Undo: "undo 1"},
+			{Line: "fail 2", Undo: "undo 2", IgnoreErrors: true},
+			{Line: "fail 3", Undo: "undo 3"},
+		},
+		template: defaultTemplate,
+	}
+	_ = RunCmds(context.Background(), TrafPolCleanup, nil)
+	want := []string{"cmd 1", "fail 2", "fail 3", "undo 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// testJournal is a journal for testing.
+type testJournal struct {
+	undo []string
+	err  error
+}
+
+func (j *testJournal) Add(undo *Cmd) error {
+	j.undo = append(j.undo, strings.Join(append([]string{undo.Cmd}, undo.Args...), " "))
+	return j.err
+}
+
+func (j *testJournal) Remove(undo *Cmd) error {
+	j.undo = slices.DeleteFunc(j.undo, func(s string) bool {
+		return s == strings.Join(append([]string{undo.Cmd}, undo.Args...), " ")
+	})
+	return j.err
+}
+
+// TestRunCmdsJournal tests RunCmds with journal.
+func TestRunCmdsJournal(t *testing.T) {
+	defer Reset()
+	oldRunCmd := RunCmd
+	defer func() { RunCmd = oldRunCmd }()
+	RunCmd = func(_ context.Context, cmd string, _ string, _ ...string) ([]byte, []byte, error) {
+		if cmd == "fail" {
+			return nil, nil, errors.New("test error")
+		}
+		return nil, nil, nil
+	}
+	CommandLists[TrafPolCleanup] = &CommandList{
+		Name: TrafPolCleanup,
+		Commands: []*Command{
+			{Line: "cmd 1", Undo: "undo 1"},
+			{Line: "fail 2", Undo: "undo 2"},
+			{Line: "fail 3", Undo: "undo 3", IgnoreErrors: true},
+			{Line: "cmd 4"},
+			{Line: "cmd 5", Undo: "undo 5"},
+		},
+		template: defaultTemplate,
+	}
+
+	// only undo commands of applied commands are added
+	j := &testJournal{}
+	ctx := WithJournal(context.Background(), j)
+	_ = RunCmds(ctx, TrafPolCleanup, nil)
+	want := []string{"undo 1", "undo 5"}
+	if !reflect.DeepEqual(j.undo, want) {
+		t.Errorf("got %q, want %q", j.undo, want)
+	}
+
+	// rolled back commands are removed
+	j = &testJournal{}
+	ctx = WithJournal(context.Background(), j)
+	CommandLists[TrafPolCleanup].OnFailure = OnFailureRollback
+	_ = RunCmds(ctx, TrafPolCleanup, nil)
+	if len(j.undo) != 0 {
+		t.Errorf("got %q, want no undo commands", j.undo)
+	}
+
+	// journal errors
+	j = &testJournal{err: errors.New("test error")}
+	ctx = WithJournal(context.Background(), j)
+	CommandLists[TrafPolCleanup].OnFailure = OnFailureContinue
+	err := RunCmds(ctx, TrafPolCleanup, nil)
+	if err == nil || !strings.Contains(err.Error(), "journal") {
+		t.Errorf("got invalid error %v", err)
+	}
+}
+
+// TestLoad tests Load.
+func TestLoad(t *testing.T) {
+	defer Reset()
+	dir := t.TempDir()
+	templates := filepath.Join(dir, "templates")
+	lists := filepath.Join(dir, "lists.json")
+	hooks := filepath.Join(dir, "hooks.d")
+
+	// not existing files, built-in defaults
+	if err := Load(templates, lists, hooks); err != nil {
+		t.Fatal(err)
+	}
+	if tmpl, cls := Get(); tmpl != "" || !reflect.DeepEqual(cls, builtinCommandLists) {
+		t.Errorf("got %q, %v, want built-in defaults", tmpl, cls)
+	}
+
+	// valid files
+	if err := os.WriteFile(templates, []byte(`{{define "Test"}}test{{end}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lists, []byte(`[{
+		"Name": "TrafPolCleanup",
+		"Commands": [{"Line": "echo {{template \"Test\"}}"}]
+	}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(hooks, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(hooks, "hooks.json"), []byte(`[{
+		"Name": "HookConnected",
+		"Commands": [{"Line": "echo connected"}]
+	}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(templates, lists, hooks); err != nil {
+		t.Fatal(err)
+	}
+	cmds, err := GetCmds(TrafPolCleanup, nil)
+	if err != nil || len(cmds) != 1 || !reflect.DeepEqual(cmds[0].Args, []string{"test"}) {
+		t.Errorf("got %v, %v, want command list from file", cmds, err)
+	}
+	if !HasCommandList(HookConnected) {
+		t.Error("hook should exist")
+	}
+
+	// invalid files, keep current templates and command lists
+	tmpl, cls := Get()
+	for _, invalid := range []string{templates, lists, filepath.Join(hooks, "hooks.json")} {
+		old, err := os.ReadFile(invalid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(invalid, []byte("{{ invalid"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := Load(templates, lists, hooks); err == nil {
+			t.Errorf("invalid file %s should return error", invalid)
+		}
+		if gotTmpl, gotCls := Get(); gotTmpl != tmpl || !reflect.DeepEqual(gotCls, cls) {
+			t.Errorf("%s: got %q, %v, want current state", invalid, gotTmpl, gotCls)
+		}
+		if err := os.WriteFile(invalid, old, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestLoadHooks tests LoadHooks.
+func TestLoadHooks(t *testing.T) {
+	defer Reset()
+	dir := t.TempDir()
+
+	// not existing dir
+	if err := LoadHooks(filepath.Join(dir, "does not exist")); err != nil {
+		t.Errorf("not existing dir should not return error: %v", err)
+	}
+	if HasCommandList(HookConnected) {
+		t.Error("hook should not exist")
+	}
+
+	// invalid files
+	f := filepath.Join(dir, "10-hooks.json")
+	for _, invalid := range []string{
+		"invalid json",
+		`[{"Name": "TrafPolCleanup"}]`,
+		`[{"Name": "HookConnected", "OnFailure": "invalid"}]`,
+		`[{"Name": "HookConnected", "Commands": [{"Line": "true", "Timeout": -1}]}]`,
+		`[{"Name": "HookConnected", "OnFailure": "abort"},
+		  {"Name": "HookConnected", "OnFailure": "rollback"}]`,
+	} {
+		if err := os.WriteFile(f, []byte(invalid), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := LoadHooks(dir); err == nil {
+			t.Errorf("invalid file %q should return error", invalid)
+		}
+		if HasCommandList(HookConnected) {
+			t.Error("hook should not exist after invalid file")
+		}
+	}
+
+	// hook in command lists file
+	lists := filepath.Join(t.TempDir(), "command-lists.json")
+	if err := os.WriteFile(lists, []byte(`[{
+		"Name": "HookConnected",
+		"Commands": [{"Line": "echo 1"}]
+	}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadCommandLists(lists); err != nil {
+		t.Fatal(err)
+	}
+
+	// valid files, appended in order of file names
+	if err := os.WriteFile(f, []byte(`[{
+		"Name": "HookConnected",
+		"Commands": [{"Line": "echo 2"}]
+	}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "20-hooks.json"), []byte(`[{
+		"Name": "HookConnected",
+		"OnFailure": "abort",
+		"Commands": [{"Line": "echo {{.Verbose}}"}]
+	}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ignored.txt"), []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadHooks(dir); err != nil {
+		t.Fatal(err)
+	}
+	cmds, err := GetCmds(HookConnected, daemoncfg.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, c := range cmds {
+		got = append(got, c.Cmd+" "+strings.Join(c.Args, " ")+" "+c.OnFailure)
+	}
+	want := []string{"echo 1 abort", "echo 2 abort", "echo false abort"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
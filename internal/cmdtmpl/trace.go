@@ -0,0 +1,134 @@
+package cmdtmpl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	// traceMaxEntries is the maximum number of entries in the command
+	// trace, older entries are dropped.
+	traceMaxEntries = 256
+
+	// traceMaxStderr is the maximum length of stderr in a trace entry.
+	traceMaxStderr = 1024
+)
+
+// TraceEntry is an entry in the command trace, it contains the command list
+// name, the command and its arguments, the SHA-256 digest of stdin and the
+// exit code, error, duration and stderr of the command. ExitCode is -1 if
+// the command did not exit normally.
+type TraceEntry struct {
+	Time        time.Time
+	List        string
+	Command     string
+	Args        []string
+	StdinDigest string `json:",omitempty"`
+	ExitCode    int
+	Error       string `json:",omitempty"`
+	Duration    time.Duration
+	Stderr      string `json:",omitempty"`
+}
+
+// Failed returns whether the command in the trace entry failed.
+func (t *TraceEntry) Failed() bool {
+	return t.ExitCode != 0 || t.Error != ""
+}
+
+// Copy returns a copy of the trace entry.
+func (t *TraceEntry) Copy() *TraceEntry {
+	c := *t
+	c.Args = append([]string(nil), t.Args...)
+	return &c
+}
+
+// newTraceEntry returns a new trace entry for the command c started at start
+// that returned stderr and err.
+func newTraceEntry(c *Cmd, start time.Time, stderr []byte, err error) *TraceEntry {
+	t := &TraceEntry{
+		Time:     start,
+		List:     c.List,
+		Command:  c.Cmd,
+		Args:     append([]string(nil), c.Args...),
+		Duration: time.Since(start),
+	}
+	if c.Stdin != "" {
+		digest := sha256.Sum256([]byte(c.Stdin))
+		t.StdinDigest = hex.EncodeToString(digest[:])
+	}
+	if err != nil {
+		t.ExitCode = -1
+		t.Error = err.Error()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.Exited() {
+			t.ExitCode = exitErr.ExitCode()
+		}
+	}
+	if len(stderr) > traceMaxStderr {
+		stderr = stderr[:traceMaxStderr]
+	}
+	t.Stderr = string(stderr)
+	return t
+}
+
+// trace is the bounded trace of executed commands.
+type trace struct {
+	mutex   sync.Mutex
+	entries []*TraceEntry
+	next    int
+}
+
+// add adds entry to the trace, replacing the oldest entry if the trace is
+// full.
+func (t *trace) add(entry *TraceEntry) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.entries) < traceMaxEntries {
+		t.entries = append(t.entries, entry)
+		return
+	}
+	t.entries[t.next] = entry
+	t.next = (t.next + 1) % traceMaxEntries
+}
+
+// get returns copies of all entries in the trace, oldest first.
+func (t *trace) get() []*TraceEntry {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	entries := make([]*TraceEntry, 0, len(t.entries))
+	for i := range t.entries {
+		e := t.entries[(t.next+i)%len(t.entries)]
+		entries = append(entries, e.Copy())
+	}
+	return entries
+}
+
+// commandTrace is the trace of all executed commands.
+var commandTrace = &trace{}
+
+// Trace returns the trace of the recently executed commands, oldest first.
+func Trace() []*TraceEntry {
+	return commandTrace.get()
+}
+
+// FilterTrace returns the entries in trace of the command list identified by
+// list and of the command cmd, only the failed commands if failed is set.
+// Empty list and cmd match all command lists and commands.
+func FilterTrace(trace []*TraceEntry, list, cmd string, failed bool) []*TraceEntry {
+	var entries []*TraceEntry
+	for _, e := range trace {
+		if (list != "" && e.List != list) ||
+			(cmd != "" && e.Command != cmd) ||
+			(failed && !e.Failed()) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
@@ -0,0 +1,135 @@
+package cmdtmpl
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNewTraceEntry tests newTraceEntry.
+func TestNewTraceEntry(t *testing.T) {
+	// successful command without stdin
+	c := &Cmd{List: "TestList", Cmd: "test", Args: []string{"a", "b"}}
+	e := newTraceEntry(c, time.Now(), nil, nil)
+	if e.List != "TestList" || e.Command != "test" ||
+		!reflect.DeepEqual(e.Args, c.Args) || e.StdinDigest != "" ||
+		e.ExitCode != 0 || e.Error != "" || e.Failed() {
+		t.Errorf("got invalid trace entry %v", e)
+	}
+
+	// failed command with stdin and long stderr
+	c.Stdin = "test"
+	stderr := []byte(strings.Repeat("e", traceMaxStderr+1))
+	e = newTraceEntry(c, time.Now(), stderr, errors.New("test error"))
+	want := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	if e.StdinDigest != want {
+		t.Errorf("got %s, want %s", e.StdinDigest, want)
+	}
+	if e.ExitCode != -1 || e.Error != "test error" || !e.Failed() {
+		t.Errorf("got invalid trace entry %v", e)
+	}
+	if len(e.Stderr) != traceMaxStderr {
+		t.Errorf("got stderr length %d, want %d", len(e.Stderr), traceMaxStderr)
+	}
+
+	// exit code
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	e = newTraceEntry(c, time.Now(), nil, err)
+	if e.ExitCode != 3 {
+		t.Errorf("got exit code %d, want 3", e.ExitCode)
+	}
+}
+
+// TestTrace tests trace.
+func TestTrace(t *testing.T) {
+	tr := &trace{}
+	if got := tr.get(); len(got) != 0 {
+		t.Errorf("got %v, want empty trace", got)
+	}
+
+	// fill trace and overwrite oldest entries
+	for i := range traceMaxEntries + 2 {
+		tr.add(&TraceEntry{ExitCode: i})
+	}
+	got := tr.get()
+	if len(got) != traceMaxEntries {
+		t.Fatalf("got %d entries, want %d", len(got), traceMaxEntries)
+	}
+	for i, e := range got {
+		if e.ExitCode != i+2 {
+			t.Errorf("got entry %d at %d, want %d", e.ExitCode, i, i+2)
+		}
+	}
+
+	// entries are copies
+	got[0].ExitCode = 0
+	if tr.get()[0].ExitCode != 2 {
+		t.Error("trace should return copies of entries")
+	}
+}
+
+// TestCmdRunTrace tests the trace of Run of Cmd.
+func TestCmdRunTrace(t *testing.T) {
+	oldRunCmd := RunCmd
+	oldTrace := commandTrace
+	defer func() {
+		RunCmd = oldRunCmd
+		commandTrace = oldTrace
+	}()
+	RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
+		return nil, []byte("test stderr"), errors.New("test error")
+	}
+	commandTrace = &trace{}
+
+	cmd := &Cmd{
+		List:         "TestList",
+		Cmd:          "test",
+		Args:         []string{"a"},
+		Retries:      1,
+		IgnoreErrors: true,
+	}
+	_, _, _ = cmd.Run(context.Background())
+
+	// each attempt is traced
+	got := Trace()
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+	for _, e := range got {
+		if e.List != "TestList" || e.Command != "test" ||
+			e.Stderr != "test stderr" || e.Error != "test error" {
+			t.Errorf("got invalid trace entry %v", e)
+		}
+	}
+}
+
+// TestFilterTrace tests FilterTrace.
+func TestFilterTrace(t *testing.T) {
+	a := &TraceEntry{List: "ListA", Command: "ip"}
+	b := &TraceEntry{List: "ListA", Command: "nft", ExitCode: 1}
+	c := &TraceEntry{List: "ListB", Command: "ip", Error: "test error", ExitCode: -1}
+	trace := []*TraceEntry{a, b, c}
+
+	for _, test := range []struct {
+		list   string
+		cmd    string
+		failed bool
+		want   []*TraceEntry
+	}{
+		{"", "", false, trace},
+		{"ListA", "", false, []*TraceEntry{a, b}},
+		{"", "ip", false, []*TraceEntry{a, c}},
+		{"ListA", "ip", false, []*TraceEntry{a}},
+		{"", "", true, []*TraceEntry{b, c}},
+		{"ListB", "nft", false, nil},
+	} {
+		got := FilterTrace(trace, test.list, test.cmd, test.failed)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("got %v, want %v", got, test.want)
+		}
+	}
+}
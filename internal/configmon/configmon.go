@@ -0,0 +1,158 @@
+// Package configmon contains the configuration file monitor.
+package configmon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// Monitor is the Config Monitor interface.
+type Monitor interface {
+	Start() error
+	Stop()
+	Updates() chan struct{}
+}
+
+var _ Monitor = &ConfigMon{}
+
+// ConfigMon is a configuration file monitor.
+type ConfigMon struct {
+	watcher *fsnotify.Watcher
+	files   []string
+	updates chan struct{}
+	done    chan struct{}
+	closed  chan struct{}
+	hashes  map[string][sha256.Size]byte
+}
+
+// sendUpdate sends an update over the updates channel.
+func (c *ConfigMon) sendUpdate() {
+	// send an update or abort if we are shutting down
+	select {
+	case c.updates <- struct{}{}:
+	case <-c.done:
+	}
+}
+
+// hashFile returns the hash of the contents of file. The hash of a
+// missing file is the hash of an empty file.
+func hashFile(file string) [sha256.Size]byte {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		log.WithError(err).WithField("file", file).
+			Debug("Could not read config file in watcher")
+	}
+	return sha256.Sum256(b)
+}
+
+// handleEvent compares file hashes to see if the file changed and sends an
+// update notification.
+func (c *ConfigMon) handleEvent(file string) {
+	hash := hashFile(file)
+	old := c.hashes[file]
+	if bytes.Equal(hash[:], old[:]) {
+		return
+	}
+
+	c.hashes[file] = hash
+	c.sendUpdate()
+}
+
+// start starts the config monitor.
+func (c *ConfigMon) start() {
+	defer close(c.closed)
+	defer close(c.updates)
+	defer func() {
+		if err := c.watcher.Close(); err != nil {
+			log.WithError(err).Error("Config watcher close error")
+		}
+	}()
+
+	// watch files
+	for {
+		select {
+		case event, ok := <-c.watcher.Events:
+			if !ok {
+				log.Error("Config watcher got unexpected " +
+					"close of events channel")
+				return
+			}
+			if slices.Contains(c.files, event.Name) {
+				log.WithFields(log.Fields{
+					"name": event.Name,
+					"op":   event.Op,
+				}).Debug("Config watcher handling file event")
+				c.handleEvent(event.Name)
+			}
+
+		case err, ok := <-c.watcher.Errors:
+			if !ok {
+				log.Error("Config watcher got unexpected " +
+					"close of errors channel")
+				return
+			}
+			log.WithError(err).Error("Config watcher error event")
+
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Start starts the config monitor.
+func (c *ConfigMon) Start() error {
+	// create watcher
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not create file watcher: %w", err)
+	}
+
+	// save current file hashes and add folders to watcher
+	for _, file := range c.files {
+		c.hashes[file] = hashFile(file)
+		dir := filepath.Dir(file)
+		if err := watcher.Add(dir); err != nil {
+			log.WithError(err).WithField("dir", dir).
+				Debug("Config watcher add config dir error")
+		}
+	}
+
+	c.watcher = watcher
+	go c.start()
+	return nil
+}
+
+// Stop stops the config monitor.
+func (c *ConfigMon) Stop() {
+	close(c.done)
+	<-c.closed
+}
+
+// Updates returns the channel for config updates.
+func (c *ConfigMon) Updates() chan struct{} {
+	return c.updates
+}
+
+// NewConfigMon returns a new config monitor for files.
+func NewConfigMon(files ...string) *ConfigMon {
+	var f []string
+	for _, file := range files {
+		if file != "" && !slices.Contains(f, file) {
+			f = append(f, file)
+		}
+	}
+	return &ConfigMon{
+		files:   f,
+		updates: make(chan struct{}),
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+		hashes:  make(map[string][sha256.Size]byte),
+	}
+}
@@ -0,0 +1,119 @@
+package configmon
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// createConfigMonTestFile creates a temporary file for ConfigMon testing.
+func createConfigMonTestFile(t *testing.T) string {
+	f := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(f, []byte("test"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// TestConfigMonHandleEvent tests handleEvent of ConfigMon.
+func TestConfigMonHandleEvent(t *testing.T) {
+	f := createConfigMonTestFile(t)
+	c := NewConfigMon(f)
+
+	// test with unitialized hash, should update hash and send update
+	h := c.hashes[f]
+	go c.handleEvent(f)
+	<-c.updates
+	got := c.hashes[f]
+	if bytes.Equal(h[:], got[:]) {
+		t.Errorf("got %v, want other", h)
+	}
+
+	// test with same file content, hash should stay the same, no update
+	h = c.hashes[f]
+	c.handleEvent(f)
+	got = c.hashes[f]
+	if !bytes.Equal(h[:], got[:]) {
+		t.Errorf("got %v, want %v", got, h)
+	}
+
+	// test with removed file, should update hash and send update
+	if err := os.Remove(f); err != nil {
+		t.Fatal(err)
+	}
+	go c.handleEvent(f)
+	<-c.updates
+}
+
+// TestConfigMonStartEvents tests start of ConfigMon, events.
+func TestConfigMonStartEvents(t *testing.T) {
+	f := createConfigMonTestFile(t)
+	c := NewConfigMon(f)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = watcher.Close() }()
+	c.watcher = watcher
+
+	go c.start()
+
+	c.watcher.Events <- fsnotify.Event{}
+	c.watcher.Events <- fsnotify.Event{Name: f}
+	<-c.Updates()
+
+	c.watcher.Errors <- errors.New("test error")
+
+	if err := watcher.Close(); err != nil {
+		t.Error(err)
+	}
+	<-c.closed
+}
+
+// TestConfigMonStartStop tests Start and Stop of ConfigMon.
+func TestConfigMonStartStop(t *testing.T) {
+	f := createConfigMonTestFile(t)
+	c := NewConfigMon(f)
+	if err := c.Start(); err != nil {
+		t.Error(err)
+	}
+
+	// change file, should send update
+	if err := os.WriteFile(f, []byte("changed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	<-c.Updates()
+
+	c.Stop()
+}
+
+// TestConfigMonUpdates tests Updates of ConfigMon.
+func TestConfigMonUpdates(t *testing.T) {
+	c := NewConfigMon()
+	want := c.updates
+	got := c.Updates()
+	if got != want {
+		t.Errorf("got %p, want %p", got, want)
+	}
+}
+
+// TestNewConfigMon tests NewConfigMon.
+func TestNewConfigMon(t *testing.T) {
+	c := NewConfigMon("file1", "", "file2", "file1")
+	want := []string{"file1", "file2"}
+	if !slices.Equal(c.files, want) {
+		t.Errorf("got %v, want %v", c.files, want)
+	}
+	if c.updates == nil ||
+		c.done == nil ||
+		c.closed == nil ||
+		c.hashes == nil {
+
+		t.Errorf("got nil, want != nil")
+	}
+}
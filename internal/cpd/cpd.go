@@ -15,6 +15,7 @@ import (
 type Report struct {
 	Detected bool
 	Host     string
+	URL      string
 }
 
 // CPD is a captive portal detection instance.
@@ -76,14 +77,17 @@ func (c *CPD) check() *Report {
 	case http.StatusFound:
 		// 302, redirect, captive portal detected
 		hostname := ""
+		location := ""
 		if url, err := resp.Location(); err != nil {
 			log.WithError(err).Error("CPD could not get location in response")
 		} else {
 			hostname = url.Hostname()
+			location = url.String()
 		}
 		return &Report{
 			Detected: true,
 			Host:     hostname,
+			URL:      location,
 		}
 	default:
 		// other, captive protal detected
@@ -6,10 +6,12 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/sdnotify"
 )
 
 var (
@@ -22,6 +24,7 @@ const (
 	argConfig  = "config"
 	argVerbose = "verbose"
 	argVersion = "version"
+	argPlan    = "plan"
 )
 
 // osMkdirAll is os.MkdirAll for testing.
@@ -34,6 +37,7 @@ func prepareFolders(config *daemoncfg.Config) error {
 		config.SocketServer.SocketFile,
 		config.OpenConnect.XMLProfile,
 		config.OpenConnect.PIDFile,
+		config.GracefulRestart.StateFile,
 	} {
 		dir := filepath.Dir(file)
 		if err := osMkdirAll(dir, 0755); err != nil {
@@ -55,6 +59,24 @@ func flagIsSet(flags *flag.FlagSet, name string) bool {
 	return isSet
 }
 
+// loadCommandLists loads the command templates, lists and hooks from the
+// files in config, missing files are set to defaults. If the files could not
+// be loaded, the current command lists are kept.
+func loadCommandLists(config *daemoncfg.Config) {
+	fields := log.Fields{
+		"templates": config.CommandLists.TemplatesFile,
+		"lists":     config.CommandLists.ListsFile,
+		"hooks":     config.CommandLists.HooksDir,
+	}
+	if err := cmdtmpl.Load(config.CommandLists.TemplatesFile,
+		config.CommandLists.ListsFile, config.CommandLists.HooksDir); err != nil {
+		log.WithError(err).WithFields(fields).
+			Error("Daemon could not load command lists, keeping current command lists")
+		return
+	}
+	log.WithFields(fields).Info("Daemon loaded command lists")
+}
+
 // run is the main entry point for the daemon.
 func run(args []string) error {
 	// parse command line arguments
@@ -63,6 +85,7 @@ func run(args []string) error {
 	cfgFile := flags.String(argConfig, defaults.Config, "set config `file`")
 	verbose := flags.Bool(argVerbose, defaults.Verbose, "enable verbose output")
 	version := flags.Bool(argVersion, false, "print version")
+	planFile := flags.String(argPlan, "", "print commands of all command lists for VPN config `file` without running them")
 	if err := flags.Parse(args[1:]); err != nil {
 		return err
 	}
@@ -91,22 +114,14 @@ func run(args []string) error {
 			Warn("Daemon loaded invalid config, using default config")
 	}
 
-	// load command lists
-	if err := cmdtmpl.LoadTemplates(config.CommandLists.TemplatesFile); err != nil {
-		log.WithError(err).WithField("file", config.CommandLists.TemplatesFile).
-			Debug("Daemon did not load command templates, using defaults")
-	} else {
-		log.WithField("file", config.CommandLists.TemplatesFile).
-			Info("Daemon loaded command templates from file")
-	}
-	if err := cmdtmpl.LoadCommandLists(config.CommandLists.ListsFile); err != nil {
-		log.WithError(err).WithField("file", config.CommandLists.ListsFile).
-			Debug("Daemon did not load command lists, using defaults")
-	} else {
-		log.WithField("file", config.CommandLists.ListsFile).
-			Info("Daemon loaded command lists from file")
+	// print commands for sample VPN config?
+	if *planFile != "" {
+		return runPlan(config, *planFile)
 	}
 
+	// load command lists
+	loadCommandLists(config)
+
 	// check executables
 	if err := config.Executables.CheckExecutables(); err != nil {
 		return fmt.Errorf("Daemon could not find all executables: %w", err)
@@ -137,19 +152,30 @@ func run(args []string) error {
 	}
 	defer daemon.Stop()
 
-	// catch interrupt signal
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	// notify systemd about startup and shutdown
+	notify(sdnotify.Ready)
+	defer notify(sdnotify.Stopping)
 
-	// wait for interrupt signal or daemon error
-	var err error
-	select {
-	case <-c:
-		log.Info("Daemon got interrupt signal")
-	case err = <-daemon.Errors():
+	// catch interrupt and hangup signals
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGHUP)
+
+	// wait for interrupt signal or daemon error,
+	// reload config on hangup signal
+	for {
+		select {
+		case s := <-c:
+			if s == syscall.SIGHUP {
+				log.Info("Daemon got hangup signal")
+				daemon.Reload()
+				continue
+			}
+			log.Info("Daemon got interrupt signal")
+			return nil
+		case err := <-daemon.Errors():
+			return err
+		}
 	}
-
-	return err
 }
 
 // Run is the main entry point for the daemon.
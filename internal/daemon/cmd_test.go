@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
 )
 
@@ -52,6 +53,11 @@ func TestRun(t *testing.T) {
 		t.Errorf("help should return ErrHelp, got: %v", err)
 	}
 
+	// test with "-plan" and not existing VPN config
+	if err := run([]string{"test", "-plan", filepath.Join(t.TempDir(), "vpnconfig.json")}); err == nil {
+		t.Errorf("plan should return error")
+	}
+
 	// return error in osMkdirAll, so daemon start stops at preprareFolders
 	osMkdirAll = func(string, fs.FileMode) error {
 		return errors.New("test error")
@@ -146,3 +152,36 @@ func TestRun(t *testing.T) {
 		t.Errorf("start should return error")
 	}
 }
+
+// TestLoadCommandLists tests loadCommandLists.
+func TestLoadCommandLists(t *testing.T) {
+	defer cmdtmpl.Reset()
+	config := daemoncfg.NewConfig()
+	dir := t.TempDir()
+	config.CommandLists.TemplatesFile = filepath.Join(dir, "templates")
+	config.CommandLists.ListsFile = filepath.Join(dir, "lists.json")
+	config.CommandLists.HooksDir = filepath.Join(dir, "hooks.d")
+
+	// valid command lists file
+	if err := os.WriteFile(config.CommandLists.ListsFile, []byte(`[{
+		"Name": "TrafPolCleanup",
+		"Commands": [{"Line": "echo custom"}]
+	}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	loadCommandLists(config)
+	_, lists := cmdtmpl.Get()
+	want := lists[cmdtmpl.TrafPolCleanup]
+	if want.Commands[0].Line != "echo custom" {
+		t.Fatalf("got %v, want custom command list", want)
+	}
+
+	// invalid command lists file, keep custom command lists
+	if err := os.WriteFile(config.CommandLists.ListsFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	loadCommandLists(config)
+	if _, lists := cmdtmpl.Get(); lists[cmdtmpl.TrafPolCleanup] != want {
+		t.Errorf("got %v, want %v", lists[cmdtmpl.TrafPolCleanup], want)
+	}
+}
@@ -0,0 +1,512 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/dbusapi"
+	"github.com/telekom-mms/oc-daemon/internal/ocrunner"
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnconfig"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnhistory"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
+)
+
+// ocrunnerNewConnect is ocrunner.NewConnect for testing.
+var ocrunnerNewConnect = func() ocrunner.Runner {
+	return ocrunner.NewConnect()
+}
+
+// namedConnection is a named VPN connection in addition to the default VPN
+// connection.
+type namedConnection struct {
+	// config is the configuration of the VPN connection
+	config *daemoncfg.Config
+
+	// runner runs openconnect for the VPN connection
+	runner ocrunner.Runner
+
+	// status is the status of the VPN connection
+	status *vpnstatus.Connection
+
+	// serverIP is the IP address of the VPN server
+	serverIP netip.Addr
+
+	// serverIPAllowed indicates whether server IP was added to
+	// the allowed addresses
+	serverIPAllowed bool
+
+	// session is the current session in the connection history, nil if
+	// there is no session
+	session *vpnhistory.Session
+
+	// disconnectCause is the cause of the requested disconnect
+	disconnectCause vpnhistory.DisconnectCause
+}
+
+// connectionEvent is a runner event of a named VPN connection.
+type connectionEvent struct {
+	name  string
+	event *ocrunner.ConnectEvent
+}
+
+// connections are the named VPN connections.
+type connections struct {
+	conns map[string]*namedConnection
+
+	// events are the runner events of all named VPN connections
+	events chan *connectionEvent
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// forwardEvents forwards the events of runner of the named VPN connection
+// name to the events channel.
+func (c *connections) forwardEvents(name string, runner ocrunner.Runner) {
+	defer c.wg.Done()
+	for {
+		select {
+		case e, ok := <-runner.Events():
+			if !ok {
+				return
+			}
+			select {
+			case c.events <- &connectionEvent{name: name, event: e}:
+			case <-c.done:
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// start starts the runners of the named VPN connections.
+func (c *connections) start() {
+	for name, conn := range c.conns {
+		conn.runner.Start()
+		c.wg.Add(1)
+		go c.forwardEvents(name, conn.runner)
+	}
+}
+
+// stop stops the runners of the named VPN connections.
+func (c *connections) stop() {
+	close(c.done)
+	c.wg.Wait()
+	for _, conn := range c.conns {
+		conn.runner.Stop()
+	}
+}
+
+// get returns the named VPN connection name, nil if it does not exist.
+func (c *connections) get(name string) *namedConnection {
+	return c.conns[name]
+}
+
+// names returns the sorted names of the named VPN connections.
+func (c *connections) names() []string {
+	names := make([]string, 0, len(c.conns))
+	for name := range c.conns {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// running returns whether openconnect is running for any named VPN
+// connection.
+func (c *connections) running() bool {
+	for _, conn := range c.conns {
+		if conn.status.OCRunning.Running() {
+			return true
+		}
+	}
+	return false
+}
+
+// eventsC returns the channel for runner events of the named VPN
+// connections.
+func (c *connections) eventsC() <-chan *connectionEvent {
+	return c.events
+}
+
+// newConnections returns the named VPN connections in config.
+func newConnections(config *daemoncfg.Config) *connections {
+	c := &connections{
+		conns:  make(map[string]*namedConnection),
+		events: make(chan *connectionEvent),
+		done:   make(chan struct{}),
+	}
+	for _, name := range config.Connections.Names() {
+		c.conns[name] = &namedConnection{
+			config: config.ConnectionConfig(name),
+			runner: ocrunnerNewConnect(),
+			status: &vpnstatus.Connection{
+				Name:            name,
+				ConnectionState: vpnstatus.ConnectionStateDisconnected,
+				OCRunning:       vpnstatus.OCRunningNotRunning,
+			},
+		}
+	}
+	return c
+}
+
+// setStatusConnections sets the status of the named VPN connections in
+// status.
+func (d *Daemon) setStatusConnections() {
+	var conns []*vpnstatus.Connection
+	for _, name := range d.conns.names() {
+		conns = append(conns, d.conns.get(name).status.Copy())
+	}
+	if reflect.DeepEqual(d.status.Connections, conns) {
+		// connections not changed
+		return
+	}
+
+	// connections changed
+	d.status.Connections = conns
+
+	if conns == nil {
+		// remove connections
+		d.dbus.SetProperty(dbusapi.PropertyConnections, dbusapi.ConnectionsInvalid)
+		return
+	}
+
+	// update json connections
+	b, err := json.Marshal(conns)
+	if err != nil {
+		log.WithError(err).Error("Daemon could not convert connections status to JSON")
+		d.dbus.SetProperty(dbusapi.PropertyConnections, dbusapi.ConnectionsInvalid)
+		return
+	}
+	s := string(b)
+	log.WithField("Connections", s).Info("Daemon changed Connections status")
+	d.dbus.SetProperty(dbusapi.PropertyConnections, s)
+}
+
+// namedStatus returns the status of the daemon with the status of the named
+// VPN connection conn, e.g., for hooks.
+func (d *Daemon) namedStatus(conn *namedConnection) *vpnstatus.Status {
+	status := d.status.Copy()
+	status.ConnectionState = conn.status.ConnectionState
+	status.OCRunning = conn.status.OCRunning
+	status.OCPID = conn.status.OCPID
+	status.IP = conn.status.IP
+	status.Device = conn.status.Device
+	status.Server = conn.status.Server
+	status.ServerIP = conn.status.ServerIP
+	status.ConnectedAt = conn.status.ConnectedAt
+	return status
+}
+
+// setNamedConnectionState sets the connection state of the named VPN
+// connection conn in status and runs the hooks when it is connected or
+// disconnected.
+func (d *Daemon) setNamedConnectionState(conn *namedConnection, connectionState vpnstatus.ConnectionState) {
+	if conn.status.ConnectionState == connectionState {
+		// state not changed
+		return
+	}
+
+	// state changed
+	log.WithFields(log.Fields{
+		"connection":      conn.status.Name,
+		"ConnectionState": connectionState,
+	}).Info("Daemon changed ConnectionState status of named VPN connection")
+	old := conn.status.ConnectionState
+	conn.status.ConnectionState = connectionState
+	d.setStatusConnections()
+
+	// run hooks when the vpn is connected or disconnected
+	switch {
+	case connectionState == vpnstatus.ConnectionStateConnected:
+		d.hooks.trigger(cmdtmpl.HookConnected, conn.config, d.namedStatus(conn))
+	case connectionState == vpnstatus.ConnectionStateDisconnected &&
+		(old == vpnstatus.ConnectionStateConnected ||
+			old == vpnstatus.ConnectionStateDisconnecting):
+		d.hooks.trigger(cmdtmpl.HookDisconnected, conn.config, d.namedStatus(conn))
+	}
+}
+
+// namedConnectFailed returns the reason of the ConnectFailed signal for the
+// named VPN connection name.
+func namedConnectFailed(name, reason string) string {
+	return fmt.Sprintf("connection %s: %s", name, reason)
+}
+
+// getRunnerEnv returns the environment variables for openconnect and
+// vpncscript of the VPN connection name, empty for the default VPN
+// connection.
+func (d *Daemon) getRunnerEnv(name string) []string {
+	env := []string{
+		"oc_daemon_token=" + d.token,
+		"oc_daemon_socket_file=" + d.config.SocketServer.SocketFile,
+		"oc_daemon_verbose=" + strconv.FormatBool(d.config.Verbose),
+	}
+	if name != "" {
+		env = append(env, "oc_daemon_connection="+name)
+	}
+	return env
+}
+
+// connectNamedVPN connects the named VPN connection name using login info
+// from client request.
+func (d *Daemon) connectNamedVPN(name string, login *logininfo.LoginInfo) error {
+	conn := d.conns.get(name)
+	if conn == nil {
+		return dbusapi.ErrUnknownConnection
+	}
+
+	// allow only one openconnect process per connection
+	if conn.status.OCRunning.Running() {
+		return fmt.Errorf("VPN connection %s already running", name)
+	}
+
+	// ignore invalid login information
+	if !login.Valid() {
+		d.dbus.EmitSignal(dbusapi.SignalConnectFailed,
+			namedConnectFailed(name, connectFailedInvalidLogin))
+		return errors.New(connectFailedInvalidLogin)
+	}
+
+	// set server address
+	conn.serverIP = netip.Addr{}
+	if serverIP, err := netip.ParseAddr(strings.Trim(login.Host, "[]")); err == nil {
+		conn.serverIP = serverIP
+	}
+
+	// update status
+	conn.status.OCRunning = vpnstatus.OCRunningRunning
+	conn.status.Server = login.Server
+	conn.status.ServerIP = conn.serverIP.String()
+	d.setNamedConnectionState(conn, vpnstatus.ConnectionStateConnecting)
+
+	// start session in connection history
+	conn.session = newSession(name, login.Server, conn.serverIP.String())
+
+	// add server address to allowed addrs in trafpol
+	if d.trafpol != nil && conn.serverIP.IsValid() {
+		conn.serverIPAllowed = d.trafpol.AddAllowedAddr(conn.serverIP)
+	}
+
+	// save login and connect using runner
+	log.WithField("connection", name).Info("Daemon connecting named VPN connection")
+	conn.config.LoginInfo = login
+	conn.runner.Connect(conn.config.Copy(), d.getRunnerEnv(name))
+	return nil
+}
+
+// disconnectNamedVPN disconnects the named VPN connection name on request of
+// the user.
+func (d *Daemon) disconnectNamedVPN(name string) error {
+	conn := d.conns.get(name)
+	if conn == nil {
+		return dbusapi.ErrUnknownConnection
+	}
+
+	// check if vpn is flagged as running
+	if !conn.status.OCRunning.Running() {
+		return fmt.Errorf("VPN connection %s not running", name)
+	}
+
+	// update status and stop runner
+	log.WithField("connection", name).Info("Daemon disconnecting named VPN connection")
+	conn.disconnectCause = vpnhistory.DisconnectCauseUser
+	d.setNamedConnectionState(conn, vpnstatus.ConnectionStateDisconnecting)
+	conn.runner.Disconnect()
+	return nil
+}
+
+// updateNamedVPNConfigUp updates the VPN config of conn for VPN connect.
+func (d *Daemon) updateNamedVPNConfigUp(conn *namedConnection, config *vpnconfig.Config) {
+	// check if vpn is flagged as running and not connected yet
+	if !conn.status.OCRunning.Running() ||
+		conn.status.ConnectionState.Connected() {
+		log.WithFields(log.Fields{
+			"connection": conn.status.Name,
+			"error":      "vpn not running or already connected",
+		}).Error("Daemon config up error")
+		return
+	}
+
+	// connecting, set up configuration
+	log.WithField("connection", conn.status.Name).
+		Info("Daemon setting up vpn configuration")
+	conn.config.VPNConfig = daemoncfg.GetVPNConfig(config)
+	d.vpnsetup.Setup(conn.config.Copy())
+
+	// update status
+	ip := ""
+	for _, p := range []netip.Prefix{conn.config.VPNConfig.IPv4, conn.config.VPNConfig.IPv6} {
+		// this assumes either a single IPv4 or a single IPv6 address
+		// is configured on a vpn device
+		if p.IsValid() {
+			ip = p.Addr().String()
+		}
+	}
+	conn.status.IP = ip
+	conn.status.Device = config.Device.Name
+	conn.status.ConnectedAt = time.Now().Unix()
+	sessionConnected(conn.session, ip)
+	d.setNamedConnectionState(conn, vpnstatus.ConnectionStateConnected)
+}
+
+// updateNamedVPNConfigDown updates the VPN config of conn for VPN
+// disconnect.
+func (d *Daemon) updateNamedVPNConfigDown(conn *namedConnection) {
+	// check if vpn is still flagged as connecting or connected
+	if conn.status.ConnectionState.Connecting() ||
+		conn.status.ConnectionState.Connected() {
+		log.WithFields(log.Fields{
+			"connection": conn.status.Name,
+			"error":      "vpn still connecting or connected",
+		}).Error("Daemon config down error")
+		return
+	}
+
+	// disconnecting, tear down configuration
+	if conn.config.VPNConfig.Device.Name != "" {
+		log.WithField("connection", conn.status.Name).
+			Info("Daemon tearing down vpn configuration")
+		d.vpnsetup.Teardown(conn.config.Copy())
+	}
+
+	// remove login and VPN config
+	conn.config.LoginInfo = &logininfo.LoginInfo{}
+	conn.config.VPNConfig = &daemoncfg.VPNConfig{}
+
+	// update status
+	conn.status.IP = ""
+	conn.status.Device = ""
+	conn.status.ConnectedAt = 0
+	d.setStatusConnections()
+}
+
+// updateNamedVPNConfig updates the VPN config of a named VPN connection with
+// configUpdate from vpncscript.
+func (d *Daemon) updateNamedVPNConfig(configUpdate *VPNConfigUpdate) error {
+	conn := d.conns.get(configUpdate.Connection)
+	if conn == nil {
+		return dbusapi.ErrUnknownConnection
+	}
+
+	switch configUpdate.Reason {
+	case "connect":
+		d.updateNamedVPNConfigUp(conn, configUpdate.Config)
+	case "disconnect":
+		d.updateNamedVPNConfigDown(conn)
+	case "attempt-reconnect":
+		if conn.status.ConnectionState.Connected() {
+			d.setNamedConnectionState(conn, vpnstatus.ConnectionStateConnecting)
+		}
+	case "reconnect":
+		if conn.status.ConnectionState.Connecting() {
+			d.setNamedConnectionState(conn, vpnstatus.ConnectionStateConnected)
+		}
+	}
+	return nil
+}
+
+// handleNamedRunnerDisconnect cleans up after openconnect of conn exited.
+func (d *Daemon) handleNamedRunnerDisconnect(conn *namedConnection) {
+	// make sure running and connected are not set
+	conn.status.OCRunning = vpnstatus.OCRunningNotRunning
+	conn.status.OCPID = 0
+	d.setNamedConnectionState(conn, vpnstatus.ConnectionStateDisconnected)
+	conn.status.Server = ""
+	conn.status.ServerIP = ""
+
+	// make sure the vpn config is not active any more
+	d.updateNamedVPNConfigDown(conn)
+
+	// remove server ip from allowed addrs
+	if d.trafpol != nil && conn.serverIPAllowed {
+		d.trafpol.RemoveAllowedAddr(conn.serverIP)
+	}
+	conn.serverIP = netip.Addr{}
+	conn.serverIPAllowed = false
+}
+
+// handleConnectionEvent handles a runner event of a named VPN connection.
+func (d *Daemon) handleConnectionEvent(e *connectionEvent) error {
+	log.WithFields(log.Fields{
+		"connection": e.name,
+		"event":      e.event,
+	}).Debug("Daemon handling Runner event of named VPN connection")
+
+	conn := d.conns.get(e.name)
+	if conn == nil {
+		return nil
+	}
+
+	if e.event.Connect {
+		// make sure running is set
+		conn.status.OCRunning = vpnstatus.OCRunningRunning
+		conn.status.OCPID = e.event.PID
+		d.setStatusConnections()
+		return nil
+	}
+
+	// openconnect exited before the connection was established
+	if conn.status.ConnectionState.Connecting() &&
+		conn.disconnectCause == vpnhistory.DisconnectCauseUnknown {
+		log.WithFields(log.Fields{
+			"connection": e.name,
+			"exitCode":   e.event.ExitCode,
+		}).Warn("Daemon named VPN connection failed, " + connectFailedOCExit)
+		reason := fmt.Sprintf("%s with exit code %d", connectFailedOCExit, e.event.ExitCode)
+		d.dbus.EmitSignal(dbusapi.SignalConnectFailed, namedConnectFailed(e.name, reason))
+	}
+
+	// clean up after disconnect
+	d.endNamedSession(conn, e.event.ExitCode)
+	d.handleNamedRunnerDisconnect(conn)
+
+	// apply config changes deferred during connection
+	return d.applyPendingConfig()
+}
+
+// endNamedSession ends the current session of the named VPN connection conn
+// in the connection history with the exit code of openconnect.
+func (d *Daemon) endNamedSession(conn *namedConnection, exitCode int) {
+	cause := conn.disconnectCause
+	if cause == vpnhistory.DisconnectCauseUnknown {
+		// disconnect was not requested, openconnect exited
+		cause = vpnhistory.DisconnectCauseOpenConnectExit
+	}
+	d.history.add(conn.session, cause, exitCode)
+	conn.session = nil
+	conn.disconnectCause = vpnhistory.DisconnectCauseUnknown
+}
+
+// stopConnections stops the named VPN connections and cleans up.
+func (d *Daemon) stopConnections() {
+	d.conns.stop()
+	for _, name := range d.conns.names() {
+		conn := d.conns.get(name)
+		if conn.disconnectCause == vpnhistory.DisconnectCauseUnknown {
+			conn.disconnectCause = vpnhistory.DisconnectCauseShutdown
+		}
+		d.endNamedSession(conn, -1)
+		d.handleNamedRunnerDisconnect(conn)
+	}
+}
+
+// tunnelsActive returns whether openconnect is running for the default or
+// any named VPN connection.
+func (d *Daemon) tunnelsActive() bool {
+	return d.status.OCRunning.Running() || d.conns.running()
+}
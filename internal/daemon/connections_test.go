@@ -0,0 +1,441 @@
+package daemon
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/dbusapi"
+	"github.com/telekom-mms/oc-daemon/internal/ocrunner"
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnconfig"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnhistory"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
+)
+
+// connRunner is OC-Runner of named connections for testing.
+type connRunner struct {
+	ocRunner
+	config      *daemoncfg.Config
+	env         []string
+	disconnects int
+}
+
+func (c *connRunner) Connect(config *daemoncfg.Config, env []string) {
+	c.config = config
+	c.env = env
+}
+func (c *connRunner) Disconnect() { c.disconnects++ }
+
+// getTestConnectionsConfig returns a config with the named connection "lab"
+// for testing.
+func getTestConnectionsConfig() *daemoncfg.Config {
+	config := daemoncfg.NewConfig()
+	config.Connections["lab"] = &daemoncfg.Connection{
+		VPNDevice: "tun1",
+		PIDFile:   "/run/oc-daemon/openconnect-lab.pid",
+		SplitRouting: &daemoncfg.SplitRouting{
+			RoutingTable:  "42112",
+			RulePriority1: "2113",
+			RulePriority2: "2114",
+			FirewallMark:  "42112",
+			NftTable:      "oc-daemon-routing-lab",
+		},
+	}
+	return config
+}
+
+// getTestConnectionsDaemon returns a Daemon with the named connection "lab"
+// and its runner for testing.
+func getTestConnectionsDaemon(t *testing.T) (*Daemon, *connRunner) {
+	runner := &connRunner{ocRunner: ocRunner{e: make(chan *ocrunner.ConnectEvent)}}
+	oldNewConnect := ocrunnerNewConnect
+	ocrunnerNewConnect = func() ocrunner.Runner { return runner }
+	t.Cleanup(func() { ocrunnerNewConnect = oldNewConnect })
+
+	d := getTestDaemon()
+	d.config = getTestConnectionsConfig()
+	d.conns = newConnections(d.config)
+	return d, runner
+}
+
+// TestConnectionsStartStop tests start and stop of connections.
+func TestConnectionsStartStop(t *testing.T) {
+	d, runner := getTestConnectionsDaemon(t)
+	d.conns.start()
+
+	// forward runner event
+	runner.e <- &ocrunner.ConnectEvent{Connect: true, PID: 123}
+	e := <-d.conns.eventsC()
+	if e.name != "lab" || !e.event.Connect || e.event.PID != 123 {
+		t.Errorf("got invalid event %v", e)
+	}
+
+	// closed runner events
+	close(runner.e)
+	d.conns.stop()
+}
+
+// TestConnectionsRunning tests running of connections.
+func TestConnectionsRunning(t *testing.T) {
+	d, _ := getTestConnectionsDaemon(t)
+	if d.conns.running() {
+		t.Error("connections should not be running")
+	}
+	d.conns.get("lab").status.OCRunning = vpnstatus.OCRunningRunning
+	if !d.conns.running() {
+		t.Error("connections should be running")
+	}
+	if !d.tunnelsActive() {
+		t.Error("tunnels should be active")
+	}
+}
+
+// TestNewConnections tests newConnections.
+func TestNewConnections(t *testing.T) {
+	config := getTestConnectionsConfig()
+	config.Connections["corp"] = config.Connections["lab"].Copy()
+	c := newConnections(config)
+
+	if !slices.Equal(c.names(), []string{"corp", "lab"}) {
+		t.Errorf("got %v", c.names())
+	}
+	lab := c.get("lab")
+	if lab == nil ||
+		lab.runner == nil ||
+		lab.config.Connection != "lab" ||
+		lab.config.OpenConnect.VPNDevice != "tun1" ||
+		lab.status.Name != "lab" ||
+		lab.status.ConnectionState != vpnstatus.ConnectionStateDisconnected ||
+		lab.status.OCRunning != vpnstatus.OCRunningNotRunning {
+		t.Errorf("got invalid connection %v", lab)
+	}
+	if c.get("other") != nil {
+		t.Error("unknown connection should be nil")
+	}
+}
+
+// TestDaemonSetStatusConnections tests setStatusConnections of Daemon.
+func TestDaemonSetStatusConnections(t *testing.T) {
+	// no connections
+	d := getTestDaemon()
+	d.setStatusConnections()
+	if d.status.Connections != nil {
+		t.Errorf("got %v, want nil", d.status.Connections)
+	}
+
+	// named connection
+	d, _ = getTestConnectionsDaemon(t)
+	d.setStatusConnections()
+	if len(d.status.Connections) != 1 ||
+		d.status.Connections[0].Name != "lab" {
+		t.Errorf("got invalid connections %v", d.status.Connections)
+	}
+
+	// status is a copy
+	d.conns.get("lab").status.Server = "changed"
+	if d.status.Connections[0].Server != "" {
+		t.Error("status should not change without update")
+	}
+}
+
+// TestDaemonConnectNamedVPN tests connectNamedVPN of Daemon.
+func TestDaemonConnectNamedVPN(t *testing.T) {
+	d, runner := getTestConnectionsDaemon(t)
+	login := &logininfo.LoginInfo{
+		Server:      "lab.example.com",
+		Cookie:      "cookie",
+		Host:        "10.0.0.2",
+		ConnectURL:  "https://lab.example.com",
+		Fingerprint: "fingerprint",
+		Resolve:     "lab.example.com:10.0.0.2",
+	}
+
+	// unknown connection
+	if err := d.connectNamedVPN("other", login); !errors.Is(err, dbusapi.ErrUnknownConnection) {
+		t.Errorf("got %v, want %v", err, dbusapi.ErrUnknownConnection)
+	}
+
+	// invalid login
+	if err := d.connectNamedVPN("lab", &logininfo.LoginInfo{}); err == nil {
+		t.Error("invalid login should return error")
+	}
+
+	// valid login
+	if err := d.connectNamedVPN("lab", login); err != nil {
+		t.Fatal(err)
+	}
+	status := d.status.GetConnection("lab")
+	if status == nil ||
+		!status.OCRunning.Running() ||
+		!status.ConnectionState.Connecting() ||
+		status.Server != "lab.example.com" ||
+		status.ServerIP != "10.0.0.2" {
+		t.Errorf("got invalid status %v", status)
+	}
+	if runner.config == nil ||
+		runner.config.Connection != "lab" ||
+		runner.config.OpenConnect.VPNDevice != "tun1" ||
+		runner.config.LoginInfo.Cookie != "cookie" {
+		t.Errorf("got invalid runner config %v", runner.config)
+	}
+	if !slices.Contains(runner.env, "oc_daemon_connection=lab") {
+		t.Errorf("got invalid runner env %v", runner.env)
+	}
+
+	// already running
+	if err := d.connectNamedVPN("lab", login); err == nil {
+		t.Error("running connection should return error")
+	}
+}
+
+// TestDaemonDisconnectNamedVPN tests disconnectNamedVPN of Daemon.
+func TestDaemonDisconnectNamedVPN(t *testing.T) {
+	d, runner := getTestConnectionsDaemon(t)
+
+	// unknown connection
+	if err := d.disconnectNamedVPN("other"); !errors.Is(err, dbusapi.ErrUnknownConnection) {
+		t.Errorf("got %v, want %v", err, dbusapi.ErrUnknownConnection)
+	}
+
+	// not running
+	if err := d.disconnectNamedVPN("lab"); err == nil {
+		t.Error("not running connection should return error")
+	}
+
+	// running, disconnect policy does not apply to named connections
+	d.status.DisconnectPolicy = vpnstatus.DisconnectPolicyLocked
+	d.conns.get("lab").status.OCRunning = vpnstatus.OCRunningRunning
+	if err := d.disconnectNamedVPN("lab"); err != nil {
+		t.Fatal(err)
+	}
+	if runner.disconnects != 1 {
+		t.Errorf("got %d disconnects, want 1", runner.disconnects)
+	}
+	if status := d.status.GetConnection("lab"); status.ConnectionState !=
+		vpnstatus.ConnectionStateDisconnecting {
+		t.Errorf("got %s, want disconnecting", status.ConnectionState)
+	}
+}
+
+// TestDaemonUpdateNamedVPNConfig tests updateNamedVPNConfig of Daemon.
+func TestDaemonUpdateNamedVPNConfig(t *testing.T) {
+	d, _ := getTestConnectionsDaemon(t)
+	conn := d.conns.get("lab")
+
+	// unknown connection
+	if err := d.updateNamedVPNConfig(&VPNConfigUpdate{
+		Reason:     "connect",
+		Connection: "other",
+	}); !errors.Is(err, dbusapi.ErrUnknownConnection) {
+		t.Errorf("got %v, want %v", err, dbusapi.ErrUnknownConnection)
+	}
+
+	config := vpnconfig.New()
+	config.Device.Name = "tun1"
+	for i, test := range []struct {
+		reason    string
+		running   vpnstatus.OCRunning
+		state     vpnstatus.ConnectionState
+		wantState vpnstatus.ConnectionState
+		wantDev   string
+	}{
+		// connect, not running
+		{"connect", vpnstatus.OCRunningNotRunning, vpnstatus.ConnectionStateDisconnected,
+			vpnstatus.ConnectionStateDisconnected, ""},
+		// connect, running
+		{"connect", vpnstatus.OCRunningRunning, vpnstatus.ConnectionStateConnecting,
+			vpnstatus.ConnectionStateConnected, "tun1"},
+		// attempt reconnect, connected
+		{"attempt-reconnect", vpnstatus.OCRunningRunning, vpnstatus.ConnectionStateConnected,
+			vpnstatus.ConnectionStateConnecting, "tun1"},
+		// reconnect, connecting
+		{"reconnect", vpnstatus.OCRunningRunning, vpnstatus.ConnectionStateConnecting,
+			vpnstatus.ConnectionStateConnected, "tun1"},
+		// disconnect, still connected
+		{"disconnect", vpnstatus.OCRunningRunning, vpnstatus.ConnectionStateConnected,
+			vpnstatus.ConnectionStateConnected, "tun1"},
+		// disconnect, disconnecting
+		{"disconnect", vpnstatus.OCRunningRunning, vpnstatus.ConnectionStateDisconnecting,
+			vpnstatus.ConnectionStateDisconnecting, ""},
+	} {
+		conn.status.OCRunning = test.running
+		conn.status.ConnectionState = test.state
+		update := &VPNConfigUpdate{Reason: test.reason, Connection: "lab"}
+		if test.reason == "connect" {
+			update.Config = config
+		}
+		if err := d.updateNamedVPNConfig(update); err != nil {
+			t.Fatal(err)
+		}
+		if conn.status.ConnectionState != test.wantState ||
+			conn.status.Device != test.wantDev {
+			t.Errorf("%d: got %s %q, want %s %q", i,
+				conn.status.ConnectionState, conn.status.Device,
+				test.wantState, test.wantDev)
+		}
+	}
+}
+
+// TestDaemonHandleConnectionEvent tests handleConnectionEvent of Daemon.
+func TestDaemonHandleConnectionEvent(t *testing.T) {
+	d, _ := getTestConnectionsDaemon(t)
+	conn := d.conns.get("lab")
+
+	// unknown connection
+	if err := d.handleConnectionEvent(&connectionEvent{
+		name:  "other",
+		event: &ocrunner.ConnectEvent{},
+	}); err != nil {
+		t.Error(err)
+	}
+
+	// connect
+	if err := d.handleConnectionEvent(&connectionEvent{
+		name:  "lab",
+		event: &ocrunner.ConnectEvent{Connect: true, PID: 123},
+	}); err != nil {
+		t.Error(err)
+	}
+	if !conn.status.OCRunning.Running() || conn.status.OCPID != 123 {
+		t.Errorf("got invalid status %v", conn.status)
+	}
+
+	// disconnect
+	conn.status.ConnectionState = vpnstatus.ConnectionStateConnecting
+	conn.status.Server = "lab.example.com"
+	if err := d.handleConnectionEvent(&connectionEvent{
+		name:  "lab",
+		event: &ocrunner.ConnectEvent{ExitCode: 1},
+	}); err != nil {
+		t.Error(err)
+	}
+	if conn.status.OCRunning.Running() ||
+		conn.status.OCPID != 0 ||
+		!conn.status.ConnectionState.Disconnected() ||
+		conn.status.Server != "" {
+		t.Errorf("got invalid status %v", conn.status)
+	}
+}
+
+// TestDaemonNamedConnectionEvents tests signals, connection history and hooks
+// of named VPN connections of Daemon.
+func TestDaemonNamedConnectionEvents(t *testing.T) {
+	ran := setTestHooks(t)
+	d, _ := getTestConnectionsDaemon(t)
+	defer d.hooks.stop()
+	login := &logininfo.LoginInfo{
+		Server:      "lab.example.com",
+		Cookie:      "cookie",
+		Host:        "10.0.0.2",
+		Fingerprint: "fingerprint",
+	}
+	exit := func(exitCode int) {
+		if err := d.handleConnectionEvent(&connectionEvent{
+			name:  "lab",
+			event: &ocrunner.ConnectEvent{ExitCode: exitCode},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// invalid login
+	_ = d.connectNamedVPN("lab", &logininfo.LoginInfo{})
+
+	// openconnect exits while connecting
+	if err := d.connectNamedVPN("lab", login); err != nil {
+		t.Fatal(err)
+	}
+	exit(1)
+
+	want := [][]any{
+		{dbusapi.SignalConnectFailed, "connection lab: " + connectFailedInvalidLogin},
+		{dbusapi.SignalConnectFailed, "connection lab: " + connectFailedOCExit + " with exit code 1"},
+	}
+	if got := d.dbus.(*dbusService).signals; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// connect and disconnect by user
+	if err := d.connectNamedVPN("lab", login); err != nil {
+		t.Fatal(err)
+	}
+	config := vpnconfig.New()
+	config.Device.Name = "tun1"
+	config.IPv4.Address = net.IPv4(192, 168, 0, 123)
+	config.IPv4.Netmask = net.IPv4Mask(255, 255, 255, 0)
+	if err := d.updateNamedVPNConfig(&VPNConfigUpdate{
+		Reason:     "connect",
+		Connection: "lab",
+		Config:     config,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.disconnectNamedVPN("lab"); err != nil {
+		t.Fatal(err)
+	}
+	exit(0)
+
+	// hooks
+	for _, want := range []struct {
+		name  string
+		state vpnstatus.ConnectionState
+	}{
+		{cmdtmpl.HookConnected, vpnstatus.ConnectionStateConnected},
+		{cmdtmpl.HookDisconnected, vpnstatus.ConnectionStateDisconnected},
+	} {
+		got := <-ran
+		if got.name != want.name ||
+			got.data.Connection != "lab" ||
+			got.data.Status.ConnectionState != want.state ||
+			got.data.Status.Device != "tun1" ||
+			got.data.Status.IP != "192.168.0.123" {
+			t.Errorf("got %s %s %v, want %s %s", got.name, got.data.Connection,
+				got.data.Status, want.name, want.state)
+		}
+	}
+	select {
+	case got := <-ran:
+		t.Errorf("unexpected hook %s", got.name)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// connection history
+	sessions := d.history.get().Sessions
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+	for i, want := range []struct {
+		ip       string
+		cause    vpnhistory.DisconnectCause
+		exitCode int
+	}{
+		{"", vpnhistory.DisconnectCauseOpenConnectExit, 1},
+		{"192.168.0.123", vpnhistory.DisconnectCauseUser, 0},
+	} {
+		s := sessions[i]
+		if s.Connection != "lab" ||
+			s.Server != "lab.example.com" ||
+			s.IP != want.ip ||
+			s.Cause != want.cause ||
+			s.ExitCode != want.exitCode {
+			t.Errorf("%d: got invalid session %v", i, s)
+		}
+	}
+
+	// shutdown ends running session
+	if err := d.connectNamedVPN("lab", login); err != nil {
+		t.Fatal(err)
+	}
+	d.stopConnections()
+	sessions = d.history.get().Sessions
+	if s := sessions[len(sessions)-1]; len(sessions) != 3 ||
+		s.Cause != vpnhistory.DisconnectCauseShutdown {
+		t.Errorf("got invalid session %v", s)
+	}
+}
@@ -0,0 +1,35 @@
+package daemon
+
+import "github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+
+// connectFailure counts consecutive connection failures for the connect
+// failure policy in the XML profile.
+type connectFailure struct {
+	config *daemoncfg.ConnectFailure
+
+	// failures is the number of consecutive connection failures
+	failures int
+}
+
+// failed records a connection failure.
+func (c *connectFailure) failed() {
+	c.failures++
+}
+
+// reset resets the connection failures after a successful connection.
+func (c *connectFailure) reset() {
+	c.failures = 0
+}
+
+// exceeded returns whether the maximum number of consecutive connection
+// failures is reached.
+func (c *connectFailure) exceeded() bool {
+	return c.failures >= c.config.MaxFailures
+}
+
+// newConnectFailure returns a new connection failure counter.
+func newConnectFailure(config *daemoncfg.ConnectFailure) *connectFailure {
+	return &connectFailure{
+		config: config,
+	}
+}
@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// TestConnectFailure tests connectFailure.
+func TestConnectFailure(t *testing.T) {
+	config := daemoncfg.NewConnectFailure()
+	config.MaxFailures = 2
+	c := newConnectFailure(config)
+
+	for i, want := range []bool{false, true, true} {
+		c.failed()
+		if c.exceeded() != want {
+			t.Errorf("%d: got %t, want %t", i, c.exceeded(), want)
+		}
+	}
+
+	c.reset()
+	if c.failures != 0 || c.exceeded() {
+		t.Errorf("failures should be reset, got %d", c.failures)
+	}
+}
+
+// TestNewConnectFailure tests newConnectFailure.
+func TestNewConnectFailure(t *testing.T) {
+	config := daemoncfg.NewConnectFailure()
+	c := newConnectFailure(config)
+	if c.config != config {
+		t.Errorf("got %p, want %p", c.config, config)
+	}
+	if c.failures != 0 {
+		t.Errorf("got %d, want 0", c.failures)
+	}
+}
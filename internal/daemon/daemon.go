@@ -4,6 +4,7 @@ package daemon
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/netip"
@@ -16,22 +17,49 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/telekom-mms/oc-daemon/internal/api"
 	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/configmon"
+	"github.com/telekom-mms/oc-daemon/internal/cpd"
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
 	"github.com/telekom-mms/oc-daemon/internal/dbusapi"
-	"github.com/telekom-mms/oc-daemon/internal/dnsproxy"
+	"github.com/telekom-mms/oc-daemon/internal/dnsmon"
+	"github.com/telekom-mms/oc-daemon/internal/dnstnd"
+	"github.com/telekom-mms/oc-daemon/internal/journal"
+	"github.com/telekom-mms/oc-daemon/internal/metrics"
 	"github.com/telekom-mms/oc-daemon/internal/ocrunner"
 	"github.com/telekom-mms/oc-daemon/internal/profilemon"
+	"github.com/telekom-mms/oc-daemon/internal/sdnotify"
 	"github.com/telekom-mms/oc-daemon/internal/sleepmon"
 	"github.com/telekom-mms/oc-daemon/internal/trafpol"
 	"github.com/telekom-mms/oc-daemon/internal/vpnsetup"
 	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
 	"github.com/telekom-mms/oc-daemon/pkg/vpnconfig"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnhistory"
 	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
 	"github.com/telekom-mms/oc-daemon/pkg/xmlprofile"
 	"github.com/telekom-mms/tnd/pkg/tnd"
 	"golang.org/x/sys/unix"
 )
 
+// Connect failed reasons.
+const (
+	connectFailedInvalidLogin = "invalid login information"
+	connectFailedOCExit       = "openconnect exited before connection was established"
+)
+
+// sdnotifyNotify is sdnotify.Notify for testing.
+var sdnotifyNotify = sdnotify.Notify
+
+// sdnotifyWatchdogInterval is sdnotify.WatchdogInterval for testing.
+var sdnotifyWatchdogInterval = sdnotify.WatchdogInterval
+
+// notify sends the notification state to systemd.
+func notify(state string) {
+	if err := sdnotifyNotify(state); err != nil {
+		log.WithError(err).WithField("state", state).
+			Error("Daemon could not notify systemd")
+	}
+}
+
 // Daemon is used to run the daemon.
 type Daemon struct {
 	config *daemoncfg.Config
@@ -41,6 +69,12 @@ type Daemon struct {
 
 	tnd tnd.TND
 
+	// dnstnd is the DNS-based TND
+	dnstnd dnstnd.TND
+
+	// tndResults are the results of the HTTPS-based and DNS-based TND
+	tndResults tndResults
+
 	vpnsetup vpnsetup.Setup
 	trafpol  trafpol.Policer
 
@@ -50,6 +84,10 @@ type Daemon struct {
 
 	runner ocrunner.Runner
 
+	// conns are the named VPN connections in addition to the default
+	// VPN connection
+	conns *connections
+
 	// token is used for client authentication
 	token string
 
@@ -64,6 +102,27 @@ type Daemon struct {
 	profile *xmlprofile.Profile
 	profmon profilemon.Monitor
 
+	// cfgmon is the config monitor
+	cfgmon configmon.Monitor
+
+	// channel for config reload requests
+	reloads chan struct{}
+
+	// pendingConfig is the reloaded config that is applied
+	// after the VPN tunnel is disconnected
+	pendingConfig *daemoncfg.Config
+
+	// recovered are the journal entries recovered during startup
+	recovered []*journal.Recovered
+
+	// adopted is the state of the VPN tunnel kept running during the
+	// restart of the daemon, nil if there is no VPN tunnel to adopt
+	adopted *restartState
+
+	// detached indicates that the VPN tunnel keeps running after the
+	// daemon is stopped
+	detached bool
+
 	// disableTrafPol determines if traffic policing should be disabled,
 	// overrides other traffic policing settings
 	disableTrafPol bool
@@ -74,6 +133,37 @@ type Daemon struct {
 	// serverIPAllowed indicates whether server IP was added to
 	// the allowed addresses
 	serverIPAllowed bool
+
+	// reconnect is the reconnect supervisor
+	reconnect *reconnect
+
+	// history is the connection history
+	history *history
+
+	// idle is the idle monitor of the VPN tunnel
+	idle *idle
+
+	// resume handles the VPN connection on resume after suspend
+	resume *resume
+
+	// connfail counts connection failures for the connect failure policy
+	connfail *connectFailure
+
+	// netpolicy keeps the state of the trusted and untrusted network
+	// policies
+	netpolicy *netPolicy
+
+	// hooks runs the hook command lists
+	hooks *hooks
+
+	// machineauth authenticates with the machine certificate
+	machineauth *machineAuth
+
+	// disconnectCause is the cause of the current disconnect
+	disconnectCause vpnhistory.DisconnectCause
+
+	// metricsrv is the metrics server, nil if metrics are disabled
+	metricsrv *metrics.Server
 }
 
 // setStatusTrustedNetwork sets the trusted network status in status.
@@ -93,7 +183,12 @@ func (d *Daemon) setStatusTrustedNetwork(trusted bool) {
 	// status changed
 	log.WithField("TrustedNetwork", trustedNetwork).Info("Daemon changed TrustedNetwork status")
 	d.status.TrustedNetwork = trustedNetwork
+	metrics.TrustedNetwork.Set(int64(trustedNetwork))
 	d.dbus.SetProperty(dbusapi.PropertyTrustedNetwork, trustedNetwork)
+	if trustedNetwork.Trusted() {
+		d.dbus.EmitSignal(dbusapi.SignalTrustedNetworkDetected)
+	}
+	d.hooks.trigger(cmdtmpl.HookTrustedNetwork, d.config, d.status)
 }
 
 // setStatusConnectionState sets the connection state in status.
@@ -105,8 +200,21 @@ func (d *Daemon) setStatusConnectionState(connectionState vpnstatus.ConnectionSt
 
 	// state changed
 	log.WithField("ConnectionState", connectionState).Info("Daemon changed ConnectionState status")
+	old := d.status.ConnectionState
 	d.status.ConnectionState = connectionState
+	metrics.ConnectionState.Set(int64(connectionState))
 	d.dbus.SetProperty(dbusapi.PropertyConnectionState, connectionState)
+	notify(sdnotify.Status("VPN " + connectionState.String()))
+
+	// run hooks when the vpn is connected or disconnected
+	switch {
+	case connectionState == vpnstatus.ConnectionStateConnected:
+		d.hooks.trigger(cmdtmpl.HookConnected, d.config, d.status)
+	case connectionState == vpnstatus.ConnectionStateDisconnected &&
+		(old == vpnstatus.ConnectionStateConnected ||
+			old == vpnstatus.ConnectionStateDisconnecting):
+		d.hooks.trigger(cmdtmpl.HookDisconnected, d.config, d.status)
+	}
 }
 
 // setStatusIP sets the IP in status.
@@ -227,6 +335,7 @@ func (d *Daemon) setStatusTrafPolState(state vpnstatus.TrafPolState) {
 	// TrafPol state changed
 	log.WithField("TrafPolState", state).Info("Daemon changed TrafPolState status")
 	d.status.TrafPolState = state
+	metrics.TrafPolState.Set(int64(state))
 	d.dbus.SetProperty(dbusapi.PropertyTrafPolState, state)
 }
 
@@ -253,7 +362,9 @@ func (d *Daemon) setStatusCaptivePortal(capPortal vpnstatus.CaptivePortal) {
 	// state changed
 	log.WithField("CaptivePortal", capPortal).Info("Daemon changed CaptivePortal status")
 	d.status.CaptivePortal = capPortal
+	metrics.CaptivePortal.Set(int64(capPortal))
 	d.dbus.SetProperty(dbusapi.PropertyCaptivePortal, capPortal)
+	d.hooks.trigger(cmdtmpl.HookCaptivePortal, d.config, d.status)
 }
 
 // setStatusTNDState sets the TND state in status.
@@ -282,6 +393,19 @@ func (d *Daemon) setStatusTNDServers(servers []string) {
 	d.dbus.SetProperty(dbusapi.PropertyTNDServers, servers)
 }
 
+// setStatusTNDMatches sets the matching TND criteria in status.
+func (d *Daemon) setStatusTNDMatches(matches []string) {
+	if slices.Equal(d.status.TNDMatches, matches) {
+		// TND matches not changed
+		return
+	}
+
+	// TND matches changed
+	log.WithField("TNDMatches", matches).Info("Daemon changed TNDMatches status")
+	d.status.TNDMatches = matches
+	d.dbus.SetProperty(dbusapi.PropertyTNDMatches, matches)
+}
+
 // setStatusVPNConfig sets the VPN config in status.
 func (d *Daemon) setStatusVPNConfig(config *vpnconfig.Config) {
 	if d.status.VPNConfig.Equal(config) {
@@ -310,18 +434,206 @@ func (d *Daemon) setStatusVPNConfig(config *vpnconfig.Config) {
 	d.dbus.SetProperty(dbusapi.PropertyVPNConfig, s)
 }
 
-// connectVPN connects to the VPN using login info from client request.
-func (d *Daemon) connectVPN(login *logininfo.LoginInfo) {
+// setStatusReconnectAttempts sets the reconnect attempts in status.
+func (d *Daemon) setStatusReconnectAttempts(attempts uint32) {
+	if d.status.ReconnectAttempts == attempts {
+		// reconnect attempts not changed
+		return
+	}
+
+	// reconnect attempts changed
+	log.WithField("ReconnectAttempts", attempts).Info("Daemon changed ReconnectAttempts status")
+	d.status.ReconnectAttempts = attempts
+	d.dbus.SetProperty(dbusapi.PropertyReconnectAttempts, attempts)
+}
+
+// setStatusReconnectAt sets the time of the next reconnect attempt in status.
+func (d *Daemon) setStatusReconnectAt(reconnectAt int64) {
+	if d.status.ReconnectAt == reconnectAt {
+		// reconnect time not changed
+		return
+	}
+
+	// reconnect time changed
+	log.WithField("ReconnectAt", reconnectAt).Info("Daemon changed ReconnectAt status")
+	d.status.ReconnectAt = reconnectAt
+	d.dbus.SetProperty(dbusapi.PropertyReconnectAt, reconnectAt)
+}
+
+// setStatusReconnectGiveUpReason sets the reconnect give up reason in status.
+func (d *Daemon) setStatusReconnectGiveUpReason(reason string) {
+	if d.status.ReconnectGiveUpReason == reason {
+		// reconnect give up reason not changed
+		return
+	}
+
+	// reconnect give up reason changed
+	log.WithField("ReconnectGiveUpReason", reason).Info("Daemon changed ReconnectGiveUpReason status")
+	d.status.ReconnectGiveUpReason = reason
+	d.dbus.SetProperty(dbusapi.PropertyReconnectGiveUpReason, reason)
+}
+
+// setStatusIdleTimeoutAt sets the time of the upcoming idle timeout in status.
+func (d *Daemon) setStatusIdleTimeoutAt(idleTimeoutAt int64) {
+	if d.status.IdleTimeoutAt == idleTimeoutAt {
+		// idle timeout at not changed
+		return
+	}
+
+	// idle timeout at changed
+	log.WithField("IdleTimeoutAt", idleTimeoutAt).Info("Daemon changed IdleTimeoutAt status")
+	d.status.IdleTimeoutAt = idleTimeoutAt
+	d.dbus.SetProperty(dbusapi.PropertyIdleTimeoutAt, idleTimeoutAt)
+}
+
+// setStatusResumeAction sets the action on resume after suspend in status.
+func (d *Daemon) setStatusResumeAction(action string) {
+	if d.status.ResumeAction == action {
+		// resume action not changed
+		return
+	}
+
+	// resume action changed
+	log.WithField("ResumeAction", action).Info("Daemon changed ResumeAction status")
+	d.status.ResumeAction = action
+	d.dbus.SetProperty(dbusapi.PropertyResumeAction, action)
+}
+
+// setStatusResumeOutcome sets the outcome of the action on resume after
+// suspend in status.
+func (d *Daemon) setStatusResumeOutcome(outcome string) {
+	if d.status.ResumeOutcome == outcome {
+		// resume outcome not changed
+		return
+	}
+
+	// resume outcome changed
+	log.WithField("ResumeOutcome", outcome).Info("Daemon changed ResumeOutcome status")
+	d.status.ResumeOutcome = outcome
+	d.dbus.SetProperty(dbusapi.PropertyResumeOutcome, outcome)
+}
+
+// setStatusNetworkPolicyAction sets the applied network policy action in
+// status.
+func (d *Daemon) setStatusNetworkPolicyAction(action string) {
+	if d.status.NetworkPolicyAction == action {
+		// network policy action not changed
+		return
+	}
+
+	// network policy action changed
+	log.WithField("NetworkPolicyAction", action).Info("Daemon changed NetworkPolicyAction status")
+	d.status.NetworkPolicyAction = action
+	d.dbus.SetProperty(dbusapi.PropertyNetworkPolicyAction, action)
+}
+
+// setStatusNetworkPolicyDecision sets the network policy decision in status.
+func (d *Daemon) setStatusNetworkPolicyDecision(decision string) {
+	if d.status.NetworkPolicyDecision == decision {
+		// network policy decision not changed
+		return
+	}
+
+	// network policy decision changed
+	log.WithField("NetworkPolicyDecision", decision).Info("Daemon changed NetworkPolicyDecision status")
+	d.status.NetworkPolicyDecision = decision
+	d.dbus.SetProperty(dbusapi.PropertyNetworkPolicyDecision, decision)
+}
+
+// setStatusDisconnectPolicy sets the disconnect policy in status.
+func (d *Daemon) setStatusDisconnectPolicy(policy vpnstatus.DisconnectPolicy) {
+	if d.status.DisconnectPolicy == policy {
+		// disconnect policy not changed
+		return
+	}
+
+	// disconnect policy changed
+	log.WithField("DisconnectPolicy", policy).Info("Daemon changed DisconnectPolicy status")
+	d.status.DisconnectPolicy = policy
+	d.dbus.SetProperty(dbusapi.PropertyDisconnectPolicy, policy)
+}
+
+// checkDisconnectPolicy checks if the user is allowed to disconnect the VPN
+// and updates the disconnect policy in status. Disconnecting is locked if
+// Always On is enabled and VPN disconnects are not allowed in the xml
+// profile, unless Always On is disabled by the VPN server.
+func (d *Daemon) checkDisconnectPolicy() {
+	if d.profile.GetAlwaysOn() &&
+		!d.profile.GetAllowVPNDisconnect() &&
+		!d.disableTrafPol {
+		d.setStatusDisconnectPolicy(vpnstatus.DisconnectPolicyLocked)
+		return
+	}
+	d.setStatusDisconnectPolicy(vpnstatus.DisconnectPolicyAllowed)
+}
+
+// setStatusConnectFailureState sets the connect failure policy state in status.
+func (d *Daemon) setStatusConnectFailureState(state vpnstatus.ConnectFailureState) {
+	if d.status.ConnectFailureState == state {
+		// connect failure state not changed
+		return
+	}
+
+	// connect failure state changed
+	log.WithField("ConnectFailureState", state).Info("Daemon changed ConnectFailureState status")
+	d.status.ConnectFailureState = state
+	d.dbus.SetProperty(dbusapi.PropertyConnectFailureState, state)
+}
+
+// setStatusConnectFailures sets the connection failures in status.
+func (d *Daemon) setStatusConnectFailures(failures uint32) {
+	if d.status.ConnectFailures == failures {
+		// connect failures not changed
+		return
+	}
+
+	// connect failures changed
+	log.WithField("ConnectFailures", failures).Info("Daemon changed ConnectFailures status")
+	d.status.ConnectFailures = failures
+	d.dbus.SetProperty(dbusapi.PropertyConnectFailures, failures)
+}
+
+// checkConnectFailurePolicy checks the connect failure policy in the xml
+// profile and updates the connect failure state in status. With an open
+// policy, traffic policing is relaxed after repeated connection failures.
+// With a closed policy, traffic policing is kept.
+func (d *Daemon) checkConnectFailurePolicy() {
+	switch {
+	case !d.profile.GetAlwaysOn() || d.disableTrafPol:
+		d.setStatusConnectFailureState(vpnstatus.ConnectFailureStateInactive)
+	case !d.profile.GetConnectFailurePolicyOpen():
+		d.setStatusConnectFailureState(vpnstatus.ConnectFailureStateClosed)
+	case d.connfail.exceeded():
+		d.setStatusConnectFailureState(vpnstatus.ConnectFailureStateRelaxed)
+	default:
+		d.setStatusConnectFailureState(vpnstatus.ConnectFailureStateOpen)
+	}
+}
+
+// connectVPN connects to the VPN using login info from client request. It
+// returns whether connecting was started.
+func (d *Daemon) connectVPN(login *logininfo.LoginInfo) bool {
 	// allow only one connection
 	if d.status.OCRunning.Running() {
-		return
+		return false
 	}
 
 	// ignore invalid login information
 	if !login.Valid() {
-		return
+		d.dbus.EmitSignal(dbusapi.SignalConnectFailed, connectFailedInvalidLogin)
+		return false
 	}
 
+	// save login and connect using runner
+	d.setStatusConnecting(login)
+	d.config.LoginInfo = login
+	d.runner.Connect(d.config.Copy(), d.getRunnerEnv(""))
+	return true
+}
+
+// setStatusConnecting sets the status, the connection history and the
+// allowed server address for connecting to the VPN with login info.
+func (d *Daemon) setStatusConnecting(login *logininfo.LoginInfo) {
 	// set server address
 	if serverIP, err := netip.ParseAddr(strings.Trim(login.Host, "[]")); err == nil {
 		d.serverIP = serverIP
@@ -333,23 +645,101 @@ func (d *Daemon) connectVPN(login *logininfo.LoginInfo) {
 	d.setStatusServerIP(d.serverIP.String())
 	d.setStatusConnectionState(vpnstatus.ConnectionStateConnecting)
 
+	// start session in connection history
+	d.history.start(login.Server, d.serverIP.String())
+
 	// add server address to allowed addrs in trafpol
 	if d.trafpol != nil && d.serverIP.IsValid() {
 		d.serverIPAllowed = d.trafpol.AddAllowedAddr(d.serverIP)
 	}
+}
 
-	// save login and connect using runner
-	d.config.LoginInfo = login
-	env := []string{
-		"oc_daemon_token=" + d.token,
-		"oc_daemon_socket_file=" + d.config.SocketServer.SocketFile,
-		"oc_daemon_verbose=" + strconv.FormatBool(d.config.Verbose),
+// startReconnect starts supervising the VPN connection with login info for
+// reconnects.
+func (d *Daemon) startReconnect(login *logininfo.LoginInfo) {
+	d.reconnect.start(login)
+	d.setStatusReconnectAttempts(0)
+	d.setStatusReconnectAt(0)
+	d.setStatusReconnectGiveUpReason("")
+}
+
+// stopReconnect stops supervising the VPN connection for reconnects.
+func (d *Daemon) stopReconnect() {
+	d.reconnect.stop()
+	d.setStatusReconnectAttempts(0)
+	d.setStatusReconnectAt(0)
+}
+
+// giveUpReconnect stops reconnecting the VPN for reason.
+func (d *Daemon) giveUpReconnect(reason string) {
+	log.WithField("reason", reason).Warn("Daemon giving up reconnecting VPN")
+	metrics.ReconnectGiveUps.Inc()
+	d.reconnect.stop()
+	d.setStatusReconnectAt(0)
+	d.setStatusReconnectGiveUpReason(reason)
+}
+
+// checkReconnect checks if we need to reconnect the VPN after openconnect
+// exited.
+func (d *Daemon) checkReconnect() {
+	if !d.reconnect.active() {
+		// disconnect was expected, e.g., requested by user
+		return
+	}
+
+	// do not reconnect in trusted network
+	if d.status.TrustedNetwork.Trusted() && d.disconnectsInTrustedNetwork() {
+		d.giveUpReconnect(reconnectReasonTrustedNetwork)
+		return
 	}
-	d.runner.Connect(d.config.Copy(), env)
+
+	// schedule next reconnect attempt
+	next, ok := d.reconnect.schedule()
+	if !ok {
+		d.giveUpReconnect(reconnectReasonMaxAttempts)
+		return
+	}
+	log.WithFields(log.Fields{
+		"attempt": d.reconnect.attempts,
+		"at":      next,
+	}).Warn("Daemon detected unexpected exit of OpenConnect, scheduling reconnect")
+	d.setStatusReconnectAttempts(d.reconnect.attempts)
+	d.setStatusReconnectAt(next.Unix())
+	d.dbus.EmitSignal(dbusapi.SignalReconnecting, d.reconnect.attempts, next.Unix())
 }
 
-// disconnectVPN disconnects from the VPN.
-func (d *Daemon) disconnectVPN() {
+// handleReconnectTimer handles the timer of a scheduled reconnect attempt.
+func (d *Daemon) handleReconnectTimer() {
+	d.reconnect.fired()
+	d.setStatusReconnectAt(0)
+	if !d.reconnect.active() {
+		return
+	}
+
+	log.WithField("attempt", d.reconnect.attempts).Info("Daemon reconnecting VPN")
+	metrics.ReconnectAttempts.Inc()
+	d.connectVPN(d.reconnect.getLogin())
+}
+
+// disconnectVPN disconnects from the VPN because of cause. openconnect logs
+// off the session, so the stored login info cannot be used for network
+// policies any more.
+func (d *Daemon) disconnectVPN(cause vpnhistory.DisconnectCause) {
+	d.stopVPN(cause, false)
+}
+
+// pauseVPN disconnects from the VPN because of cause without logging off the
+// session, so it can be resumed with the stored login info.
+func (d *Daemon) pauseVPN(cause vpnhistory.DisconnectCause) {
+	d.stopVPN(cause, true)
+}
+
+// stopVPN disconnects from the VPN because of cause. If keepSession is set,
+// the session is not logged off.
+func (d *Daemon) stopVPN(cause vpnhistory.DisconnectCause, keepSession bool) {
+	// disconnect is expected, stop reconnecting
+	d.stopReconnect()
+
 	// check if vpn is flagged as running
 	if !d.status.OCRunning.Running() {
 		log.WithField("error", "vpn not running").
@@ -359,14 +749,39 @@ func (d *Daemon) disconnectVPN() {
 
 	// update status
 	d.setStatusConnectionState(vpnstatus.ConnectionStateDisconnecting)
+	d.disconnectCause = cause
+
+	// session is logged off, do not reuse stored login info
+	if !keepSession {
+		d.netpolicy.clear()
+	}
 
 	// stop runner
 	if d.runner == nil {
 		return
 	}
+	if keepSession {
+		d.runner.DisconnectKeepSession()
+		return
+	}
 	d.runner.Disconnect()
 }
 
+// userDisconnectVPN disconnects the VPN on request of the user. It returns
+// an error if the disconnect policy does not allow the user to disconnect.
+func (d *Daemon) userDisconnectVPN() error {
+	if d.status.DisconnectPolicy.Locked() {
+		log.Warn("Daemon refused disconnect request, disconnect not allowed by XML profile")
+		return dbusapi.ErrDisconnectLocked
+	}
+
+	d.resume.stop()
+	d.netpolicy.clear()
+	d.machineauth.stop()
+	d.disconnectVPN(vpnhistory.DisconnectCauseUser)
+	return nil
+}
+
 // updateVPNConfigUp updates the VPN config for VPN connect.
 func (d *Daemon) updateVPNConfigUp(config *vpnconfig.Config) {
 	// check if old and new config differ
@@ -398,9 +813,11 @@ func (d *Daemon) updateVPNConfigUp(config *vpnconfig.Config) {
 	// set traffic policing setting from Disable Always On VPN setting
 	// in configuration
 	d.disableTrafPol = config.Flags.DisableAlwaysOnVPN
+	d.checkDisconnectPolicy()
 
 	// save config
 	d.setStatusVPNConfig(config)
+	d.updateDNSTNDExcludedDomains()
 	ip := ""
 	for _, p := range []netip.Prefix{d.config.VPNConfig.IPv4, d.config.VPNConfig.IPv6} {
 		// this assumes either a single IPv4 or a single IPv6 address
@@ -411,12 +828,88 @@ func (d *Daemon) updateVPNConfigUp(config *vpnconfig.Config) {
 	}
 	d.setStatusIP(ip)
 	d.setStatusDevice(config.Device.Name)
+	d.history.connected(ip)
 
 	d.setStatusConnectionState(vpnstatus.ConnectionStateConnected)
 	d.setStatusConnectedAt(time.Now().Unix())
+
+	// connection established, store login for network policies
+	d.netpolicy.connected(d.config.LoginInfo)
+
+	// connection established, reset reconnect attempts
+	d.reconnect.connected()
+	d.setStatusReconnectAttempts(0)
+
+	// connection established, reset connection failures and restore
+	// traffic policing relaxed by the connect failure policy
+	if d.connfail.failures > 0 {
+		d.connfail.reset()
+		d.setStatusConnectFailures(0)
+		if err := d.checkTrafPol(); err != nil {
+			log.WithError(err).Error("Daemon could not restore traffic policing after connection failures")
+		}
+	}
+	if d.status.ResumeOutcome == resumeOutcomeReconnecting {
+		d.setStatusResumeOutcome(resumeOutcomeReconnected)
+	}
+
+	// monitor VPN tunnel for idle timeout of the server
+	d.startIdle(config)
 	log.Info("Daemon configured VPN connection")
 }
 
+// startIdle starts monitoring the VPN tunnel in config for the idle timeout.
+func (d *Daemon) startIdle(config *vpnconfig.Config) {
+	timeout := time.Duration(config.Timeout) * time.Second
+	netns := ""
+	if d.config.NetNS.Enabled {
+		netns = d.config.NetNS.Name
+	}
+	d.idle.start(netns, config.Device.Name, timeout)
+	if d.idle.active() {
+		log.WithFields(log.Fields{
+			"device":  config.Device.Name,
+			"timeout": timeout,
+		}).Info("Daemon monitoring VPN tunnel for idle timeout")
+	}
+}
+
+// stopIdle stops monitoring the VPN tunnel for the idle timeout.
+func (d *Daemon) stopIdle() {
+	d.idle.stop()
+	d.setStatusIdleTimeoutAt(0)
+}
+
+// handleIdleCheck checks the VPN tunnel for the idle timeout.
+func (d *Daemon) handleIdleCheck() {
+	if !d.idle.active() {
+		return
+	}
+
+	state := d.idle.check(time.Now())
+	if state == idleStateActive {
+		d.setStatusIdleTimeoutAt(0)
+		return
+	}
+
+	// warn user about idle timeout
+	idleTimeoutAt := d.idle.deadline().Unix()
+	if d.status.IdleTimeoutAt != idleTimeoutAt {
+		log.WithField("at", d.idle.deadline()).
+			Warn("Daemon detected idle VPN tunnel")
+		d.setStatusIdleTimeoutAt(idleTimeoutAt)
+		d.dbus.EmitSignal(dbusapi.SignalIdleTimeoutWarning, idleTimeoutAt)
+	}
+
+	// disconnect on idle timeout
+	if state == idleStateTimeout &&
+		d.config.IdleTimeout.Action == daemoncfg.IdleTimeoutActionDisconnect {
+		log.Warn("Daemon reached idle timeout of VPN tunnel, disconnecting VPN")
+		d.idle.stop()
+		d.disconnectVPN(vpnhistory.DisconnectCauseIdleTimeout)
+	}
+}
+
 // updateVPNConfigDown updates the VPN config for VPN disconnect.
 func (d *Daemon) updateVPNConfigDown() {
 	// TODO: only call this from Runner Event only and remove down message?
@@ -429,7 +922,8 @@ func (d *Daemon) updateVPNConfigDown() {
 		return
 	}
 
-	// disconnecting, tear down configuration
+	// disconnecting, stop idle monitor and tear down configuration
+	d.stopIdle()
 	log.Info("Daemon tearing down vpn configuration")
 	if d.status.VPNConfig != nil {
 		d.vpnsetup.Teardown(d.config)
@@ -441,6 +935,7 @@ func (d *Daemon) updateVPNConfigDown() {
 
 	// save config
 	d.setStatusVPNConfig(nil)
+	d.updateDNSTNDExcludedDomains()
 	d.setStatusServer("")
 	d.setStatusServerIP("")
 	d.setStatusConnectedAt(0)
@@ -508,8 +1003,19 @@ func (d *Daemon) updateVPNConfig(request *api.Request) {
 
 	// handle config update for vpn pre-init, connect, disconnect,
 	// attempt-reconnect, reconnect
-	log.WithField("reason", configUpdate.Reason).
-		Info("Daemon got OpenConnect event from VPNCScript")
+	log.WithFields(log.Fields{
+		"reason":     configUpdate.Reason,
+		"connection": configUpdate.Connection,
+	}).Info("Daemon got OpenConnect event from VPNCScript")
+	if configUpdate.Connection != "" {
+		// config update of named VPN connection
+		if err := d.updateNamedVPNConfig(configUpdate); err != nil {
+			log.WithError(err).WithField("connection", configUpdate.Connection).
+				Error("Daemon got config update for invalid connection")
+			request.Error("invalid connection in config update message")
+		}
+		return
+	}
 	switch configUpdate.Reason {
 	case "connect":
 		d.updateVPNConfigUp(configUpdate.Config)
@@ -536,22 +1042,36 @@ func (d *Daemon) handleClientRequest(request *api.Request) {
 
 // dumpState returns the internal daemon state as json string.
 func (d *Daemon) dumpState() string {
-	// define state type
+	// define state types
+	type ConnectFailure struct {
+		State    string
+		Failures int
+	}
 	type State struct {
 		DaemonConfig     *daemoncfg.Config
 		TrafficPolicing  *trafpol.State
 		VPNSetup         *vpnsetup.State
 		CommandLists     map[string]*cmdtmpl.CommandList
 		CommandTemplates string
+		RecoveredJournal []*journal.Recovered
+		ConnectFailure   *ConnectFailure
+		CommandTrace     []*cmdtmpl.TraceEntry
 	}
 
 	// collect internal state
 	c := d.config.Copy()
 	c.LoginInfo.Cookie = "HIDDEN" // hide cookie
+	templates, lists := cmdtmpl.Get()
 	state := State{
 		DaemonConfig:     c,
-		CommandLists:     cmdtmpl.CommandLists,
-		CommandTemplates: cmdtmpl.LoadedTemplates,
+		CommandLists:     lists,
+		CommandTemplates: templates,
+		RecoveredJournal: d.recovered,
+		ConnectFailure: &ConnectFailure{
+			State:    d.status.ConnectFailureState.String(),
+			Failures: d.connfail.failures,
+		},
+		CommandTrace: cmdtmpl.Trace(),
 	}
 	if d.trafpol != nil {
 		state.TrafficPolicing = d.trafpol.GetState()
@@ -570,6 +1090,17 @@ func (d *Daemon) dumpState() string {
 	return string(b)
 }
 
+// getHistory returns the connection history as json string.
+func (d *Daemon) getHistory() string {
+	b, err := d.history.get().JSON()
+	if err != nil {
+		log.WithError(err).Error("Daemon could not convert connection history to JSON")
+		return ""
+	}
+
+	return string(b)
+}
+
 // handleDBusRequest handles a D-Bus API client request.
 func (d *Daemon) handleDBusRequest(request *dbusapi.Request) {
 	defer request.Close()
@@ -596,38 +1127,232 @@ func (d *Daemon) handleDBusRequest(request *dbusapi.Request) {
 
 		// connect VPN
 		log.Info("Daemon got connect request from client")
-		d.connectVPN(login)
+		if d.connectVPN(login) {
+			d.startReconnect(login)
+		}
 
 	case dbusapi.RequestDisconnect:
 		// disconnect VPN
 		log.Info("Daemon got disconnect request from client")
-		d.disconnectVPN()
+		request.Error = d.userDisconnectVPN()
+
+	case dbusapi.RequestConnectNamed:
+		// create login info
+		name := request.Parameters[0].(string)
+		login := &logininfo.LoginInfo{
+			Server:      request.Parameters[1].(string),
+			Cookie:      request.Parameters[2].(string),
+			Host:        request.Parameters[3].(string),
+			ConnectURL:  request.Parameters[4].(string),
+			Fingerprint: request.Parameters[5].(string),
+			Resolve:     request.Parameters[6].(string),
+		}
+
+		// connect named VPN connection
+		log.WithField("connection", name).Info("Daemon got connect request for named connection from client")
+		request.Error = d.connectNamedVPN(name, login)
+
+	case dbusapi.RequestDisconnectNamed:
+		// disconnect named VPN connection
+		name := request.Parameters[0].(string)
+		log.WithField("connection", name).Info("Daemon got disconnect request for named connection from client")
+		request.Error = d.disconnectNamedVPN(name)
 
 	case dbusapi.RequestDumpState:
 		// dump state
 		state := d.dumpState()
 		log.WithField("state", state).Info("Daemon got dump state request from client")
 		request.Results = []any{state}
+
+	case dbusapi.RequestGetHistory:
+		// get connection history
+		log.Info("Daemon got get history request from client")
+		request.Results = []any{d.getHistory()}
+
+	case dbusapi.RequestRemediate:
+		// remediate captive portal
+		log.Info("Daemon got captive portal remediation request from client")
+		request.Error = d.remediateCaptivePortal()
 	}
 }
 
-// checkDisconnectVPN checks if we need to disconnect the VPN when handling a
-// TND result.
-func (d *Daemon) checkDisconnectVPN() {
-	if d.status.TrustedNetwork.Trusted() && d.status.OCRunning.Running() {
+// disconnectsInTrustedNetwork returns whether the trusted network policy
+// disconnects the VPN in a trusted network.
+func (d *Daemon) disconnectsInTrustedNetwork() bool {
+	switch d.profile.GetTrustedNetworkPolicy() {
+	case xmlprofile.NetworkPolicyDisconnect, xmlprofile.NetworkPolicyPause:
+		return true
+	}
+	return false
+}
+
+// disconnectTrustedNetwork disconnects the VPN in a trusted network. If pause
+// is set, the VPN connection is paused and resumed in an untrusted network.
+func (d *Daemon) disconnectTrustedNetwork(pause bool) {
+	running := d.status.OCRunning.Running()
+	if !running && !d.reconnect.active() {
+		// nothing to disconnect
+		return
+	}
+
+	paused := pause && d.netpolicy.pause()
+	switch {
+	case running && paused:
+		log.Info("Daemon detected trusted network, pausing VPN connection")
+		d.setStatusNetworkPolicyDecision(netPolicyDecisionPaused)
+	case running:
 		// disconnect VPN when switching from untrusted network with
 		// active VPN connection to a trusted network
 		log.Info("Daemon detected trusted network, disconnecting VPN connection")
-		d.disconnectVPN()
+		d.setStatusNetworkPolicyDecision(netPolicyDecisionDisconnected)
+	case paused:
+		d.setStatusNetworkPolicyDecision(netPolicyDecisionPaused)
+	default:
+		d.setStatusNetworkPolicyDecision(netPolicyDecisionGaveUp)
 	}
+
+	if running && paused {
+		d.pauseVPN(vpnhistory.DisconnectCauseTrustedNetwork)
+		return
+	}
+	if running {
+		d.disconnectVPN(vpnhistory.DisconnectCauseTrustedNetwork)
+		return
+	}
+
+	// stop pending reconnect attempts in trusted network
+	d.giveUpReconnect(reconnectReasonTrustedNetwork)
+}
+
+// startMachineAuth starts the authentication with the machine certificate.
+// It returns false if machine authentication is not possible.
+func (d *Daemon) startMachineAuth() bool {
+	if !d.config.MachineAuth.Enabled {
+		return false
+	}
+
+	server := d.config.MachineAuth.VPNServer
+	if server == "" {
+		servers := d.profile.GetVPNServerHostNames()
+		if len(servers) == 0 {
+			log.Error("Daemon could not find VPN server for machine authentication")
+			return false
+		}
+		server = servers[0]
+	}
+
+	log.WithField("server", server).Info("Daemon authenticating with machine certificate")
+	d.machineauth.start(d.config.Copy(), server)
+	return true
+}
+
+// handleMachineAuthResult handles the result of the authentication with the
+// machine certificate and connects the VPN.
+func (d *Daemon) handleMachineAuthResult(result *machineAuthResult) {
+	d.machineauth.finished()
+	if result.err != nil {
+		log.WithError(result.err).Error("Daemon could not authenticate with machine certificate")
+		d.setStatusNetworkPolicyDecision(netPolicyDecisionAuthFailed)
+		d.dbus.EmitSignal(dbusapi.SignalConnectFailed,
+			fmt.Sprintf("%s: %v", netPolicyDecisionAuthFailed, result.err))
+		return
+	}
+
+	if d.status.OCRunning.Running() {
+		// vpn connected in the meantime
+		return
+	}
+
+	log.Info("Daemon connecting VPN after machine authentication")
+	d.setStatusNetworkPolicyDecision(netPolicyDecisionAuthConnect)
+	if d.connectVPN(result.login) {
+		d.startReconnect(result.login)
+	}
+}
+
+// connectNetworkPolicy connects the VPN with the login info of the last VPN
+// connection or with machine authentication.
+func (d *Daemon) connectNetworkPolicy() {
+	if d.status.OCRunning.Running() || d.reconnect.active() ||
+		d.machineauth.active() {
+		// vpn running, reconnect pending or authentication running
+		return
+	}
+
+	login := d.netpolicy.getLogin()
+	if login == nil && d.startMachineAuth() {
+		d.setStatusNetworkPolicyDecision(netPolicyDecisionMachineAuth)
+		return
+	}
+	if login == nil {
+		d.setStatusNetworkPolicyDecision(netPolicyDecisionNoLogin)
+		return
+	}
+
+	log.Info("Daemon connecting VPN with stored login because of network policy")
+	d.setStatusNetworkPolicyDecision(netPolicyDecisionConnecting)
+	if d.connectVPN(login) {
+		d.startReconnect(login)
+	}
+}
+
+// applyNetworkPolicy applies the trusted or untrusted network policy in the
+// XML profile when handling a TND result.
+func (d *Daemon) applyNetworkPolicy() {
+	trusted := d.status.TrustedNetwork.Trusted()
+	action := d.profile.GetUntrustedNetworkPolicy()
+	if trusted {
+		action = d.profile.GetTrustedNetworkPolicy()
+	}
+	if d.status.NetworkPolicyAction != action {
+		d.setStatusNetworkPolicyAction(action)
+		d.setStatusNetworkPolicyDecision(netPolicyDecisionNone)
+	}
+
+	// resume paused VPN connection when leaving the trusted network
+	if !trusted && !d.status.OCRunning.Running() {
+		if login := d.netpolicy.resume(); login != nil {
+			log.Info("Daemon detected untrusted network, resuming paused VPN connection")
+			d.setStatusNetworkPolicyDecision(netPolicyDecisionResumed)
+			if d.connectVPN(login) {
+				d.startReconnect(login)
+			}
+			return
+		}
+	}
+
+	switch action {
+	case xmlprofile.NetworkPolicyDisconnect:
+		d.disconnectTrustedNetwork(false)
+	case xmlprofile.NetworkPolicyPause:
+		d.disconnectTrustedNetwork(true)
+	case xmlprofile.NetworkPolicyConnect:
+		d.connectNetworkPolicy()
+	}
+}
+
+// handleTrustedNetwork combines the results of the HTTPS-based and
+// DNS-based TND and updates the trusted network.
+func (d *Daemon) handleTrustedNetwork() error {
+	trusted, matches := d.tndResults.trusted(d.tnd != nil, d.dnstnd != nil)
+	d.setStatusTrustedNetwork(trusted)
+	d.setStatusTNDMatches(matches)
+	d.applyNetworkPolicy()
+	return d.checkTrafPol()
 }
 
 // handleTNDResult handles a TND result.
 func (d *Daemon) handleTNDResult(trusted bool) error {
 	log.WithField("trusted", trusted).Debug("Daemon handling TND result")
-	d.setStatusTrustedNetwork(trusted)
-	d.checkDisconnectVPN()
-	return d.checkTrafPol()
+	d.tndResults.https = trusted
+	return d.handleTrustedNetwork()
+}
+
+// handleDNSTNDResult handles a DNS-based TND result.
+func (d *Daemon) handleDNSTNDResult(result *dnstnd.Result) error {
+	log.WithField("result", result).Debug("Daemon handling DNS-based TND result")
+	d.tndResults.dns = result
+	return d.handleTrustedNetwork()
 }
 
 // handleRunnerDisconnect handles a disconnect event from the OC runner,
@@ -640,6 +1365,7 @@ func (d *Daemon) handleRunnerDisconnect() {
 	d.setStatusServer("")
 	d.setStatusServerIP("")
 	d.setStatusConnectedAt(0)
+	d.setStatusCommandListError("")
 
 	// make sure the vpn config is not active any more
 	d.updateVPNConfigDown()
@@ -652,30 +1378,158 @@ func (d *Daemon) handleRunnerDisconnect() {
 	d.serverIPAllowed = false
 }
 
+// endSession ends the current session in the connection history with the
+// exit code of openconnect.
+func (d *Daemon) endSession(exitCode int) {
+	cause := d.disconnectCause
+	if cause == vpnhistory.DisconnectCauseUnknown {
+		// disconnect was not requested, openconnect exited
+		cause = vpnhistory.DisconnectCauseOpenConnectExit
+	}
+	d.history.end(cause, exitCode)
+	d.disconnectCause = vpnhistory.DisconnectCauseUnknown
+}
+
 // handleRunnerEvent handles a connect event from the OC runner.
-func (d *Daemon) handleRunnerEvent(e *ocrunner.ConnectEvent) {
+func (d *Daemon) handleRunnerEvent(e *ocrunner.ConnectEvent) error {
 	log.WithField("event", e).Debug("Daemon handling Runner event")
 
 	if e.Connect {
 		// make sure running is set
 		d.setStatusOCRunning(true)
 		d.setStatusOCPID(e.PID)
-		return
+		return nil
+	}
+
+	// openconnect exited before the connection was established
+	if d.status.ConnectionState.Connecting() &&
+		d.disconnectCause == vpnhistory.DisconnectCauseUnknown {
+		reason := fmt.Sprintf("%s with exit code %d", connectFailedOCExit, e.ExitCode)
+		d.dbus.EmitSignal(dbusapi.SignalConnectFailed, reason)
+		if d.status.ResumeOutcome == resumeOutcomeReconnecting {
+			d.setStatusResumeOutcome(resumeOutcomeReconnectFailed)
+		}
+
+		// stored login may be invalid, do not reuse it for network
+		// policies
+		d.netpolicy.clear()
+
+		// count connection failure for connect failure policy, e.g.,
+		// VPN servers are not reachable
+		d.connfail.failed()
+		d.setStatusConnectFailures(uint32(d.connfail.failures))
+		if err := d.checkTrafPol(); err != nil {
+			return err
+		}
 	}
 
 	// clean up after disconnect
+	d.endSession(e.ExitCode)
 	d.handleRunnerDisconnect()
+
+	// apply config changes deferred during connection
+	if err := d.applyPendingConfig(); err != nil {
+		return err
+	}
+
+	// reconnect after disconnect on resume
+	if login := d.resume.takeLogin(); login != nil {
+		log.Info("Daemon reconnecting VPN after resume")
+		if d.connectVPN(login) {
+			d.startReconnect(login)
+		}
+		return nil
+	}
+
+	// resume paused vpn connection if network is not trusted any more
+	if d.netpolicy.paused && !d.status.TrustedNetwork.Trusted() {
+		d.applyNetworkPolicy()
+		return nil
+	}
+
+	// reconnect after unexpected disconnect
+	d.checkReconnect()
+	return nil
 }
 
 // handleSleepMonEvent handles a suspend/resume event from SleepMon.
 func (d *Daemon) handleSleepMonEvent(sleep bool) {
 	log.WithField("sleep", sleep).Debug("Daemon handling SleepMon event")
 
+	// going to sleep, cancel actions of previous resume
+	if sleep {
+		d.resume.stop()
+		return
+	}
+
+	// nothing to do on resume without vpn
+	if !d.status.OCRunning.Running() {
+		return
+	}
+
+	policy := d.config.Resume.Policy
+	d.setStatusResumeAction(policy)
+
 	// disconnect vpn on resume
-	if !sleep && d.status.OCRunning.Running() {
+	if policy == daemoncfg.ResumePolicyDisconnect {
 		log.Info("Daemon resuming after sleep, disconnecting")
-		d.disconnectVPN()
+		d.setStatusResumeOutcome(resumeOutcomeDisconnected)
+		d.disconnectVPN(vpnhistory.DisconnectCauseResume)
+		return
+	}
+
+	// re-validate network before applying resume policy
+	log.WithField("policy", policy).
+		Info("Daemon resuming after sleep, validating network")
+	d.setStatusResumeOutcome(resumeOutcomeValidating)
+	if d.tnd != nil {
+		d.tnd.Probe()
+	}
+	if d.dnstnd != nil {
+		d.dnstnd.Probe()
+	}
+	if d.trafpol != nil {
+		d.trafpol.ProbeCPD()
 	}
+	d.resume.startValidation()
+}
+
+// handleResumeTimer handles the end of the network validation after resume
+// and applies the resume policy.
+func (d *Daemon) handleResumeTimer() {
+	d.resume.fired()
+
+	switch {
+	case d.status.TrustedNetwork.Trusted() && d.disconnectsInTrustedNetwork():
+		// vpn is disconnected in trusted network
+		d.setStatusResumeOutcome(resumeOutcomeTrustedNetwork)
+		d.applyNetworkPolicy()
+		return
+
+	case !d.status.OCRunning.Running():
+		d.setStatusResumeOutcome(resumeOutcomeNotRunning)
+		return
+
+	case d.status.CaptivePortal == vpnstatus.CaptivePortalDetected:
+		// reconnecting is not possible behind captive portal, let
+		// openconnect reconnect after captive portal login
+		log.Info("Daemon detected captive portal after resume, keeping VPN connection")
+		d.setStatusResumeOutcome(resumeOutcomeCaptivePortal)
+		return
+	}
+
+	if d.config.Resume.Policy != daemoncfg.ResumePolicyReconnect {
+		log.Info("Daemon keeping VPN connection after resume")
+		d.setStatusResumeOutcome(resumeOutcomeKept)
+		return
+	}
+
+	// disconnect without logging off the session and reconnect with
+	// stored login info after disconnect
+	log.Info("Daemon disconnecting VPN after resume for reconnect")
+	d.setStatusResumeOutcome(resumeOutcomeReconnecting)
+	d.resume.setLogin(d.config.LoginInfo)
+	d.pauseVPN(vpnhistory.DisconnectCauseResume)
 }
 
 // readXMLProfile reads the XML profile from file.
@@ -703,25 +1557,113 @@ func (d *Daemon) handleProfileUpdate() error {
 		return err
 	}
 	d.setStatusServers(d.profile.GetVPNServerHostNames())
+	d.checkDisconnectPolicy()
+	d.dbus.EmitSignal(dbusapi.SignalProfileReloaded)
 	return nil
 }
 
 // handleCPDStatusUpdate handles a CPD status update.
-func (d *Daemon) handleCPDStatusUpdate(detected bool) {
-	log.WithField("detected", detected).Debug("Daemon handling CPD status update")
+func (d *Daemon) handleCPDStatusUpdate(report *cpd.Report) {
+	log.WithField("report", report).Debug("Daemon handling CPD status update")
 
-	if detected {
+	if report.Detected {
 		d.setStatusCaptivePortal(vpnstatus.CaptivePortalDetected)
+		d.dbus.EmitSignal(dbusapi.SignalCaptivePortalDetected, report.URL)
 		return
 	}
 	d.setStatusCaptivePortal(vpnstatus.CaptivePortalNotDetected)
 }
 
+// remediateCaptivePortal opens the network for another captive portal
+// remediation window.
+func (d *Daemon) remediateCaptivePortal() error {
+	if d.trafpol == nil || !d.trafpol.Remediate() {
+		return errors.New("captive portal remediation not possible")
+	}
+	return nil
+}
+
+// handleRemediationExpired handles an expired captive portal remediation.
+func (d *Daemon) handleRemediationExpired() {
+	log.Info("Daemon got expired captive portal remediation")
+	d.dbus.EmitSignal(dbusapi.SignalRemediationExpired)
+}
+
+// handleCommandFailure handles a failed command in a command list.
+func (d *Daemon) handleCommandFailure(f *cmdtmpl.Failure) {
+	log.WithField("failure", f).Debug("Daemon handling command failure")
+	d.dbus.EmitSignal(dbusapi.SignalCommandFailed, f.List, f.Command, f.Stderr)
+
+	// command lists that stop after failures are in an error state
+	switch f.OnFailure {
+	case cmdtmpl.OnFailureAbort:
+		d.setStatusCommandListError(fmt.Sprintf("%s aborted after failed command %q",
+			f.List, f.Command))
+	case cmdtmpl.OnFailureRollback:
+		d.setStatusCommandListError(fmt.Sprintf("%s rolled back after failed command %q",
+			f.List, f.Command))
+	}
+}
+
+// setStatusCommandListError sets the command list error in status.
+func (d *Daemon) setStatusCommandListError(cmdListError string) {
+	if d.status.CommandListError == cmdListError {
+		// command list error not changed
+		return
+	}
+
+	// command list error changed
+	log.WithField("CommandListError", cmdListError).Info("Daemon changed CommandListError status")
+	d.status.CommandListError = cmdListError
+	d.dbus.SetProperty(dbusapi.PropertyCommandListError, cmdListError)
+}
+
 // cleanup cleans up after a failed shutdown.
 func (d *Daemon) cleanup(ctx context.Context) {
-	ocrunner.CleanupConnect(d.config.OpenConnect)
-	vpnsetup.Cleanup(ctx, d.config)
+	// keep the openconnect process and the journal entry of an adopted
+	// VPN tunnel
+	var keep []string
+	if d.adopted == nil {
+		ocrunner.CleanupConnect(d.config.OpenConnect)
+	} else {
+		keep = append(keep, vpnsetup.JournalName(""))
+	}
+
+	// undo exactly the changes left behind in the journal first,
+	// then clean up with the current config
+	d.recovered = journal.Recover(ctx, d.config.CommandLists.JournalDir, keep...)
+	if len(d.recovered) > 0 {
+		var undone, failed []string
+		for _, e := range d.recovered {
+			if e.Failed() {
+				failed = append(failed, e.Name)
+				continue
+			}
+			undone = append(undone, e.Name)
+		}
+		log.WithFields(log.Fields{
+			"undone": undone,
+			"failed": failed,
+		}).Warn("Daemon recovered changes from journal")
+	}
+	if d.adopted == nil {
+		vpnsetup.Cleanup(ctx, d.config)
+	} else {
+		// only reset the configuration of the adopted VPN tunnel, it
+		// is set up again when the VPN tunnel is adopted
+		config := d.config.Copy()
+		config.VPNConfig = daemoncfg.GetVPNConfig(d.adopted.VPNConfig)
+		vpnsetup.Reset(ctx, config)
+	}
+	for _, name := range d.config.Connections.Names() {
+		config := d.config.ConnectionConfig(name)
+		ocrunner.CleanupConnect(config.OpenConnect)
+		vpnsetup.Cleanup(ctx, config)
+	}
 	trafpol.Cleanup(ctx, d.config)
+	if d.config.NetNS.Enabled && d.adopted == nil {
+		vpnsetup.TeardownNetNS(ctx, d.config)
+	}
 }
 
 // initToken creates the daemon token for client authentication.
@@ -791,8 +1733,8 @@ var tndNewDetector = func(config *tnd.Config) tnd.TND {
 	return tnd.NewDetector(config)
 }
 
-// startTND starts TND if it's not running.
-func (d *Daemon) startTND() error {
+// startHTTPSTND starts the HTTPS-based TND if it's not running.
+func (d *Daemon) startHTTPSTND() error {
 	if d.tnd != nil {
 		return nil
 	}
@@ -816,27 +1758,106 @@ func (d *Daemon) startTND() error {
 	return nil
 }
 
-// stopTND stops TND if it's running.
-func (d *Daemon) stopTND() {
+// stopHTTPSTND stops the HTTPS-based TND if it's running.
+func (d *Daemon) stopHTTPSTND() {
 	if d.tnd == nil {
 		return
 	}
 	log.Info("Daemon stopping TND")
 	d.tnd.Stop()
 	d.tnd = nil
+	d.tndResults.https = false
 
 	// update tnd status
-	d.setStatusTNDState(vpnstatus.TNDStateInactive)
+	if d.dnstnd == nil {
+		d.setStatusTNDState(vpnstatus.TNDStateInactive)
+		d.setStatusTNDMatches(nil)
+	}
 	d.setStatusTNDServers(nil)
 }
 
-// checkTND checks if TND should be running and starts or stops it.
+// dnstndNewDetector is dnstnd.NewDetector for testing.
+var dnstndNewDetector = func(config *dnsmon.Config) dnstnd.TND {
+	return dnstnd.NewDetector(config)
+}
+
+// getVPNDNSDomains returns the DNS domains of the VPN connection.
+func (d *Daemon) getVPNDNSDomains() []string {
+	if d.status.VPNConfig == nil {
+		return nil
+	}
+	return strings.Fields(d.status.VPNConfig.DNS.DefaultDomain)
+}
+
+// updateDNSTNDExcludedDomains excludes the DNS domains of the VPN connection
+// in the DNS-based TND.
+func (d *Daemon) updateDNSTNDExcludedDomains() {
+	if d.dnstnd == nil {
+		return
+	}
+	d.dnstnd.SetExcludedDomains(d.getVPNDNSDomains())
+	d.dnstnd.Probe()
+}
+
+// startDNSTND starts the DNS-based TND if it's not running.
+func (d *Daemon) startDNSTND() error {
+	if d.dnstnd != nil {
+		return nil
+	}
+	log.Info("Daemon starting DNS-based TND")
+	dnsTND := dnstndNewDetector(dnsmon.NewConfig())
+	dnsTND.SetDomains(d.profile.GetTrustedDNSDomains())
+	dnsTND.SetServers(d.profile.GetTrustedDNSServers())
+	dnsTND.SetExcludedDomains(d.getVPNDNSDomains())
+	if err := dnsTND.Start(); err != nil {
+		return fmt.Errorf("Daemon could not start DNS-based TND: %w", err)
+	}
+	d.dnstnd = dnsTND
+
+	// update tnd status
+	d.setStatusTNDState(vpnstatus.TNDStateActive)
+
+	return nil
+}
+
+// stopDNSTND stops the DNS-based TND if it's running.
+func (d *Daemon) stopDNSTND() {
+	if d.dnstnd == nil {
+		return
+	}
+	log.Info("Daemon stopping DNS-based TND")
+	d.dnstnd.Stop()
+	d.dnstnd = nil
+	d.tndResults.dns = nil
+
+	// update tnd status
+	if d.tnd == nil {
+		d.setStatusTNDState(vpnstatus.TNDStateInactive)
+		d.setStatusTNDMatches(nil)
+	}
+}
+
+// stopTND stops the HTTPS-based and DNS-based TND if they are running.
+func (d *Daemon) stopTND() {
+	d.stopHTTPSTND()
+	d.stopDNSTND()
+}
+
+// checkTND checks if the HTTPS-based and DNS-based TND should be running and
+// starts or stops them.
 func (d *Daemon) checkTND() error {
 	if len(d.profile.GetTNDServers()) == 0 {
-		d.stopTND()
+		d.stopHTTPSTND()
+	} else if err := d.startHTTPSTND(); err != nil {
+		return err
+	}
+
+	if len(d.profile.GetTrustedDNSDomains()) == 0 &&
+		len(d.profile.GetTrustedDNSServers()) == 0 {
+		d.stopDNSTND()
 		return nil
 	}
-	return d.startTND()
+	return d.startDNSTND()
 }
 
 // getTNDResults returns the TND results channel.
@@ -848,6 +1869,14 @@ func (d *Daemon) getTNDResults() chan bool {
 	return d.tnd.Results()
 }
 
+// getDNSTNDResults returns the DNS-based TND results channel.
+func (d *Daemon) getDNSTNDResults() chan *dnstnd.Result {
+	if d.dnstnd == nil {
+		return nil
+	}
+	return d.dnstnd.Results()
+}
+
 // trafpolNewTrafPol is trafpol.NewTrafPol for testing.
 var trafpolNewTrafPol = func(c *daemoncfg.Config) trafpol.Policer {
 	return trafpol.NewTrafPol(c)
@@ -861,6 +1890,11 @@ func (d *Daemon) startTrafPol() error {
 	log.Info("Daemon starting TrafPol")
 	c := d.config.Copy()
 	c.TrafficPolicing.AllowedHosts = append(c.TrafficPolicing.AllowedHosts, d.getProfileAllowedHosts()...)
+	c.TrafficPolicing.PortalRemediation = c.TrafficPolicing.PortalRemediation &&
+		d.profile.GetAllowCaptivePortalRemediation()
+	if timeout := d.profile.GetCaptivePortalRemediationTimeout(); timeout > 0 {
+		c.TrafficPolicing.PortalRemediationTimeout = timeout
+	}
 	d.trafpol = trafpolNewTrafPol(c)
 	if err := d.trafpol.Start(); err != nil {
 		return fmt.Errorf("Daemon could not start TrafPol: %w", err)
@@ -902,6 +1936,8 @@ func (d *Daemon) stopTrafPol() {
 // checkTrafPol checks if traffic policing should be running and
 // starts or stops it.
 func (d *Daemon) checkTrafPol() error {
+	d.checkConnectFailurePolicy()
+
 	// check if traffic policing is disabled in the daemon
 	if d.disableTrafPol {
 		d.stopTrafPol()
@@ -914,6 +1950,16 @@ func (d *Daemon) checkTrafPol() error {
 		return nil
 	}
 
+	// check if traffic policing is relaxed by the connect failure policy
+	if d.status.ConnectFailureState == vpnstatus.ConnectFailureStateRelaxed {
+		if d.trafpol != nil {
+			log.WithField("failures", d.connfail.failures).
+				Warn("Daemon relaxing TrafPol after connection failures")
+		}
+		d.stopTrafPol()
+		return nil
+	}
+
 	// check if we are connected to a trusted network
 	if d.status.TrustedNetwork.Trusted() {
 		d.stopTrafPol()
@@ -923,26 +1969,81 @@ func (d *Daemon) checkTrafPol() error {
 	return d.startTrafPol()
 }
 
+// startMetrics starts the metrics server if it is enabled.
+func (d *Daemon) startMetrics() error {
+	if !d.config.Metrics.Enabled {
+		return nil
+	}
+
+	s := metrics.NewServer(d.config.Metrics)
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("Daemon could not start metrics server: %w", err)
+	}
+	d.metricsrv = s
+	return nil
+}
+
+// stopMetrics stops the metrics server.
+func (d *Daemon) stopMetrics() {
+	if d.metricsrv == nil {
+		return
+	}
+	d.metricsrv.Stop()
+	d.metricsrv = nil
+}
+
 // start starts the daemon.
 func (d *Daemon) start() {
 	defer close(d.closed)
+	defer d.hooks.stop()
+	defer d.reconnect.stop()
+	defer d.machineauth.stop()
 	defer d.sleepmon.Stop()
-	defer d.profmon.Stop()
+	// monitors and vpn setup may be replaced during config reloads
+	defer func() { d.profmon.Stop() }()
+	defer func() { d.cfgmon.Stop() }()
 	defer d.stopTrafPol()
 	defer d.stopTND()
-	defer d.vpnsetup.Stop()
+	defer d.stopMetrics()
+	defer d.teardownNetNS(context.Background())
+	defer func() { d.vpnsetup.Stop() }()
 	defer d.server.Stop()
 	defer d.runner.Stop()
-	defer d.handleRunnerDisconnect() // clean up vpn config
+	defer func() {
+		// clean up vpn config, unless the vpn tunnel keeps running
+		if !d.detached {
+			d.handleRunnerDisconnect()
+		}
+	}()
+	// named connections may be replaced during config reloads
+	defer func() { d.stopConnections() }()
+	defer func() {
+		// end session, unless the vpn tunnel keeps running
+		if !d.detached {
+			d.history.end(vpnhistory.DisconnectCauseShutdown, -1)
+		}
+	}()
+	defer d.detachVPN()
 	defer d.dbus.Stop()
 	defer d.server.Shutdown()
 
+	// ping systemd watchdog from main loop
+	var watchdog <-chan time.Time
+	if interval := sdnotifyWatchdogInterval(); interval > 0 {
+		log.WithField("interval", interval).Info("Daemon enabling systemd watchdog")
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		watchdog = ticker.C
+	}
+
 	// run main loop
 	log.Info("Daemon started")
 	for {
-		var cpdStatus <-chan bool
+		var cpdStatus <-chan *cpd.Report
+		var remediationExpired <-chan struct{}
 		if d.trafpol != nil {
 			cpdStatus = d.trafpol.CPDStatus()
+			remediationExpired = d.trafpol.RemediationExpired()
 		}
 
 		select {
@@ -959,8 +2060,38 @@ func (d *Daemon) start() {
 				return
 			}
 
+		case r := <-d.getDNSTNDResults():
+			if err := d.handleDNSTNDResult(r); err != nil {
+				// send error event and stop daemon
+				d.errors <- fmt.Errorf("Daemon could not handle DNS-based TND result: %w", err)
+				return
+			}
+
 		case e := <-d.runner.Events():
-			d.handleRunnerEvent(e)
+			if err := d.handleRunnerEvent(e); err != nil {
+				// send error event and stop daemon
+				d.errors <- fmt.Errorf("Daemon could not handle Runner event: %w", err)
+				return
+			}
+
+		case e := <-d.conns.eventsC():
+			if err := d.handleConnectionEvent(e); err != nil {
+				// send error event and stop daemon
+				d.errors <- fmt.Errorf("Daemon could not handle Runner event of named connection: %w", err)
+				return
+			}
+
+		case <-d.reconnect.timerC():
+			d.handleReconnectTimer()
+
+		case r := <-d.machineauth.resultsC():
+			d.handleMachineAuthResult(r)
+
+		case <-d.idle.tickerC():
+			d.handleIdleCheck()
+
+		case <-d.resume.timerC():
+			d.handleResumeTimer()
 
 		case e := <-d.sleepmon.Events():
 			d.handleSleepMonEvent(e)
@@ -975,6 +2106,29 @@ func (d *Daemon) start() {
 		case s := <-cpdStatus:
 			d.handleCPDStatusUpdate(s)
 
+		case <-remediationExpired:
+			d.handleRemediationExpired()
+
+		case f := <-cmdtmpl.Failures():
+			d.handleCommandFailure(f)
+
+		case <-d.cfgmon.Updates():
+			if err := d.handleConfigReload(); err != nil {
+				// send error event and stop daemon
+				d.errors <- fmt.Errorf("Daemon could not handle config reload: %w", err)
+				return
+			}
+
+		case <-d.reloads:
+			if err := d.handleConfigReload(); err != nil {
+				// send error event and stop daemon
+				d.errors <- fmt.Errorf("Daemon could not handle config reload: %w", err)
+				return
+			}
+
+		case <-watchdog:
+			notify(sdnotify.Watchdog)
+
 		case <-d.done:
 			log.Info("Daemon stopping")
 			return
@@ -987,9 +2141,17 @@ func (d *Daemon) Start() error {
 	// create context
 	ctx := context.Background()
 
+	// load VPN tunnel kept running during restart and
 	// cleanup after a failed shutdown
+	d.loadAdoption()
 	d.cleanup(ctx)
 
+	// load connection history
+	if err := d.history.load(); err != nil {
+		log.WithError(err).WithField("file", d.config.History.File).
+			Error("Daemon could not load connection history")
+	}
+
 	// init token
 	if err := d.initToken(); err != nil {
 		return fmt.Errorf("Daemon could not init token: %w", err)
@@ -1007,12 +2169,23 @@ func (d *Daemon) Start() error {
 		goto cleanup_profmon
 	}
 
-	// start VPN setup
+	// start config monitor
+	err = d.cfgmon.Start()
+	if err != nil {
+		err = fmt.Errorf("Daemon could not start ConfigMon: %w", err)
+		goto cleanup_cfgmon
+	}
+
+	// set up network namespace and start VPN setup
+	d.setupNetNS(ctx)
 	d.vpnsetup.Start()
 
 	// start OC runner
 	d.runner.Start()
 
+	// start runners of named VPN connections
+	d.conns.start()
+
 	// start unix server
 	err = d.server.Start()
 	if err != nil {
@@ -1027,14 +2200,23 @@ func (d *Daemon) Start() error {
 		goto cleanup_dbus
 	}
 
+	// start metrics server
+	err = d.startMetrics()
+	if err != nil {
+		goto cleanup_metrics
+	}
+
 	// set initial status
 	d.setStatusTrustedNetwork(false)
 	d.setStatusConnectionState(vpnstatus.ConnectionStateDisconnected)
 	d.setStatusServers(d.profile.GetVPNServerHostNames())
 	d.setStatusConnectedAt(0)
+	d.setStatusReconnectAt(0)
 	d.setStatusOCRunning(false)
 	d.setStatusTrafPolState(vpnstatus.TrafPolStateInactive)
 	d.setStatusTNDState(vpnstatus.TNDStateInactive)
+	d.setStatusConnections()
+	d.checkDisconnectPolicy()
 
 	// start traffic policing
 	err = d.checkTrafPol()
@@ -1048,6 +2230,16 @@ func (d *Daemon) Start() error {
 		goto cleanup_tnd
 	}
 
+	// adopt VPN tunnel kept running during restart
+	if d.adopted != nil {
+		d.adoptVPN()
+	}
+
+	// apply untrusted network policy without TND, e.g., connect on boot
+	if d.tnd == nil && d.dnstnd == nil {
+		d.applyNetworkPolicy()
+	}
+
 	go d.start()
 	return nil
 
@@ -1055,13 +2247,19 @@ func (d *Daemon) Start() error {
 cleanup_tnd:
 	d.stopTrafPol()
 cleanup_trafpol:
+	d.stopMetrics()
+cleanup_metrics:
 	d.dbus.Stop()
 	d.server.Stop()
 cleanup_dbus:
 	d.server.Stop()
 cleanup_unix:
+	d.conns.stop()
 	d.runner.Stop()
 	d.vpnsetup.Stop()
+	d.teardownNetNS(ctx)
+	d.cfgmon.Stop()
+cleanup_cfgmon:
 	d.profmon.Stop()
 cleanup_profmon:
 	d.sleepmon.Stop()
@@ -1076,6 +2274,14 @@ func (d *Daemon) Stop() {
 	<-d.closed
 }
 
+// Reload reloads the daemon config.
+func (d *Daemon) Reload() {
+	select {
+	case d.reloads <- struct{}{}:
+	case <-d.closed:
+	}
+}
+
 // Errors returns the error channel of the daemon.
 func (d *Daemon) Errors() chan error {
 	return d.errors
@@ -1087,13 +2293,24 @@ func NewDaemon(config *daemoncfg.Config) *Daemon {
 		config: config,
 
 		server: api.NewServer(config.SocketServer),
-		dbus:   dbusapi.NewService(),
+		dbus:   dbusapi.NewService(config.Polkit),
 
 		sleepmon: sleepmon.NewSleepMon(),
 
-		vpnsetup: vpnsetup.NewVPNSetup(dnsproxy.NewProxy(config.DNSProxy)),
+		vpnsetup: vpnsetup.NewVPNSetup(newDNSProxy(config)),
 
 		runner: ocrunner.NewConnect(),
+		conns:  newConnections(config),
+
+		reconnect: newReconnect(config.Reconnect),
+		history:   newHistory(config.History),
+		idle:      newIdle(config.IdleTimeout),
+		resume:    newResume(config.Resume),
+		connfail:  newConnectFailure(config.ConnectFailure),
+		netpolicy: newNetPolicy(),
+		hooks:     newHooks(),
+
+		machineauth: newMachineAuth(config.MachineAuth),
 
 		status: vpnstatus.New(),
 
@@ -1104,5 +2321,8 @@ func NewDaemon(config *daemoncfg.Config) *Daemon {
 
 		profile: readXMLProfile(config.OpenConnect.XMLProfile),
 		profmon: profilemon.NewProfileMon(config.OpenConnect.XMLProfile),
+
+		cfgmon:  configmon.NewConfigMon(getConfigMonFiles(config)...),
+		reloads: make(chan struct{}),
 	}
 }
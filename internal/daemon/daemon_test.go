@@ -1,22 +1,34 @@
 package daemon
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"net"
 	"net/netip"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/telekom-mms/oc-daemon/internal/api"
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/cpd"
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
 	"github.com/telekom-mms/oc-daemon/internal/dbusapi"
+	"github.com/telekom-mms/oc-daemon/internal/dnsmon"
+	"github.com/telekom-mms/oc-daemon/internal/dnstnd"
+	"github.com/telekom-mms/oc-daemon/internal/journal"
+	"github.com/telekom-mms/oc-daemon/internal/metrics"
 	"github.com/telekom-mms/oc-daemon/internal/ocrunner"
+	"github.com/telekom-mms/oc-daemon/internal/sdnotify"
 	"github.com/telekom-mms/oc-daemon/internal/trafpol"
 	"github.com/telekom-mms/oc-daemon/internal/vpnsetup"
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
 	"github.com/telekom-mms/oc-daemon/pkg/vpnconfig"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnhistory"
 	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
 	"github.com/telekom-mms/oc-daemon/pkg/xmlprofile"
 	"github.com/telekom-mms/tnd/pkg/tnd"
@@ -32,13 +44,22 @@ func (s *socketServer) Start() error                { return nil }
 func (s *socketServer) Stop()                       {}
 
 // dbusService is a D-Bus API service for testing.
-type dbusService struct{ r chan *dbusapi.Request }
+type dbusService struct {
+	r chan *dbusapi.Request
+
+	// signals are the emitted signals and their values
+	signals [][]any
+}
 
 func (d *dbusService) Requests() chan *dbusapi.Request { return d.r }
 func (d *dbusService) SetProperty(string, any)         {}
 func (d *dbusService) Start() error                    { return nil }
 func (d *dbusService) Stop()                           {}
 
+func (d *dbusService) EmitSignal(name string, values ...any) {
+	d.signals = append(d.signals, append([]any{name}, values...))
+}
+
 // vpnSetup is VPN Setup for testing.
 type vpnSetup struct{}
 
@@ -49,14 +70,37 @@ func (v *vpnSetup) Stop()                      {}
 func (v *vpnSetup) Teardown(*daemoncfg.Config) {}
 
 // trafPolicer is TrafPol for testing.
-type trafPolicer struct{ s chan bool }
+type trafPolicer struct {
+	s chan *cpd.Report
+	r bool
+}
+
+func (t *trafPolicer) AddAllowedAddr(netip.Addr) bool      { return false }
+func (t *trafPolicer) CPDStatus() <-chan *cpd.Report       { return t.s }
+func (t *trafPolicer) GetState() *trafpol.State            { return nil }
+func (t *trafPolicer) ProbeCPD()                           {}
+func (t *trafPolicer) Remediate() bool                     { return t.r }
+func (t *trafPolicer) RemediationExpired() <-chan struct{} { return nil }
+func (t *trafPolicer) RemoveAllowedAddr(netip.Addr) bool   { return false }
+func (t *trafPolicer) Start() error                        { return nil }
+func (t *trafPolicer) Stop()                               {}
+
+// dnsTND is DNS-based TND for testing.
+type dnsTND struct {
+	domains  []string
+	servers  []string
+	excluded []string
+	probes   int
+	r        chan *dnstnd.Result
+}
 
-func (t *trafPolicer) AddAllowedAddr(netip.Addr) bool    { return false }
-func (t *trafPolicer) CPDStatus() <-chan bool            { return t.s }
-func (t *trafPolicer) GetState() *trafpol.State          { return nil }
-func (t *trafPolicer) RemoveAllowedAddr(netip.Addr) bool { return false }
-func (t *trafPolicer) Start() error                      { return nil }
-func (t *trafPolicer) Stop()                             {}
+func (d *dnsTND) SetDomains(domains []string)         { d.domains = domains }
+func (d *dnsTND) SetServers(servers []string)         { d.servers = servers }
+func (d *dnsTND) SetExcludedDomains(domains []string) { d.excluded = domains }
+func (d *dnsTND) Start() error                        { return nil }
+func (d *dnsTND) Stop()                               {}
+func (d *dnsTND) Probe()                              { d.probes++ }
+func (d *dnsTND) Results() chan *dnstnd.Result        { return d.r }
 
 // sleepMonitor is SleepMon for testing.
 type sleepMonitor struct{ e chan bool }
@@ -66,13 +110,22 @@ func (s *sleepMonitor) Start() error      { return nil }
 func (s *sleepMonitor) Stop()             {}
 
 // ocRunner is OC-Runner for testing.
-type ocRunner struct{ e chan *ocrunner.ConnectEvent }
+type ocRunner struct {
+	e           chan *ocrunner.ConnectEvent
+	adopted     uint32
+	detached    bool
+	disconnects int
+	keptSession int
+}
 
-func (o *ocRunner) Connect(*daemoncfg.Config, []string) {}
-func (o *ocRunner) Disconnect()                         {}
-func (o *ocRunner) Events() chan *ocrunner.ConnectEvent { return o.e }
-func (o *ocRunner) Start()                              {}
-func (o *ocRunner) Stop()                               {}
+func (o *ocRunner) Connect(*daemoncfg.Config, []string)   {}
+func (o *ocRunner) Adopt(_ *daemoncfg.Config, pid uint32) { o.adopted = pid }
+func (o *ocRunner) Detach()                               { o.detached = true }
+func (o *ocRunner) Disconnect()                           { o.disconnects++ }
+func (o *ocRunner) DisconnectKeepSession()                { o.keptSession++ }
+func (o *ocRunner) Events() chan *ocrunner.ConnectEvent   { return o.e }
+func (o *ocRunner) Start()                                {}
+func (o *ocRunner) Stop()                                 {}
 
 // profMonitor is Profile monitor for testing.
 type profMonitor struct{ u chan struct{} }
@@ -81,10 +134,34 @@ func (p *profMonitor) Start() error           { return nil }
 func (p *profMonitor) Stop()                  {}
 func (p *profMonitor) Updates() chan struct{} { return p.u }
 
+// cfgMonitor is Config monitor for testing.
+type cfgMonitor struct{ u chan struct{} }
+
+func (c *cfgMonitor) Start() error           { return nil }
+func (c *cfgMonitor) Stop()                  {}
+func (c *cfgMonitor) Updates() chan struct{} { return c.u }
+
+// testHistoryDir is the directory for connection history files in tests.
+var testHistoryDir string
+
+// TestMain creates and removes the directory for connection history files.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "oc-daemon-test")
+	if err != nil {
+		panic(err)
+	}
+	testHistoryDir = dir
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 // getTestDaemon returns a Daemon for testing.
 func getTestDaemon() *Daemon {
+	config := daemoncfg.NewConfig()
+	config.History.File = filepath.Join(testHistoryDir, "history.json")
 	return &Daemon{
-		config:  daemoncfg.NewConfig(),
+		config:  config,
 		status:  vpnstatus.New(),
 		errors:  make(chan error, 1),
 		done:    make(chan struct{}),
@@ -95,10 +172,23 @@ func getTestDaemon() *Daemon {
 		dbus:     &dbusService{r: make(chan *dbusapi.Request)},
 		tnd:      tndtest.NewDetector(),
 		vpnsetup: &vpnSetup{},
-		trafpol:  &trafPolicer{s: make(chan bool)},
+		trafpol:  &trafPolicer{s: make(chan *cpd.Report)},
 		sleepmon: &sleepMonitor{e: make(chan bool)},
 		runner:   &ocRunner{e: make(chan *ocrunner.ConnectEvent)},
+		conns:    newConnections(config),
 		profmon:  &profMonitor{u: make(chan struct{})},
+		cfgmon:   &cfgMonitor{u: make(chan struct{})},
+		reloads:  make(chan struct{}),
+
+		reconnect: newReconnect(config.Reconnect),
+		history:   newHistory(config.History),
+		idle:      newIdle(config.IdleTimeout),
+		resume:    newResume(config.Resume),
+		connfail:  newConnectFailure(config.ConnectFailure),
+		netpolicy: newNetPolicy(),
+		hooks:     newHooks(),
+
+		machineauth: newMachineAuth(config.MachineAuth),
 	}
 }
 
@@ -143,6 +233,9 @@ func TestDaemonSetStatusConnectionState(t *testing.T) {
 		if got != want {
 			t.Errorf("%d: got %d, want %d", i, got, want)
 		}
+		if m := metrics.ConnectionState.Value(); m != int64(want) {
+			t.Errorf("%d: got metric %d, want %d", i, m, want)
+		}
 	}
 }
 
@@ -402,6 +495,216 @@ func TestDaemonSetStatusVPNConfig(t *testing.T) {
 	}
 }
 
+// TestDaemonSetStatusReconnectAttempts tests setStatusReconnectAttempts of Daemon.
+func TestDaemonSetStatusReconnectAttempts(t *testing.T) {
+	d := getTestDaemon()
+	for i, want := range []uint32{
+		1,
+		1,
+		0,
+		0,
+	} {
+		d.setStatusReconnectAttempts(want)
+		got := d.status.ReconnectAttempts
+		if got != want {
+			t.Errorf("%d: got %d, want %d", i, got, want)
+		}
+	}
+}
+
+// TestDaemonSetStatusReconnectAt tests setStatusReconnectAt of Daemon.
+func TestDaemonSetStatusReconnectAt(t *testing.T) {
+	d := getTestDaemon()
+	for i, want := range []int64{
+		1753274054,
+		1753274054,
+		0,
+		0,
+	} {
+		d.setStatusReconnectAt(want)
+		got := d.status.ReconnectAt
+		if got != want {
+			t.Errorf("%d: got %d, want %d", i, got, want)
+		}
+	}
+}
+
+// TestDaemonSetStatusReconnectGiveUpReason tests setStatusReconnectGiveUpReason of Daemon.
+func TestDaemonSetStatusReconnectGiveUpReason(t *testing.T) {
+	d := getTestDaemon()
+	for i, want := range []string{
+		"reason",
+		"reason",
+		"",
+		"",
+	} {
+		d.setStatusReconnectGiveUpReason(want)
+		got := d.status.ReconnectGiveUpReason
+		if got != want {
+			t.Errorf("%d: got %s, want %s", i, got, want)
+		}
+	}
+}
+
+// TestDaemonSetStatusCommandListError tests setStatusCommandListError of Daemon.
+func TestDaemonSetStatusCommandListError(t *testing.T) {
+	d := getTestDaemon()
+	for i, want := range []string{
+		"error",
+		"error",
+		"",
+		"",
+	} {
+		d.setStatusCommandListError(want)
+		got := d.status.CommandListError
+		if got != want {
+			t.Errorf("%d: got %s, want %s", i, got, want)
+		}
+	}
+}
+
+// TestDaemonSetStatusIdleTimeoutAt tests setStatusIdleTimeoutAt of Daemon.
+func TestDaemonSetStatusIdleTimeoutAt(t *testing.T) {
+	d := getTestDaemon()
+	for i, want := range []int64{
+		1700000000,
+		1700000000,
+		0,
+		0,
+	} {
+		d.setStatusIdleTimeoutAt(want)
+		got := d.status.IdleTimeoutAt
+		if got != want {
+			t.Errorf("%d: got %d, want %d", i, got, want)
+		}
+	}
+}
+
+// TestDaemonSetStatusDisconnectPolicy tests setStatusDisconnectPolicy of Daemon.
+func TestDaemonSetStatusDisconnectPolicy(t *testing.T) {
+	d := getTestDaemon()
+	for i, want := range []vpnstatus.DisconnectPolicy{
+		vpnstatus.DisconnectPolicyLocked,
+		vpnstatus.DisconnectPolicyLocked,
+		vpnstatus.DisconnectPolicyAllowed,
+		vpnstatus.DisconnectPolicyAllowed,
+	} {
+		d.setStatusDisconnectPolicy(want)
+		got := d.status.DisconnectPolicy
+		if got != want {
+			t.Errorf("%d: got %s, want %s", i, got, want)
+		}
+	}
+}
+
+// TestDaemonCheckDisconnectPolicy tests checkDisconnectPolicy of Daemon.
+func TestDaemonCheckDisconnectPolicy(t *testing.T) {
+	for i, test := range []struct {
+		alwaysOn       bool
+		allow          string
+		disableTrafPol bool
+		want           vpnstatus.DisconnectPolicy
+	}{
+		{false, "", false, vpnstatus.DisconnectPolicyAllowed},
+		{false, "false", false, vpnstatus.DisconnectPolicyAllowed},
+		{true, "", false, vpnstatus.DisconnectPolicyAllowed},
+		{true, "true", false, vpnstatus.DisconnectPolicyAllowed},
+		{true, "false", true, vpnstatus.DisconnectPolicyAllowed},
+		{true, "false", false, vpnstatus.DisconnectPolicyLocked},
+	} {
+		d := getTestDaemon()
+		d.profile.AutomaticVPNPolicy.AlwaysOn.Flag = test.alwaysOn
+		d.profile.AutomaticVPNPolicy.AlwaysOn.AllowVPNDisconnect = test.allow
+		d.disableTrafPol = test.disableTrafPol
+
+		d.checkDisconnectPolicy()
+		got := d.status.DisconnectPolicy
+		if got != test.want {
+			t.Errorf("%d: got %s, want %s", i, got, test.want)
+		}
+	}
+}
+
+// TestDaemonStartIdle tests startIdle of Daemon.
+func TestDaemonStartIdle(t *testing.T) {
+	oldGetPackets := idleGetPackets
+	defer func() { idleGetPackets = oldGetPackets }()
+	var got []string
+	idleGetPackets = func(name, device string) (uint64, error) {
+		got = append(got, name+"/"+device)
+		return 0, nil
+	}
+
+	config := vpnconfig.New()
+	config.Device.Name = "tun0"
+	config.Timeout = 60
+
+	// device in host network namespace
+	d := getTestDaemon()
+	d.startIdle(config)
+	d.stopIdle()
+
+	// device in network namespace
+	d.config.NetNS.Enabled = true
+	d.startIdle(config)
+	d.stopIdle()
+
+	want := []string{"/tun0", d.config.NetNS.Name + "/tun0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestDaemonHandleIdleCheck tests handleIdleCheck of Daemon.
+func TestDaemonHandleIdleCheck(t *testing.T) {
+	oldGetPackets := idleGetPackets
+	defer func() { idleGetPackets = oldGetPackets }()
+	idleGetPackets = func(string, string) (uint64, error) { return 0, nil }
+
+	// not active
+	d := getTestDaemon()
+	d.handleIdleCheck()
+	if d.dbus.(*dbusService).signals != nil {
+		t.Error("inactive idle monitor should not emit signals")
+	}
+
+	// active
+	d.idle.start("", "tun0", time.Hour)
+	d.handleIdleCheck()
+	if d.status.IdleTimeoutAt != 0 || d.dbus.(*dbusService).signals != nil {
+		t.Error("active tunnel should not be idle")
+	}
+
+	// warning, with warn action
+	d.config.IdleTimeout.Action = daemoncfg.IdleTimeoutActionWarn
+	d.idle.lastActivity = time.Now().Add(-time.Hour)
+	d.handleIdleCheck()
+	d.handleIdleCheck()
+	want := [][]any{{dbusapi.SignalIdleTimeoutWarning, d.idle.deadline().Unix()}}
+	if got := d.dbus.(*dbusService).signals; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !d.idle.active() || d.status.IdleTimeoutAt != d.idle.deadline().Unix() {
+		t.Error("idle monitor should warn and stay active")
+	}
+
+	// timeout, with disconnect action
+	d.config.IdleTimeout.Action = daemoncfg.IdleTimeoutActionDisconnect
+	d.setStatusOCRunning(true)
+	d.handleIdleCheck()
+	if d.idle.active() ||
+		d.disconnectCause != vpnhistory.DisconnectCauseIdleTimeout ||
+		d.status.ConnectionState != vpnstatus.ConnectionStateDisconnecting {
+		t.Error("idle timeout should disconnect VPN")
+	}
+
+	// stop
+	d.stopIdle()
+	if d.status.IdleTimeoutAt != 0 {
+		t.Error("stopped idle monitor should reset idle timeout at")
+	}
+}
+
 // TestDaemonCheck tests checkTND of Daemon.
 func TestDaemonCheckTND(t *testing.T) {
 	oldTndNewDetector := tndNewDetector
@@ -468,13 +771,315 @@ func TestDaemonCheckTND(t *testing.T) {
 	}
 }
 
+// TestDaemonCheckTNDDNS tests checkTND of Daemon with the DNS-based TND.
+func TestDaemonCheckTNDDNS(t *testing.T) {
+	oldDNSTNDNewDetector := dnstndNewDetector
+	defer func() { dnstndNewDetector = oldDNSTNDNewDetector }()
+	var detector *dnsTND
+	dnstndNewDetector = func(*dnsmon.Config) dnstnd.TND {
+		detector = &dnsTND{}
+		return detector
+	}
+
+	// start with trusted dns domains and servers, vpn connected
+	d := getTestDaemon()
+	d.tnd = nil
+	d.status.VPNConfig = vpnconfig.New()
+	d.status.VPNConfig.DNS.DefaultDomain = "vpn.mycompany.com"
+	d.profile.AutomaticVPNPolicy.TrustedDNSDomains = []string{"mycompany.com"}
+	d.profile.AutomaticVPNPolicy.TrustedDNSServers = []string{"10.1.*.*"}
+	if err := d.checkTND(); err != nil {
+		t.Fatal(err)
+	}
+	if d.dnstnd == nil || d.status.TNDState != vpnstatus.TNDStateActive {
+		t.Fatal("DNS-based TND should be running")
+	}
+	if !reflect.DeepEqual(detector.domains, []string{"mycompany.com"}) ||
+		!reflect.DeepEqual(detector.servers, []string{"10.1.*.*"}) ||
+		!reflect.DeepEqual(detector.excluded, []string{"vpn.mycompany.com"}) {
+		t.Errorf("got invalid settings %v", detector)
+	}
+
+	// check again, keep running
+	old := d.dnstnd
+	if err := d.checkTND(); err != nil {
+		t.Fatal(err)
+	}
+	if d.dnstnd != old {
+		t.Error("DNS-based TND should not be restarted")
+	}
+
+	// vpn disconnected, update excluded domains
+	d.status.VPNConfig = nil
+	d.updateDNSTNDExcludedDomains()
+	if detector.excluded != nil || detector.probes != 1 {
+		t.Errorf("got excluded %v and %d probes, want nil and 1",
+			detector.excluded, detector.probes)
+	}
+
+	// remove trusted dns domains and servers, stop
+	d.profile.AutomaticVPNPolicy.TrustedDNSDomains = nil
+	d.profile.AutomaticVPNPolicy.TrustedDNSServers = nil
+	d.status.TNDMatches = []string{"DNS domain: mycompany.com"}
+	if err := d.checkTND(); err != nil {
+		t.Fatal(err)
+	}
+	if d.dnstnd != nil || d.status.TNDState != vpnstatus.TNDStateInactive {
+		t.Error("DNS-based TND should not be running")
+	}
+	if d.status.TNDMatches != nil {
+		t.Errorf("got %v, want nil", d.status.TNDMatches)
+	}
+}
+
+// TestDaemonHandleDNSTNDResult tests handleDNSTNDResult of Daemon.
+func TestDaemonHandleDNSTNDResult(t *testing.T) {
+	// dns-based tnd only, trusted network
+	d := getTestDaemon()
+	d.tnd = nil
+	d.dnstnd = &dnsTND{}
+	d.status.OCRunning = vpnstatus.OCRunningRunning
+	if err := d.handleDNSTNDResult(&dnstnd.Result{
+		Trusted: true,
+		Domain:  "mycompany.com",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !d.status.TrustedNetwork.Trusted() {
+		t.Error("network should be trusted")
+	}
+	want := []string{"DNS domain: mycompany.com"}
+	if !reflect.DeepEqual(d.status.TNDMatches, want) {
+		t.Errorf("got %v, want %v", d.status.TNDMatches, want)
+	}
+	if d.status.ConnectionState != vpnstatus.ConnectionStateDisconnecting {
+		t.Error("vpn should be disconnected in trusted network")
+	}
+
+	// https-based and dns-based tnd, only dns matches
+	d = getTestDaemon()
+	d.dnstnd = &dnsTND{}
+	if err := d.handleDNSTNDResult(&dnstnd.Result{
+		Trusted: true,
+		Domain:  "mycompany.com",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if d.status.TrustedNetwork.Trusted() {
+		t.Error("network should not be trusted")
+	}
+
+	// https matches as well
+	if err := d.handleTNDResult(true); err != nil {
+		t.Fatal(err)
+	}
+	if !d.status.TrustedNetwork.Trusted() {
+		t.Error("network should be trusted")
+	}
+	want = []string{"DNS domain: mycompany.com", "HTTPS server"}
+	if !reflect.DeepEqual(d.status.TNDMatches, want) {
+		t.Errorf("got %v, want %v", d.status.TNDMatches, want)
+	}
+}
+
+// TestDaemonApplyNetworkPolicy tests applyNetworkPolicy of Daemon.
+func TestDaemonApplyNetworkPolicy(t *testing.T) {
+	login := &logininfo.LoginInfo{
+		Server:      "server",
+		Cookie:      "cookie",
+		Host:        "10.0.0.1",
+		Fingerprint: "fingerprint",
+	}
+
+	// trusted network, default policy, disconnect
+	d := getTestDaemon()
+	d.status.OCRunning = vpnstatus.OCRunningRunning
+	d.netpolicy.connected(login)
+	if err := d.handleTNDResult(true); err != nil {
+		t.Fatal(err)
+	}
+	if d.status.ConnectionState != vpnstatus.ConnectionStateDisconnecting ||
+		d.status.NetworkPolicyAction != xmlprofile.NetworkPolicyDisconnect ||
+		d.status.NetworkPolicyDecision != netPolicyDecisionDisconnected {
+		t.Errorf("got invalid status %v", d.status)
+	}
+	if d.netpolicy.paused {
+		t.Error("vpn should not be paused")
+	}
+	if r := d.runner.(*ocRunner); r.disconnects != 1 || r.keptSession != 0 {
+		t.Errorf("session should be logged off, got %d, %d",
+			r.disconnects, r.keptSession)
+	}
+	if d.netpolicy.getLogin() != nil {
+		t.Error("stored login should be cleared after logging off")
+	}
+
+	// trusted network, do nothing
+	d = getTestDaemon()
+	d.profile.AutomaticVPNPolicy.TrustedNetworkPolicy = "DoNothing"
+	d.status.OCRunning = vpnstatus.OCRunningRunning
+	if err := d.handleTNDResult(true); err != nil {
+		t.Fatal(err)
+	}
+	if d.status.ConnectionState == vpnstatus.ConnectionStateDisconnecting ||
+		d.status.NetworkPolicyDecision != netPolicyDecisionNone {
+		t.Errorf("got invalid status %v", d.status)
+	}
+
+	// trusted network, pause, then resume in untrusted network
+	d = getTestDaemon()
+	d.profile.AutomaticVPNPolicy.TrustedNetworkPolicy = "Pause"
+	d.status.OCRunning = vpnstatus.OCRunningRunning
+	d.netpolicy.connected(login)
+	if err := d.handleTNDResult(true); err != nil {
+		t.Fatal(err)
+	}
+	if !d.netpolicy.paused ||
+		d.status.NetworkPolicyDecision != netPolicyDecisionPaused {
+		t.Errorf("vpn should be paused, got %v", d.status)
+	}
+	if r := d.runner.(*ocRunner); r.disconnects != 0 || r.keptSession != 1 {
+		t.Errorf("session should be kept, got %d, %d",
+			r.disconnects, r.keptSession)
+	}
+	if err := d.handleRunnerEvent(&ocrunner.ConnectEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.handleTNDResult(false); err != nil {
+		t.Fatal(err)
+	}
+	if d.netpolicy.paused || !d.status.OCRunning.Running() ||
+		d.status.NetworkPolicyAction != xmlprofile.NetworkPolicyDoNothing ||
+		d.status.NetworkPolicyDecision != netPolicyDecisionResumed {
+		t.Errorf("vpn should be resumed, got %v", d.status)
+	}
+
+	// untrusted network, connect without login
+	d = getTestDaemon()
+	d.profile.AutomaticVPNPolicy.UntrustedNetworkPolicy = "Connect"
+	if err := d.handleTNDResult(false); err != nil {
+		t.Fatal(err)
+	}
+	if d.status.OCRunning.Running() ||
+		d.status.NetworkPolicyDecision != netPolicyDecisionNoLogin {
+		t.Errorf("vpn should not be connected, got %v", d.status)
+	}
+
+	// untrusted network, connect with stored login
+	d.netpolicy.connected(login)
+	if err := d.handleTNDResult(false); err != nil {
+		t.Fatal(err)
+	}
+	if !d.status.OCRunning.Running() ||
+		d.status.NetworkPolicyDecision != netPolicyDecisionConnecting {
+		t.Errorf("vpn should be connected, got %v", d.status)
+	}
+
+	// connection failed, stored login cleared
+	if err := d.handleRunnerEvent(&ocrunner.ConnectEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	if d.netpolicy.getLogin() != nil {
+		t.Error("stored login should be cleared after connection failure")
+	}
+
+	// user disconnect clears stored login
+	d = getTestDaemon()
+	d.netpolicy.connected(login)
+	if err := d.userDisconnectVPN(); err != nil {
+		t.Fatal(err)
+	}
+	if d.netpolicy.getLogin() != nil {
+		t.Error("stored login should be cleared after user disconnect")
+	}
+}
+
+// TestDaemonMachineAuth tests connecting with machine authentication of
+// Daemon.
+func TestDaemonMachineAuth(t *testing.T) {
+	oldMachineAuthenticate := machineAuthenticate
+	defer func() { machineAuthenticate = oldMachineAuthenticate }()
+
+	login := &logininfo.LoginInfo{
+		Server:      "server",
+		Cookie:      "cookie",
+		Host:        "10.0.0.1",
+		Fingerprint: "fingerprint",
+	}
+	authErr := errors.New("test error")
+	var servers []string
+	machineAuthenticate = func(_ context.Context, _ *daemoncfg.Config, server string) (*logininfo.LoginInfo, error) {
+		servers = append(servers, server)
+		if server == "fail" {
+			return nil, authErr
+		}
+		return login, nil
+	}
+
+	// machine authentication disabled
+	d := getTestDaemon()
+	d.profile.AutomaticVPNPolicy.UntrustedNetworkPolicy = "Connect"
+	if err := d.handleTNDResult(false); err != nil {
+		t.Fatal(err)
+	}
+	if d.machineauth.active() ||
+		d.status.NetworkPolicyDecision != netPolicyDecisionNoLogin {
+		t.Errorf("machine authentication should not be active, got %v", d.status)
+	}
+
+	// no vpn server
+	d.config.MachineAuth.Enabled = true
+	if err := d.handleTNDResult(false); err != nil {
+		t.Fatal(err)
+	}
+	if d.machineauth.active() {
+		t.Error("machine authentication should not be active without server")
+	}
+
+	// vpn server in xml profile, connect
+	d.profile.ServerList.HostEntry = []xmlprofile.HostEntry{{HostName: "profile server"}}
+	if err := d.handleTNDResult(false); err != nil {
+		t.Fatal(err)
+	}
+	if !d.machineauth.active() ||
+		d.status.NetworkPolicyDecision != netPolicyDecisionMachineAuth {
+		t.Fatalf("machine authentication should be active, got %v", d.status)
+	}
+	d.handleMachineAuthResult(<-d.machineauth.resultsC())
+	if d.machineauth.active() || !d.status.OCRunning.Running() ||
+		d.status.NetworkPolicyDecision != netPolicyDecisionAuthConnect {
+		t.Errorf("vpn should be connected, got %v", d.status)
+	}
+	if !reflect.DeepEqual(servers, []string{"profile server"}) {
+		t.Errorf("got %v, want [profile server]", servers)
+	}
+
+	// configured vpn server, authentication fails
+	d = getTestDaemon()
+	d.profile.AutomaticVPNPolicy.UntrustedNetworkPolicy = "Connect"
+	d.config.MachineAuth.Enabled = true
+	d.config.MachineAuth.VPNServer = "fail"
+	if err := d.handleTNDResult(false); err != nil {
+		t.Fatal(err)
+	}
+	d.handleMachineAuthResult(<-d.machineauth.resultsC())
+	if d.status.OCRunning.Running() ||
+		d.status.NetworkPolicyDecision != netPolicyDecisionAuthFailed {
+		t.Errorf("vpn should not be connected, got %v", d.status)
+	}
+	if got := d.dbus.(*dbusService).signals; len(got) != 1 ||
+		got[0][0] != dbusapi.SignalConnectFailed {
+		t.Errorf("got %v, want connect failed signal", got)
+	}
+}
+
 // TestDaemonCheckTrafPol tests checkTrafPol of Daemon.
 func TestDaemonCheckTrafPol(t *testing.T) {
 	// cleanup after tests
 	oldTrafPolNewTafPol := trafpolNewTrafPol
 	defer func() { trafpolNewTrafPol = oldTrafPolNewTafPol }()
 	trafpolNewTrafPol = func(*daemoncfg.Config) trafpol.Policer {
-		return &trafPolicer{s: make(chan bool)}
+		return &trafPolicer{s: make(chan *cpd.Report)}
 	}
 
 	for i, test := range []struct {
@@ -498,7 +1103,7 @@ func TestDaemonCheckTrafPol(t *testing.T) {
 		},
 		// check with TrafPol disabled and running TrafPol, stop
 		{
-			trafpol:              &trafPolicer{s: make(chan bool)},
+			trafpol:              &trafPolicer{s: make(chan *cpd.Report)},
 			serverIP:             netip.Addr{},
 			profileAlwaysOn:      false,
 			statusTrustedNetwork: vpnstatus.TrustedNetworkNotTrusted,
@@ -518,7 +1123,7 @@ func TestDaemonCheckTrafPol(t *testing.T) {
 		},
 		// check without trafpol/alwayson setting and running TrafPol, stop
 		{
-			trafpol:              &trafPolicer{s: make(chan bool)},
+			trafpol:              &trafPolicer{s: make(chan *cpd.Report)},
 			serverIP:             netip.Addr{},
 			profileAlwaysOn:      false,
 			statusTrustedNetwork: vpnstatus.TrustedNetworkNotTrusted,
@@ -538,7 +1143,7 @@ func TestDaemonCheckTrafPol(t *testing.T) {
 		},
 		// check with trusted network and running TrafPol, stop
 		{
-			trafpol:              &trafPolicer{s: make(chan bool)},
+			trafpol:              &trafPolicer{s: make(chan *cpd.Report)},
 			serverIP:             netip.Addr{},
 			profileAlwaysOn:      true,
 			statusTrustedNetwork: vpnstatus.TrustedNetworkTrusted,
@@ -568,7 +1173,7 @@ func TestDaemonCheckTrafPol(t *testing.T) {
 		},
 		// check with trafpol/alwayson setting and running TrafPol
 		{
-			trafpol:              &trafPolicer{s: make(chan bool)},
+			trafpol:              &trafPolicer{s: make(chan *cpd.Report)},
 			serverIP:             netip.Addr{},
 			profileAlwaysOn:      true,
 			statusTrustedNetwork: vpnstatus.TrustedNetworkNotTrusted,
@@ -961,6 +1566,20 @@ func TestDaemonHandleDBusRequest(t *testing.T) {
 	if err := json.Unmarshal([]byte(r.Results[0].(string)), &state); err != nil {
 		t.Error("no json string in dump state results")
 	}
+
+	// disconnect, disconnect locked
+	d = getTestDaemon()
+	d.status.OCRunning = vpnstatus.OCRunningRunning
+	d.status.DisconnectPolicy = vpnstatus.DisconnectPolicyLocked
+	r = dbusapi.NewRequest(dbusapi.RequestDisconnect, make(chan struct{}))
+	go d.handleDBusRequest(r)
+	r.Wait()
+	if !errors.Is(r.Error, dbusapi.ErrDisconnectLocked) {
+		t.Errorf("got %v, want %v", r.Error, dbusapi.ErrDisconnectLocked)
+	}
+	if d.status.ConnectionState == vpnstatus.ConnectionStateDisconnecting {
+		t.Error("locked disconnect should not disconnect")
+	}
 }
 
 // TestDaemonHandleTNDResult tests handleTNDResult of Daemon.
@@ -1041,6 +1660,171 @@ func TestDaemonHandleRunnerEvent(t *testing.T) {
 	}
 }
 
+// TestDaemonReconnect tests reconnecting the VPN after unexpected
+// disconnects of Daemon.
+func TestDaemonReconnect(t *testing.T) {
+	login := &logininfo.LoginInfo{
+		Server:      "server",
+		Cookie:      "cookie",
+		Host:        "10.0.0.1",
+		Fingerprint: "fingerprint",
+	}
+	connect := func(d *Daemon) {
+		r := dbusapi.NewRequest(dbusapi.RequestConnect, make(chan struct{}))
+		r.Parameters = []any{"server", "cookie", "10.0.0.1", "", "fingerprint", ""}
+		go d.handleDBusRequest(r)
+		r.Wait()
+	}
+
+	// unexpected disconnect, reconnect
+	d := getTestDaemon()
+	d.config.Reconnect.MaxAttempts = 2
+	connect(d)
+	if !d.reconnect.active() {
+		t.Fatal("reconnect should be active after connect")
+	}
+	if !reflect.DeepEqual(d.reconnect.getLogin(), login) {
+		t.Errorf("got login %v, want %v", d.reconnect.getLogin(), login)
+	}
+
+	d.handleRunnerEvent(&ocrunner.ConnectEvent{})
+	if d.status.ReconnectAttempts != 1 || d.status.ReconnectAt <= 0 {
+		t.Errorf("reconnect should be scheduled, got attempts %d, at %d",
+			d.status.ReconnectAttempts, d.status.ReconnectAt)
+	}
+	if d.reconnect.timerC() == nil {
+		t.Error("reconnect timer should be set")
+	}
+
+	d.handleReconnectTimer()
+	if !d.status.OCRunning.Running() ||
+		d.status.ConnectionState != vpnstatus.ConnectionStateConnecting {
+		t.Error("reconnect timer should connect the VPN")
+	}
+	if d.status.ReconnectAt != 0 {
+		t.Errorf("got reconnect at %d, want 0", d.status.ReconnectAt)
+	}
+
+	// unexpected disconnect, maximum attempts reached
+	d.handleRunnerEvent(&ocrunner.ConnectEvent{})
+	d.handleReconnectTimer()
+	d.handleRunnerEvent(&ocrunner.ConnectEvent{})
+	if d.reconnect.active() {
+		t.Error("reconnect should not be active after maximum attempts")
+	}
+	if d.status.ReconnectGiveUpReason != reconnectReasonMaxAttempts {
+		t.Errorf("got give up reason %s, want %s",
+			d.status.ReconnectGiveUpReason, reconnectReasonMaxAttempts)
+	}
+
+	// user disconnect, no reconnect
+	d = getTestDaemon()
+	connect(d)
+	d.disconnectVPN(vpnhistory.DisconnectCauseUser)
+	d.handleRunnerEvent(&ocrunner.ConnectEvent{})
+	if d.reconnect.active() || d.reconnect.timerC() != nil {
+		t.Error("reconnect should not be active after user disconnect")
+	}
+
+	// unexpected disconnect in trusted network, no reconnect
+	d = getTestDaemon()
+	connect(d)
+	d.status.TrustedNetwork = vpnstatus.TrustedNetworkTrusted
+	d.handleRunnerEvent(&ocrunner.ConnectEvent{})
+	if d.status.ReconnectGiveUpReason != reconnectReasonTrustedNetwork {
+		t.Errorf("got give up reason %s, want %s",
+			d.status.ReconnectGiveUpReason, reconnectReasonTrustedNetwork)
+	}
+
+	// pending reconnect, switch to trusted network
+	d = getTestDaemon()
+	connect(d)
+	d.handleRunnerEvent(&ocrunner.ConnectEvent{})
+	if err := d.handleTNDResult(true); err != nil {
+		t.Fatal(err)
+	}
+	if d.reconnect.active() || d.reconnect.timerC() != nil {
+		t.Error("reconnect should not be active in trusted network")
+	}
+
+	// reconnect disabled
+	d = getTestDaemon()
+	d.config.Reconnect.Enabled = false
+	connect(d)
+	d.handleRunnerEvent(&ocrunner.ConnectEvent{})
+	if d.reconnect.active() || d.status.ReconnectAttempts != 0 {
+		t.Error("reconnect should not be active when disabled")
+	}
+}
+
+// TestDaemonConnectFailurePolicy tests the connect failure policy of Daemon.
+func TestDaemonConnectFailurePolicy(t *testing.T) {
+	oldTrafPolNewTafPol := trafpolNewTrafPol
+	defer func() { trafpolNewTrafPol = oldTrafPolNewTafPol }()
+	trafpolNewTrafPol = func(*daemoncfg.Config) trafpol.Policer {
+		return &trafPolicer{}
+	}
+
+	// connection failures until trafpol is relaxed
+	fail := func(d *Daemon) {
+		d.status.OCRunning = vpnstatus.OCRunningRunning
+		d.status.ConnectionState = vpnstatus.ConnectionStateConnecting
+		if err := d.handleRunnerEvent(&ocrunner.ConnectEvent{ExitCode: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, test := range []struct {
+		policy    string
+		wantState vpnstatus.ConnectFailureState
+		wantTP    vpnstatus.TrafPolState
+	}{
+		{"Closed", vpnstatus.ConnectFailureStateClosed, vpnstatus.TrafPolStateActive},
+		{"Open", vpnstatus.ConnectFailureStateRelaxed, vpnstatus.TrafPolStateInactive},
+	} {
+		d := getTestDaemon()
+		d.config.ConnectFailure.MaxFailures = 2
+		d.profile.AutomaticVPNPolicy.AlwaysOn.Flag = true
+		d.profile.AutomaticVPNPolicy.AlwaysOn.ConnectFailurePolicy.Flag = test.policy
+		d.trafpol = nil
+		if err := d.checkTrafPol(); err != nil {
+			t.Fatal(err)
+		}
+
+		fail(d)
+		if d.status.TrafPolState != vpnstatus.TrafPolStateActive {
+			t.Errorf("%d: trafpol should be active after first failure", i)
+		}
+		fail(d)
+		if d.status.ConnectFailures != 2 {
+			t.Errorf("%d: got failures %d, want 2", i, d.status.ConnectFailures)
+		}
+		if d.status.ConnectFailureState != test.wantState {
+			t.Errorf("%d: got state %s, want %s", i, d.status.ConnectFailureState, test.wantState)
+		}
+		if d.status.TrafPolState != test.wantTP {
+			t.Errorf("%d: got trafpol state %s, want %s", i, d.status.TrafPolState, test.wantTP)
+		}
+
+		// successful connection restores trafpol
+		d.status.OCRunning = vpnstatus.OCRunningRunning
+		d.status.ConnectionState = vpnstatus.ConnectionStateConnecting
+		d.updateVPNConfigUp(vpnconfig.New())
+		if d.status.ConnectFailures != 0 {
+			t.Errorf("%d: got failures %d, want 0", i, d.status.ConnectFailures)
+		}
+		if d.status.TrafPolState != vpnstatus.TrafPolStateActive {
+			t.Errorf("%d: trafpol should be active after connect", i)
+		}
+	}
+
+	// always on disabled
+	d := getTestDaemon()
+	d.checkConnectFailurePolicy()
+	if d.status.ConnectFailureState != vpnstatus.ConnectFailureStateInactive {
+		t.Errorf("got state %s, want inactive", d.status.ConnectFailureState)
+	}
+}
+
 // TestDaemonHandleSleepMonEvent tests handleSleepMonEvent of Daemon.
 func TestDaemonHandleSleepMonEvent(t *testing.T) {
 	for i, test := range []struct {
@@ -1077,6 +1861,215 @@ func TestDaemonHandleSleepMonEvent(t *testing.T) {
 	}
 }
 
+// TestDaemonHandleSleepMonEventPolicy tests handleSleepMonEvent of Daemon
+// with resume policies.
+func TestDaemonHandleSleepMonEventPolicy(t *testing.T) {
+	for _, policy := range []string{
+		daemoncfg.ResumePolicyKeep,
+		daemoncfg.ResumePolicyReconnect,
+	} {
+		d := getTestDaemon()
+		d.config.Resume.Policy = policy
+		d.status.OCRunning = vpnstatus.OCRunningRunning
+		d.status.ConnectionState = vpnstatus.ConnectionStateConnected
+
+		// resume, validate network
+		d.handleSleepMonEvent(false)
+		if !d.resume.validating() ||
+			d.status.ConnectionState != vpnstatus.ConnectionStateConnected ||
+			d.status.ResumeAction != policy ||
+			d.status.ResumeOutcome != resumeOutcomeValidating {
+			t.Errorf("%s: resume should validate network", policy)
+		}
+
+		// sleep, cancel validation
+		d.handleSleepMonEvent(true)
+		if d.resume.validating() {
+			t.Errorf("%s: sleep should cancel validation", policy)
+		}
+	}
+}
+
+// TestDaemonHandleResumeTimer tests handleResumeTimer of Daemon.
+func TestDaemonHandleResumeTimer(t *testing.T) {
+	getDaemon := func(policy string) *Daemon {
+		d := getTestDaemon()
+		d.config.Resume.Policy = policy
+		d.config.LoginInfo = &logininfo.LoginInfo{
+			Server:      "server",
+			Cookie:      "cookie",
+			Host:        "10.0.0.1",
+			Fingerprint: "fingerprint",
+		}
+		d.status.OCRunning = vpnstatus.OCRunningRunning
+		d.status.ConnectionState = vpnstatus.ConnectionStateConnected
+		return d
+	}
+
+	// keep
+	d := getDaemon(daemoncfg.ResumePolicyKeep)
+	d.handleResumeTimer()
+	if d.status.ResumeOutcome != resumeOutcomeKept ||
+		d.status.ConnectionState != vpnstatus.ConnectionStateConnected {
+		t.Error("keep policy should keep connection")
+	}
+
+	// trusted network
+	d = getDaemon(daemoncfg.ResumePolicyKeep)
+	d.status.TrustedNetwork = vpnstatus.TrustedNetworkTrusted
+	d.handleResumeTimer()
+	if d.status.ResumeOutcome != resumeOutcomeTrustedNetwork ||
+		d.status.ConnectionState != vpnstatus.ConnectionStateDisconnecting {
+		t.Error("trusted network should disconnect")
+	}
+	if r := d.runner.(*ocRunner); r.disconnects != 1 || r.keptSession != 0 {
+		t.Errorf("session should be logged off, got %d, %d",
+			r.disconnects, r.keptSession)
+	}
+
+	// not running
+	d = getDaemon(daemoncfg.ResumePolicyReconnect)
+	d.status.OCRunning = vpnstatus.OCRunningNotRunning
+	d.handleResumeTimer()
+	if d.status.ResumeOutcome != resumeOutcomeNotRunning {
+		t.Error("resume outcome should be not running")
+	}
+
+	// captive portal
+	d = getDaemon(daemoncfg.ResumePolicyReconnect)
+	d.status.CaptivePortal = vpnstatus.CaptivePortalDetected
+	d.handleResumeTimer()
+	if d.status.ResumeOutcome != resumeOutcomeCaptivePortal ||
+		d.status.ConnectionState != vpnstatus.ConnectionStateConnected {
+		t.Error("captive portal should keep connection")
+	}
+
+	// reconnect
+	d = getDaemon(daemoncfg.ResumePolicyReconnect)
+	d.handleResumeTimer()
+	if d.status.ResumeOutcome != resumeOutcomeReconnecting ||
+		d.status.ConnectionState != vpnstatus.ConnectionStateDisconnecting ||
+		d.resume.login == nil {
+		t.Fatal("reconnect policy should disconnect for reconnect")
+	}
+	if r := d.runner.(*ocRunner); r.disconnects != 0 || r.keptSession != 1 {
+		t.Errorf("session should be kept for reconnect, got %d, %d",
+			r.disconnects, r.keptSession)
+	}
+
+	// openconnect exits, reconnect with stored login info
+	if err := d.handleRunnerEvent(&ocrunner.ConnectEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	if !d.status.OCRunning.Running() ||
+		d.status.Server != "server" ||
+		!d.reconnect.active() {
+		t.Error("daemon should reconnect after resume")
+	}
+
+	// reconnect fails
+	if err := d.handleRunnerEvent(&ocrunner.ConnectEvent{ExitCode: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if d.status.ResumeOutcome != resumeOutcomeReconnectFailed {
+		t.Error("resume outcome should be reconnect failed")
+	}
+}
+
+// TestDaemonSignals tests signals emitted by Daemon.
+func TestDaemonSignals(t *testing.T) {
+	connect := func(d *Daemon) {
+		r := dbusapi.NewRequest(dbusapi.RequestConnect, make(chan struct{}))
+		r.Parameters = []any{"server", "cookie", "10.0.0.1", "", "fingerprint", ""}
+		go d.handleDBusRequest(r)
+		r.Wait()
+	}
+	signals := func(d *Daemon) (names []any) {
+		for _, s := range d.dbus.(*dbusService).signals {
+			names = append(names, s[0])
+		}
+		return
+	}
+
+	// invalid login
+	d := getTestDaemon()
+	d.connectVPN(&logininfo.LoginInfo{})
+	want := [][]any{{dbusapi.SignalConnectFailed, connectFailedInvalidLogin}}
+	if got := d.dbus.(*dbusService).signals; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// openconnect exit while connecting, reconnect
+	d = getTestDaemon()
+	connect(d)
+	_ = d.handleRunnerEvent(&ocrunner.ConnectEvent{ExitCode: 1})
+	wantNames := []any{dbusapi.SignalConnectFailed, dbusapi.SignalReconnecting}
+	if got := signals(d); !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("got %v, want %v", got, wantNames)
+	}
+
+	// user disconnect while connecting
+	d = getTestDaemon()
+	connect(d)
+	d.disconnectVPN(vpnhistory.DisconnectCauseUser)
+	_ = d.handleRunnerEvent(&ocrunner.ConnectEvent{})
+	if got := signals(d); got != nil {
+		t.Errorf("got %v, want no signals", got)
+	}
+
+	// trusted network
+	d = getTestDaemon()
+	d.setStatusTrustedNetwork(true)
+	d.setStatusTrustedNetwork(true)
+	wantNames = []any{dbusapi.SignalTrustedNetworkDetected}
+	if got := signals(d); !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("got %v, want %v", got, wantNames)
+	}
+
+	// command failure
+	d = getTestDaemon()
+	d.handleCommandFailure(&cmdtmpl.Failure{
+		List:    "TestList",
+		Command: "test command",
+		Stderr:  "test error",
+	})
+	want = [][]any{{dbusapi.SignalCommandFailed, "TestList", "test command", "test error"}}
+	if got := d.dbus.(*dbusService).signals; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if d.status.CommandListError != "" {
+		t.Errorf("command failure should not set command list error")
+	}
+
+	// command failures in command lists with abort and rollback
+	for onFailure, want := range map[string]string{
+		cmdtmpl.OnFailureAbort:    `TestList aborted after failed command "test command"`,
+		cmdtmpl.OnFailureRollback: `TestList rolled back after failed command "test command"`,
+	} {
+		d = getTestDaemon()
+		d.handleCommandFailure(&cmdtmpl.Failure{
+			List:      "TestList",
+			Command:   "test command",
+			OnFailure: onFailure,
+		})
+		if d.status.CommandListError != want {
+			t.Errorf("got %q, want %q", d.status.CommandListError, want)
+		}
+	}
+
+	// profile update
+	d = getTestDaemon()
+	d.config.OpenConnect.XMLProfile = filepath.Join(t.TempDir(), "does-not-exist")
+	d.trafpol = nil
+	if err := d.handleProfileUpdate(); err != nil {
+		t.Fatal(err)
+	}
+	wantNames = []any{dbusapi.SignalProfileReloaded}
+	if got := signals(d); !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("got %v, want %v", got, wantNames)
+	}
+}
+
 // TestDaemonHandleProfileUpdate tests handleProfileUpdate of Daemon.
 func TestDaemonHandleProfileUpdate(t *testing.T) {
 	dir := t.TempDir()
@@ -1126,18 +2119,22 @@ func TestDaemonHandleProfileUpdate(t *testing.T) {
 // TestDaemonHandleCPDStatusUpdate tests handleCPDStatusUpdate of Daemon.
 func TestDaemonHandleCPDStatusUpdate(t *testing.T) {
 	for i, test := range []struct {
-		update bool
-		want   vpnstatus.CaptivePortal
+		update  *cpd.Report
+		want    vpnstatus.CaptivePortal
+		signals [][]any
 	}{
 		// no captive portal
 		{
-			update: false,
+			update: &cpd.Report{},
 			want:   vpnstatus.CaptivePortalNotDetected,
 		},
 		// captive portal
 		{
-			update: true,
+			update: &cpd.Report{Detected: true, URL: "http://portal.example.com/"},
 			want:   vpnstatus.CaptivePortalDetected,
+			signals: [][]any{
+				{dbusapi.SignalCaptivePortalDetected, "http://portal.example.com/"},
+			},
 		},
 	} {
 		d := getTestDaemon()
@@ -1147,9 +2144,135 @@ func TestDaemonHandleCPDStatusUpdate(t *testing.T) {
 			t.Errorf("%d: got captive portal %d, want %d",
 				i, got, test.want)
 		}
+		signals := d.dbus.(*dbusService).signals
+		if !reflect.DeepEqual(signals, test.signals) {
+			t.Errorf("%d: got signals %v, want %v", i, signals, test.signals)
+		}
+	}
+}
+
+// TestDaemonRemediateCaptivePortal tests captive portal remediation of Daemon.
+func TestDaemonRemediateCaptivePortal(t *testing.T) {
+	// remediation request
+	for i, test := range []struct {
+		trafpol trafpol.Policer
+		wantErr bool
+	}{
+		{trafpol: nil, wantErr: true},
+		{trafpol: &trafPolicer{r: false}, wantErr: true},
+		{trafpol: &trafPolicer{r: true}, wantErr: false},
+	} {
+		d := getTestDaemon()
+		d.trafpol = test.trafpol
+		r := dbusapi.NewRequest(dbusapi.RequestRemediate, make(chan struct{}))
+		go d.handleDBusRequest(r)
+		r.Wait()
+		if (r.Error != nil) != test.wantErr {
+			t.Errorf("%d: got error %v, want error %t", i, r.Error, test.wantErr)
+		}
+	}
+
+	// expired remediation
+	d := getTestDaemon()
+	d.handleRemediationExpired()
+	want := [][]any{{dbusapi.SignalRemediationExpired}}
+	if got := d.dbus.(*dbusService).signals; !reflect.DeepEqual(got, want) {
+		t.Errorf("got signals %v, want %v", got, want)
 	}
 }
 
+// TestDaemonCleanup tests cleanup of Daemon.
+func TestDaemonCleanup(t *testing.T) {
+	oldRunCmd := cmdtmpl.RunCmd
+	defer func() { cmdtmpl.RunCmd = oldRunCmd }()
+	cmdtmpl.RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
+		return nil, nil, nil
+	}
+
+	// leave journal entry behind
+	dir := t.TempDir()
+	undo := []*cmdtmpl.Cmd{{Cmd: "ip", Args: []string{"link", "delete", "old-tun0"}}}
+	if err := journal.Save(dir, "VPNSetup", undo); err != nil {
+		t.Fatal(err)
+	}
+
+	// cleanup should recover journal entry and report it in state
+	d := getTestDaemon()
+	d.config.CommandLists.JournalDir = dir
+	d.cleanup(context.Background())
+	if len(d.recovered) != 1 || d.recovered[0].Name != "VPNSetup" {
+		t.Errorf("got %v, want recovered VPNSetup entry", d.recovered)
+	}
+	state := struct{ RecoveredJournal []*journal.Recovered }{}
+	if err := json.Unmarshal([]byte(d.dumpState()), &state); err != nil {
+		t.Fatal(err)
+	}
+	if len(state.RecoveredJournal) != 1 {
+		t.Errorf("got %v, want recovered entry in state", state.RecoveredJournal)
+	}
+}
+
+// TestDaemonDumpStateCommandTrace tests the command trace in dumpState of
+// Daemon.
+func TestDaemonDumpStateCommandTrace(t *testing.T) {
+	oldRunCmd := cmdtmpl.RunCmd
+	defer func() { cmdtmpl.RunCmd = oldRunCmd }()
+	cmdtmpl.RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
+		return nil, nil, nil
+	}
+
+	cmd := &cmdtmpl.Cmd{List: "TestList", Cmd: "test-dump-state", Args: []string{"a"}}
+	if _, _, err := cmd.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	d := getTestDaemon()
+	state := struct{ CommandTrace []*cmdtmpl.TraceEntry }{}
+	if err := json.Unmarshal([]byte(d.dumpState()), &state); err != nil {
+		t.Fatal(err)
+	}
+	entries := cmdtmpl.FilterTrace(state.CommandTrace, "TestList", "test-dump-state", false)
+	if len(entries) != 1 || !reflect.DeepEqual(entries[0].Args, []string{"a"}) {
+		t.Errorf("got %v, want traced command in state", entries)
+	}
+}
+
+// TestDaemonSystemdNotify tests systemd notifications of Daemon.
+func TestDaemonSystemdNotify(t *testing.T) {
+	// set testing functions and cleanup after tests
+	oldNotify := sdnotifyNotify
+	defer func() { sdnotifyNotify = oldNotify }()
+	states := make(chan string, 16)
+	sdnotifyNotify = func(state string) error {
+		select {
+		case states <- state:
+		default:
+		}
+		return nil
+	}
+	oldWatchdogInterval := sdnotifyWatchdogInterval
+	defer func() { sdnotifyWatchdogInterval = oldWatchdogInterval }()
+	sdnotifyWatchdogInterval = func() time.Duration { return time.Millisecond }
+
+	// status
+	d := getTestDaemon()
+	d.setStatusConnectionState(vpnstatus.ConnectionStateConnected)
+	want := sdnotify.Status("VPN connected")
+	if got := <-states; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// watchdog
+	go d.start()
+	for got := range states {
+		if got == sdnotify.Watchdog {
+			break
+		}
+	}
+	close(d.done)
+	<-d.closed
+}
+
 // TestDaemonStartStop tests Start and Stop of Daemon with some events.
 func TestDaemonStartStop(t *testing.T) {
 	// set testing functions and cleanup after tests
@@ -1166,7 +2289,7 @@ func TestDaemonStartStop(t *testing.T) {
 	oldTrafPolNewTafPol := trafpolNewTrafPol
 	defer func() { trafpolNewTrafPol = oldTrafPolNewTafPol }()
 	trafpolNewTrafPol = func(*daemoncfg.Config) trafpol.Policer {
-		return &trafPolicer{s: make(chan bool)}
+		return &trafPolicer{s: make(chan *cpd.Report)}
 	}
 
 	dir := t.TempDir()
@@ -1176,6 +2299,7 @@ func TestDaemonStartStop(t *testing.T) {
 	d := getTestDaemon()
 	d.profile = readXMLProfile(file)
 	d.config.OpenConnect.XMLProfile = file
+	d.config.CommandLists.JournalDir = dir
 	d.tnd = nil
 	d.trafpol = nil
 	if err := d.Start(); err != nil {
@@ -1202,6 +2326,10 @@ func TestDaemonStartStop(t *testing.T) {
 	// this changes trafpol and tnd in d
 	d.profmon.(*profMonitor).u <- struct{}{}
 
+	// cfgmon event and reload request
+	d.cfgmon.(*cfgMonitor).u <- struct{}{}
+	d.Reload()
+
 	d.Stop()
 
 	// create profile
@@ -1233,6 +2361,7 @@ func TestDaemonStartStop(t *testing.T) {
 	d = getTestDaemon()
 	d.profile = readXMLProfile(file)
 	d.config.OpenConnect.XMLProfile = file
+	d.config.CommandLists.JournalDir = dir
 	d.tnd = nil
 	d.trafpol = nil
 	if err := d.Start(); err != nil {
@@ -1259,7 +2388,7 @@ func TestDaemonStartStop(t *testing.T) {
 	d.tnd.Results() <- false
 
 	// trafpol/cpd event
-	d.trafpol.(*trafPolicer).s <- false
+	d.trafpol.(*trafPolicer).s <- &cpd.Report{}
 
 	// profmon event
 	// this changes trafpol and tnd in d
@@ -1302,12 +2431,21 @@ func TestNewDaemon(t *testing.T) {
 		d.sleepmon,
 		d.vpnsetup,
 		d.runner,
+		d.conns,
 		d.status,
 		d.errors,
 		d.done,
 		d.closed,
 		d.profile,
 		d.profmon,
+		d.cfgmon,
+		d.reloads,
+		d.history,
+		d.idle,
+		d.resume,
+		d.connfail,
+		d.netpolicy,
+		d.machineauth,
 	} {
 		if s == nil {
 			t.Errorf("%d: unexpected nil", i)
@@ -0,0 +1,147 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnhistory"
+)
+
+// history records the VPN sessions in the connection history and persists
+// the history in its file.
+type history struct {
+	config *daemoncfg.History
+
+	// history is the connection history
+	history *vpnhistory.History
+
+	// current is the current session, nil if there is no session
+	current *vpnhistory.Session
+}
+
+// load loads the connection history from its file.
+func (h *history) load() error {
+	b, err := os.ReadFile(h.config.File)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	history, err := vpnhistory.NewFromJSON(b)
+	if err != nil {
+		return err
+	}
+	h.history = history
+	return nil
+}
+
+// save saves the connection history to its file.
+func (h *history) save() error {
+	b, err := h.history.JSON()
+	if err != nil {
+		return fmt.Errorf("could not convert history to JSON: %w", err)
+	}
+
+	// write history to temporary file and rename it
+	dir := filepath.Dir(h.config.File)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("could not create history dir: %w", err)
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(h.config.File)+".tmp")
+	if err != nil {
+		return fmt.Errorf("could not create history file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("could not write history file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("could not close history file: %w", err)
+	}
+	if err := os.Rename(f.Name(), h.config.File); err != nil {
+		return fmt.Errorf("could not rename history file: %w", err)
+	}
+	return nil
+}
+
+// newSession returns a new session of the VPN connection with server and
+// serverIP, connection is empty for the default VPN connection.
+func newSession(connection, server, serverIP string) *vpnhistory.Session {
+	return &vpnhistory.Session{
+		Connection: connection,
+		Server:     server,
+		ServerIP:   serverIP,
+		StartedAt:  time.Now().Unix(),
+		ExitCode:   -1,
+	}
+}
+
+// sessionConnected marks session as connected with ip.
+func sessionConnected(session *vpnhistory.Session, ip string) {
+	if session == nil {
+		return
+	}
+	session.IP = ip
+	session.ConnectedAt = time.Now().Unix()
+}
+
+// start starts a new session with server and serverIP.
+func (h *history) start(server, serverIP string) {
+	h.current = newSession("", server, serverIP)
+}
+
+// connected marks the current session as connected with ip.
+func (h *history) connected(ip string) {
+	sessionConnected(h.current, ip)
+}
+
+// resume resumes session as current session, e.g., after a restart of the
+// daemon that kept the VPN tunnel running.
+func (h *history) resume(session *vpnhistory.Session) {
+	h.current = session
+}
+
+// end ends the current session with cause and exit code of openconnect,
+// adds it to the history and saves the history.
+func (h *history) end(cause vpnhistory.DisconnectCause, exitCode int) {
+	h.add(h.current, cause, exitCode)
+	h.current = nil
+}
+
+// add ends session with cause and exit code of openconnect, adds it to the
+// history and saves the history.
+func (h *history) add(session *vpnhistory.Session, cause vpnhistory.DisconnectCause, exitCode int) {
+	if session == nil {
+		return
+	}
+	session.DisconnectedAt = time.Now().Unix()
+	session.Cause = cause
+	session.ExitCode = exitCode
+	h.history.Add(session, h.config.MaxSessions)
+
+	if err := h.save(); err != nil {
+		log.WithError(err).WithField("file", h.config.File).
+			Error("Daemon could not save connection history")
+	}
+}
+
+// get returns a copy of the connection history.
+func (h *history) get() *vpnhistory.History {
+	return h.history.Copy()
+}
+
+// newHistory returns a new history with config.
+func newHistory(config *daemoncfg.History) *history {
+	return &history{
+		config:  config,
+		history: vpnhistory.New(),
+	}
+}
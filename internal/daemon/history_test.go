@@ -0,0 +1,123 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/dbusapi"
+	"github.com/telekom-mms/oc-daemon/internal/ocrunner"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnhistory"
+)
+
+// TestHistoryLoadSave tests load and save of history.
+func TestHistoryLoadSave(t *testing.T) {
+	config := daemoncfg.NewHistory()
+	config.File = filepath.Join(t.TempDir(), "dir", "history.json")
+	config.MaxSessions = 2
+
+	// load without file
+	h := newHistory(config)
+	if err := h.load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.get().Sessions) != 0 {
+		t.Errorf("got %v, want empty history", h.get())
+	}
+
+	// end sessions, saves history
+	for _, server := range []string{"server1", "server2", "server3"} {
+		h.start(server, "192.168.1.1")
+		h.connected("10.0.0.1")
+		h.end(vpnhistory.DisconnectCauseUser, 0)
+	}
+
+	// end without session, does not change history
+	h.end(vpnhistory.DisconnectCauseShutdown, -1)
+
+	// load saved history
+	h = newHistory(config)
+	if err := h.load(); err != nil {
+		t.Fatal(err)
+	}
+	got := h.get()
+	if len(got.Sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(got.Sessions))
+	}
+	for i, want := range []string{"server2", "server3"} {
+		s := got.Sessions[i]
+		if s.Server != want || s.IP != "10.0.0.1" || s.ConnectedAt == 0 ||
+			s.Cause != vpnhistory.DisconnectCauseUser {
+			t.Errorf("got %v, want session with server %s", s, want)
+		}
+	}
+
+	// load invalid file
+	if err := os.WriteFile(config.File, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.load(); err == nil {
+		t.Error("invalid file should return error")
+	}
+}
+
+// TestDaemonHistory tests the connection history of Daemon.
+func TestDaemonHistory(t *testing.T) {
+	connect := func(d *Daemon) {
+		r := dbusapi.NewRequest(dbusapi.RequestConnect, make(chan struct{}))
+		r.Parameters = []any{"server", "cookie", "10.0.0.1", "", "fingerprint", ""}
+		go d.handleDBusRequest(r)
+		r.Wait()
+	}
+	getHistory := func(d *Daemon) *vpnhistory.History {
+		r := dbusapi.NewRequest(dbusapi.RequestGetHistory, make(chan struct{}))
+		go d.handleDBusRequest(r)
+		r.Wait()
+		h, err := vpnhistory.NewFromJSON([]byte(r.Results[0].(string)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	d := getTestDaemon()
+	d.config.History.File = filepath.Join(t.TempDir(), "history.json")
+
+	// user disconnect
+	connect(d)
+	r := dbusapi.NewRequest(dbusapi.RequestDisconnect, make(chan struct{}))
+	go d.handleDBusRequest(r)
+	r.Wait()
+	_ = d.handleRunnerEvent(&ocrunner.ConnectEvent{ExitCode: 0})
+
+	// unexpected openconnect exit
+	connect(d)
+	_ = d.handleRunnerEvent(&ocrunner.ConnectEvent{ExitCode: 1})
+
+	// disconnect on resume
+	d.stopReconnect()
+	connect(d)
+	d.handleSleepMonEvent(false)
+	_ = d.handleRunnerEvent(&ocrunner.ConnectEvent{ExitCode: 0})
+
+	h := getHistory(d)
+	want := []struct {
+		cause    vpnhistory.DisconnectCause
+		exitCode int
+	}{
+		{vpnhistory.DisconnectCauseUser, 0},
+		{vpnhistory.DisconnectCauseOpenConnectExit, 1},
+		{vpnhistory.DisconnectCauseResume, 0},
+	}
+	if len(h.Sessions) != len(want) {
+		t.Fatalf("got %d sessions, want %d", len(h.Sessions), len(want))
+	}
+	for i, w := range want {
+		s := h.Sessions[i]
+		if s.Server != "server" || s.ServerIP != "10.0.0.1" ||
+			s.Cause != w.cause || s.ExitCode != w.exitCode {
+			t.Errorf("%d: got %v, want %v", i, s, w)
+		}
+	}
+}
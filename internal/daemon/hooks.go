@@ -0,0 +1,122 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
+)
+
+// hooksMaxQueued is the maximum number of queued hooks, further hooks are
+// dropped until the queue has room again.
+const hooksMaxQueued = 32
+
+// hookData is the data of the hook command list templates.
+type hookData struct {
+	daemoncfg.Config
+	Status *vpnstatus.Status
+}
+
+// hook is a queued hook command list.
+type hook struct {
+	name    string
+	timeout time.Duration
+	data    *hookData
+}
+
+// hooks runs the hook command lists asynchronously in a separate goroutine
+// in the order they were triggered, so they never block the main loop.
+type hooks struct {
+	mutex   sync.Mutex
+	queue   []*hook
+	running bool
+	stopped bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// runHook runs the hook command list identified by name on data, for
+// testing.
+var runHook = cmdtmpl.RunCmds
+
+// run runs the queued hooks until the queue is empty.
+func (h *hooks) run() {
+	defer h.wg.Done()
+	for {
+		h.mutex.Lock()
+		if len(h.queue) == 0 {
+			h.running = false
+			h.mutex.Unlock()
+			return
+		}
+		next := h.queue[0]
+		h.queue = h.queue[1:]
+		h.mutex.Unlock()
+
+		ctx, cancel := context.WithTimeout(h.ctx, next.timeout)
+		if err := runHook(ctx, next.name, next.data); err != nil {
+			log.WithError(err).WithField("hook", next.name).
+				Error("Daemon could not run hook")
+		}
+		cancel()
+	}
+}
+
+// trigger queues the hook command list identified by name with config and
+// status if the hook is configured.
+func (h *hooks) trigger(name string, config *daemoncfg.Config, status *vpnstatus.Status) {
+	if !cmdtmpl.HasCommandList(name) {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.stopped {
+		return
+	}
+	if len(h.queue) >= hooksMaxQueued {
+		log.WithField("hook", name).Warn("Daemon dropped hook, too many queued hooks")
+		return
+	}
+	log.WithField("hook", name).Debug("Daemon running hook")
+	h.queue = append(h.queue, &hook{
+		name:    name,
+		timeout: config.CommandLists.HookTimeout,
+		data: &hookData{
+			Config: *config.Copy(),
+			Status: status.Copy(),
+		},
+	})
+	if !h.running {
+		h.running = true
+		h.wg.Add(1)
+		go h.run()
+	}
+}
+
+// stop cancels running hooks, drops queued hooks and waits until the hooks
+// goroutine terminated.
+func (h *hooks) stop() {
+	h.mutex.Lock()
+	h.stopped = true
+	h.queue = nil
+	h.mutex.Unlock()
+
+	h.cancel()
+	h.wg.Wait()
+}
+
+// newHooks returns new hooks.
+func newHooks() *hooks {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &hooks{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
+)
+
+// testHook is a hook run by runHook in tests.
+type testHook struct {
+	name string
+	data *hookData
+}
+
+// setTestHooks configures all hooks and records the hooks run by runHook
+// in the returned channel.
+func setTestHooks(t *testing.T) chan *testHook {
+	t.Cleanup(cmdtmpl.Reset)
+	for _, name := range []string{
+		cmdtmpl.HookConnected,
+		cmdtmpl.HookDisconnected,
+		cmdtmpl.HookTrustedNetwork,
+		cmdtmpl.HookCaptivePortal,
+	} {
+		cmdtmpl.CommandLists[name] = &cmdtmpl.CommandList{Name: name}
+	}
+
+	oldRunHook := runHook
+	t.Cleanup(func() { runHook = oldRunHook })
+	ran := make(chan *testHook, hooksMaxQueued)
+	runHook = func(ctx context.Context, name string, data any) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("hook %s should run with timeout", name)
+		}
+		ran <- &testHook{name: name, data: data.(*hookData)}
+		return nil
+	}
+	return ran
+}
+
+// TestHooksTrigger tests trigger of hooks.
+func TestHooksTrigger(t *testing.T) {
+	config := daemoncfg.NewConfig()
+	status := vpnstatus.New()
+
+	// hook not configured
+	h := newHooks()
+	h.trigger(cmdtmpl.HookConnected, config, status)
+	if h.running || len(h.queue) != 0 {
+		t.Error("hook should not run when not configured")
+	}
+
+	// hook configured, runs with copies of config and status
+	ran := setTestHooks(t)
+	status.IP = "192.168.1.1"
+	h.trigger(cmdtmpl.HookConnected, config, status)
+	got := <-ran
+	if got.name != cmdtmpl.HookConnected ||
+		got.data.Status.IP != "192.168.1.1" ||
+		got.data.Status == status ||
+		got.data.Config.CommandLists.HookTimeout != config.CommandLists.HookTimeout {
+		t.Errorf("got invalid hook %v", got)
+	}
+
+	// hooks run in order
+	for _, name := range []string{cmdtmpl.HookTrustedNetwork, cmdtmpl.HookCaptivePortal} {
+		h.trigger(name, config, status)
+	}
+	for _, want := range []string{cmdtmpl.HookTrustedNetwork, cmdtmpl.HookCaptivePortal} {
+		if got := (<-ran).name; got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+
+	// stopped hooks do not run
+	h.stop()
+	h.trigger(cmdtmpl.HookConnected, config, status)
+	select {
+	case <-ran:
+		t.Error("hook should not run after stop")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// TestHooksTriggerQueueFull tests trigger of hooks with full queue.
+func TestHooksTriggerQueueFull(t *testing.T) {
+	setTestHooks(t)
+	h := newHooks()
+	h.running = true
+	for range hooksMaxQueued + 1 {
+		h.trigger(cmdtmpl.HookConnected, daemoncfg.NewConfig(), vpnstatus.New())
+	}
+	if len(h.queue) != hooksMaxQueued {
+		t.Errorf("got %d queued hooks, want %d", len(h.queue), hooksMaxQueued)
+	}
+}
+
+// TestDaemonHooks tests the hooks triggered by status changes of Daemon.
+func TestDaemonHooks(t *testing.T) {
+	ran := setTestHooks(t)
+	d := getTestDaemon()
+	defer d.hooks.stop()
+
+	d.setStatusTrustedNetwork(true)
+	d.setStatusCaptivePortal(vpnstatus.CaptivePortalDetected)
+	d.setStatusConnectionState(vpnstatus.ConnectionStateConnecting)
+	d.setStatusConnectionState(vpnstatus.ConnectionStateDisconnected)
+	d.setStatusConnectionState(vpnstatus.ConnectionStateConnecting)
+	d.setStatusConnectionState(vpnstatus.ConnectionStateConnected)
+	d.setStatusConnectionState(vpnstatus.ConnectionStateDisconnecting)
+	d.setStatusConnectionState(vpnstatus.ConnectionStateDisconnected)
+
+	for _, want := range []struct {
+		name  string
+		state vpnstatus.ConnectionState
+	}{
+		{cmdtmpl.HookTrustedNetwork, vpnstatus.ConnectionStateUnknown},
+		{cmdtmpl.HookCaptivePortal, vpnstatus.ConnectionStateUnknown},
+		{cmdtmpl.HookConnected, vpnstatus.ConnectionStateConnected},
+		{cmdtmpl.HookDisconnected, vpnstatus.ConnectionStateDisconnected},
+	} {
+		got := <-ran
+		if got.name != want.name || got.data.Status.ConnectionState != want.state {
+			t.Errorf("got %s %s, want %s %s", got.name,
+				got.data.Status.ConnectionState, want.name, want.state)
+		}
+	}
+	select {
+	case got := <-ran:
+		t.Errorf("unexpected hook %s", got.name)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,153 @@
+package daemon
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+// idleState is the state of the idle monitor.
+type idleState int
+
+// idle monitor states.
+const (
+	idleStateActive idleState = iota
+	idleStateWarning
+	idleStateTimeout
+)
+
+// idleGetPackets returns the number of packets sent and received on the
+// network device in the network namespace with name, in the current network
+// namespace if name is empty, used for testing.
+var idleGetPackets = func(name, device string) (uint64, error) {
+	h := &netlink.Handle{}
+	if name != "" {
+		ns, err := netns.GetFromName(name)
+		if err != nil {
+			return 0, err
+		}
+		defer func() { _ = ns.Close() }()
+		h, err = netlink.NewHandleAt(ns)
+		if err != nil {
+			return 0, err
+		}
+		defer h.Close()
+	}
+	link, err := h.LinkByName(device)
+	if err != nil {
+		return 0, err
+	}
+	stats := link.Attrs().Statistics
+	if stats == nil {
+		return 0, nil
+	}
+	return stats.RxPackets + stats.TxPackets, nil
+}
+
+// idle monitors the traffic on the VPN device and detects when the VPN
+// tunnel reaches the idle timeout reported by the VPN server.
+type idle struct {
+	config *daemoncfg.IdleTimeout
+
+	// device is the monitored VPN device, empty if no device is monitored
+	device string
+
+	// netns is the network namespace of the VPN device, empty if the
+	// device is in the current network namespace
+	netns string
+
+	// timeout is the idle timeout of the VPN server
+	timeout time.Duration
+
+	// packets is the last packet count of the VPN device
+	packets uint64
+
+	// lastActivity is the time of the last traffic on the VPN device
+	lastActivity time.Time
+
+	// ticker is the ticker for checking the VPN device,
+	// nil if no device is monitored
+	ticker *time.Ticker
+}
+
+// start starts monitoring VPN device in network namespace netns with idle
+// timeout.
+func (i *idle) start(netns, device string, timeout time.Duration) {
+	i.stop()
+	if !i.config.Enabled || timeout <= 0 {
+		return
+	}
+	i.device = device
+	i.netns = netns
+	i.timeout = timeout
+	i.packets, _ = idleGetPackets(netns, device)
+	i.lastActivity = time.Now()
+	i.ticker = time.NewTicker(i.config.CheckInterval)
+}
+
+// stop stops monitoring the VPN device.
+func (i *idle) stop() {
+	if i.ticker != nil {
+		i.ticker.Stop()
+		i.ticker = nil
+	}
+	i.device = ""
+	i.netns = ""
+	i.timeout = 0
+}
+
+// active returns whether a VPN device is monitored.
+func (i *idle) active() bool {
+	return i.ticker != nil
+}
+
+// deadline returns the time when the VPN tunnel reaches the idle timeout.
+func (i *idle) deadline() time.Time {
+	return i.lastActivity.Add(i.timeout)
+}
+
+// check checks the traffic on the VPN device at time now and returns the
+// current idle state.
+func (i *idle) check(now time.Time) idleState {
+	packets, err := idleGetPackets(i.netns, i.device)
+	switch {
+	case err != nil:
+		// device not readable, keep last activity
+		log.WithError(err).WithFields(log.Fields{
+			"device": i.device,
+			"netns":  i.netns,
+		}).Error("Daemon could not get packets of VPN device for idle timeout")
+	case packets != i.packets:
+		// traffic on device
+		i.packets = packets
+		i.lastActivity = now
+	}
+
+	deadline := i.deadline()
+	switch {
+	case !now.Before(deadline):
+		return idleStateTimeout
+	case !now.Before(deadline.Add(-i.config.WarningTime)):
+		return idleStateWarning
+	}
+	return idleStateActive
+}
+
+// tickerC returns the ticker channel for checking the VPN device,
+// nil if no device is monitored.
+func (i *idle) tickerC() <-chan time.Time {
+	if i.ticker == nil {
+		return nil
+	}
+	return i.ticker.C
+}
+
+// newIdle returns a new idle monitor.
+func newIdle(config *daemoncfg.IdleTimeout) *idle {
+	return &idle{
+		config: config,
+	}
+}
@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// TestIdleStartStop tests start and stop of idle.
+func TestIdleStartStop(t *testing.T) {
+	oldGetPackets := idleGetPackets
+	defer func() { idleGetPackets = oldGetPackets }()
+	idleGetPackets = func(string, string) (uint64, error) { return 0, nil }
+
+	// enabled
+	i := newIdle(daemoncfg.NewIdleTimeout())
+	i.start("", "tun0", time.Minute)
+	if !i.active() || i.tickerC() == nil {
+		t.Error("idle should be active")
+	}
+
+	i.stop()
+	if i.active() || i.tickerC() != nil {
+		t.Error("idle should not be active")
+	}
+
+	// no timeout
+	i.start("", "tun0", 0)
+	if i.active() {
+		t.Error("idle without timeout should not be active")
+	}
+
+	// disabled
+	config := daemoncfg.NewIdleTimeout()
+	config.Enabled = false
+	i = newIdle(config)
+	i.start("", "tun0", time.Minute)
+	if i.active() {
+		t.Error("disabled idle should not be active")
+	}
+}
+
+// TestIdleCheck tests check of idle.
+func TestIdleCheck(t *testing.T) {
+	oldGetPackets := idleGetPackets
+	defer func() { idleGetPackets = oldGetPackets }()
+	packets := uint64(0)
+	var err error
+	idleGetPackets = func(string, string) (uint64, error) { return packets, err }
+
+	config := daemoncfg.NewIdleTimeout()
+	config.WarningTime = time.Minute
+	i := newIdle(config)
+	i.start("", "tun0", 10*time.Minute)
+	defer i.stop()
+	start := i.lastActivity
+
+	for _, test := range []struct {
+		after   time.Duration
+		packets uint64
+		err     error
+		want    idleState
+	}{
+		{time.Minute, 0, nil, idleStateActive},
+		{9 * time.Minute, 0, nil, idleStateWarning},
+		{10 * time.Minute, 0, nil, idleStateTimeout},
+		{11 * time.Minute, 1, nil, idleStateActive},
+		{20 * time.Minute, 1, nil, idleStateWarning},
+		{20 * time.Minute, 1, errors.New("test error"), idleStateWarning},
+		{21 * time.Minute, 1, errors.New("test error"), idleStateTimeout},
+	} {
+		packets = test.packets
+		err = test.err
+		got := i.check(start.Add(test.after))
+		if got != test.want {
+			t.Errorf("%s: got %d, want %d", test.after, got, test.want)
+		}
+	}
+}
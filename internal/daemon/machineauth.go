@@ -0,0 +1,148 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+)
+
+// machineAuthResult is the result of a machine authentication.
+type machineAuthResult struct {
+	login *logininfo.LoginInfo
+	err   error
+}
+
+// execCommandContext is exec.CommandContext for testing.
+var execCommandContext = exec.CommandContext
+
+// machineAuthenticate runs openconnect in authentication mode with the
+// machine certificate and returns the login info, used for testing.
+var machineAuthenticate = func(ctx context.Context, config *daemoncfg.Config, server string) (*logininfo.LoginInfo, error) {
+	// create openconnect command:
+	//
+	// openconnect \
+	//   --protocol=anyconnect \
+	//   --useragent=AnyConnect \
+	//   --certificate="$CLIENT_CERT" \
+	//   --sslkey="$PRIVATE_KEY" \
+	//   --cafile="$CA_CERT" \
+	//   --xmlconfig="$XML_CONFIG" \
+	//   --authenticate \
+	//   --non-inter \
+	//   --quiet \
+	//   "$SERVER"
+	//
+	auth := config.MachineAuth
+	parameters := []string{
+		"--protocol=" + auth.Protocol,
+		"--useragent=" + auth.UserAgent,
+		"--certificate=" + auth.ClientCertificate,
+		"--sslkey=" + auth.ClientKey,
+		"--xmlconfig=" + config.OpenConnect.XMLProfile,
+		"--authenticate",
+		"--non-inter",
+		"--quiet",
+	}
+	if auth.CACertificate != "" {
+		parameters = append(parameters, "--cafile="+auth.CACertificate)
+	}
+	if config.OpenConnect.NoProxy {
+		parameters = append(parameters, "--no-proxy")
+	}
+	parameters = append(parameters, server)
+
+	// run command and buffer output
+	var stdout, stderr bytes.Buffer
+	command := execCommandContext(ctx, config.OpenConnect.OpenConnect, parameters...)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	command.Env = append(os.Environ(), config.OpenConnect.ExtraEnv...)
+	if err := command.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// parse login info
+	login := &logininfo.LoginInfo{Server: server}
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		login.ParseLine(scanner.Text())
+	}
+	if !login.Valid() {
+		return nil, errors.New("openconnect returned invalid login information")
+	}
+	return login, nil
+}
+
+// machineAuth authenticates on the VPN server with the machine certificate
+// in the background.
+type machineAuth struct {
+	config *daemoncfg.MachineAuth
+
+	// cancel cancels the running authentication,
+	// nil if no authentication is running
+	cancel context.CancelFunc
+
+	// results is the channel for the result of the running
+	// authentication, nil if no authentication is running
+	results chan *machineAuthResult
+}
+
+// start starts the authentication on server with config if no
+// authentication is running.
+func (m *machineAuth) start(config *daemoncfg.Config, server string) {
+	if m.active() {
+		return
+	}
+
+	// use buffered channel, so the result of a stopped authentication
+	// does not block
+	ctx, cancel := context.WithTimeout(context.Background(), m.config.Timeout)
+	results := make(chan *machineAuthResult, 1)
+	m.cancel = cancel
+	m.results = results
+	go func() {
+		defer cancel()
+		login, err := machineAuthenticate(ctx, config, server)
+		results <- &machineAuthResult{login: login, err: err}
+	}()
+}
+
+// stop stops the running authentication.
+func (m *machineAuth) stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.finished()
+}
+
+// finished marks the running authentication as finished.
+func (m *machineAuth) finished() {
+	m.cancel = nil
+	m.results = nil
+}
+
+// active returns whether an authentication is running.
+func (m *machineAuth) active() bool {
+	return m.results != nil
+}
+
+// resultsC returns the channel for the result of the running
+// authentication, nil if no authentication is running.
+func (m *machineAuth) resultsC() <-chan *machineAuthResult {
+	return m.results
+}
+
+// newMachineAuth returns a new machine authentication.
+func newMachineAuth(config *daemoncfg.MachineAuth) *machineAuth {
+	return &machineAuth{
+		config: config,
+	}
+}
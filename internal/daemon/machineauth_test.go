@@ -0,0 +1,123 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+)
+
+// TestMachineAuthenticate tests machineAuthenticate.
+func TestMachineAuthenticate(t *testing.T) {
+	defer func() { execCommandContext = exec.CommandContext }()
+
+	config := daemoncfg.NewConfig()
+	config.MachineAuth.ClientCertificate = "/test/cert"
+	config.MachineAuth.ClientKey = "/test/key"
+	config.MachineAuth.CACertificate = "/test/ca"
+
+	// test exec error
+	execCommandContext = func(ctx context.Context, _ string, _ ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "")
+	}
+	if _, err := machineAuthenticate(context.Background(), config, "server"); err == nil {
+		t.Error("authenticate should return error")
+	}
+
+	// test invalid login info
+	execCommandContext = func(ctx context.Context, _ string, _ ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "echo", "COOKIE=TestCookie")
+	}
+	if _, err := machineAuthenticate(context.Background(), config, "server"); err == nil {
+		t.Error("authenticate should return error")
+	}
+
+	// test valid login info
+	var args []string
+	execCommandContext = func(ctx context.Context, _ string, arg ...string) *exec.Cmd {
+		args = arg
+		return exec.CommandContext(ctx, "echo",
+			"COOKIE=TestCookie\nHOST=10.0.0.1\nFINGERPRINT=TestFingerprint")
+	}
+	got, err := machineAuthenticate(context.Background(), config, "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &logininfo.LoginInfo{
+		Server:      "server",
+		Cookie:      "TestCookie",
+		Host:        "10.0.0.1",
+		Fingerprint: "TestFingerprint",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, arg := range []string{
+		"--certificate=/test/cert",
+		"--sslkey=/test/key",
+		"--cafile=/test/ca",
+		"--authenticate",
+		"--non-inter",
+		"server",
+	} {
+		if !slices.Contains(args, arg) {
+			t.Errorf("argument %s missing in %v", arg, args)
+		}
+	}
+}
+
+// TestMachineAuthStartStop tests start, stop and finished of machineAuth.
+func TestMachineAuthStartStop(t *testing.T) {
+	oldMachineAuthenticate := machineAuthenticate
+	defer func() { machineAuthenticate = oldMachineAuthenticate }()
+
+	login := &logininfo.LoginInfo{Server: "server"}
+	machineAuthenticate = func(ctx context.Context, _ *daemoncfg.Config, server string) (*logininfo.LoginInfo, error) {
+		if server == "block" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return login, nil
+	}
+
+	config := daemoncfg.NewMachineAuth()
+	m := newMachineAuth(config)
+	if m.active() || m.resultsC() != nil {
+		t.Error("authentication should not be active")
+	}
+
+	// start and wait for result
+	m.start(daemoncfg.NewConfig(), "server")
+	if !m.active() {
+		t.Error("authentication should be active")
+	}
+	r := <-m.resultsC()
+	if r.err != nil || r.login != login {
+		t.Errorf("got %v, want %v", r, login)
+	}
+	m.finished()
+	if m.active() {
+		t.Error("authentication should not be active after finish")
+	}
+
+	// start and stop
+	m.start(daemoncfg.NewConfig(), "block")
+	m.stop()
+	if m.active() {
+		t.Error("authentication should not be active after stop")
+	}
+
+	// timeout
+	config.Timeout = time.Millisecond
+	m.start(daemoncfg.NewConfig(), "block")
+	r = <-m.resultsC()
+	if !errors.Is(r.err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", r.err, context.DeadlineExceeded)
+	}
+}
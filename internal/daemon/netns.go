@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/dbusapi"
+	"github.com/telekom-mms/oc-daemon/internal/dnsproxy"
+	"github.com/telekom-mms/oc-daemon/internal/vpnsetup"
+)
+
+// vpnsetupSetupNetNS is vpnsetup.SetupNetNS for testing.
+var vpnsetupSetupNetNS = vpnsetup.SetupNetNS
+
+// vpnsetupTeardownNetNS is vpnsetup.TeardownNetNS for testing.
+var vpnsetupTeardownNetNS = vpnsetup.TeardownNetNS
+
+// newDNSProxy returns a new DNS proxy for config. If the network namespace
+// is enabled, the DNS proxy listens in the network namespace.
+func newDNSProxy(config *daemoncfg.Config) *dnsproxy.Proxy {
+	if config.NetNS.Enabled {
+		return dnsproxy.NewNetNSProxy(config.DNSProxy, config.NetNS)
+	}
+	return dnsproxy.NewProxy(config.DNSProxy)
+}
+
+// setupNetNS sets up the network namespace if it is enabled.
+func (d *Daemon) setupNetNS(ctx context.Context) {
+	if !d.config.NetNS.Enabled {
+		return
+	}
+	if d.adopted == nil {
+		// network namespace of adopted VPN tunnel is still set up
+		log.WithField("netns", d.config.NetNS.Name).Info("Daemon setting up network namespace")
+		vpnsetupSetupNetNS(ctx, d.config)
+	}
+	d.setStatusNetNS(d.config.NetNS.Name)
+}
+
+// teardownNetNS tears down the network namespace if it is enabled and the
+// VPN tunnel does not keep running in it.
+func (d *Daemon) teardownNetNS(ctx context.Context) {
+	if !d.config.NetNS.Enabled || d.detached {
+		return
+	}
+	log.WithField("netns", d.config.NetNS.Name).Info("Daemon tearing down network namespace")
+	vpnsetupTeardownNetNS(ctx, d.config)
+	d.setStatusNetNS(dbusapi.NetNSInvalid)
+}
+
+// setStatusNetNS sets the network namespace in status.
+func (d *Daemon) setStatusNetNS(name string) {
+	if d.status.NetNS == name {
+		// network namespace not changed
+		return
+	}
+
+	// network namespace changed
+	log.WithField("NetNS", name).Info("Daemon changed NetNS status")
+	d.status.NetNS = name
+	d.dbus.SetProperty(dbusapi.PropertyNetNS, name)
+}
@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// TestNewDNSProxy tests newDNSProxy.
+func TestNewDNSProxy(t *testing.T) {
+	config := daemoncfg.NewConfig()
+	if p := newDNSProxy(config); p == nil {
+		t.Error("dns proxy should not be nil")
+	}
+
+	config.NetNS.Enabled = true
+	if p := newDNSProxy(config); p == nil {
+		t.Error("dns proxy in network namespace should not be nil")
+	}
+}
+
+// TestDaemonSetupTeardownNetNS tests setupNetNS and teardownNetNS of Daemon.
+func TestDaemonSetupTeardownNetNS(t *testing.T) {
+	oldSetup := vpnsetupSetupNetNS
+	oldTeardown := vpnsetupTeardownNetNS
+	defer func() {
+		vpnsetupSetupNetNS = oldSetup
+		vpnsetupTeardownNetNS = oldTeardown
+	}()
+	setups, teardowns := 0, 0
+	vpnsetupSetupNetNS = func(context.Context, *daemoncfg.Config) { setups++ }
+	vpnsetupTeardownNetNS = func(context.Context, *daemoncfg.Config) { teardowns++ }
+
+	// network namespace disabled
+	d := getTestDaemon()
+	d.setupNetNS(context.Background())
+	d.teardownNetNS(context.Background())
+	if setups != 0 || teardowns != 0 || d.status.NetNS != "" {
+		t.Errorf("got %d setups, %d teardowns, netns %q",
+			setups, teardowns, d.status.NetNS)
+	}
+
+	// network namespace enabled
+	d.config.NetNS.Enabled = true
+	d.setupNetNS(context.Background())
+	if setups != 1 || d.status.NetNS != "oc-daemon" {
+		t.Errorf("got %d setups, netns %q", setups, d.status.NetNS)
+	}
+	d.teardownNetNS(context.Background())
+	if teardowns != 1 || d.status.NetNS != "" {
+		t.Errorf("got %d teardowns, netns %q", teardowns, d.status.NetNS)
+	}
+}
@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+)
+
+// Network policy decisions.
+const (
+	netPolicyDecisionNone         = ""
+	netPolicyDecisionDisconnected = "vpn disconnected in trusted network"
+	netPolicyDecisionPaused       = "vpn paused in trusted network"
+	netPolicyDecisionResumed      = "paused vpn resumed in untrusted network"
+	netPolicyDecisionConnecting   = "connecting with stored login"
+	netPolicyDecisionNoLogin      = "no stored login, not connecting"
+	netPolicyDecisionGaveUp       = "reconnect given up in trusted network"
+	netPolicyDecisionMachineAuth  = "authenticating with machine certificate"
+	netPolicyDecisionAuthFailed   = "machine authentication failed"
+	netPolicyDecisionAuthConnect  = "connecting after machine authentication"
+)
+
+// netPolicy keeps the state of the trusted and untrusted network policies
+// in the XML profile.
+type netPolicy struct {
+	// login is the login info of the last VPN connection for connecting
+	// on network changes, nil if not available
+	login *logininfo.LoginInfo
+
+	// paused indicates whether the VPN connection is paused in a trusted
+	// network
+	paused bool
+}
+
+// connected stores the login info of the established VPN connection.
+func (n *netPolicy) connected(login *logininfo.LoginInfo) {
+	n.login = login.Copy()
+	n.paused = false
+}
+
+// pause marks the VPN connection as paused. It returns false if the VPN
+// connection cannot be resumed without login info.
+func (n *netPolicy) pause() bool {
+	n.paused = n.login != nil
+	return n.paused
+}
+
+// resume returns and clears the login info for resuming the paused VPN
+// connection, nil if the VPN connection is not paused.
+func (n *netPolicy) resume() *logininfo.LoginInfo {
+	if !n.paused {
+		return nil
+	}
+	n.paused = false
+	return n.login.Copy()
+}
+
+// getLogin returns the stored login info, nil if not available.
+func (n *netPolicy) getLogin() *logininfo.LoginInfo {
+	return n.login.Copy()
+}
+
+// clear clears the stored login info and the paused VPN connection.
+func (n *netPolicy) clear() {
+	n.login = nil
+	n.paused = false
+}
+
+// newNetPolicy returns a new network policy state.
+func newNetPolicy() *netPolicy {
+	return &netPolicy{}
+}
@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+)
+
+// TestNetPolicyPauseResume tests connected, pause and resume of netPolicy.
+func TestNetPolicyPauseResume(t *testing.T) {
+	n := newNetPolicy()
+
+	// without login
+	if n.pause() {
+		t.Error("pause should fail without login")
+	}
+	if n.resume() != nil {
+		t.Error("resume should return nil without pause")
+	}
+
+	// with login
+	login := &logininfo.LoginInfo{Server: "server"}
+	n.connected(login)
+	if n.resume() != nil {
+		t.Error("resume should return nil without pause")
+	}
+	if !n.pause() {
+		t.Error("pause should succeed with login")
+	}
+	got := n.resume()
+	if got == login || *got != *login {
+		t.Errorf("got %v, want copy of %v", got, login)
+	}
+	if n.paused || n.resume() != nil {
+		t.Error("connection should not be paused after resume")
+	}
+
+	// connected resets pause
+	n.pause()
+	n.connected(login)
+	if n.paused {
+		t.Error("connection should not be paused after connect")
+	}
+}
+
+// TestNetPolicyLogin tests getLogin and clear of netPolicy.
+func TestNetPolicyLogin(t *testing.T) {
+	n := newNetPolicy()
+	if n.getLogin() != nil {
+		t.Error("login should be nil")
+	}
+
+	login := &logininfo.LoginInfo{Server: "server"}
+	n.connected(login)
+	got := n.getLogin()
+	if got == login || *got != *login {
+		t.Errorf("got %v, want copy of %v", got, login)
+	}
+
+	n.pause()
+	n.clear()
+	if n.getLogin() != nil || n.paused {
+		t.Error("login and pause should be cleared")
+	}
+}
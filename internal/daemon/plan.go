@@ -0,0 +1,213 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/telekom-mms/oc-daemon/internal/backend"
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
+)
+
+// loadPlanVPNConfig loads the sample VPN configuration from file.
+func loadPlanVPNConfig(file string) (*daemoncfg.VPNConfig, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read VPN config: %w", err)
+	}
+	vpnconf := &daemoncfg.VPNConfig{}
+	if err := json.Unmarshal(b, vpnconf); err != nil {
+		return nil, fmt.Errorf("could not parse VPN config: %w", err)
+	}
+	if vpnconf.Empty() || !vpnconf.Valid() {
+		return nil, errors.New("invalid VPN config")
+	}
+	return vpnconf, nil
+}
+
+// loadPlanCommandLists loads the command templates, lists and hooks from the
+// files in config, unlike loadCommandLists it returns errors.
+func loadPlanCommandLists(config *daemoncfg.Config) error {
+	return cmdtmpl.Load(config.CommandLists.TemplatesFile,
+		config.CommandLists.ListsFile, config.CommandLists.HooksDir)
+}
+
+// planCmd is a command recorded in plan mode.
+type planCmd struct {
+	cmd   string
+	args  []string
+	stdin string
+}
+
+// quoteArg returns arg quoted for a shell if necessary.
+func quoteArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`;&|<>()*?[]#~") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// String returns the command as shell command line with stdin as here
+// document.
+func (p *planCmd) String() string {
+	fields := []string{quoteArg(p.cmd)}
+	for _, arg := range p.args {
+		fields = append(fields, quoteArg(arg))
+	}
+	line := strings.Join(fields, " ")
+	if p.stdin == "" {
+		return line + "\n"
+	}
+	stdin := p.stdin
+	if !strings.HasSuffix(stdin, "\n") {
+		stdin += "\n"
+	}
+	return line + " <<'EOF'\n" + stdin + "EOF\n"
+}
+
+// planData returns sample data for the command lists that need more than
+// the configuration: allowed devices, hosts, ports and split excludes.
+func planData(config *daemoncfg.Config) (devices []string, hosts, excludes []netip.Prefix, ports []uint16) {
+	devices = []string{config.VPNConfig.Device.Name}
+	for _, h := range config.TrafficPolicing.AllowedHosts {
+		if p, err := netip.ParsePrefix(h); err == nil {
+			hosts = append(hosts, p)
+			continue
+		}
+		if a, err := netip.ParseAddr(h); err == nil {
+			hosts = append(hosts, netip.PrefixFrom(a, a.BitLen()))
+		}
+	}
+	if g := config.VPNConfig.Gateway; g.IsValid() {
+		hosts = append(hosts, netip.PrefixFrom(g, g.BitLen()))
+	}
+	excludes = append(excludes, config.VPNConfig.Split.ExcludeIPv4...)
+	excludes = append(excludes, config.VPNConfig.Split.ExcludeIPv6...)
+	ports = config.TrafficPolicing.PortalPorts
+	return
+}
+
+// planHookData returns sample data for the hook command lists with the
+// status of a connected VPN.
+func planHookData(config *daemoncfg.Config) *hookData {
+	status := vpnstatus.New()
+	status.TrustedNetwork = vpnstatus.TrustedNetworkNotTrusted
+	status.ConnectionState = vpnstatus.ConnectionStateConnected
+	status.Device = config.VPNConfig.Device.Name
+	status.ServerIP = config.VPNConfig.Gateway.String()
+	status.CaptivePortal = vpnstatus.CaptivePortalNotDetected
+	if config.VPNConfig.IPv4.IsValid() {
+		status.IP = config.VPNConfig.IPv4.Addr().String()
+	} else if config.VPNConfig.IPv6.IsValid() {
+		status.IP = config.VPNConfig.IPv6.Addr().String()
+	}
+	return &hookData{Config: *config, Status: status}
+}
+
+// planList is a command list rendered in plan mode.
+type planList struct {
+	name   string
+	render func() error
+}
+
+// plan renders all command lists for config and writes the resulting
+// commands to w without running them. It returns the errors of all command
+// lists that could not be rendered.
+func plan(w io.Writer, config *daemoncfg.Config) error {
+	// record commands instead of running them
+	var cmds []*planCmd
+	oldRunCmd := cmdtmpl.RunCmd
+	defer func() { cmdtmpl.RunCmd = oldRunCmd }()
+	cmdtmpl.RunCmd = func(_ context.Context, cmd string, s string, arg ...string) ([]byte, []byte, error) {
+		cmds = append(cmds, &planCmd{cmd: cmd, args: arg, stdin: s})
+		return nil, nil, nil
+	}
+
+	// render command lists with the template backend
+	ctx := context.Background()
+	t := &backend.Template{}
+	devices, hosts, excludes, ports := planData(config)
+	hook := func(name string) func() error {
+		return func() error {
+			return cmdtmpl.RunCmds(ctx, name, planHookData(config))
+		}
+	}
+	netns := func(name string) error {
+		c, err := cmdtmpl.GetCmds(name, config)
+		if err != nil {
+			return err
+		}
+		for _, cmd := range c {
+			_, _, _ = cmd.Run(ctx)
+		}
+		return nil
+	}
+	lists := []*planList{
+		{cmdtmpl.TrafPolSetFilterRules, func() error { return t.SetFilterRules(ctx, config) }},
+		{cmdtmpl.TrafPolUnsetFilterRules, func() error { return t.UnsetFilterRules(ctx, config) }},
+		{cmdtmpl.TrafPolSetAllowedDevices, func() error { return t.SetAllowedDevices(ctx, config, devices) }},
+		{cmdtmpl.TrafPolSetAllowedHosts, func() error { return t.SetAllowedHosts(ctx, config, hosts) }},
+		{cmdtmpl.TrafPolSetAllowedPorts, func() error { return t.SetAllowedPorts(ctx, config, ports) }},
+		{cmdtmpl.TrafPolCleanup, func() error { return t.CleanupFilterRules(ctx, config) }},
+		{cmdtmpl.VPNSetupSetup, func() error { return t.SetupVPN(ctx, config) }},
+		{cmdtmpl.VPNSetupTeardown, func() error { return t.TeardownVPN(ctx, config) }},
+		{cmdtmpl.VPNSetupSetExcludes, func() error { return t.SetExcludes(ctx, config, excludes) }},
+		{cmdtmpl.VPNSetupSetDNS, func() error { return t.SetDNS(ctx, config) }},
+		{cmdtmpl.VPNSetupGetDNS, func() error { _, err := t.GetDNS(ctx, config); return err }},
+		{cmdtmpl.VPNSetupCleanup, func() error { return t.CleanupVPN(ctx, config) }},
+		{cmdtmpl.VPNSetupReset, func() error { return t.ResetVPN(ctx, config) }},
+		{cmdtmpl.VPNSetupSetupNetNS, func() error { return netns(cmdtmpl.VPNSetupSetupNetNS) }},
+		{cmdtmpl.VPNSetupTeardownNetNS, func() error { return netns(cmdtmpl.VPNSetupTeardownNetNS) }},
+	}
+	for _, name := range []string{
+		cmdtmpl.HookConnected,
+		cmdtmpl.HookDisconnected,
+		cmdtmpl.HookTrustedNetwork,
+		cmdtmpl.HookCaptivePortal,
+	} {
+		// hooks are optional
+		if cmdtmpl.HasCommandList(name) {
+			lists = append(lists, &planList{name, hook(name)})
+		}
+	}
+
+	var errs []error
+	for _, list := range lists {
+		cmds = nil
+		_, _ = fmt.Fprintf(w, "# %s\n", list.name)
+		if err := list.render(); err != nil {
+			_, _ = fmt.Fprintf(w, "# error: %v\n\n", err)
+			errs = append(errs, fmt.Errorf("%s: %w", list.name, err))
+			continue
+		}
+		for _, c := range cmds {
+			_, _ = fmt.Fprint(w, c)
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	return errors.Join(errs...)
+}
+
+// runPlan loads the sample VPN configuration from file into config and
+// writes the commands of all command lists to stdout.
+func runPlan(config *daemoncfg.Config, file string) error {
+	vpnconf, err := loadPlanVPNConfig(file)
+	if err != nil {
+		return fmt.Errorf("Daemon could not load plan: %w", err)
+	}
+	config.VPNConfig = vpnconf
+	if err := loadPlanCommandLists(config); err != nil {
+		return fmt.Errorf("Daemon could not load plan: %w", err)
+	}
+	if err := plan(os.Stdout, config); err != nil {
+		return fmt.Errorf("Daemon could not render all command lists: %w", err)
+	}
+	return nil
+}
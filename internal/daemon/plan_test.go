@@ -0,0 +1,174 @@
+package daemon
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// testPlanVPNConfig is a sample VPN config for plan tests.
+const testPlanVPNConfig = `{
+	"Gateway": "192.0.2.1",
+	"Device": {"Name": "tun0", "MTU": 1300},
+	"IPv4": "10.0.0.2/24",
+	"DNS": {"DefaultDomain": "example.com", "ServersIPv4": ["10.0.0.53"]},
+	"Split": {"ExcludeIPv4": ["192.168.0.0/16"]}
+}`
+
+// TestLoadPlanVPNConfig tests loadPlanVPNConfig.
+func TestLoadPlanVPNConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "vpnconfig.json")
+
+	// not existing file
+	if _, err := loadPlanVPNConfig(file); err == nil {
+		t.Error("not existing file should return error")
+	}
+
+	// invalid files
+	for _, content := range []string{
+		"invalid",
+		"{}",
+		`{"Gateway": "192.0.2.1"}`,
+	} {
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadPlanVPNConfig(file); err == nil {
+			t.Errorf("invalid file %q should return error", content)
+		}
+	}
+
+	// valid file
+	if err := os.WriteFile(file, []byte(testPlanVPNConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	vpnconf, err := loadPlanVPNConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vpnconf.Device.Name != "tun0" {
+		t.Errorf("got invalid VPN config %v", vpnconf)
+	}
+}
+
+// TestPlanCmdString tests String of planCmd.
+func TestPlanCmdString(t *testing.T) {
+	for _, test := range []struct {
+		cmd  *planCmd
+		want string
+	}{
+		{&planCmd{cmd: "ip", args: []string{"link", "set", "tun0", "up"}},
+			"ip link set tun0 up\n"},
+		{&planCmd{cmd: "nft", args: []string{"-f", "-"}, stdin: "flush ruleset"},
+			"nft -f - <<'EOF'\nflush ruleset\nEOF\n"},
+		{&planCmd{cmd: "nft", args: []string{"-f", "-"}, stdin: "flush ruleset\n"},
+			"nft -f - <<'EOF'\nflush ruleset\nEOF\n"},
+		{&planCmd{cmd: "resolvectl", args: []string{"domain", "tun0", "a b", "", "it's"}},
+			"resolvectl domain tun0 'a b' '' 'it'\\''s'\n"},
+	} {
+		if got := test.cmd.String(); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
+
+// TestPlan tests plan.
+func TestPlan(t *testing.T) {
+	defer cmdtmpl.Reset()
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "vpnconfig.json")
+	if err := os.WriteFile(file, []byte(testPlanVPNConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	vpnconf, err := loadPlanVPNConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// default command lists
+	config := daemoncfg.NewConfig()
+	config.VPNConfig = vpnconf
+	buf := &bytes.Buffer{}
+	if err := plan(buf, config); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# " + cmdtmpl.TrafPolSetFilterRules + "\nnft -f - <<'EOF'\n",
+		"add element inet oc-daemon-filter allowdevs { tun0 }\n",
+		"add element inet oc-daemon-filter allowhosts4 { 192.0.2.1/32 }\n",
+		"ip address add 10.0.0.2/24 dev tun0\n",
+		"add element inet oc-daemon-routing excludes4 { 192.168.0.0/16 }\n",
+		"resolvectl default-route tun0 yes\n",
+		"# " + cmdtmpl.VPNSetupTeardownNetNS + "\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("plan should contain %q", want)
+		}
+	}
+
+	if strings.Contains(buf.String(), "# "+cmdtmpl.HookConnected) {
+		t.Error("plan should not contain hooks that are not configured")
+	}
+
+	// hooks
+	config.CommandLists.HooksDir = filepath.Join(dir, "hooks.d")
+	if err := os.Mkdir(config.CommandLists.HooksDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(config.CommandLists.HooksDir, "hooks.json"), []byte(`[{
+		"Name": "HookConnected",
+		"Commands": [{"Line": "echo {{.Status.ConnectionState}} {{.Status.Device}} {{.Status.IP}}"}]
+	}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadPlanCommandLists(config); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := plan(buf, config); err != nil {
+		t.Fatal(err)
+	}
+	if want := "# " + cmdtmpl.HookConnected + "\necho connected tun0 10.0.0.2\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("plan should contain %q", want)
+	}
+
+	// RunCmd should be restored
+	if _, _, err := cmdtmpl.RunCmd(t.Context(), "", ""); err == nil {
+		t.Error("RunCmd should not be recording runner after plan")
+	}
+
+	// invalid command list
+	lists := filepath.Join(dir, "lists.json")
+	if err := os.WriteFile(lists, []byte(`[{
+		"Name": "VPNSetupSetDNS",
+		"Commands": [{"Line": "resolvectl dns {{.Invalid}}"}]
+	}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config.CommandLists.ListsFile = lists
+	if err := loadPlanCommandLists(config); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := plan(buf, config); err == nil {
+		t.Error("invalid command list should return error")
+	}
+	if !strings.Contains(buf.String(), "# "+cmdtmpl.VPNSetupSetDNS+"\n# error: ") {
+		t.Error("plan should contain error of invalid command list")
+	}
+
+	// invalid command lists file
+	if err := os.WriteFile(lists, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadPlanCommandLists(config); err == nil {
+		t.Error("invalid command lists file should return error")
+	}
+}
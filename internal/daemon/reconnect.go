@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+)
+
+// Reconnect give up reasons.
+const (
+	reconnectReasonMaxAttempts    = "maximum reconnect attempts reached"
+	reconnectReasonTrustedNetwork = "trusted network detected"
+)
+
+// reconnect supervises a VPN connection and schedules reconnect attempts
+// with exponential backoff after unexpected exits of openconnect.
+type reconnect struct {
+	config *daemoncfg.Reconnect
+
+	// login is the login info of the supervised connection,
+	// nil if no connection is supervised
+	login *logininfo.LoginInfo
+
+	// attempts is the number of reconnect attempts since the last
+	// successful connection
+	attempts uint32
+
+	// timer is the timer of the next reconnect attempt,
+	// nil if no reconnect attempt is scheduled
+	timer *time.Timer
+}
+
+// stopTimer stops the timer of the next reconnect attempt.
+func (r *reconnect) stopTimer() {
+	if r.timer == nil {
+		return
+	}
+	r.timer.Stop()
+	r.timer = nil
+}
+
+// start starts supervising the connection with login info.
+func (r *reconnect) start(login *logininfo.LoginInfo) {
+	r.stop()
+	if !r.config.Enabled {
+		return
+	}
+	r.login = login.Copy()
+}
+
+// stop stops supervising the connection.
+func (r *reconnect) stop() {
+	r.stopTimer()
+	r.login = nil
+	r.attempts = 0
+}
+
+// active returns whether a connection is supervised.
+func (r *reconnect) active() bool {
+	return r.login != nil
+}
+
+// connected resets the reconnect attempts after a successful connection.
+func (r *reconnect) connected() {
+	r.stopTimer()
+	r.attempts = 0
+}
+
+// backoff returns the backoff duration of the current reconnect attempt.
+func (r *reconnect) backoff() time.Duration {
+	backoff := r.config.InitialBackoff
+	for i := uint32(1); i < r.attempts; i++ {
+		backoff *= 2
+		if backoff >= r.config.MaxBackoff {
+			return r.config.MaxBackoff
+		}
+	}
+	return min(backoff, r.config.MaxBackoff)
+}
+
+// schedule schedules the next reconnect attempt and returns its time. It
+// returns false if the maximum number of reconnect attempts is reached.
+func (r *reconnect) schedule() (time.Time, bool) {
+	if r.attempts >= uint32(r.config.MaxAttempts) {
+		return time.Time{}, false
+	}
+	r.stopTimer()
+	r.attempts++
+	backoff := r.backoff()
+	r.timer = time.NewTimer(backoff)
+	return time.Now().Add(backoff), true
+}
+
+// fired marks the timer of the scheduled reconnect attempt as expired.
+func (r *reconnect) fired() {
+	r.timer = nil
+}
+
+// getLogin returns a copy of the login info of the supervised connection.
+func (r *reconnect) getLogin() *logininfo.LoginInfo {
+	return r.login.Copy()
+}
+
+// timerC returns the timer channel of the next reconnect attempt,
+// nil if no reconnect attempt is scheduled.
+func (r *reconnect) timerC() <-chan time.Time {
+	if r.timer == nil {
+		return nil
+	}
+	return r.timer.C
+}
+
+// newReconnect returns a new reconnect supervisor.
+func newReconnect(config *daemoncfg.Reconnect) *reconnect {
+	return &reconnect{
+		config: config,
+	}
+}
@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+)
+
+// TestReconnectStartStop tests start and stop of reconnect.
+func TestReconnectStartStop(t *testing.T) {
+	// enabled
+	r := newReconnect(daemoncfg.NewReconnect())
+	login := &logininfo.LoginInfo{Server: "server"}
+	r.start(login)
+	if !r.active() {
+		t.Error("reconnect should be active")
+	}
+	if r.getLogin() == login {
+		t.Error("reconnect should copy login info")
+	}
+
+	r.stop()
+	if r.active() {
+		t.Error("reconnect should not be active")
+	}
+
+	// disabled
+	config := daemoncfg.NewReconnect()
+	config.Enabled = false
+	r = newReconnect(config)
+	r.start(login)
+	if r.active() {
+		t.Error("disabled reconnect should not be active")
+	}
+}
+
+// TestReconnectBackoff tests backoff of reconnect.
+func TestReconnectBackoff(t *testing.T) {
+	config := &daemoncfg.Reconnect{
+		Enabled:        true,
+		MaxAttempts:    10,
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+	}
+	r := newReconnect(config)
+	for i, want := range []time.Duration{
+		time.Second,
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		10 * time.Second,
+		10 * time.Second,
+	} {
+		r.attempts = uint32(i)
+		got := r.backoff()
+		if got != want {
+			t.Errorf("%d: got %s, want %s", i, got, want)
+		}
+	}
+}
+
+// TestReconnectSchedule tests schedule of reconnect.
+func TestReconnectSchedule(t *testing.T) {
+	config := &daemoncfg.Reconnect{
+		Enabled:        true,
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}
+	r := newReconnect(config)
+	r.start(&logininfo.LoginInfo{})
+	if r.timerC() != nil {
+		t.Error("timer should not be set")
+	}
+
+	// schedule attempts until maximum is reached
+	for i := range 2 {
+		if _, ok := r.schedule(); !ok {
+			t.Fatalf("%d: attempt should be scheduled", i)
+		}
+		<-r.timerC()
+		r.fired()
+	}
+	if _, ok := r.schedule(); ok {
+		t.Error("attempt should not be scheduled after maximum attempts")
+	}
+
+	// reset attempts after successful connection
+	r.connected()
+	if r.attempts != 0 {
+		t.Errorf("got attempts %d, want 0", r.attempts)
+	}
+	if _, ok := r.schedule(); !ok {
+		t.Error("attempt should be scheduled after connection")
+	}
+	r.stop()
+	if r.timerC() != nil {
+		t.Error("timer should not be set after stop")
+	}
+}
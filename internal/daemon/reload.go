@@ -0,0 +1,257 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/configmon"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/dbusapi"
+	"github.com/telekom-mms/oc-daemon/internal/profilemon"
+	"github.com/telekom-mms/oc-daemon/internal/vpnsetup"
+)
+
+// vpnsetupNewVPNSetup is vpnsetup.NewVPNSetup with a new DNS proxy for testing.
+var vpnsetupNewVPNSetup = func(c *daemoncfg.Config) vpnsetup.Setup {
+	return vpnsetup.NewVPNSetup(newDNSProxy(c))
+}
+
+// profilemonNewProfileMon is profilemon.NewProfileMon for testing.
+var profilemonNewProfileMon = func(file string) profilemon.Monitor {
+	return profilemon.NewProfileMon(file)
+}
+
+// configmonNewConfigMon is configmon.NewConfigMon for testing.
+var configmonNewConfigMon = func(files ...string) configmon.Monitor {
+	return configmon.NewConfigMon(files...)
+}
+
+// getConfigMonFiles returns the files watched by the config monitor.
+func getConfigMonFiles(config *daemoncfg.Config) []string {
+	return []string{
+		config.Config,
+		config.CommandLists.TemplatesFile,
+		config.CommandLists.ListsFile,
+	}
+}
+
+// getCommandListsState returns the current command templates and lists as
+// string to detect changes.
+func getCommandListsState() string {
+	templates, lists := cmdtmpl.Get()
+	b, err := json.Marshal(lists)
+	if err != nil {
+		log.WithError(err).Error("Daemon could not convert command lists to JSON")
+	}
+	return templates + string(b)
+}
+
+// readConfig reads the config from the config file. It returns nil if the
+// config could not be read or is invalid.
+func (d *Daemon) readConfig() *daemoncfg.Config {
+	config := daemoncfg.NewConfig()
+	config.Config = d.config.Config
+	if err := config.Load(); err != nil {
+		log.WithError(err).WithField("config", config.Config).
+			Error("Daemon could not reload config, keeping current config")
+		return nil
+	}
+	if !config.Valid() {
+		log.WithField("config", config.Config).
+			Error("Daemon reloaded invalid config, keeping current config")
+		return nil
+	}
+	if !reflect.DeepEqual(config.Executables, d.config.Executables) {
+		if err := config.Executables.CheckExecutables(); err != nil {
+			log.WithError(err).WithField("config", config.Config).
+				Error("Daemon could not find all executables in reloaded config, keeping current config")
+			return nil
+		}
+	}
+	return config
+}
+
+// applyConfig applies the reloaded config and restarts the affected
+// components. If tunnel is not set, the sections used by an active VPN
+// tunnel and the command lists are not changed.
+func (d *Daemon) applyConfig(config *daemoncfg.Config, tunnel bool) error {
+	old := d.config
+	config = config.Copy()
+
+	// keep settings that cannot be reloaded and runtime state
+	if !reflect.DeepEqual(old.SocketServer, config.SocketServer) {
+		log.Warn("Daemon cannot reload SocketServer config, restart required")
+	}
+	if !reflect.DeepEqual(old.Polkit, config.Polkit) {
+		log.Warn("Daemon cannot reload Polkit config, restart required")
+	}
+	if !reflect.DeepEqual(old.NetNS, config.NetNS) {
+		log.Warn("Daemon cannot reload NetNS config, restart required")
+	}
+	config.Verbose = old.Verbose
+	config.SocketServer = old.SocketServer
+	config.Polkit = old.Polkit
+	config.NetNS = old.NetNS
+	config.LoginInfo = old.LoginInfo
+	config.VPNConfig = old.VPNConfig
+
+	// keep sections used by active VPN tunnels
+	if !tunnel {
+		config.OpenConnect = old.OpenConnect
+		config.Executables = old.Executables
+		config.SplitRouting = old.SplitRouting
+		config.DNSProxy = old.DNSProxy
+		config.CommandLists = old.CommandLists
+		config.Connections = old.Connections
+	}
+	d.config = config
+
+	// recreate named VPN connections with the new config, no VPN tunnel
+	// is active
+	if tunnel {
+		d.conns.stop()
+		d.conns = newConnections(config)
+		d.conns.start()
+		d.setStatusConnections()
+	}
+
+	// reload command lists
+	commandListsChanged := false
+	if tunnel {
+		state := getCommandListsState()
+		loadCommandLists(config)
+		commandListsChanged = state != getCommandListsState()
+	}
+
+	// check changed sections
+	changed := func(a, b any) bool { return !reflect.DeepEqual(a, b) }
+	restartTrafPol := commandListsChanged ||
+		changed(old.TrafficPolicing, config.TrafficPolicing) ||
+		changed(old.CPD, config.CPD) ||
+		changed(old.Executables, config.Executables) ||
+		changed(old.SplitRouting, config.SplitRouting)
+	restartTND := changed(old.TND, config.TND) ||
+		changed(old.SplitRouting, config.SplitRouting)
+
+	// update reconnect supervisor
+	d.reconnect.config = config.Reconnect
+	if !config.Reconnect.Enabled && d.reconnect.active() {
+		d.stopReconnect()
+	}
+
+	// update connection history
+	d.history.config = config.History
+
+	// update resume policy
+	d.resume.config = config.Resume
+
+	// update connect failure policy
+	d.connfail.config = config.ConnectFailure
+
+	// update machine authentication
+	d.machineauth.config = config.MachineAuth
+	if !config.MachineAuth.Enabled && d.machineauth.active() {
+		d.machineauth.stop()
+	}
+
+	// update idle monitor
+	d.idle.config = config.IdleTimeout
+	if !config.IdleTimeout.Enabled && d.idle.active() {
+		d.stopIdle()
+	}
+
+	// restart metrics server with changed settings
+	if changed(old.Metrics, config.Metrics) {
+		d.stopMetrics()
+		if err := d.startMetrics(); err != nil {
+			// metrics are optional, keep the daemon running
+			log.WithError(err).Error("Daemon could not restart metrics server")
+		}
+	}
+
+	// restart config monitor with changed files
+	if changed(getConfigMonFiles(old), getConfigMonFiles(config)) {
+		cfgmon := configmonNewConfigMon(getConfigMonFiles(config)...)
+		if err := cfgmon.Start(); err != nil {
+			return fmt.Errorf("Daemon could not restart ConfigMon: %w", err)
+		}
+		d.cfgmon.Stop()
+		d.cfgmon = cfgmon
+	}
+
+	// restart profile monitor with changed xml profile
+	if old.OpenConnect.XMLProfile != config.OpenConnect.XMLProfile {
+		profmon := profilemonNewProfileMon(config.OpenConnect.XMLProfile)
+		if err := profmon.Start(); err != nil {
+			return fmt.Errorf("Daemon could not restart ProfileMon: %w", err)
+		}
+		d.profmon.Stop()
+		d.profmon = profmon
+		d.profile = readXMLProfile(config.OpenConnect.XMLProfile)
+		d.setStatusServers(d.profile.GetVPNServerHostNames())
+		d.checkDisconnectPolicy()
+		d.dbus.EmitSignal(dbusapi.SignalProfileReloaded)
+		restartTrafPol = true
+		restartTND = true
+	}
+
+	// restart VPN setup with changed DNS proxy
+	if changed(old.DNSProxy, config.DNSProxy) {
+		log.Info("Daemon restarting VPN setup with new DNS proxy config")
+		d.vpnsetup.Stop()
+		d.vpnsetup = vpnsetupNewVPNSetup(config)
+		d.vpnsetup.Start()
+	}
+
+	// restart traffic policing and TND
+	if restartTND {
+		d.stopTND()
+	}
+	if restartTrafPol {
+		d.stopTrafPol()
+	}
+	if err := d.checkTrafPol(); err != nil {
+		return err
+	}
+	if err := d.checkTND(); err != nil {
+		return err
+	}
+
+	log.WithField("config", config).Info("Daemon applied reloaded config")
+	return nil
+}
+
+// handleConfigReload handles a reload of the config.
+func (d *Daemon) handleConfigReload() error {
+	log.Info("Daemon reloading config")
+	config := d.readConfig()
+	if config == nil {
+		return nil
+	}
+
+	if d.tunnelsActive() {
+		// VPN tunnel active, apply tunnel sections after disconnect
+		log.Info("Daemon deferring VPN tunnel config changes until disconnect")
+		d.pendingConfig = config
+		return d.applyConfig(config, false)
+	}
+
+	d.pendingConfig = nil
+	return d.applyConfig(config, true)
+}
+
+// applyPendingConfig applies the config changes deferred during active VPN
+// tunnels after all VPN tunnels are disconnected.
+func (d *Daemon) applyPendingConfig() error {
+	if d.pendingConfig == nil || d.tunnelsActive() {
+		return nil
+	}
+
+	log.Info("Daemon applying deferred VPN tunnel config changes")
+	config := d.pendingConfig
+	d.pendingConfig = nil
+	return d.applyConfig(config, true)
+}
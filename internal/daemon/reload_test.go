@@ -0,0 +1,227 @@
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/configmon"
+	"github.com/telekom-mms/oc-daemon/internal/cpd"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/profilemon"
+	"github.com/telekom-mms/oc-daemon/internal/trafpol"
+	"github.com/telekom-mms/oc-daemon/internal/vpnsetup"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
+)
+
+// writeReloadTestConfig writes config to its config file for reload testing.
+func writeReloadTestConfig(t *testing.T, config *daemoncfg.Config) {
+	b, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config.Config, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDaemonHandleConfigReload tests handleConfigReload of Daemon.
+func TestDaemonHandleConfigReload(t *testing.T) {
+	// set testing functions and cleanup after tests
+	oldTrafPolNewTafPol := trafpolNewTrafPol
+	defer func() { trafpolNewTrafPol = oldTrafPolNewTafPol }()
+	trafPols := 0
+	trafpolNewTrafPol = func(*daemoncfg.Config) trafpol.Policer {
+		trafPols++
+		return &trafPolicer{s: make(chan *cpd.Report)}
+	}
+	oldVPNSetupNewVPNSetup := vpnsetupNewVPNSetup
+	defer func() { vpnsetupNewVPNSetup = oldVPNSetupNewVPNSetup }()
+	vpnSetups := 0
+	vpnsetupNewVPNSetup = func(*daemoncfg.Config) vpnsetup.Setup {
+		vpnSetups++
+		return &vpnSetup{}
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "oc-daemon.json")
+
+	// not existing config file, keep config
+	d := getTestDaemon()
+	d.config.Config = file
+	old := d.config
+	if err := d.handleConfigReload(); err != nil {
+		t.Fatal(err)
+	}
+	if d.config != old {
+		t.Error("config should not change with missing config file")
+	}
+
+	// invalid config, keep config
+	config := daemoncfg.NewConfig()
+	config.Config = file
+	config.Reconnect.MaxAttempts = 0
+	writeReloadTestConfig(t, config)
+	if err := d.handleConfigReload(); err != nil {
+		t.Fatal(err)
+	}
+	if d.config != old {
+		t.Error("config should not change with invalid config")
+	}
+
+	// valid config, changed traffic policing, restart trafpol
+	d = getTestDaemon()
+	d.config.Config = file
+	d.profile.AutomaticVPNPolicy.AlwaysOn.Flag = true
+	d.trafpol = nil
+	if err := d.checkTrafPol(); err != nil {
+		t.Fatal(err)
+	}
+	config = daemoncfg.NewConfig()
+	config.Config = file
+	config.TrafficPolicing.AllowedHosts = []string{"reloaded.example.com"}
+	writeReloadTestConfig(t, config)
+	trafPols = 0
+	if err := d.handleConfigReload(); err != nil {
+		t.Fatal(err)
+	}
+	if trafPols != 1 {
+		t.Errorf("got %d trafpol restarts, want 1", trafPols)
+	}
+	if d.config.TrafficPolicing.AllowedHosts[0] != "reloaded.example.com" {
+		t.Errorf("got %v, want reloaded allowed hosts",
+			d.config.TrafficPolicing.AllowedHosts)
+	}
+
+	// unchanged config, do not restart trafpol
+	trafPols = 0
+	if err := d.handleConfigReload(); err != nil {
+		t.Fatal(err)
+	}
+	if trafPols != 0 {
+		t.Errorf("got %d trafpol restarts, want 0", trafPols)
+	}
+
+	// changed dns proxy with active tunnel, defer until disconnect
+	d = getTestDaemon()
+	d.config.Config = file
+	d.status.OCRunning = vpnstatus.OCRunningRunning
+	config = daemoncfg.NewConfig()
+	config.Config = file
+	config.DNSProxy.Address = "127.0.0.1:4254"
+	writeReloadTestConfig(t, config)
+	vpnSetups = 0
+	if err := d.handleConfigReload(); err != nil {
+		t.Fatal(err)
+	}
+	if vpnSetups != 0 || d.config.DNSProxy.Address == config.DNSProxy.Address {
+		t.Error("dns proxy config should not change with active tunnel")
+	}
+	if d.pendingConfig == nil {
+		t.Fatal("pending config should be set with active tunnel")
+	}
+
+	// tunnel of named connection still active, keep pending config
+	d.status.OCRunning = vpnstatus.OCRunningNotRunning
+	d.conns.conns["lab"] = &namedConnection{
+		status: &vpnstatus.Connection{OCRunning: vpnstatus.OCRunningRunning},
+	}
+	if err := d.applyPendingConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if vpnSetups != 0 || d.pendingConfig == nil {
+		t.Error("pending config should not be applied with active tunnel")
+	}
+
+	// all tunnels disconnected, apply pending config
+	delete(d.conns.conns, "lab")
+	if err := d.applyPendingConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if vpnSetups != 1 || d.config.DNSProxy.Address != config.DNSProxy.Address {
+		t.Error("dns proxy config should change after disconnect")
+	}
+	if d.pendingConfig != nil {
+		t.Error("pending config should be reset after disconnect")
+	}
+}
+
+// TestDaemonApplyConfigMonitors tests applyConfig of Daemon, monitors.
+func TestDaemonApplyConfigMonitors(t *testing.T) {
+	// set testing functions and cleanup after tests
+	oldProfileMonNewProfileMon := profilemonNewProfileMon
+	defer func() { profilemonNewProfileMon = oldProfileMonNewProfileMon }()
+	profilemonNewProfileMon = func(string) profilemon.Monitor {
+		return &profMonitor{u: make(chan struct{})}
+	}
+	oldConfigMonNewConfigMon := configmonNewConfigMon
+	defer func() { configmonNewConfigMon = oldConfigMonNewConfigMon }()
+	configmonNewConfigMon = func(...string) configmon.Monitor {
+		return &cfgMonitor{u: make(chan struct{})}
+	}
+
+	// changed xml profile and command lists files
+	d := getTestDaemon()
+	profmon := d.profmon
+	cfgmon := d.cfgmon
+	config := d.config.Copy()
+	config.OpenConnect.XMLProfile = filepath.Join(t.TempDir(), "profile.xml")
+	config.CommandLists.ListsFile = filepath.Join(t.TempDir(), "lists.json")
+	if err := d.applyConfig(config, true); err != nil {
+		t.Fatal(err)
+	}
+	if d.profmon == profmon {
+		t.Error("profile monitor should be restarted")
+	}
+	if d.cfgmon == cfgmon {
+		t.Error("config monitor should be restarted")
+	}
+
+	// disabled reconnect, stop reconnecting
+	d = getTestDaemon()
+	d.reconnect.login = d.config.LoginInfo
+	config = d.config.Copy()
+	config.Reconnect.Enabled = false
+	if err := d.applyConfig(config, true); err != nil {
+		t.Fatal(err)
+	}
+	if d.reconnect.active() {
+		t.Error("reconnect should be stopped")
+	}
+}
+
+// TestDaemonApplyConfigMetrics tests applyConfig of Daemon, metrics.
+func TestDaemonApplyConfigMetrics(t *testing.T) {
+	// enabled metrics, start metrics server
+	d := getTestDaemon()
+	config := d.config.Copy()
+	config.Metrics.Enabled = true
+	config.Metrics.SocketFile = filepath.Join(t.TempDir(), "metrics.sock")
+	if err := d.applyConfig(config, true); err != nil {
+		t.Fatal(err)
+	}
+	if d.metricsrv == nil {
+		t.Fatal("metrics server should be started")
+	}
+
+	// invalid address, keep running without metrics server
+	config = d.config.Copy()
+	config.Metrics.SocketFile = filepath.Join(t.TempDir(), "does-not-exist", "metrics.sock")
+	if err := d.applyConfig(config, true); err != nil {
+		t.Fatal(err)
+	}
+	if d.metricsrv != nil {
+		t.Error("metrics server should not be started")
+	}
+
+	// disabled metrics
+	config = d.config.Copy()
+	config.Metrics.Enabled = false
+	if err := d.applyConfig(config, true); err != nil {
+		t.Fatal(err)
+	}
+	if d.metricsrv != nil {
+		t.Error("metrics server should be stopped")
+	}
+}
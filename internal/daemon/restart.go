@@ -0,0 +1,173 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/ocrunner"
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnconfig"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnhistory"
+)
+
+// restartState is the state of the VPN tunnel that is kept running during a
+// restart of the daemon including its open session in the connection
+// history.
+type restartState struct {
+	PID         uint32
+	LoginInfo   *logininfo.LoginInfo
+	VPNConfig   *vpnconfig.Config
+	ConnectedAt int64
+	Session     *vpnhistory.Session `json:",omitempty"`
+}
+
+// valid returns whether the restart state is valid.
+func (r *restartState) valid() bool {
+	return r.PID != 0 &&
+		r.LoginInfo.Valid() &&
+		r.VPNConfig != nil &&
+		!r.VPNConfig.Empty() &&
+		r.VPNConfig.Valid()
+}
+
+// loadRestartState loads the restart state from file.
+func loadRestartState(file string) (*restartState, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	state := &restartState{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, err
+	}
+	if !state.valid() {
+		return nil, errors.New("invalid restart state")
+	}
+	return state, nil
+}
+
+// saveRestartState saves the restart state to file. The file contains the
+// login information, so it is only readable by the owner.
+func saveRestartState(file string, state *restartState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("could not convert restart state to JSON: %w", err)
+	}
+
+	// write state to temporary file and rename it
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("could not create restart state dir: %w", err)
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(file)+".tmp")
+	if err != nil {
+		return fmt.Errorf("could not create restart state file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("could not write restart state file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("could not close restart state file: %w", err)
+	}
+	if err := os.Rename(f.Name(), file); err != nil {
+		return fmt.Errorf("could not rename restart state file: %w", err)
+	}
+	return nil
+}
+
+// ocrunnerRunningPID is ocrunner.RunningPID for testing.
+var ocrunnerRunningPID = ocrunner.RunningPID
+
+// loadAdoption loads the state of the VPN tunnel kept running during the
+// restart of the daemon and checks if its openconnect process can be
+// adopted.
+func (d *Daemon) loadAdoption() {
+	if !d.config.GracefulRestart.Enabled {
+		return
+	}
+
+	// the restart state is only used once
+	file := d.config.GracefulRestart.StateFile
+	state, err := loadRestartState(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err := os.Remove(file); err != nil {
+		log.WithError(err).WithField("file", file).
+			Error("Daemon could not remove restart state")
+	}
+	if err != nil {
+		log.WithError(err).WithField("file", file).
+			Error("Daemon could not load restart state")
+		return
+	}
+
+	// check openconnect process
+	if pid := ocrunnerRunningPID(d.config.OpenConnect); pid != state.PID {
+		log.WithField("pid", state.PID).
+			Warn("Daemon could not adopt openconnect process, not running any more")
+		return
+	}
+	d.adopted = state
+}
+
+// adoptVPN adopts the VPN tunnel kept running during the restart of the
+// daemon and sets up its configuration again.
+func (d *Daemon) adoptVPN() {
+	state := d.adopted
+	log.WithFields(log.Fields{
+		"pid":    state.PID,
+		"server": state.LoginInfo.Server,
+	}).Info("Daemon adopting VPN tunnel after restart")
+
+	// adopt openconnect process and set up VPN config
+	d.setStatusConnecting(state.LoginInfo)
+	d.config.LoginInfo = state.LoginInfo
+	d.runner.Adopt(d.config.Copy(), state.PID)
+	d.updateVPNConfigUp(state.VPNConfig)
+	d.setStatusConnectedAt(state.ConnectedAt)
+
+	// the VPN tunnel is still the same session in the connection history
+	if state.Session != nil {
+		d.history.resume(state.Session)
+	}
+
+	// supervise connection for reconnects
+	d.startReconnect(state.LoginInfo)
+}
+
+// detachVPN detaches the connected VPN tunnel when the daemon is stopped,
+// so it keeps running during the restart of the daemon.
+func (d *Daemon) detachVPN() {
+	if !d.config.GracefulRestart.Enabled ||
+		!d.status.OCRunning.Running() ||
+		!d.status.ConnectionState.Connected() ||
+		d.status.VPNConfig == nil {
+		return
+	}
+
+	state := &restartState{
+		PID:         d.status.OCPID,
+		LoginInfo:   d.config.LoginInfo,
+		VPNConfig:   d.status.VPNConfig,
+		ConnectedAt: d.status.ConnectedAt,
+		Session:     d.history.current,
+	}
+	file := d.config.GracefulRestart.StateFile
+	if err := saveRestartState(file, state); err != nil {
+		log.WithError(err).WithField("file", file).
+			Error("Daemon could not save restart state, stopping VPN tunnel")
+		return
+	}
+
+	d.runner.Detach()
+	d.detached = true
+	log.WithField("pid", state.PID).Info("Daemon keeping VPN tunnel running during restart")
+}
@@ -0,0 +1,227 @@
+package daemon
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnconfig"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnhistory"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
+)
+
+// getTestRestartState returns a valid restart state for testing.
+func getTestRestartState() *restartState {
+	return &restartState{
+		PID: 123,
+		LoginInfo: &logininfo.LoginInfo{
+			Server:      "vpn.example.com",
+			Cookie:      "cookie",
+			Host:        "10.0.0.1",
+			Fingerprint: "fingerprint",
+		},
+		VPNConfig: &vpnconfig.Config{
+			Gateway: net.ParseIP("10.0.0.1"),
+			Device: vpnconfig.Device{
+				Name: "oc-daemon-tun0",
+				MTU:  1300,
+			},
+			IPv4: vpnconfig.Address{
+				Address: net.ParseIP("192.168.1.1"),
+				Netmask: net.CIDRMask(24, 32),
+			},
+			DNS: vpnconfig.DNS{
+				ServersIPv4: []net.IP{net.ParseIP("192.168.1.2")},
+			},
+		},
+		ConnectedAt: 1700000000,
+		Session: &vpnhistory.Session{
+			Server:      "vpn.example.com",
+			ServerIP:    "10.0.0.1",
+			IP:          "192.168.1.1",
+			StartedAt:   1699999990,
+			ConnectedAt: 1700000000,
+			ExitCode:    -1,
+		},
+	}
+}
+
+// TestRestartStateSaveLoad tests saveRestartState and loadRestartState.
+func TestRestartStateSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "restart", "restart.json")
+
+	// not existing file
+	if _, err := loadRestartState(file); err == nil {
+		t.Error("not existing file should return error")
+	}
+
+	// valid state
+	want := getTestRestartState()
+	if err := saveRestartState(file, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadRestartState(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if fi, err := os.Stat(file); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("got invalid file mode %v %v", fi, err)
+	}
+
+	// invalid states
+	for _, content := range []string{
+		"invalid",
+		"{}",
+		`{"PID":123}`,
+	} {
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadRestartState(file); err == nil {
+			t.Errorf("invalid state %s should return error", content)
+		}
+	}
+
+	// invalid dir
+	if err := saveRestartState(filepath.Join(file, "restart.json"), want); err == nil {
+		t.Error("invalid dir should return error")
+	}
+}
+
+// TestDaemonLoadAdoption tests loadAdoption of Daemon.
+func TestDaemonLoadAdoption(t *testing.T) {
+	oldRunningPID := ocrunnerRunningPID
+	defer func() { ocrunnerRunningPID = oldRunningPID }()
+	pid := uint32(0)
+	ocrunnerRunningPID = func(*daemoncfg.OpenConnect) uint32 { return pid }
+
+	d := getTestDaemon()
+	d.config.GracefulRestart.StateFile = filepath.Join(t.TempDir(), "restart.json")
+	file := d.config.GracefulRestart.StateFile
+	state := getTestRestartState()
+
+	// graceful restart disabled
+	if err := saveRestartState(file, state); err != nil {
+		t.Fatal(err)
+	}
+	d.loadAdoption()
+	if d.adopted != nil {
+		t.Error("state should not be adopted when disabled")
+	}
+
+	// openconnect not running, state file is removed
+	d.config.GracefulRestart.Enabled = true
+	d.loadAdoption()
+	if d.adopted != nil {
+		t.Error("state should not be adopted without openconnect")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("state file should be removed")
+	}
+
+	// no state file
+	d.loadAdoption()
+	if d.adopted != nil {
+		t.Error("state should not be adopted without state file")
+	}
+
+	// invalid state file is removed
+	if err := os.WriteFile(file, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	d.loadAdoption()
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("invalid state file should be removed")
+	}
+
+	// openconnect running
+	if err := saveRestartState(file, state); err != nil {
+		t.Fatal(err)
+	}
+	pid = state.PID
+	d.loadAdoption()
+	if !reflect.DeepEqual(d.adopted, state) {
+		t.Errorf("got %v, want %v", d.adopted, state)
+	}
+}
+
+// TestDaemonAdoptVPN tests adoptVPN of Daemon.
+func TestDaemonAdoptVPN(t *testing.T) {
+	d := getTestDaemon()
+	d.adopted = getTestRestartState()
+	d.adoptVPN()
+
+	runner := d.runner.(*ocRunner)
+	if runner.adopted != 123 {
+		t.Errorf("got adopted pid %d, want 123", runner.adopted)
+	}
+	if !d.status.OCRunning.Running() ||
+		!d.status.ConnectionState.Connected() ||
+		d.status.Server != "vpn.example.com" ||
+		d.status.ServerIP != "10.0.0.1" ||
+		d.status.Device != "oc-daemon-tun0" ||
+		d.status.IP != "192.168.1.1" ||
+		d.status.ConnectedAt != 1700000000 {
+		t.Errorf("got invalid status %v", d.status)
+	}
+	if !d.reconnect.active() {
+		t.Error("reconnect should be active")
+	}
+
+	// session in connection history should be resumed
+	if !reflect.DeepEqual(d.history.current, getTestRestartState().Session) {
+		t.Errorf("got session %v, want resumed session", d.history.current)
+	}
+}
+
+// TestDaemonDetachVPN tests detachVPN of Daemon.
+func TestDaemonDetachVPN(t *testing.T) {
+	d := getTestDaemon()
+	d.config.GracefulRestart.StateFile = filepath.Join(t.TempDir(), "restart.json")
+	file := d.config.GracefulRestart.StateFile
+	state := getTestRestartState()
+	runner := d.runner.(*ocRunner)
+
+	// graceful restart disabled
+	d.status.OCRunning = vpnstatus.OCRunningRunning
+	d.status.OCPID = state.PID
+	d.status.ConnectionState = vpnstatus.ConnectionStateConnected
+	d.status.VPNConfig = state.VPNConfig
+	d.status.ConnectedAt = state.ConnectedAt
+	d.config.LoginInfo = state.LoginInfo
+	d.history.current = state.Session
+	d.detachVPN()
+	if d.detached || runner.detached {
+		t.Error("vpn should not be detached when disabled")
+	}
+
+	// not connected
+	d.config.GracefulRestart.Enabled = true
+	d.status.ConnectionState = vpnstatus.ConnectionStateConnecting
+	d.detachVPN()
+	if d.detached || runner.detached {
+		t.Error("vpn should not be detached when not connected")
+	}
+
+	// connected
+	d.status.ConnectionState = vpnstatus.ConnectionStateConnected
+	d.detachVPN()
+	if !d.detached || !runner.detached {
+		t.Error("vpn should be detached")
+	}
+	got, err := loadRestartState(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, state) {
+		t.Errorf("got %v, want %v", got, state)
+	}
+}
@@ -0,0 +1,89 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+)
+
+// Resume outcomes.
+const (
+	resumeOutcomeValidating      = "validating network"
+	resumeOutcomeKept            = "connection kept"
+	resumeOutcomeCaptivePortal   = "captive portal detected, connection kept"
+	resumeOutcomeTrustedNetwork  = "trusted network detected, disconnected"
+	resumeOutcomeNotRunning      = "vpn not running any more"
+	resumeOutcomeReconnecting    = "reconnecting with stored cookie"
+	resumeOutcomeReconnected     = "reconnected"
+	resumeOutcomeReconnectFailed = "reconnect failed"
+	resumeOutcomeDisconnected    = "disconnected"
+)
+
+// resume handles the VPN connection on resume after suspend according to
+// the resume policy.
+type resume struct {
+	config *daemoncfg.Resume
+
+	// timer is the timer of the network validation after resume,
+	// nil if no validation is running
+	timer *time.Timer
+
+	// login is the login info for reconnecting after resume,
+	// nil if no reconnect is pending
+	login *logininfo.LoginInfo
+}
+
+// startValidation starts the network validation after resume.
+func (r *resume) startValidation() {
+	r.stop()
+	r.timer = time.NewTimer(r.config.ValidationTime)
+}
+
+// stop stops the network validation and pending reconnects.
+func (r *resume) stop() {
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	r.login = nil
+}
+
+// validating returns whether the network validation is running.
+func (r *resume) validating() bool {
+	return r.timer != nil
+}
+
+// fired marks the timer of the network validation as expired.
+func (r *resume) fired() {
+	r.timer = nil
+}
+
+// setLogin sets the login info for reconnecting after resume.
+func (r *resume) setLogin(login *logininfo.LoginInfo) {
+	r.login = login.Copy()
+}
+
+// takeLogin returns and clears the login info for reconnecting after
+// resume, nil if no reconnect is pending.
+func (r *resume) takeLogin() *logininfo.LoginInfo {
+	login := r.login
+	r.login = nil
+	return login
+}
+
+// timerC returns the timer channel of the network validation,
+// nil if no validation is running.
+func (r *resume) timerC() <-chan time.Time {
+	if r.timer == nil {
+		return nil
+	}
+	return r.timer.C
+}
+
+// newResume returns a new resume handler.
+func newResume(config *daemoncfg.Resume) *resume {
+	return &resume{
+		config: config,
+	}
+}
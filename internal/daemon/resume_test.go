@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
+)
+
+// TestResumeValidation tests startValidation, stop and fired of resume.
+func TestResumeValidation(t *testing.T) {
+	config := daemoncfg.NewResume()
+	config.ValidationTime = time.Millisecond
+	r := newResume(config)
+	if r.validating() || r.timerC() != nil {
+		t.Error("resume should not be validating")
+	}
+
+	// start and wait for timer
+	r.startValidation()
+	if !r.validating() {
+		t.Error("resume should be validating")
+	}
+	<-r.timerC()
+	r.fired()
+	if r.validating() {
+		t.Error("resume should not be validating after timer fired")
+	}
+
+	// start and stop
+	r.startValidation()
+	r.stop()
+	if r.validating() {
+		t.Error("resume should not be validating after stop")
+	}
+}
+
+// TestResumeLogin tests setLogin and takeLogin of resume.
+func TestResumeLogin(t *testing.T) {
+	r := newResume(daemoncfg.NewResume())
+	if r.takeLogin() != nil {
+		t.Error("login should be nil")
+	}
+
+	login := &logininfo.LoginInfo{Server: "server"}
+	r.setLogin(login)
+	got := r.takeLogin()
+	if got == login || *got != *login {
+		t.Errorf("got %v, want copy of %v", got, login)
+	}
+	if r.takeLogin() != nil {
+		t.Error("login should be nil after take")
+	}
+
+	// stop clears login
+	r.setLogin(login)
+	r.stop()
+	if r.takeLogin() != nil {
+		t.Error("login should be nil after stop")
+	}
+}
@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"strings"
+
+	"github.com/telekom-mms/oc-daemon/internal/dnstnd"
+)
+
+// TND match criteria.
+const (
+	tndMatchDNSDomain   = "DNS domain: "
+	tndMatchDNSServers  = "DNS servers: "
+	tndMatchHTTPSServer = "HTTPS server"
+)
+
+// tndResults combines the results of the HTTPS-based and the DNS-based
+// trusted network detection.
+type tndResults struct {
+	// https is the result of the HTTPS-based TND
+	https bool
+
+	// dns is the result of the DNS-based TND, nil if not available
+	dns *dnstnd.Result
+}
+
+// trusted returns whether the network is trusted and the matching criteria.
+// https and dns specify whether the HTTPS-based and the DNS-based TND are
+// running. The network is only trusted if the criteria of all running
+// detections match.
+func (t *tndResults) trusted(https, dns bool) (trusted bool, matches []string) {
+	if dns && t.dns != nil {
+		if t.dns.Domain != "" {
+			matches = append(matches, tndMatchDNSDomain+t.dns.Domain)
+		}
+		if len(t.dns.Servers) > 0 {
+			matches = append(matches,
+				tndMatchDNSServers+strings.Join(t.dns.Servers, " "))
+		}
+	}
+	if https && t.https {
+		matches = append(matches, tndMatchHTTPSServer)
+	}
+
+	trusted = (https || dns) &&
+		(!https || t.https) &&
+		(!dns || t.dns != nil && t.dns.Trusted)
+	return
+}
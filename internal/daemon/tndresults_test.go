@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/dnstnd"
+)
+
+// TestTNDResultsTrusted tests trusted of tndResults.
+func TestTNDResultsTrusted(t *testing.T) {
+	dnsTrusted := &dnstnd.Result{
+		Trusted: true,
+		Domain:  "mycompany.com",
+		Servers: []string{"10.1.0.1", "10.1.0.2"},
+	}
+	dnsNotTrusted := &dnstnd.Result{
+		Domain: "mycompany.com",
+	}
+
+	for i, test := range []struct {
+		results     tndResults
+		https       bool
+		dns         bool
+		wantTrusted bool
+		wantMatches []string
+	}{
+		// no detection running
+		{
+			results: tndResults{https: true, dns: dnsTrusted},
+		},
+		// https only
+		{
+			results:     tndResults{https: true},
+			https:       true,
+			wantTrusted: true,
+			wantMatches: []string{"HTTPS server"},
+		},
+		{
+			results: tndResults{https: false},
+			https:   true,
+		},
+		// dns only
+		{
+			results:     tndResults{dns: dnsTrusted},
+			dns:         true,
+			wantTrusted: true,
+			wantMatches: []string{
+				"DNS domain: mycompany.com",
+				"DNS servers: 10.1.0.1 10.1.0.2",
+			},
+		},
+		{
+			results:     tndResults{dns: dnsNotTrusted},
+			dns:         true,
+			wantMatches: []string{"DNS domain: mycompany.com"},
+		},
+		{
+			results: tndResults{},
+			dns:     true,
+		},
+		// https and dns
+		{
+			results:     tndResults{https: true, dns: dnsTrusted},
+			https:       true,
+			dns:         true,
+			wantTrusted: true,
+			wantMatches: []string{
+				"DNS domain: mycompany.com",
+				"DNS servers: 10.1.0.1 10.1.0.2",
+				"HTTPS server",
+			},
+		},
+		{
+			results:     tndResults{https: true, dns: dnsNotTrusted},
+			https:       true,
+			dns:         true,
+			wantMatches: []string{"DNS domain: mycompany.com", "HTTPS server"},
+		},
+		{
+			results: tndResults{https: false, dns: dnsTrusted},
+			https:   true,
+			dns:     true,
+			wantMatches: []string{
+				"DNS domain: mycompany.com",
+				"DNS servers: 10.1.0.1 10.1.0.2",
+			},
+		},
+	} {
+		trusted, matches := test.results.trusted(test.https, test.dns)
+		if trusted != test.wantTrusted {
+			t.Errorf("%d: got trusted %t, want %t", i, trusted, test.wantTrusted)
+		}
+		if !reflect.DeepEqual(matches, test.wantMatches) {
+			t.Errorf("%d: got matches %v, want %v", i, matches, test.wantMatches)
+		}
+	}
+}
@@ -10,6 +10,10 @@ import (
 type VPNConfigUpdate struct {
 	Reason string
 	Config *vpnconfig.Config
+
+	// Connection is the name of the VPN connection, empty for the
+	// default VPN connection
+	Connection string `json:",omitempty"`
 }
 
 // Valid returns whether the config update is valid.
@@ -3,10 +3,12 @@ package daemoncfg
 
 import (
 	"encoding/json"
+	"maps"
 	"net/netip"
 	"os"
 	"os/exec"
 	"reflect"
+	"slices"
 	"strconv"
 	"time"
 
@@ -378,6 +380,9 @@ var (
 
 	// SplitRoutingFirewallMark is the firewall mark used for split routing.
 	SplitRoutingFirewallMark = SplitRoutingRoutingTable
+
+	// SplitRoutingNftTable is the nftables table used for split routing.
+	SplitRoutingNftTable = "oc-daemon-routing"
 )
 
 // SplitRouting is the split routing configuration.
@@ -386,6 +391,7 @@ type SplitRouting struct {
 	RulePriority1 string
 	RulePriority2 string
 	FirewallMark  string
+	NftTable      string
 }
 
 // Copy returns a copy of the SplitRouting configuration.
@@ -400,7 +406,8 @@ func (c *SplitRouting) Valid() bool {
 		c.RoutingTable == "" ||
 		c.RulePriority1 == "" ||
 		c.RulePriority2 == "" ||
-		c.FirewallMark == "" {
+		c.FirewallMark == "" ||
+		c.NftTable == "" {
 
 		return false
 	}
@@ -442,6 +449,7 @@ func NewSplitRouting() *SplitRouting {
 		RulePriority1: SplitRoutingRulePriority1,
 		RulePriority2: SplitRoutingRulePriority2,
 		FirewallMark:  SplitRoutingFirewallMark,
+		NftTable:      SplitRoutingNftTable,
 	}
 }
 
@@ -466,6 +474,14 @@ var (
 		443,
 	}
 
+	// PortalRemediation specifies whether the portal ports are opened
+	// to register on a captive portal.
+	PortalRemediation = true
+
+	// PortalRemediationTimeout is the time after which the portal ports
+	// are closed again, zero means no timeout.
+	PortalRemediationTimeout time.Duration
+
 	// ResolveTimeout is the timeout for dns lookups.
 	ResolveTimeout = 2 * time.Second
 
@@ -488,6 +504,9 @@ type TrafficPolicing struct {
 	AllowedHosts []string
 	PortalPorts  []uint16
 
+	PortalRemediation        bool
+	PortalRemediationTimeout time.Duration
+
 	ResolveTimeout    time.Duration
 	ResolveTries      int
 	ResolveTriesSleep time.Duration
@@ -508,6 +527,7 @@ func (c *TrafficPolicing) Copy() *TrafficPolicing {
 func (c *TrafficPolicing) Valid() bool {
 	if c == nil ||
 		len(c.PortalPorts) == 0 ||
+		c.PortalRemediationTimeout < 0 ||
 		c.ResolveTimeout < 0 ||
 		c.ResolveTries < 1 ||
 		c.ResolveTriesSleep < 0 ||
@@ -525,6 +545,9 @@ func NewTrafficPolicing() *TrafficPolicing {
 		AllowedHosts: append(AllowedHosts[:0:0], AllowedHosts...),
 		PortalPorts:  append(PortalPorts[:0:0], PortalPorts...),
 
+		PortalRemediation:        PortalRemediation,
+		PortalRemediationTimeout: PortalRemediationTimeout,
+
 		ResolveTimeout:    ResolveTimeout,
 		ResolveTries:      ResolveTries,
 		ResolveTriesSleep: ResolveTriesSleep,
@@ -533,16 +556,40 @@ func NewTrafficPolicing() *TrafficPolicing {
 	}
 }
 
+// Command lists backends.
+const (
+	CommandListsBackendTemplate = "template"
+	CommandListsBackendNative   = "native"
+)
+
 // Command lists default values
 var (
 	CommandListsListsFile     = configDir + "/command-lists.json"
 	CommandListsTemplatesFile = configDir + "/command-lists.tmpl"
+	CommandListsJournalDir    = "/run/oc-daemon/journal"
+
+	// CommandListsHooksDir is the directory with the hook command lists
+	// files.
+	CommandListsHooksDir = configDir + "/hooks.d"
+
+	// CommandListsHookTimeout is the timeout for running a hook command
+	// list.
+	CommandListsHookTimeout = 30 * time.Second
+
+	// CommandListsBackend is the backend that changes the network
+	// configuration, the template backend runs the command lists, the
+	// native backend uses netlink and D-Bus directly.
+	CommandListsBackend = CommandListsBackendTemplate
 )
 
 // CommandLists is the command lists configuration.
 type CommandLists struct {
 	ListsFile     string
 	TemplatesFile string
+	JournalDir    string
+	Backend       string
+	HooksDir      string
+	HookTimeout   time.Duration
 }
 
 // Copy returns a copy of the command lists configuration.
@@ -555,7 +602,12 @@ func (c *CommandLists) Copy() *CommandLists {
 func (c *CommandLists) Valid() bool {
 	if c == nil ||
 		c.ListsFile == "" ||
-		c.TemplatesFile == "" {
+		c.TemplatesFile == "" ||
+		c.JournalDir == "" ||
+		(c.Backend != CommandListsBackendTemplate &&
+			c.Backend != CommandListsBackendNative) ||
+		c.HooksDir == "" ||
+		c.HookTimeout <= 0 {
 
 		return false
 	}
@@ -567,7 +619,633 @@ func NewCommandLists() *CommandLists {
 	return &CommandLists{
 		ListsFile:     CommandListsListsFile,
 		TemplatesFile: CommandListsTemplatesFile,
+		JournalDir:    CommandListsJournalDir,
+		Backend:       CommandListsBackend,
+		HooksDir:      CommandListsHooksDir,
+		HookTimeout:   CommandListsHookTimeout,
+	}
+}
+
+// Reconnect default values.
+var (
+	// ReconnectEnabled specifies whether the daemon reconnects to the VPN
+	// after an unexpected exit of openconnect.
+	ReconnectEnabled = true
+
+	// ReconnectMaxAttempts is the maximum number of reconnect attempts
+	// before giving up.
+	ReconnectMaxAttempts = 5
+
+	// ReconnectInitialBackoff is the wait time before the first reconnect
+	// attempt, it is doubled for every further attempt.
+	ReconnectInitialBackoff = 5 * time.Second
+
+	// ReconnectMaxBackoff is the maximum wait time between reconnect
+	// attempts.
+	ReconnectMaxBackoff = 5 * time.Minute
+)
+
+// Reconnect is the reconnect configuration.
+type Reconnect struct {
+	Enabled        bool
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Copy returns a copy of the reconnect configuration.
+func (c *Reconnect) Copy() *Reconnect {
+	n := *c
+	return &n
+}
+
+// Valid returns whether the reconnect configuration is valid.
+func (c *Reconnect) Valid() bool {
+	if c == nil ||
+		c.MaxAttempts < 1 ||
+		c.InitialBackoff <= 0 ||
+		c.MaxBackoff < c.InitialBackoff {
+
+		return false
+	}
+	return true
+}
+
+// NewReconnect returns a new reconnect configuration.
+func NewReconnect() *Reconnect {
+	return &Reconnect{
+		Enabled:        ReconnectEnabled,
+		MaxAttempts:    ReconnectMaxAttempts,
+		InitialBackoff: ReconnectInitialBackoff,
+		MaxBackoff:     ReconnectMaxBackoff,
+	}
+}
+
+// History default values.
+var (
+	// HistoryFile is the file that stores the connection history.
+	HistoryFile = configDir + "/history.json"
+
+	// HistoryMaxSessions is the maximum number of sessions in the
+	// connection history.
+	HistoryMaxSessions = 100
+)
+
+// History is the connection history configuration.
+type History struct {
+	File        string
+	MaxSessions int
+}
+
+// Copy returns a copy of the connection history configuration.
+func (c *History) Copy() *History {
+	n := *c
+	return &n
+}
+
+// Valid returns whether the connection history configuration is valid.
+func (c *History) Valid() bool {
+	if c == nil ||
+		c.File == "" ||
+		c.MaxSessions < 1 {
+
+		return false
+	}
+	return true
+}
+
+// NewHistory returns a new connection history configuration.
+func NewHistory() *History {
+	return &History{
+		File:        HistoryFile,
+		MaxSessions: HistoryMaxSessions,
+	}
+}
+
+// Metrics default values.
+var (
+	// MetricsEnabled specifies whether the metrics endpoint is enabled.
+	MetricsEnabled = false
+
+	// MetricsAddress is the loopback address of the metrics endpoint.
+	MetricsAddress = "127.0.0.1:9869"
+
+	// MetricsSocketFile is the unix socket file of the metrics endpoint,
+	// it is used instead of the address if set.
+	MetricsSocketFile = ""
+)
+
+// Metrics is the metrics endpoint configuration.
+type Metrics struct {
+	Enabled    bool
+	Address    string
+	SocketFile string
+}
+
+// Copy returns a copy of the metrics endpoint configuration.
+func (c *Metrics) Copy() *Metrics {
+	n := *c
+	return &n
+}
+
+// Valid returns whether the metrics endpoint configuration is valid.
+func (c *Metrics) Valid() bool {
+	if c == nil {
+		return false
+	}
+	if c.SocketFile != "" {
+		return true
+	}
+
+	// only allow listening on loopback addresses
+	addr, err := netip.ParseAddrPort(c.Address)
+	if err != nil ||
+		!addr.Addr().IsLoopback() {
+
+		return false
+	}
+	return true
+}
+
+// NewMetrics returns a new metrics endpoint configuration.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		Enabled:    MetricsEnabled,
+		Address:    MetricsAddress,
+		SocketFile: MetricsSocketFile,
+	}
+}
+
+// Idle timeout actions.
+const (
+	IdleTimeoutActionDisconnect = "disconnect"
+	IdleTimeoutActionWarn       = "warn"
+)
+
+// IdleTimeout default values.
+var (
+	// IdleTimeoutEnabled specifies whether the daemon monitors the VPN
+	// tunnel for the idle timeout reported by the VPN server.
+	IdleTimeoutEnabled = true
+
+	// IdleTimeoutAction is the action when the idle timeout is reached,
+	// only warn by default so existing installations do not start
+	// disconnecting idle VPN tunnels.
+	IdleTimeoutAction = IdleTimeoutActionWarn
+
+	// IdleTimeoutWarningTime is the time before the idle timeout when the
+	// daemon warns the user.
+	IdleTimeoutWarningTime = time.Minute
+
+	// IdleTimeoutCheckInterval is the interval for checking the traffic
+	// on the VPN device.
+	IdleTimeoutCheckInterval = 10 * time.Second
+)
+
+// IdleTimeout is the idle timeout configuration.
+type IdleTimeout struct {
+	Enabled       bool
+	Action        string
+	WarningTime   time.Duration
+	CheckInterval time.Duration
+}
+
+// Copy returns a copy of the idle timeout configuration.
+func (c *IdleTimeout) Copy() *IdleTimeout {
+	n := *c
+	return &n
+}
+
+// Valid returns whether the idle timeout configuration is valid.
+func (c *IdleTimeout) Valid() bool {
+	if c == nil ||
+		(c.Action != IdleTimeoutActionDisconnect &&
+			c.Action != IdleTimeoutActionWarn) ||
+		c.WarningTime < 0 ||
+		c.CheckInterval <= 0 {
+
+		return false
+	}
+	return true
+}
+
+// NewIdleTimeout returns a new idle timeout configuration.
+func NewIdleTimeout() *IdleTimeout {
+	return &IdleTimeout{
+		Enabled:       IdleTimeoutEnabled,
+		Action:        IdleTimeoutAction,
+		WarningTime:   IdleTimeoutWarningTime,
+		CheckInterval: IdleTimeoutCheckInterval,
+	}
+}
+
+// Resume policies.
+const (
+	ResumePolicyKeep       = "keep"
+	ResumePolicyReconnect  = "reconnect"
+	ResumePolicyDisconnect = "disconnect"
+)
+
+// Resume default values.
+var (
+	// ResumePolicy is the action on resume after suspend with an
+	// active VPN connection.
+	ResumePolicy = ResumePolicyDisconnect
+
+	// ResumeValidationTime is the time for re-validating the network
+	// with TND and CPD after resume before applying the resume policy.
+	ResumeValidationTime = 10 * time.Second
+)
+
+// Resume is the resume configuration.
+type Resume struct {
+	Policy         string
+	ValidationTime time.Duration
+}
+
+// Copy returns a copy of the resume configuration.
+func (c *Resume) Copy() *Resume {
+	n := *c
+	return &n
+}
+
+// Valid returns whether the resume configuration is valid.
+func (c *Resume) Valid() bool {
+	if c == nil ||
+		(c.Policy != ResumePolicyKeep &&
+			c.Policy != ResumePolicyReconnect &&
+			c.Policy != ResumePolicyDisconnect) ||
+		c.ValidationTime <= 0 {
+
+		return false
+	}
+	return true
+}
+
+// NewResume returns a new resume configuration.
+func NewResume() *Resume {
+	return &Resume{
+		Policy:         ResumePolicy,
+		ValidationTime: ResumeValidationTime,
+	}
+}
+
+// Polkit default values.
+var (
+	// PolkitEnabled specifies whether the D-Bus API checks the
+	// authorization of callers with polkit.
+	PolkitEnabled = true
+)
+
+// Polkit is the polkit configuration.
+type Polkit struct {
+	Enabled bool
+}
+
+// Copy returns a copy of the polkit configuration.
+func (c *Polkit) Copy() *Polkit {
+	n := *c
+	return &n
+}
+
+// Valid returns whether the polkit configuration is valid.
+func (c *Polkit) Valid() bool {
+	return c != nil
+}
+
+// NewPolkit returns a new polkit configuration.
+func NewPolkit() *Polkit {
+	return &Polkit{
+		Enabled: PolkitEnabled,
+	}
+}
+
+// ConnectFailure default values.
+var (
+	// ConnectFailureMaxFailures is the number of consecutive connection
+	// failures after which traffic policing is relaxed if the connect
+	// failure policy in the XML profile is open.
+	ConnectFailureMaxFailures = 3
+)
+
+// ConnectFailure is the connect failure configuration.
+type ConnectFailure struct {
+	MaxFailures int
+}
+
+// Copy returns a copy of the connect failure configuration.
+func (c *ConnectFailure) Copy() *ConnectFailure {
+	n := *c
+	return &n
+}
+
+// Valid returns whether the connect failure configuration is valid.
+func (c *ConnectFailure) Valid() bool {
+	if c == nil || c.MaxFailures <= 0 {
+		return false
+	}
+	return true
+}
+
+// NewConnectFailure returns a new connect failure configuration.
+func NewConnectFailure() *ConnectFailure {
+	return &ConnectFailure{
+		MaxFailures: ConnectFailureMaxFailures,
+	}
+}
+
+// MachineAuth default values.
+var (
+	// MachineAuthEnabled specifies whether the daemon authenticates on
+	// the VPN server with the machine certificate.
+	MachineAuthEnabled = false
+
+	// MachineAuthVPNServer is the VPN server used for machine
+	// authentication, the first VPN server in the XML profile if empty.
+	MachineAuthVPNServer = ""
+
+	// MachineAuthClientCertificate is the file path of the machine
+	// certificate.
+	MachineAuthClientCertificate = ""
+
+	// MachineAuthClientKey is the file path of the machine certificate's
+	// private key.
+	MachineAuthClientKey = ""
+
+	// MachineAuthCACertificate is the file path of the CA certificate
+	// used to verify the VPN server.
+	MachineAuthCACertificate = ""
+
+	// MachineAuthProtocol is the protocol used by openconnect.
+	MachineAuthProtocol = "anyconnect"
+
+	// MachineAuthUserAgent is the user agent used by openconnect.
+	MachineAuthUserAgent = "AnyConnect"
+
+	// MachineAuthTimeout is the timeout of the authentication.
+	MachineAuthTimeout = 30 * time.Second
+)
+
+// MachineAuth is the machine authentication configuration.
+type MachineAuth struct {
+	Enabled           bool
+	VPNServer         string
+	ClientCertificate string
+	ClientKey         string
+	CACertificate     string
+	Protocol          string
+	UserAgent         string
+	Timeout           time.Duration
+}
+
+// Copy returns a copy of the machine authentication configuration.
+func (c *MachineAuth) Copy() *MachineAuth {
+	n := *c
+	return &n
+}
+
+// Valid returns whether the machine authentication configuration is valid.
+func (c *MachineAuth) Valid() bool {
+	if c == nil ||
+		c.Protocol == "" ||
+		c.UserAgent == "" ||
+		c.Timeout <= 0 {
+
+		return false
+	}
+	if c.Enabled && (c.ClientCertificate == "" || c.ClientKey == "") {
+		return false
+	}
+	return true
+}
+
+// NewMachineAuth returns a new machine authentication configuration.
+func NewMachineAuth() *MachineAuth {
+	return &MachineAuth{
+		Enabled:           MachineAuthEnabled,
+		VPNServer:         MachineAuthVPNServer,
+		ClientCertificate: MachineAuthClientCertificate,
+		ClientKey:         MachineAuthClientKey,
+		CACertificate:     MachineAuthCACertificate,
+		Protocol:          MachineAuthProtocol,
+		UserAgent:         MachineAuthUserAgent,
+		Timeout:           MachineAuthTimeout,
+	}
+}
+
+// NetNS default values.
+var (
+	// NetNSEnabled specifies whether the VPN tunnel runs inside a dedicated
+	// network namespace.
+	NetNSEnabled = false
+
+	// NetNSName is the name of the network namespace.
+	NetNSName = "oc-daemon"
+
+	// NetNSHostDevice is the veth device that connects the host to the
+	// network namespace.
+	NetNSHostDevice = "oc-daemon-veth0"
+
+	// NetNSDevice is the veth device that connects the network namespace
+	// to the host.
+	NetNSDevice = "oc-daemon-veth1"
+
+	// NetNSHostAddress is the address of the veth device on the host.
+	NetNSHostAddress = netip.MustParsePrefix("169.254.42.1/30")
+
+	// NetNSAddress is the address of the veth device in the network
+	// namespace.
+	NetNSAddress = netip.MustParsePrefix("169.254.42.2/30")
+
+	// NetNSDNSAddress is the listen address of the DNS proxy in the
+	// network namespace. It is set as nameserver in the resolv.conf of the
+	// network namespace, so it must use port 53.
+	NetNSDNSAddress = "127.0.0.1:53"
+)
+
+// NetNS is the network namespace configuration.
+type NetNS struct {
+	Enabled     bool
+	Name        string
+	HostDevice  string
+	Device      string
+	HostAddress netip.Prefix
+	Address     netip.Prefix
+	DNSAddress  string
+}
+
+// Copy returns a copy of the network namespace configuration.
+func (c *NetNS) Copy() *NetNS {
+	n := *c
+	return &n
+}
+
+// Valid returns whether the network namespace configuration is valid.
+func (c *NetNS) Valid() bool {
+	if c == nil ||
+		!validConnectionName(c.Name) ||
+		c.HostDevice == "" ||
+		len(c.HostDevice) > 15 ||
+		c.Device == "" ||
+		len(c.Device) > 15 ||
+		c.HostDevice == c.Device ||
+		!c.HostAddress.Addr().Is4() ||
+		!c.Address.Addr().Is4() ||
+		c.HostAddress.Addr() == c.Address.Addr() ||
+		!c.HostAddress.Contains(c.Address.Addr()) {
+
+		return false
+	}
+	addr, err := netip.ParseAddrPort(c.DNSAddress)
+	if err != nil || addr.Port() != 53 {
+		return false
+	}
+	return true
+}
+
+// NewNetNS returns a new network namespace configuration.
+func NewNetNS() *NetNS {
+	return &NetNS{
+		Enabled:     NetNSEnabled,
+		Name:        NetNSName,
+		HostDevice:  NetNSHostDevice,
+		Device:      NetNSDevice,
+		HostAddress: NetNSHostAddress,
+		Address:     NetNSAddress,
+		DNSAddress:  NetNSDNSAddress,
+	}
+}
+
+// GracefulRestart default values.
+var (
+	// GracefulRestartEnabled specifies whether the daemon keeps a
+	// connected VPN tunnel running when it is stopped and adopts it again
+	// when it is started.
+	GracefulRestartEnabled = false
+
+	// GracefulRestartStateFile is the file that stores the state of the
+	// VPN tunnel during the restart.
+	GracefulRestartStateFile = "/run/oc-daemon/restart.json"
+)
+
+// GracefulRestart is the graceful restart configuration.
+type GracefulRestart struct {
+	Enabled   bool
+	StateFile string
+}
+
+// Copy returns a copy of the graceful restart configuration.
+func (c *GracefulRestart) Copy() *GracefulRestart {
+	n := *c
+	return &n
+}
+
+// Valid returns whether the graceful restart configuration is valid.
+func (c *GracefulRestart) Valid() bool {
+	if c == nil ||
+		c.StateFile == "" {
+
+		return false
+	}
+	return true
+}
+
+// NewGracefulRestart returns a new graceful restart configuration.
+func NewGracefulRestart() *GracefulRestart {
+	return &GracefulRestart{
+		Enabled:   GracefulRestartEnabled,
+		StateFile: GracefulRestartStateFile,
+	}
+}
+
+// Connection is the configuration of a named VPN connection in addition to
+// the default VPN connection. It replaces the settings of the default VPN
+// connection that must be unique for each VPN tunnel.
+type Connection struct {
+	VPNDevice    string
+	PIDFile      string
+	SplitRouting *SplitRouting
+}
+
+// Copy returns a copy of the connection configuration.
+func (c *Connection) Copy() *Connection {
+	if c == nil {
+		return nil
+	}
+	return &Connection{
+		VPNDevice:    c.VPNDevice,
+		PIDFile:      c.PIDFile,
+		SplitRouting: c.SplitRouting.Copy(),
+	}
+}
+
+// Valid returns whether the connection configuration is valid.
+func (c *Connection) Valid() bool {
+	if c == nil ||
+		c.VPNDevice == "" ||
+		len(c.VPNDevice) > 15 ||
+		c.PIDFile == "" ||
+		!c.SplitRouting.Valid() {
+
+		return false
+	}
+	return true
+}
+
+// validConnectionName returns whether name is a valid connection name. It
+// is used in file names and environment variables, so only letters, digits,
+// "-" and "_" are allowed.
+func validConnectionName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+		case r == '-' || r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Connections are the named VPN connections in addition to the default VPN
+// connection.
+type Connections map[string]*Connection
+
+// Copy returns a copy of the connections configuration.
+func (c Connections) Copy() Connections {
+	if c == nil {
+		return nil
 	}
+	n := make(Connections, len(c))
+	for name, conn := range c {
+		n[name] = conn.Copy()
+	}
+	return n
+}
+
+// Valid returns whether the connections configuration is valid.
+func (c Connections) Valid() bool {
+	for name, conn := range c {
+		if !validConnectionName(name) || !conn.Valid() {
+			return false
+		}
+	}
+	return true
+}
+
+// Names returns the sorted names of the connections.
+func (c Connections) Names() []string {
+	return slices.Sorted(maps.Keys(c))
+}
+
+// NewConnections returns a new connections configuration.
+func NewConnections() Connections {
+	return Connections{}
 }
 
 // VPNDevice is a VPN device configuration in VPNConfig.
@@ -833,10 +1511,24 @@ type Config struct {
 	TrafficPolicing *TrafficPolicing
 	TND             *tnd.Config
 
-	CommandLists *CommandLists
-
-	LoginInfo *logininfo.LoginInfo `json:"-"`
-	VPNConfig *VPNConfig           `json:"-"`
+	CommandLists    *CommandLists
+	Reconnect       *Reconnect
+	History         *History
+	Metrics         *Metrics
+	IdleTimeout     *IdleTimeout
+	Resume          *Resume
+	Polkit          *Polkit
+	ConnectFailure  *ConnectFailure
+	MachineAuth     *MachineAuth
+	NetNS           *NetNS
+	GracefulRestart *GracefulRestart
+	Connections     Connections
+
+	// Connection is the name of the VPN connection the configuration
+	// belongs to, empty for the default VPN connection
+	Connection string               `json:"-"`
+	LoginInfo  *logininfo.LoginInfo `json:"-"`
+	VPNConfig  *VPNConfig           `json:"-"`
 }
 
 // Copy returns a copy of the configuration.
@@ -854,10 +1546,22 @@ func (c *Config) Copy() *Config {
 		TrafficPolicing: c.TrafficPolicing.Copy(),
 		TND:             c.TND.Copy(),
 
-		CommandLists: c.CommandLists.Copy(),
-
-		LoginInfo: c.LoginInfo.Copy(),
-		VPNConfig: c.VPNConfig.Copy(),
+		CommandLists:    c.CommandLists.Copy(),
+		Reconnect:       c.Reconnect.Copy(),
+		History:         c.History.Copy(),
+		Metrics:         c.Metrics.Copy(),
+		IdleTimeout:     c.IdleTimeout.Copy(),
+		Resume:          c.Resume.Copy(),
+		Polkit:          c.Polkit.Copy(),
+		ConnectFailure:  c.ConnectFailure.Copy(),
+		MachineAuth:     c.MachineAuth.Copy(),
+		NetNS:           c.NetNS.Copy(),
+		GracefulRestart: c.GracefulRestart.Copy(),
+		Connections:     c.Connections.Copy(),
+
+		Connection: c.Connection,
+		LoginInfo:  c.LoginInfo.Copy(),
+		VPNConfig:  c.VPNConfig.Copy(),
 	}
 }
 
@@ -885,6 +1589,18 @@ func (c *Config) Valid() bool {
 		!c.TrafficPolicing.Valid() ||
 		!c.TND.Valid() ||
 		!c.CommandLists.Valid() ||
+		!c.Reconnect.Valid() ||
+		!c.History.Valid() ||
+		!c.Metrics.Valid() ||
+		!c.IdleTimeout.Valid() ||
+		!c.Resume.Valid() ||
+		!c.Polkit.Valid() ||
+		!c.ConnectFailure.Valid() ||
+		!c.MachineAuth.Valid() ||
+		!c.NetNS.Valid() ||
+		!c.GracefulRestart.Valid() ||
+		!c.Connections.Valid() ||
+		!c.connectionsUnique() ||
 		!loginInfoEmpty(c.LoginInfo) && !c.LoginInfo.Valid() ||
 		!c.VPNConfig.Valid() {
 		// invalid
@@ -893,6 +1609,64 @@ func (c *Config) Valid() bool {
 	return true
 }
 
+// connectionsUnique returns whether the settings of the default VPN
+// connection and the named VPN connections that must be unique for each VPN
+// tunnel do not overlap.
+func (c *Config) connectionsUnique() bool {
+	devices := map[string]bool{c.OpenConnect.VPNDevice: true}
+	pidFiles := map[string]bool{c.OpenConnect.PIDFile: true}
+	tables := map[string]bool{c.SplitRouting.RoutingTable: true}
+	prios := map[string]bool{
+		c.SplitRouting.RulePriority1: true,
+		c.SplitRouting.RulePriority2: true,
+	}
+	marks := map[string]bool{c.SplitRouting.FirewallMark: true}
+	nftTables := map[string]bool{c.SplitRouting.NftTable: true}
+	for _, conn := range c.Connections {
+		sr := conn.SplitRouting
+		for _, dup := range []bool{
+			devices[conn.VPNDevice],
+			pidFiles[conn.PIDFile],
+			tables[sr.RoutingTable],
+			prios[sr.RulePriority1],
+			prios[sr.RulePriority2],
+			marks[sr.FirewallMark],
+			nftTables[sr.NftTable],
+		} {
+			if dup {
+				return false
+			}
+		}
+		devices[conn.VPNDevice] = true
+		pidFiles[conn.PIDFile] = true
+		tables[sr.RoutingTable] = true
+		prios[sr.RulePriority1] = true
+		prios[sr.RulePriority2] = true
+		marks[sr.FirewallMark] = true
+		nftTables[sr.NftTable] = true
+	}
+	return true
+}
+
+// ConnectionConfig returns the configuration of the named VPN connection
+// name, nil if the connection does not exist. The configuration is a copy of
+// the configuration with the settings of the connection and without login
+// information and VPN configuration.
+func (c *Config) ConnectionConfig(name string) *Config {
+	conn, ok := c.Connections[name]
+	if !ok {
+		return nil
+	}
+	config := c.Copy()
+	config.Connection = name
+	config.OpenConnect.VPNDevice = conn.VPNDevice
+	config.OpenConnect.PIDFile = conn.PIDFile
+	config.SplitRouting = conn.SplitRouting.Copy()
+	config.LoginInfo = &logininfo.LoginInfo{}
+	config.VPNConfig = &VPNConfig{}
+	return config
+}
+
 // Load loads the configuration from the config file.
 func (c *Config) Load() error {
 	// read file contents
@@ -920,7 +1694,18 @@ func NewConfig() *Config {
 		TrafficPolicing: NewTrafficPolicing(),
 		TND:             tnd.NewConfig(),
 
-		CommandLists: NewCommandLists(),
+		CommandLists:    NewCommandLists(),
+		Reconnect:       NewReconnect(),
+		History:         NewHistory(),
+		Metrics:         NewMetrics(),
+		IdleTimeout:     NewIdleTimeout(),
+		Resume:          NewResume(),
+		Polkit:          NewPolkit(),
+		ConnectFailure:  NewConnectFailure(),
+		MachineAuth:     NewMachineAuth(),
+		NetNS:           NewNetNS(),
+		GracefulRestart: NewGracefulRestart(),
+		Connections:     NewConnections(),
 
 		LoginInfo: &logininfo.LoginInfo{},
 		VPNConfig: &VPNConfig{},
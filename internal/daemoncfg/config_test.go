@@ -298,54 +298,69 @@ func TestSplitRoutingValid(t *testing.T) {
 			FirewallMark:  "42111",
 			RulePriority1: "0",
 			RulePriority2: "1",
+			NftTable:      "oc-daemon-routing",
 		},
 		{
 			RoutingTable:  "42111",
 			FirewallMark:  "42111",
 			RulePriority1: "32766",
 			RulePriority2: "32767",
+			NftTable:      "oc-daemon-routing",
 		},
 		{
 			RoutingTable:  "42111",
 			FirewallMark:  "42111",
 			RulePriority1: "2111",
 			RulePriority2: "2111",
+			NftTable:      "oc-daemon-routing",
 		},
 		{
 			RoutingTable:  "42111",
 			FirewallMark:  "42111",
 			RulePriority1: "2112",
 			RulePriority2: "2111",
+			NftTable:      "oc-daemon-routing",
 		},
 		{
 			RoutingTable:  "42111",
 			FirewallMark:  "42111",
 			RulePriority1: "65537",
 			RulePriority2: "2111",
+			NftTable:      "oc-daemon-routing",
 		},
 		{
 			RoutingTable:  "42111",
 			FirewallMark:  "42111",
 			RulePriority1: "2111",
 			RulePriority2: "65537",
+			NftTable:      "oc-daemon-routing",
 		},
 		{
 			RoutingTable:  "0",
 			FirewallMark:  "42112",
 			RulePriority1: "2222",
 			RulePriority2: "2223",
+			NftTable:      "oc-daemon-routing",
 		},
 		{
 			RoutingTable:  "4294967295",
 			FirewallMark:  "42112",
 			RulePriority1: "2222",
 			RulePriority2: "2223",
+			NftTable:      "oc-daemon-routing",
 		},
 		{
 			RoutingTable:  "42112",
 			FirewallMark:  "4294967296",
 			RulePriority1: "2222",
 			RulePriority2: "2223",
+			NftTable:      "oc-daemon-routing",
+		},
+		{
+			RoutingTable:  "42112",
+			FirewallMark:  "42112",
+			RulePriority1: "2222",
+			RulePriority2: "2223",
 		},
 	} {
 		want := false
@@ -364,6 +379,7 @@ func TestSplitRoutingValid(t *testing.T) {
 			FirewallMark:  "42112",
 			RulePriority1: "2222",
 			RulePriority2: "2223",
+			NftTable:      "oc-daemon-routing",
 		},
 	} {
 		want := true
@@ -389,6 +405,11 @@ func TestTrafficPolicingValid(t *testing.T) {
 	for _, invalid := range []*TrafficPolicing{
 		nil,
 		{},
+		{
+			PortalPorts:              PortalPorts,
+			PortalRemediationTimeout: -1,
+			ResolveTries:             ResolveTries,
+		},
 	} {
 		want := false
 		got := invalid.Valid()
@@ -416,6 +437,510 @@ func TestNewTrafficPolicing(t *testing.T) {
 	}
 }
 
+// TestCommandListsValid tests Valid of CommandLists.
+func TestCommandListsValid(t *testing.T) {
+	// test invalid
+	for _, invalid := range []*CommandLists{
+		nil,
+		{},
+		{ListsFile: "/lists.json", TemplatesFile: "/lists.tmpl", JournalDir: "/journal"},
+		{ListsFile: "/lists.json", TemplatesFile: "/lists.tmpl", JournalDir: "/journal", Backend: "other"},
+		{ListsFile: "/lists.json", TemplatesFile: "/lists.tmpl", JournalDir: "/journal", Backend: CommandListsBackendNative},
+		{ListsFile: "/lists.json", TemplatesFile: "/lists.tmpl", JournalDir: "/journal", Backend: CommandListsBackendNative,
+			HooksDir: "/hooks.d"},
+	} {
+		if invalid.Valid() {
+			t.Errorf("config should be invalid: %v", invalid)
+		}
+	}
+
+	// test valid
+	for _, valid := range []*CommandLists{
+		NewCommandLists(),
+		{ListsFile: "/lists.json", TemplatesFile: "/lists.tmpl", JournalDir: "/journal", Backend: CommandListsBackendNative,
+			HooksDir: "/hooks.d", HookTimeout: time.Second},
+	} {
+		if !valid.Valid() {
+			t.Errorf("config should be valid: %v", valid)
+		}
+	}
+}
+
+// TestNewCommandLists tests NewCommandLists.
+func TestNewCommandLists(t *testing.T) {
+	c := NewCommandLists()
+	if !c.Valid() {
+		t.Errorf("new config should be valid")
+	}
+}
+
+// TestReconnectValid tests Valid of Reconnect.
+func TestReconnectValid(t *testing.T) {
+	// test invalid
+	for _, invalid := range []*Reconnect{
+		nil,
+		{},
+		{MaxAttempts: 1, InitialBackoff: time.Minute, MaxBackoff: time.Second},
+	} {
+		if invalid.Valid() {
+			t.Errorf("config should be invalid: %v", invalid)
+		}
+	}
+
+	// test valid
+	for _, valid := range []*Reconnect{
+		NewReconnect(),
+		{MaxAttempts: 1, InitialBackoff: time.Second, MaxBackoff: time.Second},
+	} {
+		if !valid.Valid() {
+			t.Errorf("config should be valid: %v", valid)
+		}
+	}
+}
+
+// TestNewReconnect tests NewReconnect.
+func TestNewReconnect(t *testing.T) {
+	c := NewReconnect()
+	if !c.Valid() {
+		t.Errorf("new config should be valid")
+	}
+}
+
+// TestHistoryValid tests Valid of History.
+func TestHistoryValid(t *testing.T) {
+	// test invalid
+	for _, invalid := range []*History{
+		nil,
+		{},
+		{File: "/test/history.json"},
+		{MaxSessions: 1},
+	} {
+		if invalid.Valid() {
+			t.Errorf("config should be invalid: %v", invalid)
+		}
+	}
+
+	// test valid
+	for _, valid := range []*History{
+		NewHistory(),
+		{File: "/test/history.json", MaxSessions: 1},
+	} {
+		if !valid.Valid() {
+			t.Errorf("config should be valid: %v", valid)
+		}
+	}
+}
+
+// TestNewHistory tests NewHistory.
+func TestNewHistory(t *testing.T) {
+	c := NewHistory()
+	if !c.Valid() {
+		t.Errorf("new config should be valid")
+	}
+}
+
+// TestMetricsValid tests Valid of Metrics.
+func TestMetricsValid(t *testing.T) {
+	// test invalid
+	for _, invalid := range []*Metrics{
+		nil,
+		{},
+		{Address: "invalid"},
+		{Address: "192.168.1.1:9869"},
+		{Address: "[2001:db8::1]:9869"},
+	} {
+		if invalid.Valid() {
+			t.Errorf("config should be invalid: %v", invalid)
+		}
+	}
+
+	// test valid
+	for _, valid := range []*Metrics{
+		NewMetrics(),
+		{Address: "[::1]:9869"},
+		{SocketFile: "/run/oc-daemon/metrics.sock"},
+	} {
+		if !valid.Valid() {
+			t.Errorf("config should be valid: %v", valid)
+		}
+	}
+}
+
+// TestNewMetrics tests NewMetrics.
+func TestNewMetrics(t *testing.T) {
+	c := NewMetrics()
+	if !c.Valid() {
+		t.Errorf("new config should be valid")
+	}
+}
+
+// TestIdleTimeoutValid tests Valid of IdleTimeout.
+func TestIdleTimeoutValid(t *testing.T) {
+	// test invalid
+	for _, invalid := range []*IdleTimeout{
+		nil,
+		{},
+		{Action: "invalid", CheckInterval: time.Second},
+		{Action: IdleTimeoutActionWarn},
+		{Action: IdleTimeoutActionWarn, WarningTime: -1, CheckInterval: time.Second},
+	} {
+		if invalid.Valid() {
+			t.Errorf("config should be invalid: %v", invalid)
+		}
+	}
+
+	// test valid
+	for _, valid := range []*IdleTimeout{
+		NewIdleTimeout(),
+		{Action: IdleTimeoutActionWarn, CheckInterval: time.Second},
+		{Action: IdleTimeoutActionDisconnect, CheckInterval: time.Second},
+	} {
+		if !valid.Valid() {
+			t.Errorf("config should be valid: %v", valid)
+		}
+	}
+}
+
+// TestNewIdleTimeout tests NewIdleTimeout.
+func TestNewIdleTimeout(t *testing.T) {
+	c := NewIdleTimeout()
+	if !c.Valid() {
+		t.Errorf("new config should be valid")
+	}
+}
+
+// TestResumeValid tests Valid of Resume.
+func TestResumeValid(t *testing.T) {
+	// test invalid
+	for _, invalid := range []*Resume{
+		nil,
+		{},
+		{Policy: "invalid", ValidationTime: time.Second},
+		{Policy: ResumePolicyKeep},
+	} {
+		if invalid.Valid() {
+			t.Errorf("config should be invalid: %v", invalid)
+		}
+	}
+
+	// test valid
+	for _, valid := range []*Resume{
+		NewResume(),
+		{Policy: ResumePolicyKeep, ValidationTime: time.Second},
+		{Policy: ResumePolicyReconnect, ValidationTime: time.Second},
+		{Policy: ResumePolicyDisconnect, ValidationTime: time.Second},
+	} {
+		if !valid.Valid() {
+			t.Errorf("config should be valid: %v", valid)
+		}
+	}
+}
+
+// TestNewResume tests NewResume.
+func TestNewResume(t *testing.T) {
+	c := NewResume()
+	if !c.Valid() {
+		t.Errorf("new config should be valid")
+	}
+}
+
+// TestPolkitValid tests Valid of Polkit.
+func TestPolkitValid(t *testing.T) {
+	// test invalid
+	var invalid *Polkit
+	if invalid.Valid() {
+		t.Errorf("config should be invalid: %v", invalid)
+	}
+
+	// test valid
+	for _, valid := range []*Polkit{
+		NewPolkit(),
+		{},
+	} {
+		if !valid.Valid() {
+			t.Errorf("config should be valid: %v", valid)
+		}
+	}
+}
+
+// TestNewPolkit tests NewPolkit.
+func TestNewPolkit(t *testing.T) {
+	c := NewPolkit()
+	if !c.Valid() {
+		t.Errorf("new config should be valid")
+	}
+}
+
+// TestConnectFailureValid tests Valid of ConnectFailure.
+func TestConnectFailureValid(t *testing.T) {
+	// test invalid
+	for _, invalid := range []*ConnectFailure{
+		nil,
+		{},
+		{MaxFailures: -1},
+	} {
+		if invalid.Valid() {
+			t.Errorf("config should be invalid: %v", invalid)
+		}
+	}
+
+	// test valid
+	for _, valid := range []*ConnectFailure{
+		NewConnectFailure(),
+		{MaxFailures: 1},
+	} {
+		if !valid.Valid() {
+			t.Errorf("config should be valid: %v", valid)
+		}
+	}
+}
+
+// TestNewConnectFailure tests NewConnectFailure.
+func TestNewConnectFailure(t *testing.T) {
+	c := NewConnectFailure()
+	if !c.Valid() {
+		t.Errorf("new config should be valid")
+	}
+}
+
+// TestMachineAuthValid tests Valid of MachineAuth.
+func TestMachineAuthValid(t *testing.T) {
+	// test invalid
+	for _, invalid := range []*MachineAuth{
+		nil,
+		{},
+		{Protocol: "anyconnect", UserAgent: "AnyConnect"},
+		{
+			Enabled:   true,
+			Protocol:  "anyconnect",
+			UserAgent: "AnyConnect",
+			Timeout:   time.Second,
+		},
+		{
+			Enabled:           true,
+			ClientCertificate: "/path/to/cert.pem",
+			Protocol:          "anyconnect",
+			UserAgent:         "AnyConnect",
+			Timeout:           time.Second,
+		},
+	} {
+		if invalid.Valid() {
+			t.Errorf("config should be invalid: %v", invalid)
+		}
+	}
+
+	// test valid
+	for _, valid := range []*MachineAuth{
+		NewMachineAuth(),
+		{
+			Enabled:           true,
+			ClientCertificate: "/path/to/cert.pem",
+			ClientKey:         "/path/to/key.pem",
+			Protocol:          "anyconnect",
+			UserAgent:         "AnyConnect",
+			Timeout:           time.Second,
+		},
+	} {
+		if !valid.Valid() {
+			t.Errorf("config should be valid: %v", valid)
+		}
+	}
+}
+
+// TestNewMachineAuth tests NewMachineAuth.
+func TestNewMachineAuth(t *testing.T) {
+	c := NewMachineAuth()
+	if !c.Valid() {
+		t.Errorf("new config should be valid")
+	}
+}
+
+// TestNetNSValid tests Valid of NetNS.
+func TestNetNSValid(t *testing.T) {
+	// test invalid
+	for _, invalid := range []func(*NetNS){
+		func(c *NetNS) { c.Name = "" },
+		func(c *NetNS) { c.Name = "../oc-daemon" },
+		func(c *NetNS) { c.HostDevice = "" },
+		func(c *NetNS) { c.Device = "this-name-is-too-long" },
+		func(c *NetNS) { c.Device = c.HostDevice },
+		func(c *NetNS) { c.HostAddress = netip.MustParsePrefix("fd00::1/64") },
+		func(c *NetNS) { c.Address = netip.Prefix{} },
+		func(c *NetNS) { c.Address = c.HostAddress },
+		func(c *NetNS) { c.Address = netip.MustParsePrefix("192.168.1.2/30") },
+		func(c *NetNS) { c.DNSAddress = "" },
+		func(c *NetNS) { c.DNSAddress = "127.0.0.1:4253" },
+	} {
+		c := NewNetNS()
+		invalid(c)
+		if c.Valid() {
+			t.Errorf("config should be invalid: %v", c)
+		}
+	}
+	if (*NetNS)(nil).Valid() {
+		t.Error("nil config should be invalid")
+	}
+
+	// test valid
+	c := NewNetNS()
+	c.Enabled = true
+	c.Name = "ci_runner-1"
+	c.DNSAddress = "127.0.0.53:53"
+	if !c.Valid() {
+		t.Errorf("config should be valid: %v", c)
+	}
+}
+
+// TestNewNetNS tests NewNetNS.
+func TestNewNetNS(t *testing.T) {
+	c := NewNetNS()
+	if !c.Valid() {
+		t.Errorf("new config should be valid")
+	}
+}
+
+// TestGracefulRestartValid tests Valid of GracefulRestart.
+func TestGracefulRestartValid(t *testing.T) {
+	// test invalid
+	for _, invalid := range []*GracefulRestart{
+		nil,
+		{Enabled: true},
+	} {
+		if invalid.Valid() {
+			t.Errorf("config should be invalid: %v", invalid)
+		}
+	}
+
+	// test valid
+	for _, valid := range []*GracefulRestart{
+		NewGracefulRestart(),
+		{Enabled: true, StateFile: "/tmp/restart.json"},
+	} {
+		if !valid.Valid() {
+			t.Errorf("config should be valid: %v", valid)
+		}
+	}
+}
+
+// TestNewGracefulRestart tests NewGracefulRestart.
+func TestNewGracefulRestart(t *testing.T) {
+	c := NewGracefulRestart()
+	if !c.Valid() {
+		t.Errorf("new config should be valid")
+	}
+}
+
+// getTestConnection returns a valid connection configuration.
+func getTestConnection() *Connection {
+	return &Connection{
+		VPNDevice: "oc-daemon-tun1",
+		PIDFile:   "/run/oc-daemon/openconnect-lab.pid",
+		SplitRouting: &SplitRouting{
+			RoutingTable:  "42112",
+			RulePriority1: "2113",
+			RulePriority2: "2114",
+			FirewallMark:  "42112",
+			NftTable:      "oc-daemon-routing-lab",
+		},
+	}
+}
+
+// TestConnectionValid tests Valid of Connection.
+func TestConnectionValid(t *testing.T) {
+	// test invalid
+	longDevice := getTestConnection()
+	longDevice.VPNDevice = "oc-daemon-tun-lab"
+	noPIDFile := getTestConnection()
+	noPIDFile.PIDFile = ""
+	noSplitRouting := getTestConnection()
+	noSplitRouting.SplitRouting = nil
+	for _, invalid := range []*Connection{
+		nil,
+		{},
+		longDevice,
+		noPIDFile,
+		noSplitRouting,
+	} {
+		if invalid.Valid() {
+			t.Errorf("config should be invalid: %v", invalid)
+		}
+	}
+
+	// test valid
+	if valid := getTestConnection(); !valid.Valid() {
+		t.Errorf("config should be valid: %v", valid)
+	}
+}
+
+// TestConnectionsValid tests Valid of Connections.
+func TestConnectionsValid(t *testing.T) {
+	// test invalid
+	for _, invalid := range []Connections{
+		{"": getTestConnection()},
+		{"lab/test": getTestConnection()},
+		{"lab test": getTestConnection()},
+		{"lab": nil},
+		{"lab": {}},
+	} {
+		if invalid.Valid() {
+			t.Errorf("config should be invalid: %v", invalid)
+		}
+	}
+
+	// test valid
+	for _, valid := range []Connections{
+		nil,
+		NewConnections(),
+		{"lab": getTestConnection()},
+		{"Lab_Test-1": getTestConnection()},
+	} {
+		if !valid.Valid() {
+			t.Errorf("config should be valid: %v", valid)
+		}
+	}
+}
+
+// TestConnectionsCopy tests Copy of Connections.
+func TestConnectionsCopy(t *testing.T) {
+	// test nil
+	if got := Connections(nil).Copy(); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+
+	// test modification after copy
+	c1 := Connections{"lab": getTestConnection()}
+	c2 := c1.Copy()
+	if !reflect.DeepEqual(c1, c2) {
+		t.Errorf("got %v, want %v", c2, c1)
+	}
+	c1["lab"].SplitRouting.RoutingTable = "42113"
+	if reflect.DeepEqual(c1, c2) {
+		t.Error("copies should not be equal after modification")
+	}
+}
+
+// TestConnectionsNames tests Names of Connections.
+func TestConnectionsNames(t *testing.T) {
+	c := Connections{
+		"lab":  getTestConnection(),
+		"corp": getTestConnection(),
+		"dev":  getTestConnection(),
+	}
+	want := []string{"corp", "dev", "lab"}
+	got := c.Names()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestNewConnections tests NewConnections.
+func TestNewConnections(t *testing.T) {
+	c := NewConnections()
+	if c == nil || len(c) != 0 || !c.Valid() {
+		t.Errorf("new config should be valid and empty: %v", c)
+	}
+}
+
 // TestVPNDNSRemotes tests Remotes of VPNDNS.
 func TestVPNDNSRemotes(t *testing.T) {
 	// test empty
@@ -704,6 +1229,77 @@ func TestConfigValid(t *testing.T) {
 	}
 }
 
+// TestConfigValidConnections tests Valid of Config with named connections.
+func TestConfigValidConnections(t *testing.T) {
+	// test valid
+	c := NewConfig()
+	c.Connections["lab"] = getTestConnection()
+	if !c.Valid() {
+		t.Errorf("config should be valid: %v", c)
+	}
+
+	// test settings that overlap with the default connection or other
+	// named connections
+	for _, modify := range []func(conn *Connection){
+		func(conn *Connection) { conn.VPNDevice = OpenConnectVPNDevice },
+		func(conn *Connection) { conn.PIDFile = OpenConnectPIDFile },
+		func(conn *Connection) { conn.SplitRouting.RoutingTable = SplitRoutingRoutingTable },
+		func(conn *Connection) { conn.SplitRouting.RulePriority1 = SplitRoutingRulePriority2 },
+		func(conn *Connection) { conn.SplitRouting.FirewallMark = SplitRoutingFirewallMark },
+		func(conn *Connection) { conn.SplitRouting.NftTable = SplitRoutingNftTable },
+	} {
+		c := NewConfig()
+		c.Connections["lab"] = getTestConnection()
+		conn := getTestConnection()
+		modify(conn)
+		c.Connections["dev"] = conn
+		if c.Valid() {
+			t.Errorf("config should be invalid: %v", conn)
+		}
+	}
+	c = NewConfig()
+	c.Connections["lab"] = getTestConnection()
+	c.Connections["dev"] = getTestConnection()
+	if c.Valid() {
+		t.Error("config with duplicate connections should be invalid")
+	}
+}
+
+// TestConfigConnectionConfig tests ConnectionConfig of Config.
+func TestConfigConnectionConfig(t *testing.T) {
+	c := NewConfig()
+	c.LoginInfo.Server = "vpnserver.example.com"
+	c.VPNConfig.PID = 123
+	c.Connections["lab"] = getTestConnection()
+
+	// test not existing connection
+	if got := c.ConnectionConfig("dev"); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+
+	// test existing connection
+	got := c.ConnectionConfig("lab")
+	conn := getTestConnection()
+	if got.Connection != "lab" ||
+		got.OpenConnect.VPNDevice != conn.VPNDevice ||
+		got.OpenConnect.PIDFile != conn.PIDFile ||
+		!reflect.DeepEqual(got.SplitRouting, conn.SplitRouting) ||
+		!reflect.DeepEqual(got.LoginInfo, &logininfo.LoginInfo{}) ||
+		!got.VPNConfig.Empty() {
+		t.Errorf("invalid connection config: %v", got)
+	}
+	if !reflect.DeepEqual(got.DNSProxy, c.DNSProxy) {
+		t.Errorf("got %v, want %v", got.DNSProxy, c.DNSProxy)
+	}
+
+	// make sure the config is not modified
+	if c.OpenConnect.VPNDevice != OpenConnectVPNDevice ||
+		c.Connection != "" ||
+		c.LoginInfo.Server != "vpnserver.example.com" {
+		t.Errorf("config was modified: %v", c)
+	}
+}
+
 // TestConfigLoad tests Load of Config.
 func TestConfigLoad(t *testing.T) {
 	conf := NewConfig()
@@ -824,7 +1420,8 @@ func TestConfigLoad(t *testing.T) {
 		"RoutingTable": "42111",
 		"RulePriority1": "2111",
 		"RulePriority2": "2112",
-		"FirewallMark": "42111"
+		"FirewallMark": "42111",
+		"NftTable": "oc-daemon-routing"
 	},
 	"TrafficPolicing": {
 		"AllowedHosts": ["connectivity-check.ubuntu.com", "detectportal.firefox.com", "www.gstatic.com", "clients3.google.com", "nmcheck.gnome.org", "networkcheck.kde.org"],
@@ -843,7 +1440,61 @@ func TestConfigLoad(t *testing.T) {
 	},
 	"CommandLists": {
 		"ListsFile": "/var/lib/oc-daemon/command-lists.json"
-	}
+	},
+	"Reconnect": {
+		"Enabled": true,
+		"MaxAttempts": 5,
+		"InitialBackoff": 5000000000,
+		"MaxBackoff": 300000000000
+	},
+	"History": {
+		"File": "/var/lib/oc-daemon/history.json",
+		"MaxSessions": 100
+	},
+	"Metrics": {
+		"Enabled": false,
+		"Address": "127.0.0.1:9869"
+	},
+	"IdleTimeout": {
+		"Enabled": true,
+		"Action": "warn",
+		"WarningTime": 60000000000,
+		"CheckInterval": 10000000000
+	},
+	"Resume": {
+		"Policy": "disconnect",
+		"ValidationTime": 10000000000
+	},
+	"Polkit": {
+		"Enabled": true
+	},
+	"ConnectFailure": {
+		"MaxFailures": 3
+	},
+	"MachineAuth": {
+		"Enabled": false,
+		"VPNServer": "",
+		"ClientCertificate": "",
+		"ClientKey": "",
+		"CACertificate": "",
+		"Protocol": "anyconnect",
+		"UserAgent": "AnyConnect",
+		"Timeout": 30000000000
+	},
+	"NetNS": {
+		"Enabled": false,
+		"Name": "oc-daemon",
+		"HostDevice": "oc-daemon-veth0",
+		"Device": "oc-daemon-veth1",
+		"HostAddress": "169.254.42.1/30",
+		"Address": "169.254.42.2/30",
+		"DNSAddress": "127.0.0.1:53"
+	},
+	"GracefulRestart": {
+		"Enabled": false,
+		"StateFile": "/run/oc-daemon/restart.json"
+	},
+	"Connections": {}
 }`,
 		`{
 	"Verbose": true
@@ -884,6 +1535,17 @@ func TestConfigLoad(t *testing.T) {
 			TrafficPolicing: NewTrafficPolicing(),
 			TND:             tnd.NewConfig(),
 			CommandLists:    NewCommandLists(),
+			Reconnect:       NewReconnect(),
+			History:         NewHistory(),
+			Metrics:         NewMetrics(),
+			IdleTimeout:     NewIdleTimeout(),
+			Resume:          NewResume(),
+			Polkit:          NewPolkit(),
+			ConnectFailure:  NewConnectFailure(),
+			MachineAuth:     NewMachineAuth(),
+			NetNS:           NewNetNS(),
+			GracefulRestart: NewGracefulRestart(),
+			Connections:     NewConnections(),
 			LoginInfo:       &logininfo.LoginInfo{},
 			VPNConfig:       &VPNConfig{},
 		}
@@ -925,6 +1587,17 @@ func TestNewConfig(t *testing.T) {
 		TrafficPolicing: NewTrafficPolicing(),
 		TND:             tnd.NewConfig(),
 		CommandLists:    NewCommandLists(),
+		Reconnect:       NewReconnect(),
+		History:         NewHistory(),
+		Metrics:         NewMetrics(),
+		IdleTimeout:     NewIdleTimeout(),
+		Resume:          NewResume(),
+		Polkit:          NewPolkit(),
+		ConnectFailure:  NewConnectFailure(),
+		MachineAuth:     NewMachineAuth(),
+		NetNS:           NewNetNS(),
+		GracefulRestart: NewGracefulRestart(),
+		Connections:     NewConnections(),
 		LoginInfo:       &logininfo.LoginInfo{},
 		VPNConfig:       &VPNConfig{},
 	}
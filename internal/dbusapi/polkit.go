@@ -0,0 +1,107 @@
+package dbusapi
+
+import (
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+// Polkit actions.
+const (
+	ActionConnect    = "com.telekom_mms.oc_daemon.connect"
+	ActionDisconnect = "com.telekom_mms.oc_daemon.disconnect"
+	ActionDumpState  = "com.telekom_mms.oc_daemon.dump-state"
+	ActionGetHistory = "com.telekom_mms.oc_daemon.get-history"
+	ActionRemediate  = "com.telekom_mms.oc_daemon.remediate-captive-portal"
+)
+
+// Errors.
+const (
+	ErrorAccessDenied        = "org.freedesktop.DBus.Error.AccessDenied"
+	ErrorAuthorizationFailed = Interface + ".AuthorizationFailed"
+)
+
+// polkit authority.
+const (
+	polkitInterface          = "org.freedesktop.PolicyKit1"
+	polkitPath               = "/org/freedesktop/PolicyKit1/Authority"
+	polkitCheckAuthorization = polkitInterface + ".Authority.CheckAuthorization"
+
+	// polkitAllowUserInteraction allows polkit to ask the user for
+	// authentication
+	polkitAllowUserInteraction uint32 = 1
+)
+
+// polkitSubject is the subject of a polkit authorization check.
+type polkitSubject struct {
+	Kind    string
+	Details map[string]dbus.Variant
+}
+
+// polkitResult is the result of a polkit authorization check.
+type polkitResult struct {
+	IsAuthorized bool
+	IsChallenge  bool
+	Details      map[string]string
+}
+
+// dbusGetUnixUser returns the UID of the sender, used for testing.
+var dbusGetUnixUser = func(conn dbusConn, sender dbus.Sender) (uint32, error) {
+	var uid uint32
+	err := conn.(*dbus.Conn).BusObject().
+		Call("org.freedesktop.DBus.GetConnectionUnixUser", 0, string(sender)).
+		Store(&uid)
+	return uid, err
+}
+
+// polkitCheck returns whether sender is authorized for action by polkit,
+// used for testing.
+var polkitCheck = func(conn dbusConn, sender dbus.Sender, action string) (bool, error) {
+	subject := polkitSubject{
+		Kind: "system-bus-name",
+		Details: map[string]dbus.Variant{
+			"name": dbus.MakeVariant(string(sender)),
+		},
+	}
+	result := polkitResult{}
+	err := conn.(*dbus.Conn).Object(polkitInterface, polkitPath).
+		Call(polkitCheckAuthorization, 0, subject, action,
+			map[string]string{}, polkitAllowUserInteraction, "").
+		Store(&result)
+	return result.IsAuthorized, err
+}
+
+// authorize checks if sender is authorized for action. It returns a D-Bus
+// error if sender is not authorized.
+func (d daemon) authorize(sender dbus.Sender, action string) *dbus.Error {
+	if !d.polkit {
+		return nil
+	}
+
+	// root is always authorized
+	uid, err := dbusGetUnixUser(d.conn, sender)
+	if err != nil {
+		log.WithError(err).WithField("sender", sender).
+			Error("D-Bus could not get UID of caller")
+		return dbus.NewError(ErrorAuthorizationFailed, []any{"Could not get UID of caller"})
+	}
+	if uid == 0 {
+		return nil
+	}
+
+	// check authorization with polkit
+	ok, err := polkitCheck(d.conn, sender, action)
+	if err != nil {
+		log.WithError(err).WithField("sender", sender).
+			Error("D-Bus could not check authorization with polkit")
+		return dbus.NewError(ErrorAuthorizationFailed, []any{"Could not check authorization"})
+	}
+	if !ok {
+		log.WithFields(log.Fields{
+			"sender": sender,
+			"uid":    uid,
+			"action": action,
+		}).Warn("D-Bus denied unauthorized call")
+		return dbus.NewError(ErrorAccessDenied, []any{"Not authorized for " + action})
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package dbusapi
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// TestDaemonAuthorize tests authorize of daemon.
+func TestDaemonAuthorize(t *testing.T) {
+	// clean up after tests
+	oldGetUnixUser := dbusGetUnixUser
+	oldPolkitCheck := polkitCheck
+	defer func() {
+		dbusGetUnixUser = oldGetUnixUser
+		polkitCheck = oldPolkitCheck
+	}()
+
+	var uid uint32
+	var uidErr, checkErr error
+	authorized := false
+	dbusGetUnixUser = func(dbusConn, dbus.Sender) (uint32, error) {
+		return uid, uidErr
+	}
+	polkitCheck = func(dbusConn, dbus.Sender, string) (bool, error) {
+		return authorized, checkErr
+	}
+
+	// polkit disabled
+	d := daemon{}
+	uidErr = errors.New("test error")
+	if err := d.authorize("sender", ActionConnect); err != nil {
+		t.Errorf("disabled polkit should authorize, got %v", err)
+	}
+
+	// uid error
+	d.polkit = true
+	if err := d.authorize("sender", ActionConnect); err == nil ||
+		err.Name != ErrorAuthorizationFailed {
+		t.Errorf("uid error should return authorization failed, got %v", err)
+	}
+
+	// root
+	uidErr = nil
+	checkErr = errors.New("test error")
+	if err := d.authorize("sender", ActionConnect); err != nil {
+		t.Errorf("root should be authorized, got %v", err)
+	}
+
+	// polkit error
+	uid = 1000
+	if err := d.authorize("sender", ActionConnect); err == nil ||
+		err.Name != ErrorAuthorizationFailed {
+		t.Errorf("polkit error should return authorization failed, got %v", err)
+	}
+
+	// denied
+	checkErr = nil
+	if err := d.authorize("sender", ActionConnect); err == nil ||
+		err.Name != ErrorAccessDenied {
+		t.Errorf("denied should return access denied, got %v", err)
+	}
+
+	// authorized
+	authorized = true
+	if err := d.authorize("sender", ActionConnect); err != nil {
+		t.Errorf("should be authorized, got %v", err)
+	}
+}
+
+// TestDaemonUnauthorized tests methods of daemon with unauthorized caller.
+func TestDaemonUnauthorized(t *testing.T) {
+	// clean up after tests
+	oldGetUnixUser := dbusGetUnixUser
+	oldPolkitCheck := polkitCheck
+	defer func() {
+		dbusGetUnixUser = oldGetUnixUser
+		polkitCheck = oldPolkitCheck
+	}()
+
+	dbusGetUnixUser = func(dbusConn, dbus.Sender) (uint32, error) {
+		return 1000, nil
+	}
+	polkitCheck = func(dbusConn, dbus.Sender, string) (bool, error) {
+		return false, nil
+	}
+
+	// requests must not be sent
+	d := daemon{
+		requests: nil,
+		done:     make(chan struct{}),
+		polkit:   true,
+	}
+	if err := d.Connect("sender", "", "", "", "", "", ""); err == nil {
+		t.Error("connect should return error")
+	}
+	if err := d.Disconnect("sender"); err == nil {
+		t.Error("disconnect should return error")
+	}
+	if _, err := d.DumpState("sender"); err == nil {
+		t.Error("dump state should return error")
+	}
+	if _, err := d.GetHistory("sender"); err == nil {
+		t.Error("get history should return error")
+	}
+}
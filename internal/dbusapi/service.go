@@ -9,6 +9,7 @@ import (
 	"github.com/godbus/dbus/v5/introspect"
 	"github.com/godbus/dbus/v5/prop"
 	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
 )
 
 // D-Bus object path and interface.
@@ -20,6 +21,64 @@ const (
 // PropertiesChanged is the DBus properties changed signal.
 const PropertiesChanged = "org.freedesktop.DBus.Properties.PropertiesChanged"
 
+// Signals.
+const (
+	SignalConnectFailed          = "ConnectFailed"
+	SignalReconnecting           = "Reconnecting"
+	SignalTrustedNetworkDetected = "TrustedNetworkDetected"
+	SignalCaptivePortalDetected  = "CaptivePortalDetected"
+	SignalCommandFailed          = "CommandFailed"
+	SignalProfileReloaded        = "ProfileReloaded"
+	SignalIdleTimeoutWarning     = "IdleTimeoutWarning"
+	SignalRemediationExpired     = "CaptivePortalRemediationExpired"
+)
+
+// signalsSpec are the signals and their arguments for introspection.
+var signalsSpec = []introspect.Signal{
+	{
+		Name: SignalConnectFailed,
+		Args: []introspect.Arg{
+			{Name: "reason", Type: "s"},
+		},
+	},
+	{
+		Name: SignalReconnecting,
+		Args: []introspect.Arg{
+			{Name: "attempt", Type: "u"},
+			{Name: "reconnect_at", Type: "x"},
+		},
+	},
+	{
+		Name: SignalTrustedNetworkDetected,
+	},
+	{
+		Name: SignalCaptivePortalDetected,
+		Args: []introspect.Arg{
+			{Name: "url", Type: "s"},
+		},
+	},
+	{
+		Name: SignalCommandFailed,
+		Args: []introspect.Arg{
+			{Name: "list", Type: "s"},
+			{Name: "command", Type: "s"},
+			{Name: "stderr", Type: "s"},
+		},
+	},
+	{
+		Name: SignalProfileReloaded,
+	},
+	{
+		Name: SignalIdleTimeoutWarning,
+		Args: []introspect.Arg{
+			{Name: "idle_timeout_at", Type: "x"},
+		},
+	},
+	{
+		Name: SignalRemediationExpired,
+	},
+}
+
 // Properties.
 const (
 	PropertyTrustedNetwork  = "TrustedNetwork"
@@ -37,7 +96,31 @@ const (
 	PropertyCaptivePortal   = "CaptivePortal"
 	PropertyTNDState        = "TNDState"
 	PropertyTNDServers      = "TNDServers"
+	PropertyTNDMatches      = "TNDMatches"
 	PropertyVPNConfig       = "VPNConfig"
+
+	PropertyReconnectAttempts     = "ReconnectAttempts"
+	PropertyReconnectAt           = "ReconnectAt"
+	PropertyReconnectGiveUpReason = "ReconnectGiveUpReason"
+
+	PropertyIdleTimeoutAt = "IdleTimeoutAt"
+
+	PropertyResumeAction  = "ResumeAction"
+	PropertyResumeOutcome = "ResumeOutcome"
+
+	PropertyDisconnectPolicy = "DisconnectPolicy"
+
+	PropertyConnectFailureState = "ConnectFailureState"
+	PropertyConnectFailures     = "ConnectFailures"
+
+	PropertyNetworkPolicyAction   = "NetworkPolicyAction"
+	PropertyNetworkPolicyDecision = "NetworkPolicyDecision"
+
+	PropertyConnections = "Connections"
+
+	PropertyNetNS = "NetNS"
+
+	PropertyCommandListError = "CommandListError"
 )
 
 // Property "Trusted Network" states.
@@ -129,16 +212,102 @@ var (
 	TNDServersInvalid []string
 )
 
+// Property "TND Matches" values.
+var (
+	TNDMatchesInvalid []string
+)
+
 // Property "VPNConfig" values.
 const (
 	VPNConfigInvalid = ""
 )
 
+// Property "Reconnect Attempts" values.
+const (
+	ReconnectAttemptsInvalid uint32 = 0
+)
+
+// Property "Reconnect At" values.
+const (
+	ReconnectAtInvalid int64 = -1
+)
+
+// Property "Reconnect Give Up Reason" values.
+const (
+	ReconnectGiveUpReasonInvalid = ""
+)
+
+// Property "Idle Timeout At" values.
+const (
+	IdleTimeoutAtInvalid int64 = -1
+)
+
+// Property "Resume Action" values.
+const (
+	ResumeActionInvalid = ""
+)
+
+// Property "Resume Outcome" values.
+const (
+	ResumeOutcomeInvalid = ""
+)
+
+// Property "Disconnect Policy" policies.
+const (
+	DisconnectPolicyUnknown uint32 = iota
+	DisconnectPolicyAllowed
+	DisconnectPolicyLocked
+)
+
+// Property "Connect Failure State" states.
+const (
+	ConnectFailureStateUnknown uint32 = iota
+	ConnectFailureStateInactive
+	ConnectFailureStateClosed
+	ConnectFailureStateOpen
+	ConnectFailureStateRelaxed
+)
+
+// Property "Connect Failures" values.
+const (
+	ConnectFailuresInvalid uint32 = 0
+)
+
+// Property "Network Policy Action" values.
+const (
+	NetworkPolicyActionInvalid = ""
+)
+
+// Property "Network Policy Decision" values.
+const (
+	NetworkPolicyDecisionInvalid = ""
+)
+
+// Property "Connections" values.
+const (
+	ConnectionsInvalid = ""
+)
+
+// Property "NetNS" values.
+const (
+	NetNSInvalid = ""
+)
+
+// Property "Command List Error" values.
+const (
+	CommandListErrorInvalid = ""
+)
+
 // Methods.
 const (
 	MethodConnect    = Interface + ".Connect"
 	MethodDisconnect = Interface + ".Disconnect"
 	MethodDumpState  = Interface + ".DumpState"
+	MethodGetHistory = Interface + ".GetHistory"
+	MethodRemediate  = Interface + ".RemediateCaptivePortal"
+
+	MethodConnectNamed    = Interface + ".ConnectNamed"
+	MethodDisconnectNamed = Interface + ".DisconnectNamed"
 )
 
 // Request Names.
@@ -146,8 +315,25 @@ const (
 	RequestConnect    = "Connect"
 	RequestDisconnect = "Disconnect"
 	RequestDumpState  = "DumpState"
+	RequestGetHistory = "GetHistory"
+	RequestRemediate  = "RemediateCaptivePortal"
+
+	RequestConnectNamed    = "ConnectNamed"
+	RequestDisconnectNamed = "DisconnectNamed"
 )
 
+// ErrorDisconnectLocked is the D-Bus error returned when the disconnect
+// request is refused because the disconnect policy is locked.
+const ErrorDisconnectLocked = Interface + ".DisconnectLocked"
+
+// ErrDisconnectLocked is the request error when the disconnect request is
+// refused because the disconnect policy is locked.
+var ErrDisconnectLocked = errors.New("Disconnect not allowed by XML profile")
+
+// ErrUnknownConnection is the request error when a request refers to a named
+// VPN connection that is not configured.
+var ErrUnknownConnection = errors.New("unknown VPN connection")
+
 // Request is a D-Bus client request.
 type Request struct {
 	Name       string
@@ -186,11 +372,20 @@ func NewRequest(name string, done chan struct{}) *Request {
 type daemon struct {
 	requests chan *Request
 	done     chan struct{}
+
+	// conn is the D-Bus connection for authorization checks
+	conn dbusConn
+
+	// polkit specifies whether callers are authorized with polkit
+	polkit bool
 }
 
 // Connect is the "Connect" method of the D-Bus interface.
 func (d daemon) Connect(sender dbus.Sender, server, cookie, host, connectURL, fingerprint, resolve string) *dbus.Error {
 	log.WithField("sender", sender).Debug("Received D-Bus Connect() call")
+	if err := d.authorize(sender, ActionConnect); err != nil {
+		return err
+	}
 	request := NewRequest(RequestConnect, d.done)
 	request.Parameters = []any{server, cookie, host, connectURL, fingerprint, resolve}
 	select {
@@ -209,6 +404,9 @@ func (d daemon) Connect(sender dbus.Sender, server, cookie, host, connectURL, fi
 // Disconnect is the "Disconnect" method of the D-Bus interface.
 func (d daemon) Disconnect(sender dbus.Sender) *dbus.Error {
 	log.WithField("sender", sender).Debug("Received D-Bus Disconnect() call")
+	if err := d.authorize(sender, ActionDisconnect); err != nil {
+		return err
+	}
 	request := NewRequest(RequestDisconnect, d.done)
 	select {
 	case d.requests <- request:
@@ -217,15 +415,69 @@ func (d daemon) Disconnect(sender dbus.Sender) *dbus.Error {
 	}
 
 	request.Wait()
+	if errors.Is(request.Error, ErrDisconnectLocked) {
+		return dbus.NewError(ErrorDisconnectLocked, []any{request.Error.Error()})
+	}
 	if request.Error != nil {
 		return dbus.NewError(Interface+".DisconnectAborted", []any{request.Error.Error()})
 	}
 	return nil
 }
 
+// ConnectNamed is the "ConnectNamed" method of the D-Bus interface.
+func (d daemon) ConnectNamed(sender dbus.Sender, name, server, cookie, host, connectURL, fingerprint, resolve string) *dbus.Error {
+	log.WithFields(log.Fields{
+		"sender":     sender,
+		"connection": name,
+	}).Debug("Received D-Bus ConnectNamed() call")
+	if err := d.authorize(sender, ActionConnect); err != nil {
+		return err
+	}
+	request := NewRequest(RequestConnectNamed, d.done)
+	request.Parameters = []any{name, server, cookie, host, connectURL, fingerprint, resolve}
+	select {
+	case d.requests <- request:
+	case <-d.done:
+		return dbus.NewError(Interface+".ConnectNamedAborted", []any{"ConnectNamed aborted"})
+	}
+
+	request.Wait()
+	if request.Error != nil {
+		return dbus.NewError(Interface+".ConnectNamedAborted", []any{request.Error.Error()})
+	}
+	return nil
+}
+
+// DisconnectNamed is the "DisconnectNamed" method of the D-Bus interface.
+func (d daemon) DisconnectNamed(sender dbus.Sender, name string) *dbus.Error {
+	log.WithFields(log.Fields{
+		"sender":     sender,
+		"connection": name,
+	}).Debug("Received D-Bus DisconnectNamed() call")
+	if err := d.authorize(sender, ActionDisconnect); err != nil {
+		return err
+	}
+	request := NewRequest(RequestDisconnectNamed, d.done)
+	request.Parameters = []any{name}
+	select {
+	case d.requests <- request:
+	case <-d.done:
+		return dbus.NewError(Interface+".DisconnectNamedAborted", []any{"DisconnectNamed aborted"})
+	}
+
+	request.Wait()
+	if request.Error != nil {
+		return dbus.NewError(Interface+".DisconnectNamedAborted", []any{request.Error.Error()})
+	}
+	return nil
+}
+
 // DumpState is the "DumpState" method of the D-Bus interface.
 func (d daemon) DumpState(sender dbus.Sender) (string, *dbus.Error) {
 	log.WithField("sender", sender).Debug("Received D-Bus DumpState() call")
+	if err := d.authorize(sender, ActionDumpState); err != nil {
+		return "", err
+	}
 	request := NewRequest(RequestDumpState, d.done)
 	select {
 	case d.requests <- request:
@@ -240,16 +492,64 @@ func (d daemon) DumpState(sender dbus.Sender) (string, *dbus.Error) {
 	return request.Results[0].(string), nil
 }
 
+// GetHistory is the "GetHistory" method of the D-Bus interface.
+func (d daemon) GetHistory(sender dbus.Sender) (string, *dbus.Error) {
+	log.WithField("sender", sender).Debug("Received D-Bus GetHistory() call")
+	if err := d.authorize(sender, ActionGetHistory); err != nil {
+		return "", err
+	}
+	request := NewRequest(RequestGetHistory, d.done)
+	select {
+	case d.requests <- request:
+	case <-d.done:
+		return "", dbus.NewError(Interface+".GetHistoryAborted", []any{"GetHistory aborted"})
+	}
+
+	request.Wait()
+	if request.Error != nil {
+		return "", dbus.NewError(Interface+".GetHistoryAborted", []any{request.Error.Error()})
+	}
+	return request.Results[0].(string), nil
+}
+
+// RemediateCaptivePortal is the "RemediateCaptivePortal" method of the D-Bus
+// interface.
+func (d daemon) RemediateCaptivePortal(sender dbus.Sender) *dbus.Error {
+	log.WithField("sender", sender).Debug("Received D-Bus RemediateCaptivePortal() call")
+	if err := d.authorize(sender, ActionRemediate); err != nil {
+		return err
+	}
+	request := NewRequest(RequestRemediate, d.done)
+	select {
+	case d.requests <- request:
+	case <-d.done:
+		return dbus.NewError(Interface+".RemediateCaptivePortalAborted", []any{"RemediateCaptivePortal aborted"})
+	}
+
+	request.Wait()
+	if request.Error != nil {
+		return dbus.NewError(Interface+".RemediateCaptivePortalAborted", []any{request.Error.Error()})
+	}
+	return nil
+}
+
 // propertyUpdate is an update of a property.
 type propertyUpdate struct {
 	name  string
 	value any
 }
 
+// signal is a signal to emit.
+type signal struct {
+	name   string
+	values []any
+}
+
 // DBusAPI is the D-Bus API interface.
 type DBusAPI interface {
 	Requests() chan *Request
 	SetProperty(name string, value any)
+	EmitSignal(name string, values ...any)
 	Start() error
 	Stop()
 }
@@ -258,11 +558,14 @@ var _ DBusAPI = &Service{}
 
 // Service is a D-Bus Service.
 type Service struct {
+	config *daemoncfg.Polkit
+
 	conn  dbusConn
 	props propProperties
 
 	requests chan *Request
 	propUps  chan *propertyUpdate
+	signals  chan *signal
 	done     chan struct{}
 	closed   chan struct{}
 }
@@ -270,6 +573,7 @@ type Service struct {
 // dbusConn is an interface for dbus.Conn to allow for testing.
 type dbusConn interface {
 	Close() error
+	Emit(path dbus.ObjectPath, name string, values ...any) error
 	Export(v any, path dbus.ObjectPath, iface string) error
 	RequestName(name string, flags dbus.RequestNameFlags) (dbus.RequestNameReply, error)
 }
@@ -312,7 +616,22 @@ func (s *Service) start() {
 		s.props.SetMust(Interface, PropertyCaptivePortal, CaptivePortalUnknown)
 		s.props.SetMust(Interface, PropertyTNDState, TNDStateUnknown)
 		s.props.SetMust(Interface, PropertyTNDServers, TNDServersInvalid)
+		s.props.SetMust(Interface, PropertyTNDMatches, TNDMatchesInvalid)
 		s.props.SetMust(Interface, PropertyVPNConfig, VPNConfigInvalid)
+		s.props.SetMust(Interface, PropertyReconnectAttempts, ReconnectAttemptsInvalid)
+		s.props.SetMust(Interface, PropertyReconnectAt, ReconnectAtInvalid)
+		s.props.SetMust(Interface, PropertyReconnectGiveUpReason, ReconnectGiveUpReasonInvalid)
+		s.props.SetMust(Interface, PropertyIdleTimeoutAt, IdleTimeoutAtInvalid)
+		s.props.SetMust(Interface, PropertyResumeAction, ResumeActionInvalid)
+		s.props.SetMust(Interface, PropertyResumeOutcome, ResumeOutcomeInvalid)
+		s.props.SetMust(Interface, PropertyDisconnectPolicy, DisconnectPolicyUnknown)
+		s.props.SetMust(Interface, PropertyConnectFailureState, ConnectFailureStateUnknown)
+		s.props.SetMust(Interface, PropertyConnectFailures, ConnectFailuresInvalid)
+		s.props.SetMust(Interface, PropertyNetworkPolicyAction, NetworkPolicyActionInvalid)
+		s.props.SetMust(Interface, PropertyNetworkPolicyDecision, NetworkPolicyDecisionInvalid)
+		s.props.SetMust(Interface, PropertyConnections, ConnectionsInvalid)
+		s.props.SetMust(Interface, PropertyNetNS, NetNSInvalid)
+		s.props.SetMust(Interface, PropertyCommandListError, CommandListErrorInvalid)
 	}
 
 	// set properties values to emit properties changed signal and make
@@ -330,6 +649,17 @@ func (s *Service) start() {
 			}).Debug("D-Bus updating property")
 			s.props.SetMust(Interface, u.name, u.value)
 
+		case sig := <-s.signals:
+			// emit signal
+			log.WithFields(log.Fields{
+				"name":   sig.name,
+				"values": sig.values,
+			}).Debug("D-Bus emitting signal")
+			if err := s.conn.Emit(Path, Interface+"."+sig.name, sig.values...); err != nil {
+				log.WithError(err).WithField("name", sig.name).
+					Error("D-Bus could not emit signal")
+			}
+
 		case <-s.done:
 			log.Debug("D-Bus service stopping")
 			// set properties values to unknown/invalid to emit
@@ -359,7 +689,12 @@ func (s *Service) Start() error {
 	}
 
 	// methods
-	meths := daemon{s.requests, s.done}
+	meths := daemon{
+		requests: s.requests,
+		done:     s.done,
+		conn:     conn,
+		polkit:   s.config.Enabled,
+	}
 	err = conn.Export(meths, Path, Interface)
 	if err != nil {
 		return fmt.Errorf("could not export D-Bus methods: %w", err)
@@ -458,12 +793,102 @@ func (s *Service) Start() error {
 				Emit:     prop.EmitTrue,
 				Callback: nil,
 			},
+			PropertyTNDMatches: {
+				Value:    TNDMatchesInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
 			PropertyVPNConfig: {
 				Value:    VPNConfigInvalid,
 				Writable: false,
 				Emit:     prop.EmitTrue,
 				Callback: nil,
 			},
+			PropertyReconnectAttempts: {
+				Value:    ReconnectAttemptsInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyReconnectAt: {
+				Value:    ReconnectAtInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyReconnectGiveUpReason: {
+				Value:    ReconnectGiveUpReasonInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyIdleTimeoutAt: {
+				Value:    IdleTimeoutAtInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyResumeAction: {
+				Value:    ResumeActionInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyResumeOutcome: {
+				Value:    ResumeOutcomeInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyDisconnectPolicy: {
+				Value:    DisconnectPolicyUnknown,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyConnectFailureState: {
+				Value:    ConnectFailureStateUnknown,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyConnectFailures: {
+				Value:    ConnectFailuresInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyNetworkPolicyAction: {
+				Value:    NetworkPolicyActionInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyNetworkPolicyDecision: {
+				Value:    NetworkPolicyDecisionInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyConnections: {
+				Value:    ConnectionsInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyNetNS: {
+				Value:    NetNSInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
+			PropertyCommandListError: {
+				Value:    CommandListErrorInvalid,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+				Callback: nil,
+			},
 		},
 	}
 	props, err := propExport(conn, Path, propsSpec)
@@ -485,9 +910,27 @@ func (s *Service) Start() error {
 			m.Args[5].Name = "resolve"
 		}
 
+		if m.Name == "ConnectNamed" {
+			m.Args[0].Name = "name"
+			m.Args[1].Name = "server"
+			m.Args[2].Name = "cookie"
+			m.Args[3].Name = "host"
+			m.Args[4].Name = "connect_url"
+			m.Args[5].Name = "fingerprint"
+			m.Args[6].Name = "resolve"
+		}
+
+		if m.Name == "DisconnectNamed" {
+			m.Args[0].Name = "name"
+		}
+
 		if m.Name == "DumpState" {
 			m.Args[0].Name = "state"
 		}
+
+		if m.Name == "GetHistory" {
+			m.Args[0].Name = "history"
+		}
 	}
 	// set peer interface
 	peerData := introspect.Interface{
@@ -513,6 +956,7 @@ func (s *Service) Start() error {
 			{
 				Name:       Interface,
 				Methods:    introMeths,
+				Signals:    signalsSpec,
 				Properties: props.Introspection(Interface),
 			},
 		},
@@ -546,11 +990,22 @@ func (s *Service) SetProperty(name string, value any) {
 	}
 }
 
+// EmitSignal emits signal with name and values.
+func (s *Service) EmitSignal(name string, values ...any) {
+	select {
+	case s.signals <- &signal{name, values}:
+	case <-s.done:
+	}
+}
+
 // NewService returns a new service.
-func NewService() *Service {
+func NewService(config *daemoncfg.Polkit) *Service {
 	return &Service{
+		config: config,
+
 		requests: make(chan *Request),
 		propUps:  make(chan *propertyUpdate),
+		signals:  make(chan *signal),
 		done:     make(chan struct{}),
 		closed:   make(chan struct{}),
 	}
@@ -8,6 +8,7 @@ import (
 	"github.com/godbus/dbus/v5"
 	"github.com/godbus/dbus/v5/introspect"
 	"github.com/godbus/dbus/v5/prop"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
 )
 
 // TestRequestWaitClose tests Wait and Close of Request.
@@ -134,6 +135,17 @@ func TestDaemonDisconnectErrors(t *testing.T) {
 		t.Error("should return error")
 	}
 
+	// disconnect locked
+	go func() {
+		r := <-requests
+		r.Error = ErrDisconnectLocked
+		r.Close()
+	}()
+	if err := daemon.Disconnect(""); err == nil ||
+		err.Name != ErrorDisconnectLocked {
+		t.Errorf("should return disconnect locked error, got %v", err)
+	}
+
 	// closed daemon
 	close(done)
 	if err := daemon.Disconnect(""); err == nil {
@@ -178,6 +190,134 @@ func TestDaemonDisconnect(t *testing.T) {
 	}
 }
 
+// TestDaemonConnectNamedErrors tests ConnectNamed of daemon, errors.
+func TestDaemonConnectNamedErrors(t *testing.T) {
+	// create daemon
+	requests := make(chan *Request)
+	done := make(chan struct{})
+	daemon := daemon{
+		requests: requests,
+		done:     done,
+	}
+
+	// error when handling request
+	go func() {
+		r := <-requests
+		r.Error = ErrUnknownConnection
+		r.Close()
+	}()
+	if err := daemon.ConnectNamed("", "", "", "", "", "", "", ""); err == nil {
+		t.Error("should return error")
+	}
+
+	// closed daemon
+	close(done)
+	if err := daemon.ConnectNamed("", "", "", "", "", "", "", ""); err == nil {
+		t.Error("should return error")
+	}
+}
+
+// TestDaemonConnectNamed tests ConnectNamed of daemon.
+func TestDaemonConnectNamed(t *testing.T) {
+	// create daemon
+	requests := make(chan *Request)
+	done := make(chan struct{})
+	daemon := daemon{
+		requests: requests,
+		done:     done,
+	}
+
+	// run connect and get results
+	name, server, cookie, host, connectURL, fingerprint, resolve :=
+		"lab", "server", "cookie", "host", "connectURL", "fingerprint", "resolve"
+	want := &Request{
+		Name:       RequestConnectNamed,
+		Parameters: []any{name, server, cookie, host, connectURL, fingerprint, resolve},
+		done:       done,
+	}
+	got := &Request{}
+	go func() {
+		r := <-requests
+		got = r
+		r.Close()
+	}()
+	err := daemon.ConnectNamed("sender", name, server, cookie, host, connectURL, fingerprint, resolve)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// check results
+	if got.Name != want.Name ||
+		!reflect.DeepEqual(got.Parameters, want.Parameters) ||
+		got.done != want.done {
+		// not equal
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestDaemonDisconnectNamedErrors tests DisconnectNamed of daemon, errors.
+func TestDaemonDisconnectNamedErrors(t *testing.T) {
+	// create daemon
+	requests := make(chan *Request)
+	done := make(chan struct{})
+	daemon := daemon{
+		requests: requests,
+		done:     done,
+	}
+
+	// error when handling request
+	go func() {
+		r := <-requests
+		r.Error = ErrUnknownConnection
+		r.Close()
+	}()
+	if err := daemon.DisconnectNamed("", "lab"); err == nil {
+		t.Error("should return error")
+	}
+
+	// closed daemon
+	close(done)
+	if err := daemon.DisconnectNamed("", "lab"); err == nil {
+		t.Error("should return error")
+	}
+}
+
+// TestDaemonDisconnectNamed tests DisconnectNamed of daemon.
+func TestDaemonDisconnectNamed(t *testing.T) {
+	// create daemon
+	requests := make(chan *Request)
+	done := make(chan struct{})
+	daemon := daemon{
+		requests: requests,
+		done:     done,
+	}
+
+	// run disconnect and get results
+	want := &Request{
+		Name:       RequestDisconnectNamed,
+		Parameters: []any{"lab"},
+		done:       done,
+	}
+	got := &Request{}
+	go func() {
+		r := <-requests
+		got = r
+		r.Close()
+	}()
+	err := daemon.DisconnectNamed("sender", "lab")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// check results
+	if got.Name != want.Name ||
+		!reflect.DeepEqual(got.Parameters, want.Parameters) ||
+		got.done != want.done {
+		// not equal
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
 // TestDaemonDumpStateErrors tests DumpState of daemon, errors.
 func TestDaemonDumpStateErrors(t *testing.T) {
 	// create daemon
@@ -245,18 +385,157 @@ func TestDaemonDumpState(t *testing.T) {
 	}
 }
 
+// TestDaemonRemediateCaptivePortalErrors tests RemediateCaptivePortal of
+// daemon, errors.
+func TestDaemonRemediateCaptivePortalErrors(t *testing.T) {
+	// create daemon
+	requests := make(chan *Request)
+	done := make(chan struct{})
+	daemon := daemon{
+		requests: requests,
+		done:     done,
+	}
+
+	// error when handling request
+	go func() {
+		r := <-requests
+		r.Error = errors.New("test error")
+		r.Close()
+	}()
+	if err := daemon.RemediateCaptivePortal(""); err == nil {
+		t.Error("should return error")
+	}
+
+	// closed daemon
+	close(done)
+	if err := daemon.RemediateCaptivePortal(""); err == nil {
+		t.Error("should return error")
+	}
+}
+
+// TestDaemonRemediateCaptivePortal tests RemediateCaptivePortal of daemon.
+func TestDaemonRemediateCaptivePortal(t *testing.T) {
+	// create daemon
+	requests := make(chan *Request)
+	done := make(chan struct{})
+	daemon := daemon{
+		requests: requests,
+		done:     done,
+	}
+
+	// run remediate and get results
+	want := &Request{
+		Name: RequestRemediate,
+		done: done,
+	}
+	got := &Request{}
+	go func() {
+		r := <-requests
+		got = r
+		r.Close()
+	}()
+	err := daemon.RemediateCaptivePortal("sender")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// check results
+	if got.Name != want.Name ||
+		!reflect.DeepEqual(got.Parameters, want.Parameters) ||
+		!reflect.DeepEqual(got.Results, want.Results) ||
+		got.Error != want.Error ||
+		got.done != want.done {
+		// not equal
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestDaemonGetHistoryErrors tests GetHistory of daemon, errors.
+func TestDaemonGetHistoryErrors(t *testing.T) {
+	// create daemon
+	requests := make(chan *Request)
+	done := make(chan struct{})
+	daemon := daemon{
+		requests: requests,
+		done:     done,
+	}
+
+	// error when handling request
+	go func() {
+		r := <-requests
+		r.Error = errors.New("test error")
+		r.Close()
+	}()
+	if _, err := daemon.GetHistory(""); err == nil {
+		t.Error("should return error")
+	}
+
+	// closed daemon
+	close(done)
+	if _, err := daemon.GetHistory(""); err == nil {
+		t.Error("should return error")
+	}
+}
+
+// TestDaemonGetHistory tests GetHistory of daemon.
+func TestDaemonGetHistory(t *testing.T) {
+	// create daemon
+	requests := make(chan *Request)
+	done := make(chan struct{})
+	daemon := daemon{
+		requests: requests,
+		done:     done,
+	}
+
+	// run get history and get results
+	want := &Request{
+		Name:    RequestGetHistory,
+		Results: []any{"test history"},
+		done:    done,
+	}
+	got := &Request{}
+	go func() {
+		r := <-requests
+		r.Results = append(r.Results, "test history")
+		got = r
+		r.Close()
+	}()
+	history, err := daemon.GetHistory("sender")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// check results
+	if got.Name != want.Name ||
+		!reflect.DeepEqual(got.Parameters, want.Parameters) ||
+		!reflect.DeepEqual(got.Results, want.Results) ||
+		got.Error != want.Error ||
+		got.done != want.done ||
+		history != "test history" {
+		// not equal
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
 // testConn implements the dbusConn interface for testing.
 type testConn struct {
 	reqNameReply dbus.RequestNameReply
 	reqNameError error
 	exportOKNum  int
 	exportError  error
+	emitError    error
+	emitted      []*signal
 }
 
 func (tc *testConn) Close() error {
 	return nil
 }
 
+func (tc *testConn) Emit(_ dbus.ObjectPath, name string, values ...any) error {
+	tc.emitted = append(tc.emitted, &signal{name, values})
+	return tc.emitError
+}
+
 func (tc *testConn) Export(any, dbus.ObjectPath, string) error {
 	if tc.exportOKNum > 0 {
 		tc.exportOKNum--
@@ -308,7 +587,7 @@ func TestServiceStartStop(t *testing.T) {
 	propExport = func(dbusConn, dbus.ObjectPath, prop.Map) (propProperties, error) {
 		return &testProperties{}, nil
 	}
-	s := NewService()
+	s := NewService(daemoncfg.NewPolkit())
 	if err := s.Start(); err != nil {
 		t.Error(err)
 	}
@@ -322,7 +601,7 @@ func TestServiceStartStop(t *testing.T) {
 			exportError:  errors.New("test error"),
 		}, nil
 	}
-	s = NewService()
+	s = NewService(daemoncfg.NewPolkit())
 	if err := s.Start(); err == nil {
 		t.Error("conn export introspectable error should return error")
 	}
@@ -331,7 +610,7 @@ func TestServiceStartStop(t *testing.T) {
 	propExport = func(dbusConn, dbus.ObjectPath, prop.Map) (propProperties, error) {
 		return nil, errors.New("test error")
 	}
-	s = NewService()
+	s = NewService(daemoncfg.NewPolkit())
 	if err := s.Start(); err == nil {
 		t.Error("props export error should return error")
 	}
@@ -343,7 +622,7 @@ func TestServiceStartStop(t *testing.T) {
 			exportError:  errors.New("test error"),
 		}, nil
 	}
-	s = NewService()
+	s = NewService(daemoncfg.NewPolkit())
 	if err := s.Start(); err == nil {
 		t.Error("conn export methods error should return error")
 	}
@@ -354,7 +633,7 @@ func TestServiceStartStop(t *testing.T) {
 			reqNameReply: dbus.RequestNameReplyExists,
 		}, nil
 	}
-	s = NewService()
+	s = NewService(daemoncfg.NewPolkit())
 	if err := s.Start(); err == nil {
 		t.Error("bus name already taken should return error")
 	}
@@ -365,7 +644,7 @@ func TestServiceStartStop(t *testing.T) {
 			reqNameError: errors.New("test error"),
 		}, nil
 	}
-	s = NewService()
+	s = NewService(daemoncfg.NewPolkit())
 	if err := s.Start(); err == nil {
 		t.Error("conn request name error should return error")
 	}
@@ -374,7 +653,7 @@ func TestServiceStartStop(t *testing.T) {
 	dbusConnectSystemBus = func(...dbus.ConnOption) (dbusConn, error) {
 		return nil, errors.New("test error")
 	}
-	s = NewService()
+	s = NewService(daemoncfg.NewPolkit())
 	if err := s.Start(); err == nil {
 		t.Error("dbus connect error should return error")
 	}
@@ -382,7 +661,7 @@ func TestServiceStartStop(t *testing.T) {
 
 // TestServiceRequests tests Requests of Service.
 func TestServiceRequests(t *testing.T) {
-	s := NewService()
+	s := NewService(daemoncfg.NewPolkit())
 	want := s.requests
 	got := s.Requests()
 	if got != want {
@@ -410,7 +689,7 @@ func TestServiceSetProperty(t *testing.T) {
 	propExport = func(dbusConn, dbus.ObjectPath, prop.Map) (propProperties, error) {
 		return properties, nil
 	}
-	s := NewService()
+	s := NewService(daemoncfg.NewPolkit())
 	if err := s.Start(); err != nil {
 		t.Error(err)
 	}
@@ -427,9 +706,51 @@ func TestServiceSetProperty(t *testing.T) {
 	}
 }
 
+// TestServiceEmitSignal tests EmitSignal of Service.
+func TestServiceEmitSignal(t *testing.T) {
+	// clean up after tests
+	oldDbusConnectSystemBus := dbusConnectSystemBus
+	oldPropExport := propExport
+	defer func() {
+		dbusConnectSystemBus = oldDbusConnectSystemBus
+		propExport = oldPropExport
+	}()
+
+	conn := &testConn{
+		reqNameReply: dbus.RequestNameReplyPrimaryOwner,
+		exportOKNum:  2,
+		emitError:    errors.New("test error"),
+	}
+	dbusConnectSystemBus = func(...dbus.ConnOption) (dbusConn, error) {
+		return conn, nil
+	}
+	propExport = func(dbusConn, dbus.ObjectPath, prop.Map) (propProperties, error) {
+		return &testProperties{}, nil
+	}
+	s := NewService(daemoncfg.NewPolkit())
+	if err := s.Start(); err != nil {
+		t.Error(err)
+	}
+
+	s.EmitSignal(SignalConnectFailed, "test reason")
+	s.EmitSignal(SignalProfileReloaded)
+	s.Stop()
+
+	// emitting after stop should not block
+	s.EmitSignal(SignalProfileReloaded)
+
+	want := []*signal{
+		{Interface + "." + SignalConnectFailed, []any{"test reason"}},
+		{Interface + "." + SignalProfileReloaded, nil},
+	}
+	if !reflect.DeepEqual(conn.emitted, want) {
+		t.Errorf("got %v, want %v", conn.emitted, want)
+	}
+}
+
 // TestNewService tests NewService.
 func TestNewService(t *testing.T) {
-	s := NewService()
+	s := NewService(daemoncfg.NewPolkit())
 	empty := &Service{}
 	if reflect.DeepEqual(s, empty) {
 		t.Errorf("got empty, want not empty")
@@ -0,0 +1,50 @@
+package dnsproxy
+
+import (
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/telekom-mms/oc-daemon/internal/netnsexec"
+)
+
+// netnsDialTimeout is the dial timeout for remote servers in the network
+// namespace.
+const netnsDialTimeout = 2 * time.Second
+
+// inNetNS runs f in the network namespace with name. Sockets created by f
+// stay in the network namespace, used for testing.
+var inNetNS = netnsexec.Run
+
+// listenNetNS creates the listener of server in the network namespace with
+// name.
+func listenNetNS(name string, server *dns.Server) error {
+	return inNetNS(name, func() error {
+		if server.Net == "tcp" {
+			l, err := net.Listen(server.Net, server.Addr)
+			server.Listener = l
+			return err
+		}
+		l, err := net.ListenPacket(server.Net, server.Addr)
+		server.PacketConn = l
+		return err
+	})
+}
+
+// exchangeNetNS sends the request r to the remote server from the network
+// namespace with name and returns the reply.
+func exchangeNetNS(name string, r *dns.Msg, remote string) (*dns.Msg, error) {
+	var conn net.Conn
+	if err := inNetNS(name, func() (err error) {
+		conn, err = net.DialTimeout("udp", remote, netnsDialTimeout)
+		return
+	}); err != nil {
+		return nil, err
+	}
+	co := &dns.Conn{Conn: conn}
+	defer func() { _ = co.Close() }()
+
+	client := &dns.Client{Net: "udp"}
+	reply, _, err := client.ExchangeWithConn(r, co)
+	return reply, err
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/miekg/dns"
 	log "github.com/sirupsen/logrus"
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/metrics"
 )
 
 // State is the internal state of the DNS Proxy.
@@ -19,7 +20,12 @@ type State struct {
 
 // Proxy is a DNS proxy.
 type Proxy struct {
-	config  *daemoncfg.DNSProxy
+	config *daemoncfg.DNSProxy
+
+	// netns is the name of the network namespace the proxy runs in,
+	// empty for the host
+	netns string
+
 	udp     *dns.Server
 	tcp     *dns.Server
 	remotes *Remotes
@@ -47,10 +53,13 @@ func (p *Proxy) waitReport(report *Report) {
 
 // handleRequest handles a dns client request.
 func (p *Proxy) handleRequest(w dns.ResponseWriter, r *dns.Msg) {
+	metrics.DNSProxyQueries.Inc()
+
 	// make sure the client request is valid
 	if len(r.Question) != 1 {
 		// TODO: be less strict? send error reply to client?
 		log.WithField("request", r).Error("DNS-Proxy received invalid client request")
+		metrics.DNSProxyErrors.Inc()
 		return
 	}
 
@@ -60,14 +69,16 @@ func (p *Proxy) handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 		log.WithField("name", r.Question[0].Name).
 			Error("DNS-Proxy has no remotes for question name")
 		// TODO: send error reply to client?
+		metrics.DNSProxyErrors.Inc()
 		return
 	}
 	// pick random remote server
 	// TODO: query all servers and take fastest reply?
 	remote := remotes[rand.Intn(len(remotes))]
-	reply, err := dns.Exchange(r, remote)
+	reply, err := p.exchange(r, remote)
 	if err != nil {
 		log.WithError(err).Debug("DNS-Proxy DNS exchange error")
+		metrics.DNSProxyErrors.Inc()
 		return
 	}
 
@@ -166,7 +177,27 @@ func (p *Proxy) handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 	// send reply to client
 	if err := w.WriteMsg(reply); err != nil {
 		log.WithError(err).Error("DNS-Proxy could not forward reply")
+		metrics.DNSProxyErrors.Inc()
+	}
+}
+
+// exchange sends the request r to the remote server and returns the reply.
+func (p *Proxy) exchange(r *dns.Msg, remote string) (*dns.Msg, error) {
+	if p.netns == "" {
+		return dns.Exchange(r, remote)
+	}
+	return exchangeNetNS(p.netns, r, remote)
+}
+
+// listenAndServe starts the dns server, in the network namespace if set.
+func (p *Proxy) listenAndServe(server *dns.Server) error {
+	if p.netns == "" {
+		return server.ListenAndServe()
 	}
+	if err := listenNetNS(p.netns, server); err != nil {
+		return err
+	}
+	return server.ActivateAndServe()
 }
 
 // startDNSServer starts the dns server.
@@ -176,10 +207,11 @@ func (p *Proxy) startDNSServer(server *dns.Server) {
 	}
 
 	log.WithFields(log.Fields{
-		"addr": server.Addr,
-		"net":  server.Net,
+		"addr":  server.Addr,
+		"net":   server.Net,
+		"netns": p.netns,
 	}).Debug("DNS-Proxy starting server")
-	err := server.ListenAndServe()
+	err := p.listenAndServe(server)
 	if err != nil {
 		log.WithError(err).Error("DNS-Proxy DNS server stopped")
 	}
@@ -265,24 +297,26 @@ func (p *Proxy) GetState() *State {
 	}
 }
 
-// NewProxy returns a new Proxy that listens on address.
-func NewProxy(config *daemoncfg.DNSProxy) *Proxy {
+// newProxy returns a new Proxy that listens on address in the network
+// namespace netns.
+func newProxy(config *daemoncfg.DNSProxy, address, netns string) *Proxy {
 	var udp *dns.Server
 	if config.ListenUDP {
 		udp = &dns.Server{
-			Addr: config.Address,
+			Addr: address,
 			Net:  "udp",
 		}
 	}
 	var tcp *dns.Server
 	if config.ListenTCP {
 		tcp = &dns.Server{
-			Addr: config.Address,
+			Addr: address,
 			Net:  "tcp",
 		}
 	}
 	return &Proxy{
 		config:  config,
+		netns:   netns,
 		udp:     udp,
 		tcp:     tcp,
 		remotes: NewRemotes(),
@@ -292,3 +326,14 @@ func NewProxy(config *daemoncfg.DNSProxy) *Proxy {
 		closed:  make(chan struct{}),
 	}
 }
+
+// NewProxy returns a new Proxy that listens on address.
+func NewProxy(config *daemoncfg.DNSProxy) *Proxy {
+	return newProxy(config, config.Address, "")
+}
+
+// NewNetNSProxy returns a new Proxy that runs in the network namespace and
+// listens on the DNS address of the network namespace.
+func NewNetNSProxy(config *daemoncfg.DNSProxy, netns *daemoncfg.NetNS) *Proxy {
+	return newProxy(config, netns.DNSAddress, netns.Name)
+}
@@ -322,3 +322,73 @@ func TestNewProxy(t *testing.T) {
 		t.Errorf("got nil, want != nil")
 	}
 }
+
+// TestProxyNetNS tests Proxy in network namespace.
+func TestProxyNetNS(t *testing.T) {
+	oldInNetNS := inNetNS
+	defer func() { inNetNS = oldInNetNS }()
+	names := []string{}
+	inNetNS = func(name string, f func() error) error {
+		names = append(names, name)
+		return f()
+	}
+
+	netns := daemoncfg.NewNetNS()
+	netns.DNSAddress = "127.0.0.1:4255"
+	p := NewNetNSProxy(getTestConfig(), netns)
+	if p.netns != "oc-daemon" ||
+		p.udp.Addr != "127.0.0.1:4255" ||
+		p.tcp.Addr != "127.0.0.1:4255" {
+		t.Errorf("got invalid proxy: %s, %s, %s", p.netns, p.udp.Addr, p.tcp.Addr)
+	}
+
+	// start remote
+	s := getTestDNSServer(t, dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		reply := &dns.Msg{}
+		reply.SetReply(r)
+		if err := w.WriteMsg(reply); err != nil {
+			log.WithError(err).Error("error sending reply")
+		}
+	}))
+	defer func() { _ = s.Shutdown() }()
+
+	// exchange in network namespace
+	p.SetRemotes(map[string][]string{".": {s.Addr}})
+	reply, err := p.exchange(&dns.Msg{Question: []dns.Question{{Name: "example.com."}}}, s.Addr)
+	if err != nil || reply == nil {
+		t.Errorf("exchange failed: %v, %v", reply, err)
+	}
+
+	// exchange error in network namespace
+	inNetNS = func(string, func() error) error { return errors.New("test error") }
+	if _, err := p.exchange(&dns.Msg{}, s.Addr); err == nil {
+		t.Error("exchange should fail")
+	}
+
+	// listen in network namespace
+	for _, server := range []*dns.Server{p.udp, p.tcp} {
+		if err := listenNetNS("oc-daemon", server); err == nil {
+			t.Error("listen should fail")
+		}
+	}
+	inNetNS = func(name string, f func() error) error {
+		names = append(names, name)
+		return f()
+	}
+	for _, server := range []*dns.Server{p.udp, p.tcp} {
+		if err := listenNetNS("oc-daemon", server); err != nil {
+			t.Error(err)
+		}
+	}
+	if p.udp.PacketConn == nil || p.tcp.Listener == nil {
+		t.Error("listeners should be created")
+	}
+	_ = p.udp.PacketConn.Close()
+	_ = p.tcp.Listener.Close()
+
+	for _, name := range names {
+		if name != "oc-daemon" {
+			t.Errorf("got invalid network namespace %s", name)
+		}
+	}
+}
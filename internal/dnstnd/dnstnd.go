@@ -0,0 +1,273 @@
+// Package dnstnd contains the DNS-based trusted network detection.
+package dnstnd
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/dnsmon"
+)
+
+// Result is a result of the DNS-based trusted network detection.
+type Result struct {
+	// Trusted indicates whether the network is trusted
+	Trusted bool
+
+	// Domain is the DNS domain of the network that matched the trusted
+	// DNS domains, empty if no domain matched
+	Domain string
+
+	// Servers are the DNS servers of the network if they matched the
+	// trusted DNS servers, empty if they did not match
+	Servers []string
+}
+
+// TND is the DNS-based trusted network detection interface.
+type TND interface {
+	SetDomains(domains []string)
+	SetServers(servers []string)
+	SetExcludedDomains(domains []string)
+	Start() error
+	Stop()
+	Probe()
+	Results() chan *Result
+}
+
+// Detector is the DNS-based trusted network detector. It checks the DNS
+// domains and DNS servers of the network in the resolv.conf files watched
+// by DNSMon.
+type Detector struct {
+	config *dnsmon.Config
+	dnsmon *dnsmon.DNSMon
+
+	// mutex protects the settings below
+	mutex    sync.Mutex
+	domains  []string
+	servers  []string
+	excluded []string
+
+	probes  chan struct{}
+	results chan *Result
+	done    chan struct{}
+	closed  chan struct{}
+}
+
+// SetDomains sets the trusted DNS domains, domains may contain the
+// wildcard "*".
+func (d *Detector) SetDomains(domains []string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.domains = slices.Clone(domains)
+}
+
+// SetServers sets the trusted DNS servers, servers may contain the
+// wildcard "*".
+func (d *Detector) SetServers(servers []string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.servers = slices.Clone(servers)
+}
+
+// SetExcludedDomains sets the DNS domains that are ignored in the network,
+// e.g., the DNS domains of the VPN connection.
+func (d *Detector) SetExcludedDomains(domains []string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.excluded = slices.Clone(domains)
+}
+
+// normalizeDomain returns domain in lower case without trailing dot.
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(domain), ".")
+}
+
+// matchPattern returns whether s matches pattern, pattern may contain the
+// wildcard "*".
+func matchPattern(pattern, s string) bool {
+	ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(s))
+	return err == nil && ok
+}
+
+// isLocalServer returns whether the DNS server is a local resolver like the
+// stub resolver of systemd-resolved or the DNS proxy.
+func isLocalServer(server string) bool {
+	addr, err := netip.ParseAddr(server)
+	if err != nil {
+		return false
+	}
+	return addr.IsLoopback()
+}
+
+// readResolvConf reads the DNS servers and DNS domains in the resolv.conf
+// file.
+func readResolvConf(file string) (servers, domains []string) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		log.WithError(err).WithField("file", file).
+			Debug("DNSTND could not read resolv.conf")
+		return
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		switch f[0] {
+		case "nameserver":
+			servers = append(servers, f[1])
+		case "domain", "search":
+			domains = append(domains, f[1:]...)
+		}
+	}
+	return
+}
+
+// getNetworkDNS returns the DNS servers and DNS domains of the network.
+// Local DNS servers are skipped. If a local DNS server is used, the DNS
+// servers and DNS domains in the resolv.conf of systemd-resolved are used as
+// well.
+func (d *Detector) getNetworkDNS(excluded []string) (servers, domains []string) {
+	s, dms := readResolvConf(d.config.ETCResolvConf)
+	if slices.ContainsFunc(s, isLocalServer) {
+		ss, sdms := readResolvConf(d.config.SystemdResolvConf)
+		s = append(s, ss...)
+		dms = append(dms, sdms...)
+	}
+
+	for _, server := range s {
+		if isLocalServer(server) || slices.Contains(servers, server) {
+			continue
+		}
+		servers = append(servers, server)
+	}
+	for _, domain := range dms {
+		domain = normalizeDomain(domain)
+		if domain == "" || slices.Contains(domains, domain) ||
+			slices.ContainsFunc(excluded, func(e string) bool {
+				return normalizeDomain(e) == domain
+			}) {
+			continue
+		}
+		domains = append(domains, domain)
+	}
+	return
+}
+
+// check checks the DNS settings of the network and returns the result.
+func (d *Detector) check() *Result {
+	d.mutex.Lock()
+	domains := d.domains
+	servers := d.servers
+	excluded := d.excluded
+	d.mutex.Unlock()
+
+	netServers, netDomains := d.getNetworkDNS(excluded)
+	r := &Result{}
+
+	// at least one DNS domain of the network must match
+	for _, domain := range netDomains {
+		if slices.ContainsFunc(domains, func(p string) bool {
+			return matchPattern(normalizeDomain(p), domain)
+		}) {
+			r.Domain = domain
+			break
+		}
+	}
+
+	// all DNS servers of the network must match
+	if len(servers) > 0 && len(netServers) > 0 &&
+		!slices.ContainsFunc(netServers, func(s string) bool {
+			return !slices.ContainsFunc(servers, func(p string) bool {
+				return matchPattern(p, s)
+			})
+		}) {
+		r.Servers = netServers
+	}
+
+	// all configured criteria must match
+	r.Trusted = (len(domains) > 0 || len(servers) > 0) &&
+		(len(domains) == 0 || r.Domain != "") &&
+		(len(servers) == 0 || len(r.Servers) > 0)
+
+	log.WithFields(log.Fields{
+		"domains": netDomains,
+		"servers": netServers,
+		"trusted": r.Trusted,
+	}).Debug("DNSTND checked network")
+	return r
+}
+
+// start starts the detector.
+func (d *Detector) start() {
+	defer close(d.closed)
+	defer d.dnsmon.Stop()
+
+	for {
+		select {
+		case _, ok := <-d.dnsmon.Updates():
+			if !ok {
+				log.Error("DNSTND got unexpected close of DNSMon updates channel")
+				return
+			}
+		case <-d.probes:
+		case <-d.done:
+			return
+		}
+
+		// check network and send result or abort if we are shutting
+		// down
+		select {
+		case d.results <- d.check():
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// Start starts the detector.
+func (d *Detector) Start() error {
+	if err := d.dnsmon.Start(); err != nil {
+		return fmt.Errorf("DNSTND could not start DNSMon: %w", err)
+	}
+	go d.start()
+	return nil
+}
+
+// Stop stops the detector.
+func (d *Detector) Stop() {
+	close(d.done)
+	<-d.closed
+}
+
+// Probe triggers a check of the network.
+func (d *Detector) Probe() {
+	select {
+	case d.probes <- struct{}{}:
+	default:
+		// probe already pending
+	}
+}
+
+// Results returns the channel for detection results.
+func (d *Detector) Results() chan *Result {
+	return d.results
+}
+
+// NewDetector returns a new DNS-based trusted network detector.
+func NewDetector(config *dnsmon.Config) *Detector {
+	return &Detector{
+		config:  config,
+		dnsmon:  dnsmon.NewDNSMon(config),
+		probes:  make(chan struct{}, 1),
+		results: make(chan *Result),
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
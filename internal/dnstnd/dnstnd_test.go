@@ -0,0 +1,204 @@
+package dnstnd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/dnsmon"
+)
+
+// getTestConfig returns a DNSMon config with resolv.conf files in dir.
+func getTestConfig(dir string) *dnsmon.Config {
+	return &dnsmon.Config{
+		ETCResolvConf:     filepath.Join(dir, "resolv.conf"),
+		StubResolvConf:    filepath.Join(dir, "stub-resolv.conf"),
+		SystemdResolvConf: filepath.Join(dir, "systemd-resolv.conf"),
+	}
+}
+
+// writeFile writes content to file.
+func writeFile(t *testing.T, file, content string) {
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMatchPattern tests matchPattern.
+func TestMatchPattern(t *testing.T) {
+	for i, test := range []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"example.com", "example.com", true},
+		{"Example.COM", "example.com", true},
+		{"example.com", "other.com", false},
+		{"*.example.com", "corp.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"10.1.*.*", "10.1.2.3", true},
+		{"10.1.*.*", "10.2.2.3", false},
+		{"[", "[", false},
+	} {
+		got := matchPattern(test.pattern, test.s)
+		if got != test.want {
+			t.Errorf("%d: got %t, want %t", i, got, test.want)
+		}
+	}
+}
+
+// TestReadResolvConf tests readResolvConf.
+func TestReadResolvConf(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "resolv.conf")
+
+	// not existing file
+	servers, domains := readResolvConf(file)
+	if servers != nil || domains != nil {
+		t.Errorf("got %v %v, want nil", servers, domains)
+	}
+
+	// existing file
+	writeFile(t, file, `# comment
+nameserver 192.168.1.1
+nameserver 2001:db8::1
+options edns0
+domain example.com
+search corp.example.com other.example.com
+nameserver
+`)
+	servers, domains = readResolvConf(file)
+	wantServers := []string{"192.168.1.1", "2001:db8::1"}
+	wantDomains := []string{"example.com", "corp.example.com", "other.example.com"}
+	if !reflect.DeepEqual(servers, wantServers) {
+		t.Errorf("got %v, want %v", servers, wantServers)
+	}
+	if !reflect.DeepEqual(domains, wantDomains) {
+		t.Errorf("got %v, want %v", domains, wantDomains)
+	}
+}
+
+// TestDetectorCheck tests check of Detector.
+func TestDetectorCheck(t *testing.T) {
+	dir := t.TempDir()
+	config := getTestConfig(dir)
+
+	// stub resolver with upstream servers in systemd resolv.conf
+	writeFile(t, config.ETCResolvConf, "nameserver 127.0.0.53\nsearch .\n")
+	writeFile(t, config.SystemdResolvConf, `nameserver 10.1.0.1
+nameserver 10.1.0.2
+nameserver 127.0.0.1
+search corp.example.com vpn.example.com.
+`)
+
+	for i, test := range []struct {
+		domains  []string
+		servers  []string
+		excluded []string
+		want     *Result
+	}{
+		// nothing configured
+		{
+			want: &Result{},
+		},
+		// domain matches
+		{
+			domains: []string{"other.com", "*.example.com"},
+			want:    &Result{Trusted: true, Domain: "corp.example.com"},
+		},
+		// domain does not match
+		{
+			domains: []string{"other.com"},
+			want:    &Result{},
+		},
+		// domain excluded
+		{
+			domains:  []string{"vpn.example.com"},
+			excluded: []string{"VPN.example.com"},
+			want:     &Result{},
+		},
+		// servers match
+		{
+			servers: []string{"10.1.*.*"},
+			want:    &Result{Trusted: true, Servers: []string{"10.1.0.1", "10.1.0.2"}},
+		},
+		// servers do not match all
+		{
+			servers: []string{"10.1.0.1"},
+			want:    &Result{},
+		},
+		// domain and servers match
+		{
+			domains: []string{"corp.example.com"},
+			servers: []string{"10.1.0.1", "10.1.0.2"},
+			want: &Result{
+				Trusted: true,
+				Domain:  "corp.example.com",
+				Servers: []string{"10.1.0.1", "10.1.0.2"},
+			},
+		},
+		// domain matches, servers do not match
+		{
+			domains: []string{"corp.example.com"},
+			servers: []string{"192.168.*.*"},
+			want:    &Result{Domain: "corp.example.com"},
+		},
+	} {
+		d := NewDetector(config)
+		d.SetDomains(test.domains)
+		d.SetServers(test.servers)
+		d.SetExcludedDomains(test.excluded)
+		got := d.check()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: got %+v, want %+v", i, got, test.want)
+		}
+	}
+}
+
+// TestDetectorStartStop tests Start and Stop of Detector.
+func TestDetectorStartStop(t *testing.T) {
+	dir := t.TempDir()
+	config := getTestConfig(dir)
+	writeFile(t, config.ETCResolvConf, "nameserver 10.1.0.1\nsearch example.com\n")
+
+	d := NewDetector(config)
+	d.SetDomains([]string{"example.com"})
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// initial result
+	want := &Result{Trusted: true, Domain: "example.com"}
+	if got := <-d.Results(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// probe
+	d.SetDomains([]string{"other.com"})
+	d.Probe()
+	d.Probe()
+	want = &Result{}
+	if got := <-d.Results(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	d.Stop()
+}
+
+// TestNewDetector tests NewDetector.
+func TestNewDetector(t *testing.T) {
+	config := dnsmon.NewConfig()
+	d := NewDetector(config)
+	if d.config != config {
+		t.Errorf("got %v, want %v", d.config, config)
+	}
+	if d.dnsmon == nil ||
+		d.probes == nil ||
+		d.results == nil ||
+		d.done == nil ||
+		d.closed == nil {
+
+		t.Errorf("got nil, want != nil")
+	}
+}
@@ -0,0 +1,299 @@
+// Package journal contains the journal of applied system changes.
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+)
+
+// fileSuffix is the suffix of journal entry files.
+const fileSuffix = ".json"
+
+// Entry is a journal entry with the commands that undo system changes
+// applied by a component, e.g., routing rules, nft tables, DNS settings and
+// devices, in the order the changes were applied.
+type Entry struct {
+	Name    string
+	Applied time.Time
+	Undo    []*cmdtmpl.Cmd
+}
+
+// getFile returns the file of the entry name in dir.
+func getFile(dir, name string) string {
+	return filepath.Join(dir, name+fileSuffix)
+}
+
+// save saves the entry e in dir. The entry is written atomically, so it
+// survives crashes of the daemon.
+func save(dir string, e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("could not convert journal entry to JSON: %w", err)
+	}
+
+	// write entry to temporary file and rename it
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("could not create journal dir: %w", err)
+	}
+	f, err := os.CreateTemp(dir, e.Name+".tmp")
+	if err != nil {
+		return fmt.Errorf("could not create journal file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("could not write journal file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("could not sync journal file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("could not close journal file: %w", err)
+	}
+	if err := os.Rename(f.Name(), getFile(dir, e.Name)); err != nil {
+		return fmt.Errorf("could not rename journal file: %w", err)
+	}
+	return nil
+}
+
+// Save saves the undo commands of the changes applied by component name as
+// journal entry in dir.
+func Save(dir, name string, undo []*cmdtmpl.Cmd) error {
+	return save(dir, &Entry{
+		Name:    name,
+		Applied: time.Now(),
+		Undo:    undo,
+	})
+}
+
+// Remove removes the journal entry of component name in dir after its
+// changes were undone.
+func Remove(dir, name string) error {
+	err := os.Remove(getFile(dir, name))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// loadFile loads the journal entry in file.
+func loadFile(file string) (*Entry, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, fmt.Errorf("could not parse journal file %s: %w",
+			filepath.Base(file), err)
+	}
+	return e, nil
+}
+
+// Load loads all journal entries in dir.
+func Load(dir string) ([]*Entry, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), fileSuffix) {
+			continue
+		}
+		e, err := loadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// sameCmd returns whether a and b run the same command.
+func sameCmd(a, b *cmdtmpl.Cmd) bool {
+	return a.Cmd == b.Cmd && slices.Equal(a.Args, b.Args) && a.Stdin == b.Stdin
+}
+
+// Recorder records the undo commands of the changes applied by a component
+// in its journal entry, it implements cmdtmpl.Journal.
+type Recorder struct {
+	mutex sync.Mutex
+	dir   string
+	entry *Entry
+}
+
+// Add adds the undo command of an applied change to the journal entry.
+func (r *Recorder) Add(undo *cmdtmpl.Cmd) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if slices.ContainsFunc(r.entry.Undo, func(c *cmdtmpl.Cmd) bool {
+		return sameCmd(c, undo)
+	}) {
+		// change already in journal
+		return nil
+	}
+	if len(r.entry.Undo) == 0 {
+		r.entry.Applied = time.Now()
+	}
+	r.entry.Undo = append(r.entry.Undo, undo)
+	return save(r.dir, r.entry)
+}
+
+// Remove removes the undo command of an undone change from the journal
+// entry, it removes the entry if no changes are left.
+func (r *Recorder) Remove(undo *cmdtmpl.Cmd) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.entry.Undo = slices.DeleteFunc(r.entry.Undo, func(c *cmdtmpl.Cmd) bool {
+		return sameCmd(c, undo)
+	})
+	if len(r.entry.Undo) == 0 {
+		return Remove(r.dir, r.entry.Name)
+	}
+	return save(r.dir, r.entry)
+}
+
+// NewRecorder returns a new Recorder for the journal entry of component name
+// in dir. Changes already in the journal entry are kept.
+func NewRecorder(dir, name string) *Recorder {
+	e, err := loadFile(getFile(dir, name))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.WithError(err).WithField("entry", name).
+				Error("Journal could not load entry")
+		}
+		e = &Entry{Name: name}
+	}
+	e.Name = name
+	return &Recorder{
+		dir:   dir,
+		entry: e,
+	}
+}
+
+// Item is an undo command of a recovered journal entry with the result of
+// running it.
+type Item struct {
+	Cmd    string
+	Args   []string
+	Undone bool
+	Error  string `json:",omitempty"`
+}
+
+// Recovered is a journal entry recovered after a failed shutdown with the
+// results of its undo commands in the order they were run.
+type Recovered struct {
+	Name    string
+	Applied time.Time
+	Items   []*Item
+}
+
+// Failed returns whether changes of the recovered entry could not be
+// undone.
+func (r *Recovered) Failed() bool {
+	return slices.ContainsFunc(r.Items, func(i *Item) bool { return !i.Undone })
+}
+
+// recoverEntry runs the undo commands of entry e in dir in reverse order.
+// It keeps the undo commands that failed in the entry and removes the entry
+// if all changes were undone.
+func recoverEntry(ctx context.Context, dir string, e *Entry) *Recovered {
+	r := &Recovered{
+		Name:    e.Name,
+		Applied: e.Applied,
+	}
+	var failed []*cmdtmpl.Cmd
+	for _, c := range slices.Backward(e.Undo) {
+		// errors must not be ignored to know if the change was undone
+		undo := *c
+		undo.IgnoreErrors = false
+		fields := log.Fields{
+			"entry":   e.Name,
+			"applied": e.Applied,
+			"command": c.Cmd,
+			"args":    c.Args,
+			"stdin":   c.Stdin,
+		}
+
+		item := &Item{Cmd: c.Cmd, Args: c.Args}
+		r.Items = append(r.Items, item)
+		stdout, stderr, err := undo.Run(ctx)
+		if err != nil {
+			cmdErr := &cmdtmpl.CmdError{
+				List:   e.Name,
+				Cmd:    c.Cmd,
+				Args:   c.Args,
+				Stdin:  c.Stdin,
+				Stdout: string(stdout),
+				Stderr: string(stderr),
+				Err:    err,
+			}
+			item.Error = cmdErr.Error()
+			failed = append(failed, c)
+			log.WithError(cmdErr).WithFields(fields).
+				Error("Journal could not undo change")
+			continue
+		}
+		item.Undone = true
+		log.WithFields(fields).Warn("Journal undid change")
+	}
+
+	// keep failed changes in the journal
+	if len(failed) > 0 {
+		slices.Reverse(failed)
+		if err := save(dir, &Entry{
+			Name:    e.Name,
+			Applied: e.Applied,
+			Undo:    failed,
+		}); err != nil {
+			log.WithError(err).WithField("entry", e.Name).
+				Error("Journal could not save failed changes")
+		}
+		return r
+	}
+	if err := Remove(dir, e.Name); err != nil {
+		log.WithError(err).WithField("entry", e.Name).
+			Error("Journal could not remove entry")
+	}
+	return r
+}
+
+// Recover runs the undo commands of all journal entries in dir left behind
+// after a failed shutdown and returns the results. Entries are removed if
+// all their changes were undone, otherwise only the failed changes are kept.
+// The entries with names in keep are not recovered, e.g., because their
+// configuration is still used.
+func Recover(ctx context.Context, dir string, keep ...string) []*Recovered {
+	entries, err := Load(dir)
+	if err != nil {
+		log.WithError(err).Error("Journal could not load entries")
+	}
+	var recovered []*Recovered
+	for _, e := range entries {
+		if slices.Contains(keep, e.Name) {
+			continue
+		}
+		recovered = append(recovered, recoverEntry(ctx, dir, e))
+	}
+	return recovered
+}
@@ -0,0 +1,200 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+)
+
+// getTestUndo returns undo commands for testing.
+func getTestUndo() []*cmdtmpl.Cmd {
+	return []*cmdtmpl.Cmd{
+		{Cmd: "ip", Args: []string{"link", "delete", "oc-daemon-tun0"}},
+		{Cmd: "nft", Args: []string{"-f", "-"}, Stdin: "delete table inet oc-daemon-filter"},
+	}
+}
+
+// TestSaveLoadRemove tests Save, Load and Remove.
+func TestSaveLoadRemove(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "journal")
+
+	// load without dir
+	entries, err := Load(dir)
+	if err != nil || entries != nil {
+		t.Errorf("got %v, %v, want nil, nil", entries, err)
+	}
+
+	// save and load entries
+	undo := getTestUndo()
+	if err := Save(dir, "VPNSetup", undo); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(dir, "TrafPol", undo[1:]); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	for _, e := range entries {
+		want := undo
+		if e.Name == "TrafPol" {
+			want = undo[1:]
+		}
+		if !reflect.DeepEqual(e.Undo, want) {
+			t.Errorf("%s: got %v, want %v", e.Name, e.Undo, want)
+		}
+	}
+
+	// remove entries
+	for _, name := range []string{"VPNSetup", "TrafPol", "does-not-exist"} {
+		if err := Remove(dir, name); err != nil {
+			t.Error(err)
+		}
+	}
+	entries, err = Load(dir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("got %v, %v, want no entries", entries, err)
+	}
+
+	// invalid entry
+	if err := os.WriteFile(getFile(dir, "invalid"), []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("invalid entry should return error")
+	}
+}
+
+// TestRecorder tests Recorder.
+func TestRecorder(t *testing.T) {
+	dir := t.TempDir()
+	undo := getTestUndo()
+
+	// add changes, duplicates are ignored
+	r := NewRecorder(dir, "VPNSetup")
+	for _, c := range []*cmdtmpl.Cmd{undo[0], undo[1], undo[0]} {
+		if err := r.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := Load(dir)
+	if err != nil || len(entries) != 1 || !reflect.DeepEqual(entries[0].Undo, undo) {
+		t.Errorf("got %v, %v, want entry with %v", entries, err, undo)
+	}
+
+	// new recorder keeps existing changes
+	r = NewRecorder(dir, "VPNSetup")
+	if !reflect.DeepEqual(r.entry.Undo, undo) {
+		t.Errorf("got %v, want %v", r.entry.Undo, undo)
+	}
+
+	// remove changes, last change removes entry
+	if err := r.Remove(undo[1]); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = Load(dir)
+	if err != nil || len(entries) != 1 || !reflect.DeepEqual(entries[0].Undo, undo[:1]) {
+		t.Errorf("got %v, %v, want entry with %v", entries, err, undo[:1])
+	}
+	if err := r.Remove(undo[0]); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = Load(dir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("got %v, %v, want no entries", entries, err)
+	}
+
+	// invalid entry
+	if err := os.WriteFile(getFile(dir, "invalid"), []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r = NewRecorder(dir, "invalid")
+	if r.entry.Name != "invalid" || len(r.entry.Undo) != 0 {
+		t.Errorf("got %v, want empty entry", r.entry)
+	}
+}
+
+// TestRecover tests Recover.
+func TestRecover(t *testing.T) {
+	oldRunCmd := cmdtmpl.RunCmd
+	defer func() { cmdtmpl.RunCmd = oldRunCmd }()
+
+	got := []string{}
+	fail := ""
+	cmdtmpl.RunCmd = func(_ context.Context, cmd string, s string, arg ...string) ([]byte, []byte, error) {
+		got = append(got, cmd)
+		if cmd == fail {
+			return nil, []byte("test stderr"), errors.New("test error")
+		}
+		return nil, nil, nil
+	}
+
+	// recover entry, changes are undone in reverse order
+	dir := t.TempDir()
+	if err := Save(dir, "VPNSetup", getTestUndo()); err != nil {
+		t.Fatal(err)
+	}
+	recovered := Recover(context.Background(), dir)
+	if len(recovered) != 1 || recovered[0].Name != "VPNSetup" || recovered[0].Failed() {
+		t.Errorf("got %v, want undone VPNSetup entry", recovered)
+	}
+	want := []string{"nft", "ip"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// entry should be removed
+	if recovered := Recover(context.Background(), dir); len(recovered) != 0 {
+		t.Errorf("got %v, want no entries", recovered)
+	}
+
+	// failed change should be kept, even if errors are ignored
+	fail = "ip"
+	undo := getTestUndo()
+	undo[0].IgnoreErrors = true
+	if err := Save(dir, "VPNSetup", undo); err != nil {
+		t.Fatal(err)
+	}
+	recovered = Recover(context.Background(), dir)
+	if len(recovered) != 1 || !recovered[0].Failed() {
+		t.Fatalf("got %v, want failed VPNSetup entry", recovered)
+	}
+	items := recovered[0].Items
+	if len(items) != 2 || !items[0].Undone || items[1].Undone ||
+		items[1].Cmd != "ip" || items[1].Error == "" {
+		t.Errorf("got invalid items %v", items)
+	}
+	entries, err := Load(dir)
+	if err != nil || len(entries) != 1 || !reflect.DeepEqual(entries[0].Undo, undo[:1]) {
+		t.Errorf("got %v, %v, want entry with failed change", entries, err)
+	}
+	fail = ""
+	if recovered := Recover(context.Background(), dir); len(recovered) != 1 ||
+		recovered[0].Failed() {
+		t.Errorf("got %v, want undone entry", recovered)
+	}
+
+	// keep entry
+	got = []string{}
+	if err := Save(dir, "VPNSetup", getTestUndo()); err != nil {
+		t.Fatal(err)
+	}
+	if recovered := Recover(context.Background(), dir, "VPNSetup"); len(recovered) != 0 {
+		t.Errorf("got %v, want no recovered entries", recovered)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %v, want no commands", got)
+	}
+	if entries, err := Load(dir); err != nil || len(entries) != 1 {
+		t.Errorf("got %v %v, want kept entry", entries, err)
+	}
+}
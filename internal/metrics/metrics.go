@@ -0,0 +1,180 @@
+// Package metrics contains the metrics exporter.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a metric that only increases.
+type Counter struct {
+	v atomic.Uint64
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Value returns the value of the counter.
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+// Gauge is a metric that can increase and decrease.
+type Gauge struct {
+	v atomic.Int64
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v int64) {
+	g.v.Store(v)
+}
+
+// Value returns the value of the gauge.
+func (g *Gauge) Value() int64 {
+	return g.v.Load()
+}
+
+// GaugeSum is a gauge that is the sum of the values of multiple sources,
+// e.g., of multiple VPN connections.
+type GaugeSum struct {
+	mutex sync.Mutex
+	v     map[string]int64
+}
+
+// Set sets the value of source to v.
+func (g *GaugeSum) Set(source string, v int64) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.v == nil {
+		g.v = make(map[string]int64)
+	}
+	g.v[source] = v
+}
+
+// Delete removes the value of source.
+func (g *GaugeSum) Delete(source string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.v, source)
+}
+
+// Value returns the sum of the values of all sources.
+func (g *GaugeSum) Value() int64 {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	var sum int64
+	for _, v := range g.v {
+		sum += v
+	}
+	return sum
+}
+
+// Daemon state gauges, values are the numeric values of the
+// respective vpnstatus types.
+var (
+	ConnectionState = &Gauge{}
+	TrustedNetwork  = &Gauge{}
+	TrafPolState    = &Gauge{}
+	CaptivePortal   = &Gauge{}
+)
+
+// Split routing gauges, sources are the VPN connections.
+var (
+	SplitRoutingStaticExcludes  = &GaugeSum{}
+	SplitRoutingDynamicExcludes = &GaugeSum{}
+)
+
+// Counters.
+var (
+	DNSProxyQueries   = &Counter{}
+	DNSProxyErrors    = &Counter{}
+	ResolverFailures  = &Counter{}
+	CommandFailures   = &Counter{}
+	ReconnectAttempts = &Counter{}
+	ReconnectGiveUps  = &Counter{}
+)
+
+// metric is an exported metric.
+type metric struct {
+	name  string
+	help  string
+	typ   string
+	value func() string
+}
+
+// gauge returns the exported metric of gauge g.
+func gauge(name, help string, g interface{ Value() int64 }) *metric {
+	return &metric{
+		name: name,
+		help: help,
+		typ:  "gauge",
+		value: func() string {
+			return strconv.FormatInt(g.Value(), 10)
+		},
+	}
+}
+
+// counter returns the exported metric of counter c.
+func counter(name, help string, c *Counter) *metric {
+	return &metric{
+		name: name,
+		help: help,
+		typ:  "counter",
+		value: func() string {
+			return strconv.FormatUint(c.Value(), 10)
+		},
+	}
+}
+
+// metrics are all exported metrics.
+var metrics = []*metric{
+	gauge("oc_daemon_connection_state",
+		"VPN connection state (0: unknown, 1: disconnected, 2: connecting, "+
+			"3: connected, 4: disconnecting).", ConnectionState),
+	gauge("oc_daemon_trusted_network",
+		"Trusted network state (0: unknown, 1: not trusted, 2: trusted).",
+		TrustedNetwork),
+	gauge("oc_daemon_trafpol_state",
+		"Traffic policing state (0: unknown, 1: inactive, 2: active, "+
+			"3: disabled).", TrafPolState),
+	gauge("oc_daemon_captive_portal",
+		"Captive portal state (0: unknown, 1: not detected, 2: detected).",
+		CaptivePortal),
+	gauge("oc_daemon_split_routing_static_excludes",
+		"Number of static split routing excludes of all VPN connections.",
+		SplitRoutingStaticExcludes),
+	gauge("oc_daemon_split_routing_dynamic_excludes",
+		"Number of dynamic split routing excludes of all VPN connections.",
+		SplitRoutingDynamicExcludes),
+	counter("oc_daemon_dns_proxy_queries_total",
+		"Number of DNS queries handled by the DNS proxy.", DNSProxyQueries),
+	counter("oc_daemon_dns_proxy_errors_total",
+		"Number of DNS queries the DNS proxy could not handle.",
+		DNSProxyErrors),
+	counter("oc_daemon_resolver_failures_total",
+		"Number of failed name resolutions of allowed hosts in traffic "+
+			"policing.", ResolverFailures),
+	counter("oc_daemon_command_failures_total",
+		"Number of failed commands in command lists.", CommandFailures),
+	counter("oc_daemon_reconnect_attempts_total",
+		"Number of VPN reconnect attempts.", ReconnectAttempts),
+	counter("oc_daemon_reconnect_give_ups_total",
+		"Number of times reconnecting the VPN was given up.",
+		ReconnectGiveUps),
+}
+
+// Write writes all metrics in the Prometheus text format to w.
+func Write(w io.Writer) error {
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n",
+			m.name, m.help, m.name, m.typ, m.name, m.value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
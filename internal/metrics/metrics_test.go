@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestCounter tests Counter.
+func TestCounter(t *testing.T) {
+	c := &Counter{}
+	c.Inc()
+	c.Inc()
+	if c.Value() != 2 {
+		t.Errorf("got %d, want 2", c.Value())
+	}
+}
+
+// TestGauge tests Gauge.
+func TestGauge(t *testing.T) {
+	g := &Gauge{}
+	g.Set(3)
+	g.Set(1)
+	if g.Value() != 1 {
+		t.Errorf("got %d, want 1", g.Value())
+	}
+}
+
+// TestGaugeSum tests GaugeSum.
+func TestGaugeSum(t *testing.T) {
+	g := &GaugeSum{}
+	if g.Value() != 0 {
+		t.Errorf("got %d, want 0", g.Value())
+	}
+
+	g.Set("", 3)
+	g.Set("test", 2)
+	g.Set("test", 1)
+	if g.Value() != 4 {
+		t.Errorf("got %d, want 4", g.Value())
+	}
+
+	g.Delete("test")
+	if g.Value() != 3 {
+		t.Errorf("got %d, want 3", g.Value())
+	}
+}
+
+// TestWrite tests Write.
+func TestWrite(t *testing.T) {
+	ConnectionState.Set(3)
+	DNSProxyQueries.Inc()
+
+	b := &bytes.Buffer{}
+	if err := Write(b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE oc_daemon_connection_state gauge\noc_daemon_connection_state 3\n",
+		"# TYPE oc_daemon_dns_proxy_queries_total counter\n" +
+			"oc_daemon_dns_proxy_queries_total " +
+			strconv.FormatUint(DNSProxyQueries.Value(), 10) + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("got %q, want it to contain %q", out, want)
+		}
+	}
+	if n := strings.Count(out, "# HELP "); n != len(metrics) {
+		t.Errorf("got %d metrics, want %d", n, len(metrics))
+	}
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// Server is the metrics endpoint server.
+type Server struct {
+	config *daemoncfg.Metrics
+	server *http.Server
+	closed chan struct{}
+}
+
+// handleMetrics handles a metrics request.
+func handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := Write(w); err != nil {
+		log.WithError(err).Debug("Metrics could not write metrics")
+	}
+}
+
+// listen returns the listener for the configured address or socket file.
+func (s *Server) listen() (net.Listener, error) {
+	if s.config.SocketFile == "" {
+		return net.Listen("tcp", s.config.Address)
+	}
+
+	// cleanup existing sock file, this should normally fail
+	if err := os.Remove(s.config.SocketFile); err == nil {
+		log.Warn("Metrics removed existing unix socket file")
+	}
+	return net.Listen("unix", s.config.SocketFile)
+}
+
+// Start starts the metrics server.
+func (s *Server) Start() error {
+	listen, err := s.listen()
+	if err != nil {
+		return fmt.Errorf("could not start metrics listener: %w", err)
+	}
+
+	go func() {
+		defer close(s.closed)
+		err := s.server.Serve(listen)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.WithError(err).Error("Metrics server stopped with error")
+		}
+	}()
+	return nil
+}
+
+// Stop stops the metrics server.
+func (s *Server) Stop() {
+	if err := s.server.Close(); err != nil {
+		log.WithError(err).Error("Metrics could not close server")
+	}
+	<-s.closed
+}
+
+// NewServer returns a new metrics server.
+func NewServer(config *daemoncfg.Metrics) *Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", handleMetrics)
+	return &Server{
+		config: config,
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		closed: make(chan struct{}),
+	}
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// getMetrics gets the metrics from the server using client.
+func getMetrics(t *testing.T, client *http.Client, url string) string {
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// TestServerStartStop tests Start and Stop of Server.
+func TestServerStartStop(t *testing.T) {
+	// tcp listener, get a free port first
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	_ = l.Close()
+
+	config := daemoncfg.NewMetrics()
+	config.Address = address
+	s := NewServer(config)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	got := getMetrics(t, http.DefaultClient, "http://"+address+"/metrics")
+	if !strings.Contains(got, "oc_daemon_connection_state") {
+		t.Errorf("got %q, want metrics", got)
+	}
+	s.Stop()
+
+	// unix socket listener
+	config = daemoncfg.NewMetrics()
+	config.SocketFile = filepath.Join(t.TempDir(), "metrics.sock")
+	s = NewServer(config)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", config.SocketFile)
+			},
+		},
+	}
+	got = getMetrics(t, client, "http://unix/metrics")
+	if !strings.Contains(got, "oc_daemon_connection_state") {
+		t.Errorf("got %q, want metrics", got)
+	}
+	s.Stop()
+
+	// invalid address
+	config = daemoncfg.NewMetrics()
+	config.Address = "invalid"
+	if err := NewServer(config).Start(); err == nil {
+		t.Error("invalid address should return error")
+	}
+}
@@ -0,0 +1,46 @@
+// Package netnsexec runs functions in network namespaces.
+package netnsexec
+
+import (
+	"runtime"
+
+	"github.com/vishvananda/netns"
+)
+
+// Run runs f in the network namespace with name. Sockets created by f stay
+// in the network namespace.
+func Run(name string, f func() error) error {
+	// the network namespace is set for the current thread only, so lock
+	// the goroutine to the thread
+	runtime.LockOSThread()
+	restored := false
+	defer func() {
+		// only release the thread if it is back in the original network
+		// namespace, otherwise it is terminated with the goroutine
+		if restored {
+			runtime.UnlockOSThread()
+		}
+	}()
+
+	orig, err := netns.Get()
+	if err != nil {
+		restored = true
+		return err
+	}
+	defer func() { _ = orig.Close() }()
+
+	ns, err := netns.GetFromName(name)
+	if err != nil {
+		restored = true
+		return err
+	}
+	defer func() { _ = ns.Close() }()
+
+	if err := netns.Set(ns); err != nil {
+		restored = netns.Set(orig) == nil
+		return err
+	}
+	defer func() { restored = netns.Set(orig) == nil }()
+
+	return f()
+}
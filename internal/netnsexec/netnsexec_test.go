@@ -0,0 +1,18 @@
+package netnsexec
+
+import "testing"
+
+// TestRun tests Run.
+func TestRun(t *testing.T) {
+	// not existing network namespace
+	called := false
+	if err := Run("does-not-exist", func() error {
+		called = true
+		return nil
+	}); err == nil {
+		t.Error("not existing network namespace should return error")
+	}
+	if called {
+		t.Error("function should not be called")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
@@ -24,17 +25,30 @@ type ConnectEvent struct {
 	// PID is the process ID of the running openconnect process
 	PID uint32
 
+	// ExitCode is the exit code of the openconnect process on disconnect,
+	// -1 if it is unknown
+	ExitCode int
+
 	// config is the daemon configuration.
 	config *daemoncfg.Config
 
 	// Env are extra environment variables set during execution
 	env []string
+
+	// detach indicates detaching from the running openconnect process
+	detach bool
+
+	// keepSession indicates disconnecting without logging off the session
+	keepSession bool
 }
 
 // Runner is the OpenConnect Runner interface.
 type Runner interface {
 	Connect(config *daemoncfg.Config, env []string)
+	Adopt(config *daemoncfg.Config, pid uint32)
+	Detach()
 	Disconnect()
+	DisconnectKeepSession()
 	Events() chan *ConnectEvent
 	Start()
 	Stop()
@@ -47,8 +61,12 @@ type Connect struct {
 	// openconnect command
 	command *exec.Cmd
 
-	// channel for openconnect exits
-	exits chan struct{}
+	// adoptDone stops checking the adopted openconnect process,
+	// nil if no process is adopted
+	adoptDone chan struct{}
+
+	// channel for openconnect exits with exit codes
+	exits chan int
 
 	// channels for commands from user
 	commands chan *ConnectEvent
@@ -73,6 +91,10 @@ var (
 	execCommand = exec.Command
 )
 
+// adoptCheckInterval is the interval for checking if an adopted openconnect
+// process is still running.
+var adoptCheckInterval = time.Second
+
 // sendEvent sends event over the event channel.
 func (c *Connect) sendEvent(event *ConnectEvent) {
 	select {
@@ -205,7 +227,17 @@ func (c *Connect) handleConnect(e *ConnectEvent) {
 		parameters = append(parameters, device)
 	}
 	parameters = append(parameters, e.config.OpenConnect.ExtraArgs...)
-	c.command = execCommand(e.config.OpenConnect.OpenConnect, parameters...)
+	name := e.config.OpenConnect.OpenConnect
+	if e.config.NetNS.Enabled {
+		// run openconnect in network namespace:
+		//
+		// ip netns exec $NETNS openconnect ...
+		//
+		parameters = append([]string{"netns", "exec",
+			e.config.NetNS.Name, name}, parameters...)
+		name = e.config.Executables.IP
+	}
+	c.command = execCommand(name, parameters...)
 
 	// run command in own process group so it is not canceled by interrupt
 	// signal sent to daemon
@@ -221,7 +253,7 @@ func (c *Connect) handleConnect(e *ConnectEvent) {
 
 	if err := c.command.Start(); err != nil {
 		go func() {
-			c.exits <- struct{}{}
+			c.exits <- -1
 		}()
 		return
 	}
@@ -236,45 +268,112 @@ func (c *Connect) handleConnect(e *ConnectEvent) {
 	})
 
 	// wait for program termination and signal disconnect
+	command := c.command
 	go func() {
-		if err := c.command.Wait(); err != nil {
+		if err := command.Wait(); err != nil {
 			log.WithError(err).
 				Error("OC-Runner waiting for connect termination error")
 		}
-		c.exits <- struct{}{}
+		c.exits <- command.ProcessState.ExitCode()
+	}()
+
+}
+
+// handleAdopt adopts the openconnect process with the PID in e that is
+// already running, e.g., after a restart of the daemon.
+func (c *Connect) handleAdopt(e *ConnectEvent) {
+	if c.command != nil {
+		// command seems to be running, stop here
+		log.WithField("error", "openconnect process already running").
+			Error("OC-Runner adopt error")
+		return
+	}
+
+	process, err := osFindProcess(int(e.PID))
+	if err != nil {
+		log.WithError(err).Error("OC-Runner could not find openconnect process")
+		go func() {
+			c.exits <- -1
+		}()
+		return
+	}
+	c.command = &exec.Cmd{Process: process}
+
+	// signal connect to user
+	c.sendEvent(&ConnectEvent{
+		Connect: true,
+		PID:     c.getPID(),
+	})
+
+	// the process is not a child process, so it cannot be waited for,
+	// check periodically if it is still running and signal disconnect,
+	// the exit code is unknown
+	process, signal, interval := c.command.Process, processSignal, adoptCheckInterval
+	done := make(chan struct{})
+	c.adoptDone = done
+	go func() {
+		for signal(process, syscall.Signal(0)) == nil {
+			select {
+			case <-time.After(interval):
+			case <-done:
+				return
+			}
+		}
+		c.exits <- -1
 	}()
+}
 
+// handleDetach detaches from the running openconnect process, so it keeps
+// running when the runner is stopped.
+func (c *Connect) handleDetach() {
+	if c.command == nil {
+		return
+	}
+	log.WithField("pid", c.getPID()).Info("OC-Runner detached from openconnect process")
+	if c.adoptDone != nil {
+		close(c.adoptDone)
+		c.adoptDone = nil
+	}
+	c.command = nil
 }
 
-// handleDisconnect tears down the connection by stopping openconnect.
-func (c *Connect) handleDisconnect() {
+// handleDisconnect tears down the connection by stopping openconnect. If
+// keepSession is set, openconnect is stopped with SIGHUP, so it does not log
+// off the session and the session can be resumed with its cookie. Otherwise,
+// openconnect is stopped with SIGINT and logs off the session.
+func (c *Connect) handleDisconnect(keepSession bool) {
 	if c.command == nil || c.command.Process == nil {
 		log.WithField("error", "no openconnect process running").
 			Error("OC-Runner disconnect error")
 		return
 	}
-	if err := processSignal(c.command.Process, os.Interrupt); err != nil {
+	signal := os.Interrupt
+	if keepSession {
+		signal = syscall.SIGHUP
+	}
+	if err := processSignal(c.command.Process, signal); err != nil {
 		// TODO: handle failed signal?
-		log.WithError(err).Error("OC-Runner sending interrupt for disconnect error")
+		log.WithError(err).WithField("signal", signal).
+			Error("OC-Runner sending signal for disconnect error")
 	}
 }
 
 // handleOCExit handles openconnect program terminations.
-func (c *Connect) handleOCExit() {
+func (c *Connect) handleOCExit(exitCode int) {
 	// clear command
 	c.command = nil
+	c.adoptDone = nil
 
 	// signal disconnect to user
-	c.sendEvent(&ConnectEvent{})
+	c.sendEvent(&ConnectEvent{ExitCode: exitCode})
 }
 
 // handleStop handles stopping the runner.
 func (c *Connect) handleStop() {
 	if c.command != nil {
 		// TODO: is this ok or ugly?
-		c.handleDisconnect()
-		<-c.exits
-		c.handleOCExit()
+		c.handleDisconnect(false)
+		c.handleOCExit(<-c.exits)
 	}
 }
 
@@ -285,14 +384,19 @@ func (c *Connect) start() {
 	for {
 		select {
 		case cmd := <-c.commands:
-			if cmd.Connect {
+			switch {
+			case cmd.Connect && cmd.PID != 0:
+				c.handleAdopt(cmd)
+			case cmd.Connect:
 				c.handleConnect(cmd)
-				break
+			case cmd.detach:
+				c.handleDetach()
+			default:
+				c.handleDisconnect(cmd.keepSession)
 			}
-			c.handleDisconnect()
 
-		case <-c.exits:
-			c.handleOCExit()
+		case exitCode := <-c.exits:
+			c.handleOCExit(exitCode)
 
 		case <-c.done:
 			c.handleStop()
@@ -322,12 +426,37 @@ func (c *Connect) Connect(config *daemoncfg.Config, env []string) {
 	c.commands <- e
 }
 
+// Adopt adopts the running openconnect process with pid, e.g., after a
+// restart of the daemon.
+func (c *Connect) Adopt(config *daemoncfg.Config, pid uint32) {
+	e := &ConnectEvent{
+		Connect: true,
+		PID:     pid,
+		config:  config,
+	}
+	c.commands <- e
+}
+
+// Detach detaches from the running openconnect process, so it is not
+// stopped with the runner.
+func (c *Connect) Detach() {
+	e := &ConnectEvent{detach: true}
+	c.commands <- e
+}
+
 // Disconnect disconnects the vpn by stopping openconnect.
 func (c *Connect) Disconnect() {
 	e := &ConnectEvent{}
 	c.commands <- e
 }
 
+// DisconnectKeepSession disconnects the vpn by stopping openconnect without
+// logging off the session, so it can be resumed with the session cookie.
+func (c *Connect) DisconnectKeepSession() {
+	e := &ConnectEvent{keepSession: true}
+	c.commands <- e
+}
+
 // Events returns the connect events channel.
 func (c *Connect) Events() chan *ConnectEvent {
 	return c.events
@@ -336,7 +465,7 @@ func (c *Connect) Events() chan *ConnectEvent {
 // NewConnect returns a new Connect.
 func NewConnect() *Connect {
 	return &Connect{
-		exits: make(chan struct{}),
+		exits: make(chan int),
 
 		commands: make(chan *ConnectEvent),
 		done:     make(chan struct{}),
@@ -346,31 +475,41 @@ func NewConnect() *Connect {
 	}
 }
 
-// CleanupConnect cleans up connect after a failed shutdown.
-func CleanupConnect(config *daemoncfg.OpenConnect) {
+// RunningPID returns the PID of the openconnect process in the PID file in
+// config if it is still running, 0 otherwise.
+func RunningPID(config *daemoncfg.OpenConnect) uint32 {
 	// get pid from file
 	b, err := osReadFile(config.PIDFile)
 	if err != nil {
-		return
+		return 0
 	}
 
-	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
-	if err != nil {
-		return
+	pid, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 32)
+	if err != nil || pid == 0 {
+		return 0
 	}
 
 	// check if it is running and command line starts with openconnect
 	cmdLine, err := osReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
 	if err != nil {
-		return
+		return 0
 	}
 
 	if !strings.HasPrefix(string(cmdLine), config.OpenConnect) {
+		return 0
+	}
+	return uint32(pid)
+}
+
+// CleanupConnect cleans up connect after a failed shutdown.
+func CleanupConnect(config *daemoncfg.OpenConnect) {
+	pid := RunningPID(config)
+	if pid == 0 {
 		return
 	}
 
 	// find process and send interrupt signal
-	process, err := osFindProcess(pid)
+	process, err := osFindProcess(int(pid))
 	if err != nil {
 		return
 	}
@@ -6,7 +6,10 @@ import (
 	"os"
 	"os/exec"
 	"os/user"
+	"reflect"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
 	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
@@ -138,6 +141,43 @@ func TestConnectConnect(t *testing.T) {
 	c.Stop()
 }
 
+// TestConnectConnectNetNS tests Connect of Connect with network namespace.
+func TestConnectConnectNetNS(t *testing.T) {
+	// clean up after tests
+	defer func() { execCommand = exec.Command }()
+
+	conf := daemoncfg.NewConfig()
+	conf.OpenConnect.PIDFile = t.TempDir() + "pidfile"
+	conf.NetNS.Enabled = true
+	conf.LoginInfo = &logininfo.LoginInfo{
+		Server:      "vpnserver.example.com",
+		Cookie:      "3311180634@13561856@1339425499@B315A0E29D16C6FD92EE...",
+		Host:        "10.0.0.1",
+		Fingerprint: "469bb424ec8835944d30bc77c77e8fc1d8e23a42",
+	}
+
+	// run openconnect with ip netns exec
+	var name string
+	var args []string
+	execCommand = func(n string, a ...string) *exec.Cmd {
+		name = n
+		args = a
+		return exec.Command("sleep", "10")
+	}
+
+	c := NewConnect()
+	c.Start()
+	c.Connect(conf, nil)
+	<-c.Events()
+	c.Stop()
+
+	if name != "ip" ||
+		len(args) < 4 ||
+		!reflect.DeepEqual(args[:4], []string{"netns", "exec", "oc-daemon", "openconnect"}) {
+		t.Errorf("got invalid command: %s %v", name, args)
+	}
+}
+
 // TestConnectDisconnect tests Disconnect of Connect.
 func TestConnectDisconnect(t *testing.T) {
 	// clean up after tests
@@ -174,7 +214,142 @@ func TestConnectDisconnect(t *testing.T) {
 	}
 	c = NewConnect()
 	c.command = &exec.Cmd{Process: &os.Process{}}
-	c.handleDisconnect()
+	c.handleDisconnect(false)
+}
+
+// TestConnectDisconnectSignal tests the signals sent by Disconnect and
+// DisconnectKeepSession of Connect.
+func TestConnectDisconnectSignal(t *testing.T) {
+	// clean up after tests
+	oldProcessSignal := processSignal
+	defer func() {
+		processSignal = oldProcessSignal
+		execCommand = exec.Command
+	}()
+
+	var got os.Signal
+	processSignal = func(p *os.Process, sig os.Signal) error {
+		got = sig
+		return oldProcessSignal(p, sig)
+	}
+	execCommand = func(string, ...string) *exec.Cmd {
+		return exec.Command("sleep", "10")
+	}
+	conf := daemoncfg.NewConfig()
+	conf.OpenConnect.PIDFile = t.TempDir() + "pidfile"
+
+	for _, test := range []struct {
+		disconnect func(c *Connect)
+		want       os.Signal
+	}{
+		{(*Connect).Disconnect, os.Interrupt},
+		{(*Connect).DisconnectKeepSession, syscall.SIGHUP},
+	} {
+		got = nil
+		c := NewConnect()
+		c.Start()
+		c.Connect(conf, nil)
+		<-c.Events()
+		test.disconnect(c)
+		<-c.Events()
+		c.Stop()
+
+		if got != test.want {
+			t.Errorf("got %v, want %v", got, test.want)
+		}
+	}
+}
+
+// TestConnectAdopt tests Adopt of Connect.
+func TestConnectAdopt(t *testing.T) {
+	// clean up after tests
+	oldProcessSignal := processSignal
+	oldAdoptCheckInterval := adoptCheckInterval
+	defer func() {
+		osFindProcess = os.FindProcess
+		processSignal = oldProcessSignal
+		adoptCheckInterval = oldAdoptCheckInterval
+	}()
+	adoptCheckInterval = time.Millisecond
+
+	// cannot find process
+	osFindProcess = func(int) (*os.Process, error) {
+		return nil, errors.New("test error")
+	}
+	c := NewConnect()
+	c.Start()
+	c.Adopt(daemoncfg.NewConfig(), 123)
+	if e := <-c.Events(); e.Connect || e.ExitCode != -1 {
+		t.Errorf("got invalid event %v", e)
+	}
+	c.Stop()
+
+	// adopt process until it exits
+	osFindProcess = func(int) (*os.Process, error) {
+		return &os.Process{Pid: 123}, nil
+	}
+	checks := make(chan struct{})
+	processSignal = func(*os.Process, os.Signal) error {
+		select {
+		case checks <- struct{}{}:
+			return nil
+		default:
+			return errors.New("test error")
+		}
+	}
+	c = NewConnect()
+	c.Start()
+	c.Adopt(daemoncfg.NewConfig(), 123)
+	if e := <-c.Events(); !e.Connect || e.PID != 123 {
+		t.Errorf("got invalid event %v", e)
+	}
+	go func() { <-checks }()
+	if e := <-c.Events(); e.Connect || e.ExitCode != -1 {
+		t.Errorf("got invalid event %v", e)
+	}
+
+	// already running
+	c.command = &exec.Cmd{}
+	c.handleAdopt(&ConnectEvent{Connect: true, PID: 123})
+	c.command = nil
+	c.Stop()
+}
+
+// TestConnectDetach tests Detach of Connect.
+func TestConnectDetach(t *testing.T) {
+	// clean up after tests
+	oldProcessSignal := processSignal
+	defer func() {
+		osFindProcess = os.FindProcess
+		processSignal = oldProcessSignal
+	}()
+
+	// without connection
+	c := NewConnect()
+	c.Start()
+	c.Detach()
+	c.Stop()
+
+	// with adopted connection, process is not stopped
+	osFindProcess = func(int) (*os.Process, error) {
+		return &os.Process{Pid: 123}, nil
+	}
+	interrupts := 0
+	processSignal = func(_ *os.Process, sig os.Signal) error {
+		if sig == os.Interrupt {
+			interrupts++
+		}
+		return nil
+	}
+	c = NewConnect()
+	c.Start()
+	c.Adopt(daemoncfg.NewConfig(), 123)
+	<-c.Events()
+	c.Detach()
+	c.Stop()
+	if interrupts != 0 {
+		t.Errorf("got %d interrupts, want 0", interrupts)
+	}
 }
 
 // TestConnectEvents tests Events of Connect.
@@ -202,6 +377,38 @@ func TestNewConnect(t *testing.T) {
 	}
 }
 
+// TestRunningPID tests RunningPID.
+func TestRunningPID(t *testing.T) {
+	defer func() { osReadFile = os.ReadFile }()
+
+	for i, test := range []struct {
+		pidFile []byte
+		cmdLine []byte
+		want    uint32
+	}{
+		{nil, nil, 0},
+		{[]byte("garbage"), nil, 0},
+		{[]byte("0"), []byte("openconnect"), 0},
+		{[]byte("123"), nil, 0},
+		{[]byte("123"), []byte("other"), 0},
+		{[]byte("123\n"), []byte("openconnect"), 123},
+	} {
+		osReadFile = func(name string) ([]byte, error) {
+			b := test.cmdLine
+			if name == daemoncfg.OpenConnectPIDFile {
+				b = test.pidFile
+			}
+			if b == nil {
+				return nil, errors.New("test error")
+			}
+			return b, nil
+		}
+		if got := RunningPID(daemoncfg.NewOpenConnect()); got != test.want {
+			t.Errorf("%d: got %d, want %d", i, got, test.want)
+		}
+	}
+}
+
 // TestCleanupConnect tests CleanupConnect.
 func TestCleanupConnect(_ *testing.T) {
 	// clean up after tests
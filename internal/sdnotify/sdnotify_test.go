@@ -0,0 +1,84 @@
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestStatus tests Status.
+func TestStatus(t *testing.T) {
+	want := "STATUS=VPN connected"
+	got := Status("VPN connected")
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// TestNotify tests Notify.
+func TestNotify(t *testing.T) {
+	// test without socket
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := Notify(Ready); err != nil {
+		t.Error(err)
+	}
+
+	// test with socket
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	for _, want := range []string{
+		Ready,
+		Status("test"),
+		Stopping,
+	} {
+		if err := Notify(want); err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, 64)
+		n, err := conn.Read(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b[:n]); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+
+	// test with invalid socket
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "does-not-exist"))
+	if err := Notify(Ready); err == nil {
+		t.Error("invalid socket should return error")
+	}
+}
+
+// TestWatchdogInterval tests WatchdogInterval.
+func TestWatchdogInterval(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	for _, test := range []struct {
+		usec string
+		pid  string
+		want time.Duration
+	}{
+		{"", "", 0},
+		{"invalid", "", 0},
+		{"0", "", 0},
+		{"30000000", "", 30 * time.Second},
+		{"30000000", pid, 30 * time.Second},
+		{"30000000", "1", 0},
+	} {
+		t.Setenv("WATCHDOG_USEC", test.usec)
+		t.Setenv("WATCHDOG_PID", test.pid)
+		if got := WatchdogInterval(); got != test.want {
+			t.Errorf("%v: got %s, want %s", test, got, test.want)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
 	"github.com/telekom-mms/oc-daemon/internal/devmon"
 	"github.com/telekom-mms/oc-daemon/internal/dnsproxy"
+	"github.com/telekom-mms/oc-daemon/internal/metrics"
 )
 
 // State is the internal state.
@@ -171,6 +172,13 @@ func (s *SplitRouting) handleAddressUpdate(u *addrmon.Update) {
 	s.updateLocalNetworkExcludes()
 }
 
+// updateMetrics updates the split excludes metrics.
+func (s *SplitRouting) updateMetrics() {
+	static, dynamic := s.excludes.List()
+	metrics.SplitRoutingStaticExcludes.Set(s.config.Connection, int64(len(static)))
+	metrics.SplitRoutingDynamicExcludes.Set(s.config.Connection, int64(len(dynamic)))
+}
+
 // handleDNSReport handles a DNS report.
 func (s *SplitRouting) handleDNSReport(r *dnsproxy.Report) {
 	defer r.Close()
@@ -179,6 +187,7 @@ func (s *SplitRouting) handleDNSReport(r *dnsproxy.Report) {
 	exclude := netip.PrefixFrom(r.IP, r.IP.BitLen())
 	if s.excludes.AddDynamic(exclude, r.TTL) {
 		// signal update
+		s.updateMetrics()
 		s.sendPrefixes(s.excludes.GetPrefixes())
 	}
 }
@@ -206,7 +215,9 @@ func (s *SplitRouting) start() {
 		case r := <-s.dnsreps:
 			s.handleDNSReport(r)
 		case <-timer.C:
-			s.excludes.cleanup()
+			if s.excludes.cleanup() {
+				s.updateMetrics()
+			}
 			timer.Reset(excludesTimer * time.Second)
 		case <-s.done:
 			if !timer.Stop() {
@@ -255,6 +266,7 @@ func (s *SplitRouting) Start() error {
 		}
 		s.excludes.AddStatic(e)
 	}
+	s.updateMetrics()
 
 	go s.start()
 	return nil
@@ -264,6 +276,10 @@ func (s *SplitRouting) Start() error {
 func (s *SplitRouting) Stop() {
 	close(s.done)
 	<-s.closed
+
+	// remove metrics, excludes are removed with the vpn connection
+	metrics.SplitRoutingStaticExcludes.Delete(s.config.Connection)
+	metrics.SplitRoutingDynamicExcludes.Delete(s.config.Connection)
 	log.Debug("SplitRouting stopped")
 }
 
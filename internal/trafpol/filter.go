@@ -2,152 +2,68 @@ package trafpol
 
 import (
 	"context"
-	"errors"
 	"net/netip"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/backend"
 	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/journal"
 )
 
+// journalName is the name of the journal entry of the filter rules.
+const journalName = "TrafPol"
+
 // setFilterRules sets the filter rules.
 func setFilterRules(ctx context.Context, config *daemoncfg.Config) {
-	cmds, err := cmdtmpl.GetCmds("TrafPolSetFilterRules", config)
-	if err != nil {
-		log.WithError(err).Error("TrafPol could not get set filter rules commands")
-	}
-	for _, c := range cmds {
-		if stdout, stderr, err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			log.WithFields(log.Fields{
-				"command": c.Cmd,
-				"args":    c.Args,
-				"stdin":   c.Stdin,
-				"stdout":  string(stdout),
-				"stderr":  string(stderr),
-				"error":   err,
-			}).Error("TrafPol could not run set filter rules command")
-		}
+	// record undo commands of applied changes in journal
+	j := journal.NewRecorder(config.CommandLists.JournalDir, journalName)
+	ctx = cmdtmpl.WithJournal(ctx, j)
+
+	if err := backend.New(config).SetFilterRules(ctx, config); err != nil {
+		log.WithError(err).Error("TrafPol could not set filter rules")
 	}
 }
 
 // unsetFilterRules unsets the filter rules.
 func unsetFilterRules(ctx context.Context, config *daemoncfg.Config) {
-	cmds, err := cmdtmpl.GetCmds("TrafPolUnsetFilterRules", config)
-	if err != nil {
-		log.WithError(err).Error("TrafPol could not get unset filter rules commands")
+	if err := backend.New(config).UnsetFilterRules(ctx, config); err != nil {
+		log.WithError(err).Error("TrafPol could not unset filter rules")
 	}
-	for _, c := range cmds {
-		if stdout, stderr, err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			log.WithFields(log.Fields{
-				"command": c.Cmd,
-				"args":    c.Args,
-				"stdin":   c.Stdin,
-				"stdout":  string(stdout),
-				"stderr":  string(stderr),
-				"error":   err,
-			}).Error("TrafPol could not run unset filter rules command")
-		}
+
+	// filter rules removed, remove journal entry
+	if err := journal.Remove(config.CommandLists.JournalDir, journalName); err != nil {
+		log.WithError(err).Error("TrafPol could not remove journal entry")
 	}
 }
 
 // setAllowedDevices sets devices as allowed devices.
 func setAllowedDevices(ctx context.Context, conf *daemoncfg.Config, devices []string) {
-	data := &struct {
-		daemoncfg.Config
-		Devices []string
-	}{
-		Config:  *conf,
-		Devices: devices,
-	}
-	cmds, err := cmdtmpl.GetCmds("TrafPolSetAllowedDevices", data)
-	if err != nil {
-		log.WithError(err).Error("TrafPol could not get set allowed devices commands")
-	}
-	for _, c := range cmds {
-		if stdout, stderr, err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			log.WithFields(log.Fields{
-				"devices": devices,
-				"command": c.Cmd,
-				"args":    c.Args,
-				"stdin":   c.Stdin,
-				"stdout":  string(stdout),
-				"stderr":  string(stderr),
-				"error":   err,
-			}).Error("TrafPol could not run set allowed devices command")
-		}
+	if err := backend.New(conf).SetAllowedDevices(ctx, conf, devices); err != nil {
+		log.WithError(err).WithField("devices", devices).
+			Error("TrafPol could not set allowed devices")
 	}
 }
 
 // setAllowedIPs set the allowed hosts.
 func setAllowedIPs(ctx context.Context, conf *daemoncfg.Config, ips []netip.Prefix) {
-	// set allowed hosts
-	data := &struct {
-		daemoncfg.Config
-		AllowedIPs []netip.Prefix
-	}{
-		Config:     *conf,
-		AllowedIPs: ips,
-	}
-	cmds, err := cmdtmpl.GetCmds("TrafPolSetAllowedHosts", data)
-	if err != nil {
-		log.WithError(err).Error("TrafPol could not get set allowed hosts commands")
-	}
-	for _, c := range cmds {
-		if stdout, stderr, err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			log.WithFields(log.Fields{
-				"hosts":   ips,
-				"command": c.Cmd,
-				"args":    c.Args,
-				"stdin":   c.Stdin,
-				"stdout":  string(stdout),
-				"stderr":  string(stderr),
-				"error":   err,
-			}).Error("TrafPol could not run set allowed hosts command")
-		}
+	if err := backend.New(conf).SetAllowedHosts(ctx, conf, ips); err != nil {
+		log.WithError(err).WithField("hosts", ips).
+			Error("TrafPol could not set allowed hosts")
 	}
 }
 
 // setAllowedPorts sets ports (for a captive portal) as the allowed ports.
 func setAllowedPorts(ctx context.Context, conf *daemoncfg.Config, ports []uint16) {
-	data := &struct {
-		daemoncfg.Config
-		Ports []uint16
-	}{
-		Config: *conf,
-		Ports:  ports,
-	}
-	cmds, err := cmdtmpl.GetCmds("TrafPolSetAllowedPorts", data)
-	if err != nil {
-		log.WithError(err).Error("TrafPol could not get set allowed ports commands")
-	}
-	for _, c := range cmds {
-		if stdout, stderr, err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			log.WithFields(log.Fields{
-				"ports":   ports,
-				"command": c.Cmd,
-				"args":    c.Args,
-				"stdin":   c.Stdin,
-				"stdout":  string(stdout),
-				"stderr":  string(stderr),
-				"error":   err,
-			}).Error("TrafPol could not run set allowed ports command")
-		}
+	if err := backend.New(conf).SetAllowedPorts(ctx, conf, ports); err != nil {
+		log.WithError(err).WithField("ports", ports).
+			Error("TrafPol could not set allowed ports")
 	}
 }
 
 // cleanupFilterRules cleans up the filter rules after a failed shutdown.
 func cleanupFilterRules(ctx context.Context, conf *daemoncfg.Config) {
-	cmds, err := cmdtmpl.GetCmds("TrafPolCleanup", conf)
-	if err != nil {
-		log.WithError(err).Error("TrafPol could not get cleanup commands")
-	}
-	for _, c := range cmds {
-		if _, _, err := c.Run(ctx); err == nil {
-			log.WithFields(log.Fields{
-				"command": c.Cmd,
-				"args":    c.Args,
-				"stdin":   c.Stdin,
-			}).Warn("TrafPol cleaned up configuration")
-		}
+	if err := backend.New(conf).CleanupFilterRules(ctx, conf); err == nil {
+		log.Warn("TrafPol cleaned up configuration")
 	}
 }
@@ -8,10 +8,11 @@ import (
 
 	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/journal"
 )
 
 // TestFilterFunctionsErrors tests filter functions, errors.
-func TestFilterFunctionsErrors(_ *testing.T) {
+func TestFilterFunctionsErrors(t *testing.T) {
 	oldRunCmd := cmdtmpl.RunCmd
 	cmdtmpl.RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
 		return nil, nil, errors.New("test error")
@@ -23,6 +24,7 @@ func TestFilterFunctionsErrors(_ *testing.T) {
 	// filter rules
 	conf := daemoncfg.NewConfig()
 	conf.SplitRouting.FirewallMark = "123"
+	conf.CommandLists.JournalDir = t.TempDir()
 	setFilterRules(ctx, conf)
 	unsetFilterRules(ctx, conf)
 
@@ -42,3 +44,36 @@ func TestFilterFunctionsErrors(_ *testing.T) {
 	setAllowedPorts(ctx, conf, []uint16{80, 443})
 	setAllowedPorts(ctx, conf, []uint16{})
 }
+
+// TestFilterRulesJournal tests journal entries of filter rules.
+func TestFilterRulesJournal(t *testing.T) {
+	oldRunCmd := cmdtmpl.RunCmd
+	cmdtmpl.RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
+		return nil, nil, nil
+	}
+	defer func() { cmdtmpl.RunCmd = oldRunCmd }()
+
+	ctx := context.Background()
+	conf := daemoncfg.NewConfig()
+	conf.CommandLists.JournalDir = t.TempDir()
+
+	// set filter rules, should add journal entry
+	setFilterRules(ctx, conf)
+	entries, err := journal.Load(conf.CommandLists.JournalDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != journalName || len(entries[0].Undo) == 0 {
+		t.Errorf("got %v, want journal entry with undo commands", entries)
+	}
+
+	// unset filter rules, should remove journal entry
+	unsetFilterRules(ctx, conf)
+	entries, err = journal.Load(conf.CommandLists.JournalDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("got %v, want no journal entries", entries)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+	"github.com/telekom-mms/oc-daemon/internal/metrics"
 )
 
 // ResolvedName is a resolved DNS name.
@@ -75,6 +76,7 @@ func (r *ResolvedName) resolve(ctx context.Context, config *daemoncfg.TrafficPol
 
 			// if we cannot resolve the host, retry or
 			// keep existing IPs
+			metrics.ResolverFailures.Inc()
 			continue
 		}
 		r.TTL = config.ResolveTTL
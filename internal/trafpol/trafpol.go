@@ -8,6 +8,7 @@ import (
 	"net/netip"
 	"strconv"
 	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/telekom-mms/oc-daemon/internal/cpd"
@@ -22,14 +23,16 @@ const (
 	trafPolCmdAddAddress uint8 = iota + 1
 	trafPolCmdRemoveAddress
 	trafPolCmdGetState
+	trafPolCmdRemediate
 )
 
 // State is the internal TrafPol state.
 type State struct {
-	CaptivePortal    bool
-	AllowedDevices   []string
-	AllowedAddresses []netip.Prefix
-	AllowedNames     map[string][]netip.Addr
+	CaptivePortal     bool
+	PortalRemediation bool
+	AllowedDevices    []string
+	AllowedAddresses  []netip.Prefix
+	AllowedNames      map[string][]netip.Addr
 }
 
 // trafPolCmd is a TrafPol command.
@@ -44,8 +47,11 @@ type trafPolCmd struct {
 // Policer is the Traffic Policing interface.
 type Policer interface {
 	AddAllowedAddr(addr netip.Addr) bool
-	CPDStatus() <-chan bool
+	CPDStatus() <-chan *cpd.Report
 	GetState() *State
+	ProbeCPD()
+	Remediate() bool
+	RemediationExpired() <-chan struct{}
 	RemoveAllowedAddr(addr netip.Addr) bool
 	Start() error
 	Stop()
@@ -64,7 +70,19 @@ type TrafPol struct {
 	capPortal bool
 
 	// cpdStatus is a channel for CPD status updates
-	cpdStatus chan bool
+	cpdStatus chan *cpd.Report
+
+	// remediation indicates if the portal ports are open for captive
+	// portal remediation
+	remediation bool
+
+	// remediationTimer is the timer for closing the portal ports after
+	// the remediation timeout, nil if no timeout is running
+	remediationTimer *time.Timer
+
+	// remediationExpired is a channel for expired captive portal
+	// remediations
+	remediationExpired chan struct{}
 
 	// allowed devices, addresses, names
 	allowDevs  *AllowDevs
@@ -108,6 +126,75 @@ func (t *TrafPol) handleDNSUpdate() {
 	t.cpd.Probe()
 }
 
+// startRemediation opens the portal ports for captive portal remediation
+// and starts the remediation timeout, returns whether remediation is allowed.
+func (t *TrafPol) startRemediation(ctx context.Context) bool {
+	if !t.config.TrafficPolicing.PortalRemediation {
+		log.Warn("TrafPol not opening portal ports, captive portal remediation not allowed")
+		return false
+	}
+
+	// add ports to allowed ports
+	t.stopRemediationTimer()
+	if !t.remediation {
+		setAllowedPorts(ctx, t.config, t.config.TrafficPolicing.PortalPorts)
+		t.remediation = true
+	}
+
+	// close ports again after timeout
+	timeout := t.config.TrafficPolicing.PortalRemediationTimeout
+	if timeout > 0 {
+		t.remediationTimer = time.NewTimer(timeout)
+	}
+	log.WithField("timeout", timeout).Info("TrafPol opened portal ports for captive portal remediation")
+	return true
+}
+
+// stopRemediation closes the portal ports and stops the remediation timeout.
+func (t *TrafPol) stopRemediation(ctx context.Context) {
+	t.stopRemediationTimer()
+	if !t.remediation {
+		return
+	}
+
+	// remove ports from allowed ports
+	setAllowedPorts(ctx, t.config, []uint16{})
+	t.remediation = false
+	log.Info("TrafPol closed portal ports for captive portal remediation")
+}
+
+// stopRemediationTimer stops the remediation timeout.
+func (t *TrafPol) stopRemediationTimer() {
+	if t.remediationTimer != nil {
+		t.remediationTimer.Stop()
+		t.remediationTimer = nil
+	}
+}
+
+// remediationTimerC returns the channel of the remediation timer,
+// nil if no timeout is running.
+func (t *TrafPol) remediationTimerC() <-chan time.Time {
+	if t.remediationTimer == nil {
+		return nil
+	}
+	return t.remediationTimer.C
+}
+
+// handleRemediationTimeout handles the timeout of the captive portal
+// remediation.
+func (t *TrafPol) handleRemediationTimeout(ctx context.Context) {
+	t.remediationTimer = nil
+	log.Warn("TrafPol captive portal remediation timed out")
+	t.stopRemediation(ctx)
+
+	// notify about expired remediation, do not block if previous
+	// notification was not handled yet
+	select {
+	case t.remediationExpired <- struct{}{}:
+	default:
+	}
+}
+
 // handleCPDReport handles a CPD report, returns whether status changed.
 func (t *TrafPol) handleCPDReport(ctx context.Context, report *cpd.Report) bool {
 	if !report.Detected {
@@ -119,7 +206,7 @@ func (t *TrafPol) handleCPDReport(ctx context.Context, report *cpd.Report) bool
 			t.resolver.Resolve()
 
 			// remove ports from allowed ports
-			setAllowedPorts(ctx, t.config, []uint16{})
+			t.stopRemediation(ctx)
 			t.capPortal = false
 			log.WithField("capPortal", t.capPortal).Info("TrafPol changed CPD status")
 			return true
@@ -129,7 +216,7 @@ func (t *TrafPol) handleCPDReport(ctx context.Context, report *cpd.Report) bool
 
 	// add ports to allowed ports
 	if !t.capPortal {
-		setAllowedPorts(ctx, t.config, t.config.TrafficPolicing.PortalPorts)
+		t.startRemediation(ctx)
 		t.capPortal = true
 		log.WithField("capPortal", t.capPortal).Info("TrafPol changed CPD status")
 		return true
@@ -201,13 +288,23 @@ func (t *TrafPol) handleAddressCommand(ctx context.Context, cmd *trafPolCmd) {
 func (t *TrafPol) handleGetStateCommand(cmd *trafPolCmd) {
 	// set state
 	cmd.state = &State{
-		CaptivePortal:    t.capPortal,
-		AllowedDevices:   t.allowDevs.List(),
-		AllowedAddresses: t.allowAddrs.List(),
-		AllowedNames:     t.allowNames.GetAll(),
+		CaptivePortal:     t.capPortal,
+		PortalRemediation: t.remediation,
+		AllowedDevices:    t.allowDevs.List(),
+		AllowedAddresses:  t.allowAddrs.List(),
+		AllowedNames:      t.allowNames.GetAll(),
 	}
 }
 
+// handleRemediateCommand handles a remediate command.
+func (t *TrafPol) handleRemediateCommand(ctx context.Context, cmd *trafPolCmd) {
+	if !t.capPortal {
+		// no captive portal detected
+		return
+	}
+	cmd.ok = t.startRemediation(ctx)
+}
+
 // handleCommand handles a command.
 func (t *TrafPol) handleCommand(ctx context.Context, cmd *trafPolCmd) {
 	defer close(cmd.done)
@@ -217,6 +314,8 @@ func (t *TrafPol) handleCommand(ctx context.Context, cmd *trafPolCmd) {
 		t.handleAddressCommand(ctx, cmd)
 	case trafPolCmdGetState:
 		t.handleGetStateCommand(cmd)
+	case trafPolCmdRemediate:
+		t.handleRemediateCommand(ctx, cmd)
 	}
 }
 
@@ -225,6 +324,7 @@ func (t *TrafPol) start(ctx context.Context) {
 	defer close(t.loopDone)
 	defer close(t.cpdStatus)
 	defer unsetFilterRules(ctx, t.config)
+	defer t.stopRemediationTimer()
 	defer t.resolver.Stop()
 	defer t.cpd.Stop()
 	defer t.devmon.Stop()
@@ -232,7 +332,8 @@ func (t *TrafPol) start(ctx context.Context) {
 
 	// enter main loop
 	cpdResults := t.cpd.Results()
-	var cpdStatus chan bool
+	var cpdStatus chan *cpd.Report
+	var cpdReport *cpd.Report
 	for {
 		select {
 		case u := <-t.devmon.Updates():
@@ -253,14 +354,19 @@ func (t *TrafPol) start(ctx context.Context) {
 				// results, send status
 				cpdResults = nil
 				cpdStatus = t.cpdStatus
+				cpdReport = r
 			}
 
-		case cpdStatus <- t.capPortal:
+		case cpdStatus <- cpdReport:
 			// CPD status sent, resume reading CPD results, stop
 			// sending status
 			cpdResults = t.cpd.Results()
 			cpdStatus = nil
 
+		case <-t.remediationTimerC():
+			// Remediation Timeout
+			t.handleRemediationTimeout(ctx)
+
 		case u := <-t.resolvUp:
 			// Resolver Update
 			log.WithField("update", u).Debug("TrafPol got Resolver update")
@@ -380,8 +486,35 @@ func (t *TrafPol) GetState() *State {
 	return c.state
 }
 
+// Remediate opens the portal ports for another captive portal remediation
+// window, returns whether a captive portal is detected and remediation is
+// allowed.
+func (t *TrafPol) Remediate() (ok bool) {
+	log.Debug("TrafPol starting captive portal remediation")
+
+	c := &trafPolCmd{
+		typ:  trafPolCmdRemediate,
+		done: make(chan struct{}),
+	}
+	t.cmds <- c
+	<-c.done
+
+	return c.ok
+}
+
+// RemediationExpired returns the channel for expired captive portal
+// remediations.
+func (t *TrafPol) RemediationExpired() <-chan struct{} {
+	return t.remediationExpired
+}
+
+// ProbeCPD triggers the captive portal detection.
+func (t *TrafPol) ProbeCPD() {
+	t.cpd.Probe()
+}
+
 // CPDStatus returns the channel for CPD status updates.
-func (t *TrafPol) CPDStatus() <-chan bool {
+func (t *TrafPol) CPDStatus() <-chan *cpd.Report {
 	return t.cpdStatus
 }
 
@@ -474,7 +607,9 @@ func NewTrafPol(config *daemoncfg.Config) *TrafPol {
 		dnsmon: dnsmon.NewDNSMon(dnsmon.NewConfig()),
 		cpd:    c,
 
-		cpdStatus: make(chan bool),
+		cpdStatus: make(chan *cpd.Report),
+
+		remediationExpired: make(chan struct{}, 1),
 
 		allowDevs: NewAllowDevs(),
 
@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
 	"github.com/telekom-mms/oc-daemon/internal/cpd"
@@ -85,6 +86,74 @@ func TestTrafPolHandleCPDReport(t *testing.T) {
 	}
 }
 
+// TestTrafPolRemediation tests captive portal remediation of TrafPol.
+func TestTrafPolRemediation(t *testing.T) {
+	ctx := context.Background()
+
+	oldRunCmd := cmdtmpl.RunCmd
+	cmdtmpl.RunCmd = func(_ context.Context, _ string, _ string,
+		_ ...string) ([]byte, []byte, error) {
+		return nil, nil, nil
+	}
+	defer func() { cmdtmpl.RunCmd = oldRunCmd }()
+
+	// test remediation with timeout
+	c := daemoncfg.NewConfig()
+	c.TrafficPolicing.PortalRemediationTimeout = time.Minute
+	tp := NewTrafPol(c)
+	tp.resolver.Start()
+	defer tp.resolver.Stop()
+
+	tp.handleCPDReport(ctx, &cpd.Report{Detected: true})
+	if !tp.remediation || tp.remediationTimerC() == nil {
+		t.Error("remediation should be started with timeout")
+	}
+
+	tp.handleRemediationTimeout(ctx)
+	if tp.remediation || tp.remediationTimerC() != nil {
+		t.Error("remediation should be stopped after timeout")
+	}
+	select {
+	case <-tp.RemediationExpired():
+	default:
+		t.Error("remediation expired should be sent")
+	}
+	if !tp.capPortal {
+		t.Error("captive portal should still be detected")
+	}
+
+	// test another remediation window
+	cmd := &trafPolCmd{typ: trafPolCmdRemediate, done: make(chan struct{})}
+	tp.handleCommand(ctx, cmd)
+	if !cmd.ok || !tp.remediation {
+		t.Error("remediation should be started again")
+	}
+
+	// test portal not detected any more
+	tp.handleCPDReport(ctx, &cpd.Report{})
+	if tp.remediation || tp.remediationTimerC() != nil {
+		t.Error("remediation should be stopped without portal")
+	}
+
+	// test remediation without portal
+	cmd = &trafPolCmd{typ: trafPolCmdRemediate, done: make(chan struct{})}
+	tp.handleCommand(ctx, cmd)
+	if cmd.ok || tp.remediation {
+		t.Error("remediation should not be started without portal")
+	}
+
+	// test remediation not allowed
+	c = daemoncfg.NewConfig()
+	c.TrafficPolicing.PortalRemediation = false
+	tp = NewTrafPol(c)
+	if !tp.handleCPDReport(ctx, &cpd.Report{Detected: true}) {
+		t.Error("status should have changed")
+	}
+	if tp.remediation {
+		t.Error("remediation should not be started if not allowed")
+	}
+}
+
 // TestTrafPolStartEvents tests start of TrafPol, events.
 func TestTrafPolStartEvents(t *testing.T) {
 	// set dummy low level function for devmon
@@ -102,7 +171,7 @@ func TestTrafPolStartEvents(t *testing.T) {
 	tp.dnsmon.Updates() <- struct{}{}
 	tp.cpd.Results() <- &cpd.Report{Detected: true}
 	tp.resolvUp <- &ResolvedName{}
-	if !<-tp.CPDStatus() {
+	if !(<-tp.CPDStatus()).Detected {
 		t.Error("CPD status should be true")
 	}
 	tp.Stop()
@@ -250,6 +319,40 @@ func TestTrafPolGetState(t *testing.T) {
 	tp.Stop()
 }
 
+// TestTrafPolRemediate tests Remediate of TrafPol.
+func TestTrafPolRemediate(t *testing.T) {
+	// set dummy low level function for devmon
+	oldRegisterLinkUpdates := devmon.RegisterLinkUpdates
+	devmon.RegisterLinkUpdates = func(*devmon.DevMon) (chan netlink.LinkUpdate, error) {
+		return nil, nil
+	}
+	defer func() { devmon.RegisterLinkUpdates = oldRegisterLinkUpdates }()
+
+	// start trafpol
+	tp := NewTrafPol(daemoncfg.NewConfig())
+	if err := tp.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// no captive portal detected
+	if tp.Remediate() {
+		t.Error("remediation should not be possible without portal")
+	}
+
+	// stop trafpol
+	tp.Stop()
+}
+
+// TestTrafPolProbeCPD tests ProbeCPD of TrafPol.
+func TestTrafPolProbeCPD(_ *testing.T) {
+	tp := NewTrafPol(daemoncfg.NewConfig())
+
+	tp.cpd.Start()
+	defer tp.cpd.Stop()
+
+	tp.ProbeCPD()
+}
+
 // TestTrafPolCPDStatus tests CPDStatus of TrafPol.
 func TestTrafPolCPDStatus(t *testing.T) {
 	tp := NewTrafPol(daemoncfg.NewConfig())
@@ -270,6 +373,7 @@ func TestNewTrafPol(t *testing.T) {
 		tp.dnsmon == nil ||
 		tp.cpd == nil ||
 		tp.cpdStatus == nil ||
+		tp.remediationExpired == nil ||
 		tp.allowDevs == nil ||
 		tp.allowAddrs == nil ||
 		tp.allowNames == nil ||
@@ -221,6 +221,7 @@ func createConfig(env *env) (*vpnconfig.Config, error) {
 func createConfigUpdate(env *env) (*daemon.VPNConfigUpdate, error) {
 	update := daemon.NewVPNConfigUpdate()
 	update.Reason = env.reason
+	update.Connection = env.connection
 	if env.reason == "connect" {
 		c, err := createConfig(env)
 		if err != nil {
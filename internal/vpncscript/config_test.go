@@ -83,10 +83,12 @@ func TestCreateConfigUpdate(t *testing.T) {
 		bypassVirtualSubnetsOnlyV4: true,
 		disableAlwaysOnVPN:         true,
 		token:                      "some token",
+		connection:                 "lab",
 	}
 
 	// create expected values based on test environment
 	reason := "connect"
+	connection := "lab"
 	config := &vpnconfig.Config{
 		Gateway: net.IPv4(10, 1, 1, 1),
 		PID:     12345,
@@ -128,6 +130,9 @@ func TestCreateConfigUpdate(t *testing.T) {
 	if got.Reason != reason {
 		t.Errorf("got %s, want %s", got.Reason, reason)
 	}
+	if got.Connection != connection {
+		t.Errorf("got %s, want %s", got.Connection, connection)
+	}
 	if !reflect.DeepEqual(got.Config, config) {
 		t.Errorf("got:\n%#v\nwant:\n%#v", got.Config, config)
 	}
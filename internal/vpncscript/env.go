@@ -24,7 +24,7 @@ import (
 //
 //   - TUNDEV                           -- tunnel device (always present)
 //
-//   - IDLE_TIMEOUT                     -- gateway's idle timeout in seconds (OpenConnect v8.06+); unused
+//   - IDLE_TIMEOUT                     -- gateway's idle timeout in seconds (OpenConnect v8.06+)
 //
 //   - INTERNAL_IP4_ADDRESS             -- address (always present)
 //
@@ -118,10 +118,11 @@ type env struct {
 	bypassVirtualSubnetsOnlyV4 bool
 	disableAlwaysOnVPN         bool
 
-	// openconnect daemon token, socket file, verbosity
+	// openconnect daemon token, socket file, verbosity, connection name
 	token      string
 	socketFile string
 	verbose    bool
+	connection string
 }
 
 // parseEnvironmentSplit parses split include/exclude parameters identified by
@@ -280,7 +281,7 @@ func parseEnvironment() *env {
 
 	// parse idle timeout
 	// IDLE_TIMEOUT -- gateway's idle timeout in seconds (OpenConnect
-	// v8.06+); unused
+	// v8.06+)
 	e.idleTimeout = os.Getenv("IDLE_TIMEOUT")
 
 	// parse internal ipv4 address
@@ -377,13 +378,15 @@ func parseEnvironment() *env {
 	// parse Disable Always On VPN
 	e.disableAlwaysOnVPN = parseDisableAlwaysOnVPN(e.ciscoCSTPOptions)
 
-	// parse openconnect daemon token, socket file, verbosity
+	// parse openconnect daemon token, socket file, verbosity, connection
+	// name
 	e.token = os.Getenv("oc_daemon_token")
 	e.socketFile = os.Getenv("oc_daemon_socket_file")
 	e.verbose = false
 	if os.Getenv("oc_daemon_verbose") == "true" {
 		e.verbose = true
 	}
+	e.connection = os.Getenv("oc_daemon_connection")
 
 	return e
 }
@@ -378,6 +378,7 @@ X-CSTP-Disable-Always-On-VPN=true`,
 		"oc_daemon_token":       "some token",
 		"oc_daemon_socket_file": "/run/oc-daemon/test.socket",
 		"oc_daemon_verbose":     "true",
+		"oc_daemon_connection":  "lab",
 	} {
 		t.Setenv(k, v)
 	}
@@ -415,6 +416,7 @@ X-CSTP-Disable-Always-On-VPN=true`,
 		token:                      "some token",
 		socketFile:                 "/run/oc-daemon/test.socket",
 		verbose:                    true,
+		connection:                 "lab",
 	}
 
 	// run test
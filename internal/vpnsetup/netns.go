@@ -0,0 +1,80 @@
+package vpnsetup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// netnsEtcDir is the directory with the configuration files of network
+// namespaces, that are used by "ip netns exec".
+var netnsEtcDir = "/etc/netns"
+
+// writeNetNSResolvConf writes the resolv.conf of the network namespace that
+// sets the DNS proxy as nameserver.
+func writeNetNSResolvConf(config *daemoncfg.NetNS) error {
+	addr, err := netip.ParseAddrPort(config.DNSAddress)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(netnsEtcDir, config.Name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	resolvConf := fmt.Sprintf("# generated by oc-daemon\nnameserver %s\n", addr.Addr())
+	return os.WriteFile(filepath.Join(dir, "resolv.conf"), []byte(resolvConf), 0644)
+}
+
+// SetupNetNS sets up the network namespace of the VPN tunnel.
+func SetupNetNS(ctx context.Context, config *daemoncfg.Config) {
+	cmds, err := cmdtmpl.GetCmds("VPNSetupSetupNetNS", config)
+	if err != nil {
+		log.WithError(err).Error("VPNSetup could not get setup network namespace commands")
+	}
+	for _, c := range cmds {
+		if stdout, stderr, err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.WithFields(log.Fields{
+				"command": c.Cmd,
+				"args":    c.Args,
+				"stdin":   c.Stdin,
+				"stdout":  string(stdout),
+				"stderr":  string(stderr),
+				"error":   err,
+			}).Error("VPNSetup could not run setup network namespace command")
+		}
+	}
+
+	if err := writeNetNSResolvConf(config.NetNS); err != nil {
+		log.WithError(err).Error("VPNSetup could not write resolv.conf of network namespace")
+	}
+}
+
+// TeardownNetNS tears down the network namespace of the VPN tunnel. It is
+// also used to clean up the network namespace after a failed shutdown.
+func TeardownNetNS(ctx context.Context, config *daemoncfg.Config) {
+	cmds, err := cmdtmpl.GetCmds("VPNSetupTeardownNetNS", config)
+	if err != nil {
+		log.WithError(err).Error("VPNSetup could not get teardown network namespace commands")
+	}
+	for _, c := range cmds {
+		if _, _, err := c.Run(ctx); err == nil {
+			log.WithFields(log.Fields{
+				"netns":   config.NetNS.Name,
+				"command": c.Cmd,
+				"args":    c.Args,
+				"stdin":   c.Stdin,
+			}).Debug("VPNSetup removed network namespace configuration")
+		}
+	}
+
+	if err := os.RemoveAll(filepath.Join(netnsEtcDir, config.NetNS.Name)); err != nil {
+		log.WithError(err).Error("VPNSetup could not remove configuration of network namespace")
+	}
+}
@@ -0,0 +1,93 @@
+package vpnsetup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
+	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
+)
+
+// TestSetupTeardownNetNS tests SetupNetNS and TeardownNetNS.
+func TestSetupTeardownNetNS(t *testing.T) {
+	oldRunCmd := cmdtmpl.RunCmd
+	oldEtcDir := netnsEtcDir
+	defer func() {
+		cmdtmpl.RunCmd = oldRunCmd
+		netnsEtcDir = oldEtcDir
+	}()
+	netnsEtcDir = t.TempDir()
+
+	got := []string{}
+	cmdtmpl.RunCmd = func(_ context.Context, cmd string, _ string, arg ...string) ([]byte, []byte, error) {
+		got = append(got, cmd+" "+strings.Join(arg, " "))
+		return nil, nil, nil
+	}
+	cfg := daemoncfg.NewConfig()
+	cfg.NetNS.Enabled = true
+
+	// setup
+	SetupNetNS(context.Background(), cfg)
+	want := []string{
+		"ip netns add oc-daemon",
+		"ip -n oc-daemon link set lo up",
+		"ip link add oc-daemon-veth0 type veth peer name oc-daemon-veth1 netns oc-daemon",
+		"ip address add 169.254.42.1/30 dev oc-daemon-veth0",
+		"ip -n oc-daemon address add 169.254.42.2/30 dev oc-daemon-veth1",
+		"ip link set oc-daemon-veth0 up",
+		"ip -n oc-daemon link set oc-daemon-veth1 up",
+		"ip -n oc-daemon route add default via 169.254.42.1",
+		"sysctl -q net.ipv4.ip_forward=1",
+		"nft -f -",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	b, err := os.ReadFile(filepath.Join(netnsEtcDir, "oc-daemon", "resolv.conf"))
+	if err != nil || !strings.Contains(string(b), "nameserver 127.0.0.1\n") {
+		t.Errorf("got invalid resolv.conf %q, %v", b, err)
+	}
+
+	// teardown
+	got = []string{}
+	TeardownNetNS(context.Background(), cfg)
+	want = []string{
+		"nft -f - delete table inet oc-daemon-netns",
+		"ip link delete oc-daemon-veth0",
+		"ip netns delete oc-daemon",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(netnsEtcDir, "oc-daemon")); !os.IsNotExist(err) {
+		t.Errorf("configuration of network namespace should be removed: %v", err)
+	}
+}
+
+// TestWriteNetNSResolvConf tests writeNetNSResolvConf.
+func TestWriteNetNSResolvConf(t *testing.T) {
+	oldEtcDir := netnsEtcDir
+	defer func() { netnsEtcDir = oldEtcDir }()
+
+	// invalid dns address
+	netnsEtcDir = t.TempDir()
+	config := daemoncfg.NewNetNS()
+	config.DNSAddress = "invalid"
+	if err := writeNetNSResolvConf(config); err == nil {
+		t.Error("invalid dns address should return error")
+	}
+
+	// invalid directory
+	config = daemoncfg.NewNetNS()
+	netnsEtcDir = filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(netnsEtcDir, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNetNSResolvConf(config); err == nil {
+		t.Error("invalid directory should return error")
+	}
+}
@@ -3,19 +3,24 @@ package vpnsetup
 
 import (
 	"context"
-	"errors"
+	"maps"
 	"net/netip"
 	"slices"
 	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/telekom-mms/oc-daemon/internal/backend"
 	"github.com/telekom-mms/oc-daemon/internal/cmdtmpl"
 	"github.com/telekom-mms/oc-daemon/internal/daemoncfg"
 	"github.com/telekom-mms/oc-daemon/internal/dnsproxy"
+	"github.com/telekom-mms/oc-daemon/internal/journal"
 	"github.com/telekom-mms/oc-daemon/internal/splitrt"
 )
 
+// journalName is the name of the journal entry of the VPN setup.
+const journalName = "VPNSetup"
+
 // command types.
 const (
 	commandSetup uint8 = iota
@@ -27,6 +32,10 @@ const (
 type State struct {
 	SplitRouting *splitrt.State
 	DNSProxy     *dnsproxy.State
+
+	// Connections is the split routing state of the named VPN
+	// connections
+	Connections map[string]*splitrt.State `json:",omitempty"`
 }
 
 // command is a VPNSetup command.
@@ -48,38 +57,45 @@ type Setup interface {
 
 var _ Setup = &VPNSetup{}
 
-// VPNSetup sets up the configuration of the vpn tunnel that belongs to the
-// current VPN connection.
-type VPNSetup struct {
-	config   *daemoncfg.Config
-	splitrt  *splitrt.SplitRouting
-	dnsProxy *dnsproxy.Proxy
+// connSetup is the setup of the vpn tunnel of a VPN connection.
+type connSetup struct {
+	config  *daemoncfg.Config
+	splitrt *splitrt.SplitRouting
 
 	ensureDone   chan struct{}
 	ensureClosed chan struct{}
 
+	// prefixesClosed is closed when handling the exclude prefixes of
+	// split routing stopped
+	prefixesClosed chan struct{}
+}
+
+// VPNSetup sets up the configuration of the vpn tunnels that belong to the
+// current VPN connections.
+type VPNSetup struct {
+	// conns are the set up VPN connections by connection name, the
+	// default VPN connection has an empty name
+	conns    map[string]*connSetup
+	dnsProxy *dnsproxy.Proxy
+
 	cmds   chan *command
 	done   chan struct{}
 	closed chan struct{}
 }
 
+// JournalName returns the name of the journal entry of the VPN connection
+// with name.
+func JournalName(name string) string {
+	if name == "" {
+		return journalName
+	}
+	return journalName + "-" + name
+}
+
 // resetDNS resets the DNS settings.
 func (v *VPNSetup) resetDNS(ctx context.Context, config *daemoncfg.Config) {
-	cmds, err := cmdtmpl.GetCmds("VPNSetupSetDNS", config)
-	if err != nil {
-		log.WithError(err).Error("VPNSetup could not get set DNS commands")
-	}
-	for _, c := range cmds {
-		if stdout, stderr, err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			log.WithFields(log.Fields{
-				"command": c.Cmd,
-				"args":    c.Args,
-				"stdin":   c.Stdin,
-				"stdout":  string(stdout),
-				"stderr":  string(stderr),
-				"error":   err,
-			}).Error("VPNSetup could not run set DNS command")
-		}
+	if err := backend.New(config).SetDNS(ctx, config); err != nil {
+		log.WithError(err).Error("VPNSetup could not set DNS settings")
 	}
 }
 
@@ -133,55 +149,23 @@ func (v *VPNSetup) checkDNSDomain(config *daemoncfg.Config, domains []string) bo
 
 // ensureDNS ensures the DNS config.
 func (v *VPNSetup) ensureDNS(ctx context.Context, config *daemoncfg.Config) bool {
+	if config.NetNS.Enabled {
+		// DNS settings are in the resolv.conf of the network namespace
+		return true
+	}
 	log.Debug("VPNSetup checking DNS settings")
 
 	// get dns settings
-	cmds, err := cmdtmpl.GetCmds("VPNSetupGetDNS", config)
+	dns, err := backend.New(config).GetDNS(ctx, config)
 	if err != nil {
-		log.WithError(err).Error("VPNSetup could not get get DNS commands")
-	}
-	var stdout []byte
-	for _, c := range cmds {
-		sout, serr, err := c.Run(ctx)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"command": c.Cmd,
-				"args":    c.Args,
-				"stdin":   c.Stdin,
-				"stdout":  string(sout),
-				"stderr":  string(serr),
-			}).Error("VPNSetup could not run get DNS command")
-			return false
-		}
-		// collect output
-		stdout = slices.Concat(stdout, sout)
+		log.WithError(err).Error("VPNSetup could not get DNS settings")
+		return false
 	}
 
-	// parse and check dns settings line by line
-	var protOK, srvOK, domOK bool
-	lines := strings.Split(string(stdout), "\n")
-	for _, line := range lines {
-		// try to find separator ":"
-		before, after, found := strings.Cut(strings.TrimSpace(line), ":")
-		if !found {
-			continue
-		}
-
-		// get fields after separator
-		f := strings.Fields(after)
-
-		// check settings if present
-		switch before {
-		case "Protocols":
-			protOK = v.checkDNSProtocols(f)
-
-		case "DNS Servers":
-			srvOK = v.checkDNSServers(config, f)
-
-		case "DNS Domain":
-			domOK = v.checkDNSDomain(config, f)
-		}
-	}
+	// check dns settings
+	protOK := v.checkDNSProtocols(dns.Protocols)
+	srvOK := v.checkDNSServers(config, dns.Servers)
+	domOK := v.checkDNSDomain(config, dns.Domains)
 
 	// reset settings if incorrect/not present
 	reset := false
@@ -210,9 +194,9 @@ func (v *VPNSetup) ensureDNS(ctx context.Context, config *daemoncfg.Config) bool
 	return protOK && srvOK && domOK
 }
 
-// ensureConfig ensures that the VPN config is and stays active.
-func (v *VPNSetup) ensureConfig(ctx context.Context, conf *daemoncfg.Config) {
-	defer close(v.ensureClosed)
+// ensureConfig ensures that the VPN config of c is and stays active.
+func (v *VPNSetup) ensureConfig(ctx context.Context, c *connSetup) {
+	defer close(c.ensureClosed)
 
 	timerInvalid := time.Second
 	timerValid := 15 * time.Second
@@ -223,7 +207,7 @@ func (v *VPNSetup) ensureConfig(ctx context.Context, conf *daemoncfg.Config) {
 			log.Debug("VPNSetup checking VPN configuration")
 
 			// ensure DNS settings
-			if ok := v.ensureDNS(ctx, conf); !ok {
+			if ok := v.ensureDNS(ctx, c.config); !ok {
 				timer = timerInvalid
 				break
 			}
@@ -231,109 +215,145 @@ func (v *VPNSetup) ensureConfig(ctx context.Context, conf *daemoncfg.Config) {
 			// vpn config is OK
 			timer = timerValid
 
-		case <-v.ensureDone:
+		case <-c.ensureDone:
 			return
 		}
 	}
 }
 
-// startEnsure starts ensuring the VPN config.
-func (v *VPNSetup) startEnsure(ctx context.Context, conf *daemoncfg.Config) {
-	v.ensureDone = make(chan struct{})
-	v.ensureClosed = make(chan struct{})
-	go v.ensureConfig(ctx, conf)
+// startEnsure starts ensuring the VPN config of c.
+func (v *VPNSetup) startEnsure(ctx context.Context, c *connSetup) {
+	c.ensureDone = make(chan struct{})
+	c.ensureClosed = make(chan struct{})
+	go v.ensureConfig(ctx, c)
 }
 
-// stopEnsure stops ensuring the VPN config.
-func (v *VPNSetup) stopEnsure() {
-	close(v.ensureDone)
-	<-v.ensureClosed
+// stopEnsure stops ensuring the VPN config of c.
+func (v *VPNSetup) stopEnsure(c *connSetup) {
+	close(c.ensureDone)
+	<-c.ensureClosed
+}
+
+// handlePrefixesUpdates handles the exclude prefixes updates from split
+// routing of c until split routing is stopped.
+func (v *VPNSetup) handlePrefixesUpdates(ctx context.Context, c *connSetup) {
+	defer close(c.prefixesClosed)
+	for p := range c.splitrt.Prefixes() {
+		v.handlePrefixes(ctx, c.config, p)
+	}
+}
+
+// updateDNSProxy updates the remotes and watches of the DNS proxy from the
+// set up VPN connections. If VPN connections share a domain, the remotes of
+// the default VPN connection are used first, then the remotes of the named
+// VPN connections sorted by name. Watches for DNS-based split excludes are
+// only set for the default VPN connection.
+func (v *VPNSetup) updateDNSProxy() {
+	remotes := map[string][]string{}
+	for _, name := range slices.Sorted(maps.Keys(v.conns)) {
+		for domain, servers := range v.conns[name].config.VPNConfig.DNS.Remotes() {
+			if _, ok := remotes[domain]; !ok {
+				remotes[domain] = servers
+			}
+		}
+	}
+	v.dnsProxy.SetRemotes(remotes)
+
+	excludes := []string{}
+	if c, ok := v.conns[""]; ok {
+		excludes = c.config.VPNConfig.Split.DNSExcludes()
+	}
+	log.WithField("excludes", excludes).Debug("Daemon setting DNS Split Excludes")
+	v.dnsProxy.SetWatches(excludes)
 }
 
 // setup sets up the vpn configuration.
 func (v *VPNSetup) setup(ctx context.Context, conf *daemoncfg.Config) {
+	if _, ok := v.conns[conf.Connection]; ok {
+		log.WithField("connection", conf.Connection).
+			Error("VPNSetup vpn configuration already set up")
+		return
+	}
+
 	// set config
-	v.config = conf
+	c := &connSetup{config: conf}
+	v.conns[conf.Connection] = c
 
 	// configure dns proxy
 	// - set remotes
 	// - set watches
-	remotes := conf.VPNConfig.DNS.Remotes()
-	v.dnsProxy.SetRemotes(remotes)
-	excludes := conf.VPNConfig.Split.DNSExcludes()
-	log.WithField("excludes", excludes).Debug("Daemon setting DNS Split Excludes")
-	v.dnsProxy.SetWatches(excludes)
+	v.updateDNSProxy()
 
-	// configure split routing
-	v.splitrt = splitrt.NewSplitRouting(conf, v.dnsProxy.Reports())
-	if err := v.splitrt.Start(); err != nil {
+	// configure split routing, only the default VPN connection handles
+	// DNS-based split excludes
+	var dnsReports chan *dnsproxy.Report
+	if conf.Connection == "" {
+		dnsReports = v.dnsProxy.Reports()
+	}
+	c.splitrt = splitrt.NewSplitRouting(conf, dnsReports)
+	if err := c.splitrt.Start(); err != nil {
 		log.WithError(err).Error("VPNSetup error setting split routing")
 	}
+	c.prefixesClosed = make(chan struct{})
+	go v.handlePrefixesUpdates(ctx, c)
 
-	cmds, err := cmdtmpl.GetCmds("VPNSetupSetup", conf)
-	if err != nil {
-		log.WithError(err).Error("VPNSetup could not get setup commands")
-	}
-	for _, c := range cmds {
-		if stdout, stderr, err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			log.WithFields(log.Fields{
-				"command": c.Cmd,
-				"args":    c.Args,
-				"stdin":   c.Stdin,
-				"stdout":  string(stdout),
-				"stderr":  string(stderr),
-				"error":   err,
-			}).Error("VPNSetup could not run setup command")
-		}
+	// record undo commands of applied changes in journal
+	j := journal.NewRecorder(conf.CommandLists.JournalDir, JournalName(conf.Connection))
+	if err := backend.New(conf).SetupVPN(cmdtmpl.WithJournal(ctx, j), conf); err != nil {
+		log.WithError(err).Error("VPNSetup could not set up vpn configuration")
 	}
 
 	// ensure VPN config
-	v.startEnsure(ctx, conf)
+	v.startEnsure(ctx, c)
 }
 
 // teardown tears down the vpn configuration.
 func (v *VPNSetup) teardown(ctx context.Context, conf *daemoncfg.Config) {
+	c, ok := v.conns[conf.Connection]
+	if !ok {
+		log.WithField("connection", conf.Connection).
+			Error("VPNSetup vpn configuration not set up")
+		return
+	}
+
 	// stop ensuring VPN config
-	v.stopEnsure()
+	v.stopEnsure(c)
 
 	// unconfigure split routing
-	v.splitrt.Stop()
-	v.splitrt = nil
+	c.splitrt.Stop()
+	<-c.prefixesClosed
 
 	// tear down device, routing, dns
-	cmds, err := cmdtmpl.GetCmds("VPNSetupTeardown", conf)
-	if err != nil {
-		log.WithError(err).Error("VPNSetup could not get teardown commands")
-	}
-	for _, c := range cmds {
-		if stdout, stderr, err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			log.WithFields(log.Fields{
-				"command": c.Cmd,
-				"args":    c.Args,
-				"stdin":   c.Stdin,
-				"stdout":  string(stdout),
-				"stderr":  string(stderr),
-				"error":   err,
-			}).Error("VPNSetup could not run teardown command")
-		}
+	if err := backend.New(conf).TeardownVPN(ctx, conf); err != nil {
+		log.WithError(err).Error("VPNSetup could not tear down vpn configuration")
 	}
 
+	// unset config
+	delete(v.conns, conf.Connection)
+
 	// unconfigure dns proxy
-	// - reset remotes
-	// - reset watches
-	remotes := map[string][]string{}
-	v.dnsProxy.SetRemotes(remotes)
-	v.dnsProxy.SetWatches([]string{})
+	// - reset remotes of connection
+	// - reset watches of connection
+	v.updateDNSProxy()
 
-	// unset config
-	v.config = nil
+	// configuration removed, remove journal entry
+	if err := journal.Remove(conf.CommandLists.JournalDir, JournalName(conf.Connection)); err != nil {
+		log.WithError(err).Error("VPNSetup could not remove journal entry")
+	}
 }
 
 // getState gets the internal state.
 func (v *VPNSetup) getState(c *command) {
 	state := &State{}
-	if v.splitrt != nil {
-		state.SplitRouting = v.splitrt.GetState()
+	for name, conn := range v.conns {
+		if name == "" {
+			state.SplitRouting = conn.splitrt.GetState()
+			continue
+		}
+		if state.Connections == nil {
+			state.Connections = make(map[string]*splitrt.State)
+		}
+		state.Connections[name] = conn.splitrt.GetState()
 	}
 	if v.dnsProxy != nil {
 		state.DNSProxy = v.dnsProxy.GetState()
@@ -357,29 +377,9 @@ func (v *VPNSetup) handleCommand(ctx context.Context, c *command) {
 
 // handlePrefixes handles a prefixes update from split routing.
 func (v *VPNSetup) handlePrefixes(ctx context.Context, config *daemoncfg.Config, prefixes []netip.Prefix) {
-	data := &struct {
-		daemoncfg.Config
-		Addresses []netip.Prefix
-	}{
-		Config:    *config,
-		Addresses: prefixes,
-	}
-	cmds, err := cmdtmpl.GetCmds("VPNSetupSetExcludes", data)
-	if err != nil {
-		log.WithError(err).Error("VPNSetup could not get set excludes commands")
-	}
-	for _, c := range cmds {
-		if stdout, stderr, err := c.Run(ctx); err != nil {
-			log.WithFields(log.Fields{
-				"addresses": prefixes,
-				"command":   c.Cmd,
-				"args":      c.Args,
-				"stdin":     c.Stdin,
-				"stdout":    string(stdout),
-				"stderr":    string(stderr),
-				"error":     err,
-			}).Error("VPNSetup could not run set excludes command")
-		}
+	if err := backend.New(config).SetExcludes(ctx, config, prefixes); err != nil {
+		log.WithError(err).WithField("addresses", prefixes).
+			Error("VPNSetup could not set excludes")
 	}
 }
 
@@ -396,13 +396,10 @@ func (v *VPNSetup) start() {
 
 	for {
 		dnsReports := v.dnsProxy.Reports()
-		var prefixes <-chan []netip.Prefix
-		if v.splitrt != nil {
-			// split routing active
+		if _, ok := v.conns[""]; ok {
+			// split routing of default connection active
 			// do not handle dns reports here
 			dnsReports = nil
-			// handle prefixes from split routing
-			prefixes = v.splitrt.Prefixes()
 		}
 
 		select {
@@ -411,8 +408,6 @@ func (v *VPNSetup) start() {
 		case r := <-dnsReports:
 			// split routing not active, close dns report
 			r.Close()
-		case p := <-prefixes:
-			v.handlePrefixes(ctx, v.config, p)
 		case <-v.done:
 			return
 		}
@@ -466,6 +461,7 @@ func (v *VPNSetup) GetState() *State {
 // NewVPNSetup returns a new VPNSetup.
 func NewVPNSetup(dnsProxy *dnsproxy.Proxy) *VPNSetup {
 	return &VPNSetup{
+		conns:    make(map[string]*connSetup),
 		dnsProxy: dnsProxy,
 
 		cmds:   make(chan *command),
@@ -477,19 +473,17 @@ func NewVPNSetup(dnsProxy *dnsproxy.Proxy) *VPNSetup {
 // Cleanup cleans up the configuration after a failed shutdown.
 func Cleanup(ctx context.Context, config *daemoncfg.Config) {
 	// dns, device, split routing
-	vpnDevice := config.OpenConnect.VPNDevice
-	cmds, err := cmdtmpl.GetCmds("VPNSetupCleanup", config)
-	if err != nil {
-		log.WithError(err).Error("VPNSetup could not get cleanup commands")
-	}
-	for _, c := range cmds {
-		if _, _, err := c.Run(ctx); err == nil {
-			log.WithFields(log.Fields{
-				"device":  vpnDevice,
-				"command": c.Cmd,
-				"args":    c.Args,
-				"stdin":   c.Stdin,
-			}).Debug("VPNSetup cleaned up configuration")
-		}
-	}
+	err := backend.New(config).CleanupVPN(ctx, config)
+	log.WithError(err).WithField("device", config.OpenConnect.VPNDevice).
+		Debug("VPNSetup cleaned up configuration")
+}
+
+// Reset removes the DNS, address and routing configuration of the VPN
+// tunnel in config, so it can be set up again. Unlike Cleanup, it keeps the
+// VPN device of the running openconnect process, e.g., after a restart of
+// the daemon.
+func Reset(ctx context.Context, config *daemoncfg.Config) {
+	err := backend.New(config).ResetVPN(ctx, config)
+	log.WithError(err).WithField("device", config.VPNConfig.Device.Name).
+		Debug("VPNSetup reset configuration")
 }
@@ -147,6 +147,15 @@ func TestVPNSetupEnsureDNS(t *testing.T) {
 			t.Errorf("ensure dns should not fail with %v", valid)
 		}
 	}
+
+	// test network namespace, dns settings are not checked
+	conf.NetNS.Enabled = true
+	cmdtmpl.RunCmd = func(context.Context, string, string, ...string) ([]byte, []byte, error) {
+		return nil, nil, errors.New("test error")
+	}
+	if ok := v.ensureDNS(ctx, conf); !ok {
+		t.Errorf("ensure dns should not fail in network namespace")
+	}
 }
 
 // TestVPNSetupStartStop tests Start and Stop of VPNSetup.
@@ -242,12 +251,38 @@ func TestVPNSetupGetState(t *testing.T) {
 	got = v.GetState()
 	if got == nil ||
 		got.SplitRouting == nil ||
-		got.DNSProxy == nil {
+		got.DNSProxy == nil ||
+		got.Connections != nil {
 		t.Errorf("got invalid state: %v", got)
 	}
 
-	// teardown config
+	// with additional named vpn config
+	conf.Connections["lab"] = &daemoncfg.Connection{
+		VPNDevice:    "tun1",
+		PIDFile:      "/run/oc-daemon/openconnect-lab.pid",
+		SplitRouting: daemoncfg.NewSplitRouting(),
+	}
+	lab := conf.ConnectionConfig("lab")
+	v.Setup(lab)
+
+	got = v.GetState()
+	if got == nil ||
+		got.SplitRouting == nil ||
+		got.DNSProxy == nil ||
+		got.Connections["lab"] == nil {
+		t.Errorf("got invalid state: %v", got)
+	}
+
+	// teardown configs
 	v.Teardown(conf)
+	v.Teardown(lab)
+
+	got = v.GetState()
+	if got == nil ||
+		got.SplitRouting != nil ||
+		got.Connections != nil {
+		t.Errorf("got invalid state: %v", got)
+	}
 
 	v.Stop()
 }
@@ -294,3 +329,33 @@ func TestCleanup(t *testing.T) {
 		t.Errorf("got %v, want %v", got, want)
 	}
 }
+
+// TestReset tests Reset.
+func TestReset(t *testing.T) {
+	got := []string{}
+	cmdtmpl.RunCmd = func(_ context.Context, cmd string, s string, arg ...string) ([]byte, []byte, error) {
+		if s == "" {
+			got = append(got, cmd+" "+strings.Join(arg, " "))
+			return nil, nil, nil
+		}
+		got = append(got, cmd+" "+strings.Join(arg, " ")+" "+s)
+		return nil, nil, nil
+	}
+	cfg := daemoncfg.NewConfig()
+	cfg.VPNConfig.Device.Name = "tun0"
+	Reset(context.Background(), cfg)
+	want := []string{
+		"resolvectl revert tun0",
+		"ip address flush dev tun0",
+		"ip -4 rule delete pref 2111",
+		"ip -4 rule delete pref 2112",
+		"ip -6 rule delete pref 2111",
+		"ip -6 rule delete pref 2112",
+		"ip -4 route flush table 42111",
+		"ip -6 route flush table 42111",
+		"nft -f - delete table inet oc-daemon-routing",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
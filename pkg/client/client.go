@@ -4,6 +4,8 @@ package client
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -13,6 +15,7 @@ import (
 	"github.com/telekom-mms/oc-daemon/internal/dbusapi"
 	"github.com/telekom-mms/oc-daemon/pkg/logininfo"
 	"github.com/telekom-mms/oc-daemon/pkg/vpnconfig"
+	"github.com/telekom-mms/oc-daemon/pkg/vpnhistory"
 	"github.com/telekom-mms/oc-daemon/pkg/vpnstatus"
 )
 
@@ -30,12 +33,15 @@ type Client interface {
 	Ping() error
 	Query() (*vpnstatus.Status, error)
 	Subscribe() (chan *vpnstatus.Status, error)
+	Events() (chan *Event, error)
 
 	Authenticate() error
 	Connect() error
 	Disconnect() error
 
 	DumpState() (string, error)
+	GetHistory() (*vpnhistory.History, error)
+	RemediateCaptivePortal() error
 
 	Close() error
 }
@@ -68,6 +74,19 @@ type DBusClient struct {
 	// update is used for vpn status updates
 	updates chan *vpnstatus.Status
 
+	// eventsSubscribed specifies whether the client is subscribed to
+	// event D-Bus signals
+	eventsSubscribed bool
+
+	// eventSignals is the channel for the event D-Bus signals
+	eventSignals chan *dbus.Signal
+
+	// events is used for events
+	events chan *Event
+
+	// eventsClosed signals termination of event handling is complete
+	eventsClosed chan struct{}
+
 	// done signals termination of the client
 	done chan struct{}
 
@@ -123,6 +142,18 @@ func (d *DBusClient) GetLogin() *logininfo.LoginInfo {
 	return d.login.Copy()
 }
 
+// getConnection returns the name of the named VPN connection in the client
+// config, empty for the default VPN connection.
+func (d *DBusClient) getConnection() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.config == nil {
+		return ""
+	}
+	return d.config.Connection
+}
+
 // dbusConnectSystemBus calls dbus.ConnectSystemBus.
 var dbusConnectSystemBus = func() (*dbus.Conn, error) {
 	return dbus.ConnectSystemBus()
@@ -168,6 +199,8 @@ func updateStatusFromProperties(status *vpnstatus.Status, props map[string]dbus.
 				err = v.Store(&dest.TNDState)
 			case dbusapi.PropertyTNDServers:
 				err = v.Store(&dest.TNDServers)
+			case dbusapi.PropertyTNDMatches:
+				err = v.Store(&dest.TNDMatches)
 			case dbusapi.PropertyVPNConfig:
 				s := dbusapi.VPNConfigInvalid
 				if err := v.Store(&s); err != nil {
@@ -182,6 +215,46 @@ func updateStatusFromProperties(status *vpnstatus.Status, props map[string]dbus.
 					}
 					dest.VPNConfig = config
 				}
+			case dbusapi.PropertyReconnectAttempts:
+				err = v.Store(&dest.ReconnectAttempts)
+			case dbusapi.PropertyReconnectAt:
+				err = v.Store(&dest.ReconnectAt)
+			case dbusapi.PropertyReconnectGiveUpReason:
+				err = v.Store(&dest.ReconnectGiveUpReason)
+			case dbusapi.PropertyIdleTimeoutAt:
+				err = v.Store(&dest.IdleTimeoutAt)
+			case dbusapi.PropertyResumeAction:
+				err = v.Store(&dest.ResumeAction)
+			case dbusapi.PropertyResumeOutcome:
+				err = v.Store(&dest.ResumeOutcome)
+			case dbusapi.PropertyDisconnectPolicy:
+				err = v.Store(&dest.DisconnectPolicy)
+			case dbusapi.PropertyConnectFailureState:
+				err = v.Store(&dest.ConnectFailureState)
+			case dbusapi.PropertyConnectFailures:
+				err = v.Store(&dest.ConnectFailures)
+			case dbusapi.PropertyNetworkPolicyAction:
+				err = v.Store(&dest.NetworkPolicyAction)
+			case dbusapi.PropertyNetworkPolicyDecision:
+				err = v.Store(&dest.NetworkPolicyDecision)
+			case dbusapi.PropertyNetNS:
+				err = v.Store(&dest.NetNS)
+			case dbusapi.PropertyCommandListError:
+				err = v.Store(&dest.CommandListError)
+			case dbusapi.PropertyConnections:
+				s := dbusapi.ConnectionsInvalid
+				if err := v.Store(&s); err != nil {
+					return err
+				}
+				if s == dbusapi.ConnectionsInvalid {
+					dest.Connections = nil
+				} else {
+					var conns []*vpnstatus.Connection
+					if err := json.Unmarshal([]byte(s), &conns); err != nil {
+						return err
+					}
+					dest.Connections = conns
+				}
 			}
 			if err != nil {
 				return err
@@ -296,8 +369,38 @@ func handlePropertiesChanged(s *dbus.Signal, status *vpnstatus.Status) *vpnstatu
 			status.TNDState = vpnstatus.TNDStateUnknown
 		case dbusapi.PropertyTNDServers:
 			status.TNDServers = dbusapi.TNDServersInvalid
+		case dbusapi.PropertyTNDMatches:
+			status.TNDMatches = dbusapi.TNDMatchesInvalid
 		case dbusapi.PropertyVPNConfig:
 			status.VPNConfig = nil
+		case dbusapi.PropertyReconnectAttempts:
+			status.ReconnectAttempts = dbusapi.ReconnectAttemptsInvalid
+		case dbusapi.PropertyReconnectAt:
+			status.ReconnectAt = dbusapi.ReconnectAtInvalid
+		case dbusapi.PropertyReconnectGiveUpReason:
+			status.ReconnectGiveUpReason = dbusapi.ReconnectGiveUpReasonInvalid
+		case dbusapi.PropertyIdleTimeoutAt:
+			status.IdleTimeoutAt = dbusapi.IdleTimeoutAtInvalid
+		case dbusapi.PropertyResumeAction:
+			status.ResumeAction = dbusapi.ResumeActionInvalid
+		case dbusapi.PropertyResumeOutcome:
+			status.ResumeOutcome = dbusapi.ResumeOutcomeInvalid
+		case dbusapi.PropertyDisconnectPolicy:
+			status.DisconnectPolicy = vpnstatus.DisconnectPolicyUnknown
+		case dbusapi.PropertyConnectFailureState:
+			status.ConnectFailureState = vpnstatus.ConnectFailureStateUnknown
+		case dbusapi.PropertyConnectFailures:
+			status.ConnectFailures = dbusapi.ConnectFailuresInvalid
+		case dbusapi.PropertyNetworkPolicyAction:
+			status.NetworkPolicyAction = dbusapi.NetworkPolicyActionInvalid
+		case dbusapi.PropertyNetworkPolicyDecision:
+			status.NetworkPolicyDecision = dbusapi.NetworkPolicyDecisionInvalid
+		case dbusapi.PropertyConnections:
+			status.Connections = nil
+		case dbusapi.PropertyNetNS:
+			status.NetNS = dbusapi.NetNSInvalid
+		case dbusapi.PropertyCommandListError:
+			status.CommandListError = dbusapi.CommandListErrorInvalid
 		}
 	}
 
@@ -399,14 +502,97 @@ func (d *DBusClient) Subscribe() (chan *vpnstatus.Status, error) {
 	return d.updates, nil
 }
 
+// setEventsSubscribed tries to set eventsSubscribed to true and returns true
+// if successful.
+func (d *DBusClient) setEventsSubscribed() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.eventsSubscribed {
+		// already subscribed
+		return false
+	}
+	d.eventsSubscribed = true
+	return true
+}
+
+// isEventsSubscribed returns whether eventsSubscribed is set.
+func (d *DBusClient) isEventsSubscribed() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.eventsSubscribed
+}
+
+// Events subscribes to event D-Bus signals, converts incoming signals to
+// events and sends those events over the returned channel.
+func (d *DBusClient) Events() (chan *Event, error) {
+	// make sure this only runs once
+	if ok := d.setEventsSubscribed(); !ok {
+		return nil, fmt.Errorf("already subscribed to events")
+	}
+
+	// subscribe to signals of the daemon interface
+	if err := connAddMatchSignal(d.conn,
+		dbus.WithMatchSender(dbusapi.Interface),
+		dbus.WithMatchInterface(dbusapi.Interface),
+		dbus.WithMatchPathNamespace(dbusapi.Path),
+	); err != nil {
+		return nil, err
+	}
+
+	// handle signals
+	connSignal(d.conn, d.eventSignals)
+
+	// handle events
+	go func() {
+		defer close(d.eventsClosed)
+		defer close(d.events)
+
+		for s := range d.eventSignals {
+			// get event from signal
+			e := eventFromSignal(s)
+			if e == nil {
+				// not an event
+				continue
+			}
+
+			// send event
+			select {
+			case d.events <- e:
+			case <-d.done:
+				return
+			}
+		}
+	}()
+
+	return d.events, nil
+}
+
 // checkStatus checks if client is not connected to a trusted network and the
-// VPN is not already running.
+// VPN is not already running. For a named VPN connection, only the state of
+// the named VPN connection is checked.
 func (d *DBusClient) checkStatus() error {
 	status, err := d.Query()
 	if err != nil {
 		return fmt.Errorf("could not query OC-Daemon: %w", err)
 	}
 
+	// check if we need to start the named VPN connection
+	if name := d.getConnection(); name != "" {
+		conn := status.GetConnection(name)
+		if conn == nil {
+			return fmt.Errorf("unknown VPN connection %s", name)
+		}
+		if conn.ConnectionState.Connected() {
+			return fmt.Errorf("VPN connection %s already connected, nothing to do", name)
+		}
+		if conn.OCRunning.Running() {
+			return fmt.Errorf("OpenConnect client of VPN connection %s already running, nothing to do", name)
+		}
+		return nil
+	}
+
 	// check if we need to start the VPN connection
 	if status.TrustedNetwork.Trusted() {
 		return fmt.Errorf("trusted network detected, nothing to do")
@@ -539,8 +725,22 @@ func (d *DBusClient) Authenticate() error {
 
 // connect sends a connect request with login info to the daemon.
 var connect = func(d *DBusClient) error {
-	// call connect
 	login := d.GetLogin()
+	if name := d.getConnection(); name != "" {
+		// call connect of named connection
+		return d.conn.Object(dbusapi.Interface, dbusapi.Path).
+			Call(dbusapi.MethodConnectNamed, 0,
+				name,
+				login.Server,
+				login.Cookie,
+				login.Host,
+				login.ConnectURL,
+				login.Fingerprint,
+				login.Resolve,
+			).Store()
+	}
+
+	// call connect
 	return d.conn.Object(dbusapi.Interface, dbusapi.Path).
 		Call(dbusapi.MethodConnect, 0,
 			login.Server,
@@ -564,11 +764,26 @@ func (d *DBusClient) Connect() error {
 	return connect(d)
 }
 
+// ErrDisconnectLocked is the error returned by Disconnect if disconnecting
+// the VPN is not allowed by the XML profile.
+var ErrDisconnectLocked = errors.New("disconnect not allowed by XML profile")
+
 // disconnect sends a disconnect request to the daemon.
 var disconnect = func(d *DBusClient) error {
+	if name := d.getConnection(); name != "" {
+		// call disconnect of named connection
+		return d.conn.Object(dbusapi.Interface, dbusapi.Path).
+			Call(dbusapi.MethodDisconnectNamed, 0, name).Store()
+	}
+
 	// call disconnect
-	return d.conn.Object(dbusapi.Interface, dbusapi.Path).
+	err := d.conn.Object(dbusapi.Interface, dbusapi.Path).
 		Call(dbusapi.MethodDisconnect, 0).Store()
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) && dbusErr.Name == dbusapi.ErrorDisconnectLocked {
+		return ErrDisconnectLocked
+	}
+	return err
 }
 
 // Disconnect disconnects the client from the VPN server.
@@ -578,9 +793,24 @@ func (d *DBusClient) Disconnect() error {
 	if err != nil {
 		return fmt.Errorf("could not query OC-Daemon: %w", err)
 	}
-	if !status.OCRunning.Running() {
+	if name := d.getConnection(); name != "" {
+		// named connection
+		conn := status.GetConnection(name)
+		if conn == nil {
+			return fmt.Errorf("unknown VPN connection %s", name)
+		}
+		if !conn.OCRunning.Running() {
+			return fmt.Errorf("OpenConnect client of VPN connection %s is not running, nothing to do", name)
+		}
+		return disconnect(d)
+	}
+	if !status.OCRunning.Running() && status.ReconnectAt <= 0 {
+		// neither running nor waiting for reconnect
 		return fmt.Errorf("OpenConnect client is not running, nothing to do")
 	}
+	if status.DisconnectPolicy.Locked() {
+		return ErrDisconnectLocked
+	}
 
 	// disconnect
 	return disconnect(d)
@@ -600,6 +830,38 @@ func (d *DBusClient) DumpState() (string, error) {
 	return dumpState(d)
 }
 
+// getHistory sends a get history request to the daemon.
+var getHistory = func(d *DBusClient) (string, error) {
+	// call get history
+	history := ""
+	err := d.conn.Object(dbusapi.Interface, dbusapi.Path).
+		Call(dbusapi.MethodGetHistory, 0).Store(&history)
+	return history, err
+}
+
+// GetHistory returns the connection history of the OC-Daemon.
+func (d *DBusClient) GetHistory() (*vpnhistory.History, error) {
+	history, err := getHistory(d)
+	if err != nil {
+		return nil, err
+	}
+	return vpnhistory.NewFromJSON([]byte(history))
+}
+
+// remediateCaptivePortal sends a captive portal remediation request to the
+// daemon.
+var remediateCaptivePortal = func(d *DBusClient) error {
+	// call remediate captive portal
+	return d.conn.Object(dbusapi.Interface, dbusapi.Path).
+		Call(dbusapi.MethodRemediate, 0).Store()
+}
+
+// RemediateCaptivePortal opens the network for another captive portal
+// remediation window.
+func (d *DBusClient) RemediateCaptivePortal() error {
+	return remediateCaptivePortal(d)
+}
+
 // Close closes the DBusClient.
 func (d *DBusClient) Close() error {
 	var err error
@@ -608,10 +870,15 @@ func (d *DBusClient) Close() error {
 		err = d.conn.Close()
 	}
 
-	if d.isSubscribed() {
+	if d.isSubscribed() || d.isEventsSubscribed() {
 		close(d.done)
+	}
+	if d.isSubscribed() {
 		<-d.closed
 	}
+	if d.isEventsSubscribed() {
+		<-d.eventsClosed
+	}
 
 	return err
 }
@@ -632,6 +899,10 @@ func NewDBusClient(config *Config) (*DBusClient, error) {
 		updates: make(chan *vpnstatus.Status),
 		done:    make(chan struct{}),
 		closed:  make(chan struct{}),
+
+		eventSignals: make(chan *dbus.Signal, 10),
+		events:       make(chan *Event),
+		eventsClosed: make(chan struct{}),
 	}
 
 	return client, nil
@@ -105,11 +105,38 @@ func TestDBusClientQuery(t *testing.T) {
 			dbusapi.PropertyCaptivePortal:   dbus.MakeVariant(dbusapi.CaptivePortalUnknown),
 			dbusapi.PropertyTNDState:        dbus.MakeVariant(dbusapi.TNDStateUnknown),
 			dbusapi.PropertyTNDServers:      dbus.MakeVariant(dbusapi.TNDServersInvalid),
+			dbusapi.PropertyTNDMatches:      dbus.MakeVariant(dbusapi.TNDMatchesInvalid),
 			dbusapi.PropertyVPNConfig:       dbus.MakeVariant(dbusapi.VPNConfigInvalid),
+
+			dbusapi.PropertyReconnectAttempts:     dbus.MakeVariant(dbusapi.ReconnectAttemptsInvalid),
+			dbusapi.PropertyReconnectAt:           dbus.MakeVariant(dbusapi.ReconnectAtInvalid),
+			dbusapi.PropertyReconnectGiveUpReason: dbus.MakeVariant(dbusapi.ReconnectGiveUpReasonInvalid),
+
+			dbusapi.PropertyIdleTimeoutAt: dbus.MakeVariant(dbusapi.IdleTimeoutAtInvalid),
+
+			dbusapi.PropertyResumeAction:  dbus.MakeVariant(dbusapi.ResumeActionInvalid),
+			dbusapi.PropertyResumeOutcome: dbus.MakeVariant(dbusapi.ResumeOutcomeInvalid),
+
+			dbusapi.PropertyDisconnectPolicy: dbus.MakeVariant(dbusapi.DisconnectPolicyUnknown),
+
+			dbusapi.PropertyConnectFailureState: dbus.MakeVariant(dbusapi.ConnectFailureStateUnknown),
+			dbusapi.PropertyConnectFailures:     dbus.MakeVariant(dbusapi.ConnectFailuresInvalid),
+
+			dbusapi.PropertyNetworkPolicyAction:   dbus.MakeVariant(dbusapi.NetworkPolicyActionInvalid),
+			dbusapi.PropertyNetworkPolicyDecision: dbus.MakeVariant(dbusapi.NetworkPolicyDecisionInvalid),
+
+			dbusapi.PropertyConnections: dbus.MakeVariant(dbusapi.ConnectionsInvalid),
+
+			dbusapi.PropertyNetNS: dbus.MakeVariant(dbusapi.NetNSInvalid),
+
+			dbusapi.PropertyCommandListError: dbus.MakeVariant(dbusapi.CommandListErrorInvalid),
 		},
 		{
 			dbusapi.PropertyVPNConfig: dbus.MakeVariant("{}"),
 		},
+		{
+			dbusapi.PropertyConnections: dbus.MakeVariant(`[{"Name":"lab"}]`),
+		},
 	} {
 		query = func(*DBusClient) (map[string]dbus.Variant, error) {
 			return props, nil
@@ -124,6 +151,8 @@ func TestDBusClientQuery(t *testing.T) {
 		{dbusapi.PropertyTrustedNetwork: dbus.MakeVariant("invalid")},
 		{dbusapi.PropertyVPNConfig: dbus.MakeVariant(1.23)},
 		{dbusapi.PropertyVPNConfig: dbus.MakeVariant(1234)},
+		{dbusapi.PropertyConnections: dbus.MakeVariant(1234)},
+		{dbusapi.PropertyConnections: dbus.MakeVariant("invalid")},
 	} {
 		query = func(*DBusClient) (map[string]dbus.Variant, error) {
 			return props, nil
@@ -222,7 +251,22 @@ func TestDBusClientSubscribe(t *testing.T) {
 				dbusapi.PropertyCaptivePortal,
 				dbusapi.PropertyTNDState,
 				dbusapi.PropertyTNDServers,
+				dbusapi.PropertyTNDMatches,
 				dbusapi.PropertyVPNConfig,
+				dbusapi.PropertyReconnectAttempts,
+				dbusapi.PropertyReconnectAt,
+				dbusapi.PropertyReconnectGiveUpReason,
+				dbusapi.PropertyIdleTimeoutAt,
+				dbusapi.PropertyResumeAction,
+				dbusapi.PropertyResumeOutcome,
+				dbusapi.PropertyDisconnectPolicy,
+				dbusapi.PropertyConnectFailureState,
+				dbusapi.PropertyConnectFailures,
+				dbusapi.PropertyNetworkPolicyAction,
+				dbusapi.PropertyNetworkPolicyDecision,
+				dbusapi.PropertyConnections,
+				dbusapi.PropertyNetNS,
+				dbusapi.PropertyCommandListError,
 			}},
 		},
 	} {
@@ -379,6 +423,30 @@ func TestDBusClientConnect(t *testing.T) {
 	if err := client.Connect(); err != nil {
 		t.Error(err)
 	}
+
+	// test named connection
+	client.SetConfig(&Config{Connection: "lab"})
+	for _, test := range []struct {
+		conns   string
+		wantErr bool
+	}{
+		{`[]`, true},
+		{`[{"Name":"lab","ConnectionState":3}]`, true},
+		{`[{"Name":"lab","OCRunning":2}]`, true},
+		{`[{"Name":"lab","OCRunning":1}]`, false},
+	} {
+		query = func(*DBusClient) (map[string]dbus.Variant, error) {
+			props := map[string]dbus.Variant{
+				// trusted network is ignored for named connections
+				dbusapi.PropertyTrustedNetwork: dbus.MakeVariant(dbusapi.TrustedNetworkTrusted),
+				dbusapi.PropertyConnections:    dbus.MakeVariant(test.conns),
+			}
+			return props, nil
+		}
+		if err := client.Connect(); (err != nil) != test.wantErr {
+			t.Errorf("%s: got %v, want error %t", test.conns, err, test.wantErr)
+		}
+	}
 }
 
 // TestDBusClientDisconnect tests Disconnect of DBusClient.
@@ -424,6 +492,113 @@ func TestDBusClientDisconnect(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+
+	// test with pending reconnect
+	query = func(*DBusClient) (map[string]dbus.Variant, error) {
+		props := map[string]dbus.Variant{
+			dbusapi.PropertyOCRunning:   dbus.MakeVariant(dbusapi.OCRunningNotRunning),
+			dbusapi.PropertyReconnectAt: dbus.MakeVariant(int64(1753274054)),
+		}
+		return props, nil
+	}
+	if err := client.Disconnect(); err != nil {
+		t.Error(err)
+	}
+
+	// test with locked disconnect policy
+	query = func(*DBusClient) (map[string]dbus.Variant, error) {
+		props := map[string]dbus.Variant{
+			dbusapi.PropertyOCRunning:        dbus.MakeVariant(dbusapi.OCRunningRunning),
+			dbusapi.PropertyDisconnectPolicy: dbus.MakeVariant(dbusapi.DisconnectPolicyLocked),
+		}
+		return props, nil
+	}
+	if err := client.Disconnect(); !errors.Is(err, ErrDisconnectLocked) {
+		t.Errorf("got %v, want %v", err, ErrDisconnectLocked)
+	}
+
+	// test named connection
+	client.SetConfig(&Config{Connection: "lab"})
+	for _, test := range []struct {
+		conns   string
+		wantErr bool
+	}{
+		{`[]`, true},
+		{`[{"Name":"lab","OCRunning":1}]`, true},
+		{`[{"Name":"lab","OCRunning":2}]`, false},
+	} {
+		query = func(*DBusClient) (map[string]dbus.Variant, error) {
+			props := map[string]dbus.Variant{
+				// disconnect policy is ignored for named connections
+				dbusapi.PropertyDisconnectPolicy: dbus.MakeVariant(dbusapi.DisconnectPolicyLocked),
+				dbusapi.PropertyConnections:      dbus.MakeVariant(test.conns),
+			}
+			return props, nil
+		}
+		if err := client.Disconnect(); (err != nil) != test.wantErr {
+			t.Errorf("%s: got %v, want error %t", test.conns, err, test.wantErr)
+		}
+	}
+}
+
+// TestDBusClientEvents tests Events of DBusClient.
+func TestDBusClientEvents(t *testing.T) {
+	// clean up after tests
+	oldAddMatchSignal := connAddMatchSignal
+	defer func() { connAddMatchSignal = oldAddMatchSignal }()
+	oldSignal := connSignal
+	defer func() { connSignal = oldSignal }()
+
+	connAddMatchSignal = func(*dbus.Conn, ...dbus.MatchOption) error {
+		return nil
+	}
+	connSignal = func(*dbus.Conn, chan<- *dbus.Signal) {}
+
+	// test without errors, signals
+	client := &DBusClient{
+		eventSignals: make(chan *dbus.Signal),
+		events:       make(chan *Event),
+		done:         make(chan struct{}),
+		eventsClosed: make(chan struct{}),
+	}
+	events, err := client.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Events(); err == nil {
+		t.Error("subscribing twice should return error")
+	}
+
+	go func() {
+		for _, sig := range []*dbus.Signal{
+			{},
+			{
+				Path: dbusapi.Path,
+				Name: dbusapi.PropertiesChanged,
+			},
+			{
+				Path: dbusapi.Path,
+				Name: dbusapi.Interface + "." + dbusapi.SignalProfileReloaded,
+			},
+		} {
+			client.eventSignals <- sig
+		}
+		close(client.eventSignals)
+	}()
+	want := &Event{Type: EventProfileReloaded}
+	if got := <-events; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	_ = client.Close()
+
+	// test add match signal error
+	client = &DBusClient{}
+	connAddMatchSignal = func(*dbus.Conn, ...dbus.MatchOption) error {
+		return errors.New("test error")
+	}
+	if _, err := client.Events(); err == nil {
+		t.Error("events with add match signal error should return error")
+	}
 }
 
 // TestDBusClientDumpState tests DumpState of DBusClient.
@@ -444,6 +619,69 @@ func TestDBusClientDumpState(t *testing.T) {
 	}
 }
 
+// TestDBusClientGetHistory tests GetHistory of DBusClient.
+func TestDBusClientGetHistory(t *testing.T) {
+	// clean up after tests
+	oldGetHistory := getHistory
+	defer func() { getHistory = oldGetHistory }()
+
+	// create test client
+	client := &DBusClient{}
+
+	// test with error
+	getHistory = func(_ *DBusClient) (string, error) {
+		return "", errors.New("test error")
+	}
+	if _, err := client.GetHistory(); err == nil {
+		t.Error("get history error should return error")
+	}
+
+	// test with invalid history
+	getHistory = func(_ *DBusClient) (string, error) {
+		return "invalid", nil
+	}
+	if _, err := client.GetHistory(); err == nil {
+		t.Error("invalid history should return error")
+	}
+
+	// test with valid history
+	getHistory = func(_ *DBusClient) (string, error) {
+		return `{"Sessions":[{"Server":"test server"}]}`, nil
+	}
+	history, err := client.GetHistory()
+	if err != nil || len(history.Sessions) != 1 ||
+		history.Sessions[0].Server != "test server" {
+		t.Error(err, history)
+	}
+}
+
+// TestDBusClientRemediateCaptivePortal tests RemediateCaptivePortal of
+// DBusClient.
+func TestDBusClientRemediateCaptivePortal(t *testing.T) {
+	// clean up after tests
+	oldRemediate := remediateCaptivePortal
+	defer func() { remediateCaptivePortal = oldRemediate }()
+
+	// create test client
+	client := &DBusClient{}
+
+	// test with error
+	remediateCaptivePortal = func(*DBusClient) error {
+		return errors.New("test error")
+	}
+	if err := client.RemediateCaptivePortal(); err == nil {
+		t.Error("remediate error should return error")
+	}
+
+	// test without error
+	remediateCaptivePortal = func(*DBusClient) error {
+		return nil
+	}
+	if err := client.RemediateCaptivePortal(); err != nil {
+		t.Error(err)
+	}
+}
+
 // testRWC is a reader writer closer for testing.
 type testRWC struct{}
 
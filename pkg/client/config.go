@@ -53,6 +53,7 @@ type Config struct {
 	CACertificate     string
 	XMLProfile        string
 	VPNServer         string
+	Connection        string
 	User              string
 	UserGroup         string
 	Password          string `json:"-"`
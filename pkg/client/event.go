@@ -0,0 +1,165 @@
+package client
+
+import (
+	"github.com/godbus/dbus/v5"
+	"github.com/telekom-mms/oc-daemon/internal/dbusapi"
+)
+
+// EventType is the type of an event.
+type EventType uint32
+
+// EventType types.
+const (
+	EventUnknown EventType = iota
+	EventConnectFailed
+	EventReconnecting
+	EventTrustedNetworkDetected
+	EventCaptivePortalDetected
+	EventCommandFailed
+	EventProfileReloaded
+	EventIdleTimeoutWarning
+	EventRemediationExpired
+)
+
+// String returns EventType as string.
+func (e EventType) String() string {
+	switch e {
+	case EventUnknown:
+		return "unknown"
+	case EventConnectFailed:
+		return "connect failed"
+	case EventReconnecting:
+		return "reconnecting"
+	case EventTrustedNetworkDetected:
+		return "trusted network detected"
+	case EventCaptivePortalDetected:
+		return "captive portal detected"
+	case EventCommandFailed:
+		return "command failed"
+	case EventProfileReloaded:
+		return "profile reloaded"
+	case EventIdleTimeoutWarning:
+		return "idle timeout warning"
+	case EventRemediationExpired:
+		return "captive portal remediation expired"
+	}
+	return ""
+}
+
+// Event is an event signaled by the daemon. Only the fields of the event
+// type are set.
+type Event struct {
+	Type EventType
+
+	// ConnectFailed
+	Reason string
+
+	// Reconnecting
+	Attempt     uint32
+	ReconnectAt int64
+
+	// CaptivePortalDetected
+	URL string
+
+	// CommandFailed
+	List    string
+	Command string
+	Stderr  string
+
+	// IdleTimeoutWarning
+	IdleTimeoutAt int64
+}
+
+// eventFromSignal returns the event in D-Bus signal s, nil if s is not a
+// valid event signal.
+func eventFromSignal(s *dbus.Signal) *Event {
+	if s.Path != dbusapi.Path {
+		return nil
+	}
+
+	// get strings in signal body
+	getStrings := func(n int) []string {
+		if len(s.Body) != n {
+			return nil
+		}
+		strs := []string{}
+		for _, v := range s.Body {
+			str, ok := v.(string)
+			if !ok {
+				return nil
+			}
+			strs = append(strs, str)
+		}
+		return strs
+	}
+
+	switch s.Name {
+	case dbusapi.Interface + "." + dbusapi.SignalConnectFailed:
+		strs := getStrings(1)
+		if strs == nil {
+			return nil
+		}
+		return &Event{Type: EventConnectFailed, Reason: strs[0]}
+
+	case dbusapi.Interface + "." + dbusapi.SignalReconnecting:
+		if len(s.Body) != 2 {
+			return nil
+		}
+		attempt, ok := s.Body[0].(uint32)
+		if !ok {
+			return nil
+		}
+		reconnectAt, ok := s.Body[1].(int64)
+		if !ok {
+			return nil
+		}
+		return &Event{
+			Type:        EventReconnecting,
+			Attempt:     attempt,
+			ReconnectAt: reconnectAt,
+		}
+
+	case dbusapi.Interface + "." + dbusapi.SignalTrustedNetworkDetected:
+		return &Event{Type: EventTrustedNetworkDetected}
+
+	case dbusapi.Interface + "." + dbusapi.SignalCaptivePortalDetected:
+		strs := getStrings(1)
+		if strs == nil {
+			return nil
+		}
+		return &Event{Type: EventCaptivePortalDetected, URL: strs[0]}
+
+	case dbusapi.Interface + "." + dbusapi.SignalCommandFailed:
+		strs := getStrings(3)
+		if strs == nil {
+			return nil
+		}
+		return &Event{
+			Type:    EventCommandFailed,
+			List:    strs[0],
+			Command: strs[1],
+			Stderr:  strs[2],
+		}
+
+	case dbusapi.Interface + "." + dbusapi.SignalProfileReloaded:
+		return &Event{Type: EventProfileReloaded}
+
+	case dbusapi.Interface + "." + dbusapi.SignalIdleTimeoutWarning:
+		if len(s.Body) != 1 {
+			return nil
+		}
+		idleTimeoutAt, ok := s.Body[0].(int64)
+		if !ok {
+			return nil
+		}
+		return &Event{
+			Type:          EventIdleTimeoutWarning,
+			IdleTimeoutAt: idleTimeoutAt,
+		}
+
+	case dbusapi.Interface + "." + dbusapi.SignalRemediationExpired:
+		return &Event{Type: EventRemediationExpired}
+	}
+
+	return nil
+}
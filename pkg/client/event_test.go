@@ -0,0 +1,108 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/telekom-mms/oc-daemon/internal/dbusapi"
+)
+
+// TestEventTypeString tests String of EventType.
+func TestEventTypeString(t *testing.T) {
+	for v, s := range map[EventType]string{
+		EventUnknown:                "unknown",
+		EventConnectFailed:          "connect failed",
+		EventReconnecting:           "reconnecting",
+		EventTrustedNetworkDetected: "trusted network detected",
+		EventCaptivePortalDetected:  "captive portal detected",
+		EventCommandFailed:          "command failed",
+		EventProfileReloaded:        "profile reloaded",
+		EventIdleTimeoutWarning:     "idle timeout warning",
+		EventRemediationExpired:     "captive portal remediation expired",
+		123456:                      "",
+	} {
+		if v.String() != s {
+			t.Errorf("got %s, want %s", v.String(), s)
+		}
+	}
+}
+
+// TestEventFromSignal tests eventFromSignal.
+func TestEventFromSignal(t *testing.T) {
+	signal := func(name string, body ...any) *dbus.Signal {
+		return &dbus.Signal{
+			Path: dbusapi.Path,
+			Name: dbusapi.Interface + "." + name,
+			Body: body,
+		}
+	}
+
+	// test invalid
+	for _, invalid := range []*dbus.Signal{
+		{},
+		{Path: dbusapi.Path, Name: dbusapi.PropertiesChanged},
+		signal(dbusapi.SignalConnectFailed),
+		signal(dbusapi.SignalConnectFailed, 1),
+		signal(dbusapi.SignalReconnecting, uint32(1)),
+		signal(dbusapi.SignalReconnecting, "1", int64(2)),
+		signal(dbusapi.SignalReconnecting, uint32(1), "2"),
+		signal(dbusapi.SignalCaptivePortalDetected),
+		signal(dbusapi.SignalCommandFailed, "list", "command"),
+		signal(dbusapi.SignalIdleTimeoutWarning),
+		signal(dbusapi.SignalIdleTimeoutWarning, "1"),
+	} {
+		if e := eventFromSignal(invalid); e != nil {
+			t.Errorf("got %v, want nil for signal %v", e, invalid)
+		}
+	}
+
+	// test valid
+	for _, test := range []struct {
+		signal *dbus.Signal
+		want   *Event
+	}{
+		{
+			signal(dbusapi.SignalConnectFailed, "reason"),
+			&Event{Type: EventConnectFailed, Reason: "reason"},
+		},
+		{
+			signal(dbusapi.SignalReconnecting, uint32(1), int64(2)),
+			&Event{Type: EventReconnecting, Attempt: 1, ReconnectAt: 2},
+		},
+		{
+			signal(dbusapi.SignalTrustedNetworkDetected),
+			&Event{Type: EventTrustedNetworkDetected},
+		},
+		{
+			signal(dbusapi.SignalCaptivePortalDetected, "http://portal/"),
+			&Event{Type: EventCaptivePortalDetected, URL: "http://portal/"},
+		},
+		{
+			signal(dbusapi.SignalCommandFailed, "list", "command", "stderr"),
+			&Event{
+				Type:    EventCommandFailed,
+				List:    "list",
+				Command: "command",
+				Stderr:  "stderr",
+			},
+		},
+		{
+			signal(dbusapi.SignalProfileReloaded),
+			&Event{Type: EventProfileReloaded},
+		},
+		{
+			signal(dbusapi.SignalIdleTimeoutWarning, int64(1)),
+			&Event{Type: EventIdleTimeoutWarning, IdleTimeoutAt: 1},
+		},
+		{
+			signal(dbusapi.SignalRemediationExpired),
+			&Event{Type: EventRemediationExpired},
+		},
+	} {
+		got := eventFromSignal(test.signal)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("got %v, want %v", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package vpnhistory contains the VPN connection history.
+package vpnhistory
+
+import (
+	"encoding/json"
+)
+
+// DisconnectCause is the cause of a VPN disconnect.
+type DisconnectCause uint32
+
+// DisconnectCause causes.
+const (
+	DisconnectCauseUnknown DisconnectCause = iota
+	DisconnectCauseUser
+	DisconnectCauseTrustedNetwork
+	DisconnectCauseResume
+	DisconnectCauseOpenConnectExit
+	DisconnectCauseShutdown
+	DisconnectCauseIdleTimeout
+)
+
+// String returns c as string.
+func (c DisconnectCause) String() string {
+	switch c {
+	case DisconnectCauseUnknown:
+		return "unknown"
+	case DisconnectCauseUser:
+		return "user"
+	case DisconnectCauseTrustedNetwork:
+		return "trusted network"
+	case DisconnectCauseResume:
+		return "resume"
+	case DisconnectCauseOpenConnectExit:
+		return "openconnect exit"
+	case DisconnectCauseShutdown:
+		return "shutdown"
+	case DisconnectCauseIdleTimeout:
+		return "idle timeout"
+	}
+	return ""
+}
+
+// Session is a VPN session in the connection history. Connection is the name
+// of the named VPN connection, empty for the default VPN connection.
+type Session struct {
+	Connection string `json:",omitempty"`
+
+	Server   string
+	ServerIP string
+	IP       string
+
+	StartedAt      int64
+	ConnectedAt    int64
+	DisconnectedAt int64
+
+	Cause    DisconnectCause
+	ExitCode int
+}
+
+// Copy returns a copy of Session.
+func (s *Session) Copy() *Session {
+	if s == nil {
+		return nil
+	}
+	cp := *s
+	return &cp
+}
+
+// History is the VPN connection history.
+type History struct {
+	Sessions []*Session
+}
+
+// Add adds session to the history and removes the oldest sessions if
+// the history contains more than maxSessions sessions.
+func (h *History) Add(session *Session, maxSessions int) {
+	h.Sessions = append(h.Sessions, session)
+	if len(h.Sessions) > maxSessions {
+		h.Sessions = h.Sessions[len(h.Sessions)-maxSessions:]
+	}
+}
+
+// Copy returns a copy of History.
+func (h *History) Copy() *History {
+	if h == nil {
+		return nil
+	}
+	cp := New()
+	for _, s := range h.Sessions {
+		cp.Sessions = append(cp.Sessions, s.Copy())
+	}
+	return cp
+}
+
+// JSON returns the History as JSON.
+func (h *History) JSON() ([]byte, error) {
+	return json.Marshal(h)
+}
+
+// NewFromJSON parses and returns the History in b.
+func NewFromJSON(b []byte) (*History, error) {
+	h := New()
+	err := json.Unmarshal(b, h)
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// New returns a new History.
+func New() *History {
+	return &History{}
+}
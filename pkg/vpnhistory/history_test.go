@@ -0,0 +1,108 @@
+package vpnhistory
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestDisconnectCauseString tests String of DisconnectCause.
+func TestDisconnectCauseString(t *testing.T) {
+	for v, s := range map[DisconnectCause]string{
+		DisconnectCauseUnknown:         "unknown",
+		DisconnectCauseUser:            "user",
+		DisconnectCauseTrustedNetwork:  "trusted network",
+		DisconnectCauseResume:          "resume",
+		DisconnectCauseOpenConnectExit: "openconnect exit",
+		DisconnectCauseShutdown:        "shutdown",
+		DisconnectCauseIdleTimeout:     "idle timeout",
+		123456:                         "",
+	} {
+		if v.String() != s {
+			t.Errorf("got %s, want %s", v.String(), s)
+		}
+	}
+}
+
+// TestSessionCopy tests Copy of Session.
+func TestSessionCopy(t *testing.T) {
+	// test nil
+	if (*Session)(nil).Copy() != nil {
+		t.Error("copy of nil should be nil")
+	}
+
+	// test filled
+	want := &Session{
+		Server:         "server",
+		ServerIP:       "192.168.1.1",
+		IP:             "10.0.0.1",
+		StartedAt:      1,
+		ConnectedAt:    2,
+		DisconnectedAt: 3,
+		Cause:          DisconnectCauseOpenConnectExit,
+		ExitCode:       1,
+	}
+	got := want.Copy()
+	if got == want || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestHistoryAdd tests Add of History.
+func TestHistoryAdd(t *testing.T) {
+	h := New()
+	for i := range 5 {
+		h.Add(&Session{StartedAt: int64(i)}, 3)
+	}
+
+	if len(h.Sessions) != 3 {
+		t.Fatalf("got %d sessions, want 3", len(h.Sessions))
+	}
+	for i, s := range h.Sessions {
+		if s.StartedAt != int64(i+2) {
+			t.Errorf("%d: got %d, want %d", i, s.StartedAt, i+2)
+		}
+	}
+}
+
+// TestHistoryCopy tests Copy of History.
+func TestHistoryCopy(t *testing.T) {
+	// test nil
+	if (*History)(nil).Copy() != nil {
+		t.Error("copy of nil should be nil")
+	}
+
+	// test filled
+	want := New()
+	want.Add(&Session{Server: "server"}, 1)
+	got := want.Copy()
+	if !reflect.DeepEqual(got, want) || got.Sessions[0] == want.Sessions[0] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestJSON tests JSON and NewFromJSON.
+func TestJSON(t *testing.T) {
+	want := New()
+	want.Add(&Session{
+		Server: "server",
+		Cause:  DisconnectCauseUser,
+	}, 10)
+
+	b, err := want.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewFromJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test invalid
+	if _, err := NewFromJSON([]byte("invalid")); err == nil {
+		t.Error("invalid JSON should return error")
+	}
+}
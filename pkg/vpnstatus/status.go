@@ -179,6 +179,86 @@ func (t TNDState) String() string {
 	return ""
 }
 
+// DisconnectPolicy is the current disconnect policy.
+type DisconnectPolicy uint32
+
+// DisconnectPolicy policies.
+const (
+	DisconnectPolicyUnknown DisconnectPolicy = iota
+	DisconnectPolicyAllowed
+	DisconnectPolicyLocked
+)
+
+// String returns DisconnectPolicy as string.
+func (d DisconnectPolicy) String() string {
+	switch d {
+	case DisconnectPolicyUnknown:
+		return "unknown"
+	case DisconnectPolicyAllowed:
+		return "allowed"
+	case DisconnectPolicyLocked:
+		return "locked"
+	}
+	return ""
+}
+
+// Locked returns whether DisconnectPolicy is locked.
+func (d DisconnectPolicy) Locked() bool {
+	return d == DisconnectPolicyLocked
+}
+
+// ConnectFailureState is the current connect failure policy state.
+type ConnectFailureState uint32
+
+// ConnectFailureState states.
+const (
+	ConnectFailureStateUnknown ConnectFailureState = iota
+	ConnectFailureStateInactive
+	ConnectFailureStateClosed
+	ConnectFailureStateOpen
+	ConnectFailureStateRelaxed
+)
+
+// String returns ConnectFailureState as string.
+func (c ConnectFailureState) String() string {
+	switch c {
+	case ConnectFailureStateUnknown:
+		return "unknown"
+	case ConnectFailureStateInactive:
+		return "inactive"
+	case ConnectFailureStateClosed:
+		return "closed"
+	case ConnectFailureStateOpen:
+		return "open"
+	case ConnectFailureStateRelaxed:
+		return "relaxed"
+	}
+	return ""
+}
+
+// Connection is the status of a named VPN connection.
+type Connection struct {
+	Name            string
+	ConnectionState ConnectionState
+	OCRunning       OCRunning
+	OCPID           uint32
+	IP              string
+	Device          string
+	Server          string
+	ServerIP        string
+	ConnectedAt     int64
+}
+
+// Copy returns a copy of Connection.
+func (c *Connection) Copy() *Connection {
+	if c == nil {
+		return nil
+	}
+
+	cp := *c
+	return &cp
+}
+
 // Status is a VPN status.
 type Status struct {
 	TrustedNetwork  TrustedNetwork
@@ -196,7 +276,42 @@ type Status struct {
 	CaptivePortal   CaptivePortal
 	TNDState        TNDState
 	TNDServers      []string
+	TNDMatches      []string
 	VPNConfig       *vpnconfig.Config
+
+	ReconnectAttempts     uint32
+	ReconnectAt           int64
+	ReconnectGiveUpReason string
+
+	IdleTimeoutAt int64
+
+	ResumeAction  string
+	ResumeOutcome string
+
+	DisconnectPolicy DisconnectPolicy
+
+	ConnectFailureState ConnectFailureState
+	ConnectFailures     uint32
+
+	NetworkPolicyAction   string
+	NetworkPolicyDecision string
+
+	Connections []*Connection
+
+	NetNS string
+
+	CommandListError string
+}
+
+// GetConnection returns the status of the named VPN connection with name,
+// nil if it does not exist.
+func (s *Status) GetConnection(name string) *Connection {
+	for _, c := range s.Connections {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
 }
 
 // Copy returns a copy of Status.
@@ -221,8 +336,44 @@ func (s *Status) Copy() *Status {
 		CaptivePortal:   s.CaptivePortal,
 		TNDState:        s.TNDState,
 		TNDServers:      append(s.TNDServers[:0:0], s.TNDServers...),
+		TNDMatches:      append(s.TNDMatches[:0:0], s.TNDMatches...),
 		VPNConfig:       s.VPNConfig.Copy(),
+
+		ReconnectAttempts:     s.ReconnectAttempts,
+		ReconnectAt:           s.ReconnectAt,
+		ReconnectGiveUpReason: s.ReconnectGiveUpReason,
+
+		IdleTimeoutAt: s.IdleTimeoutAt,
+
+		ResumeAction:  s.ResumeAction,
+		ResumeOutcome: s.ResumeOutcome,
+
+		DisconnectPolicy: s.DisconnectPolicy,
+
+		ConnectFailureState: s.ConnectFailureState,
+		ConnectFailures:     s.ConnectFailures,
+
+		NetworkPolicyAction:   s.NetworkPolicyAction,
+		NetworkPolicyDecision: s.NetworkPolicyDecision,
+
+		Connections: copyConnections(s.Connections),
+
+		NetNS: s.NetNS,
+
+		CommandListError: s.CommandListError,
+	}
+}
+
+// copyConnections returns a copy of the named VPN connections in c.
+func copyConnections(c []*Connection) []*Connection {
+	if c == nil {
+		return nil
+	}
+	cp := make([]*Connection, 0, len(c))
+	for _, conn := range c {
+		cp = append(cp, conn.Copy())
 	}
+	return cp
 }
 
 // JSON returns the Status as JSON.
@@ -244,6 +395,8 @@ func NewFromJSON(b []byte) (*Status, error) {
 // New returns a new Status.
 func New() *Status {
 	return &Status{
-		ConnectedAt: -1,
+		ConnectedAt:   -1,
+		ReconnectAt:   -1,
+		IdleTimeoutAt: -1,
 	}
 }
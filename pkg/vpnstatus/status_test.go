@@ -208,6 +208,60 @@ func TestTNDStateString(t *testing.T) {
 	}
 }
 
+// TestDisconnectPolicyString tests String of DisconnectPolicy.
+func TestDisconnectPolicyString(t *testing.T) {
+	for v, s := range map[DisconnectPolicy]string{
+		// valid
+		DisconnectPolicyUnknown: "unknown",
+		DisconnectPolicyAllowed: "allowed",
+		DisconnectPolicyLocked:  "locked",
+
+		// invalid
+		123456: "",
+	} {
+		if v.String() != s {
+			t.Errorf("got %s, want %s", v.String(), s)
+		}
+	}
+}
+
+// TestDisconnectPolicyLocked tests Locked of DisconnectPolicy.
+func TestDisconnectPolicyLocked(t *testing.T) {
+	// test not locked
+	for i, notLocked := range []DisconnectPolicy{
+		DisconnectPolicyUnknown,
+		DisconnectPolicyAllowed,
+	} {
+		if notLocked.Locked() {
+			t.Errorf("should not be locked: %d, %s", i, notLocked)
+		}
+	}
+
+	// test locked
+	if !DisconnectPolicyLocked.Locked() {
+		t.Errorf("should be locked: %s", DisconnectPolicyLocked)
+	}
+}
+
+// TestConnectFailureStateString tests String of ConnectFailureState.
+func TestConnectFailureStateString(t *testing.T) {
+	for v, s := range map[ConnectFailureState]string{
+		// valid
+		ConnectFailureStateUnknown:  "unknown",
+		ConnectFailureStateInactive: "inactive",
+		ConnectFailureStateClosed:   "closed",
+		ConnectFailureStateOpen:     "open",
+		ConnectFailureStateRelaxed:  "relaxed",
+
+		// invalid
+		123456: "",
+	} {
+		if v.String() != s {
+			t.Errorf("got %s, want %s", v.String(), s)
+		}
+	}
+}
+
 // TestStatusCopy tests Copy of Status.
 func TestStatusCopy(t *testing.T) {
 	// test nil
@@ -234,7 +288,41 @@ func TestStatusCopy(t *testing.T) {
 			CaptivePortal:   CaptivePortalNotDetected,
 			TNDState:        TNDStateActive,
 			TNDServers:      []string{"tnd1.local:abcdef..."},
+			TNDMatches:      []string{"DNS domain: mycompany.com"},
 			VPNConfig:       vpnconfig.New(),
+
+			ReconnectAttempts:     2,
+			ReconnectAt:           1700000060,
+			ReconnectGiveUpReason: "test reason",
+
+			IdleTimeoutAt: 1700000120,
+
+			ResumeAction:  "keep",
+			ResumeOutcome: "connection kept",
+
+			DisconnectPolicy: DisconnectPolicyLocked,
+
+			ConnectFailureState: ConnectFailureStateRelaxed,
+			ConnectFailures:     3,
+
+			NetworkPolicyAction:   "Pause",
+			NetworkPolicyDecision: "vpn paused in trusted network",
+
+			Connections: []*Connection{
+				{
+					Name:            "lab",
+					ConnectionState: ConnectionStateConnected,
+					OCRunning:       OCRunningRunning,
+					OCPID:           12346,
+					IP:              "192.168.2.1",
+					Device:          "tun1",
+					Server:          "lab server",
+					ServerIP:        "10.0.0.2",
+					ConnectedAt:     1700000000,
+				},
+			},
+
+			NetNS: "oc-daemon",
 		},
 	} {
 		got := want.Copy()
@@ -244,6 +332,20 @@ func TestStatusCopy(t *testing.T) {
 	}
 }
 
+// TestStatusGetConnection tests GetConnection of Status.
+func TestStatusGetConnection(t *testing.T) {
+	lab := &Connection{Name: "lab"}
+	s := New()
+	s.Connections = []*Connection{lab}
+
+	if got := s.GetConnection("lab"); got != lab {
+		t.Errorf("got %v, want %v", got, lab)
+	}
+	if got := s.GetConnection("other"); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+}
+
 // TestJSON tests JSON and NewFromJSON of Status.
 func TestJSON(t *testing.T) {
 	// test without json errors
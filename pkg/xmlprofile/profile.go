@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -16,6 +18,14 @@ var (
 	SystemProfile = "/var/lib/oc-daemon/profile.xml"
 )
 
+// Network policy actions.
+const (
+	NetworkPolicyDisconnect = "Disconnect"
+	NetworkPolicyConnect    = "Connect"
+	NetworkPolicyPause      = "Pause"
+	NetworkPolicyDoNothing  = "DoNothing"
+)
+
 // Profile is an XML Profile.
 type Profile AnyConnectProfile
 
@@ -82,11 +92,100 @@ func (p *Profile) GetTNDHTTPSServers() (servers map[string]string) {
 	return
 }
 
+// splitList returns the items in the comma separated lists.
+func splitList(lists []string) (items []string) {
+	for _, l := range lists {
+		for _, i := range strings.Split(l, ",") {
+			i = strings.TrimSpace(i)
+			if i == "" {
+				continue
+			}
+			items = append(items, i)
+		}
+	}
+	return
+}
+
+// GetTrustedDNSDomains returns the trusted DNS domains in the XML profile.
+func (p *Profile) GetTrustedDNSDomains() []string {
+	return splitList(p.AutomaticVPNPolicy.TrustedDNSDomains)
+}
+
+// GetTrustedDNSServers returns the trusted DNS servers in the XML profile.
+func (p *Profile) GetTrustedDNSServers() []string {
+	return splitList(p.AutomaticVPNPolicy.TrustedDNSServers)
+}
+
+// getNetworkPolicy returns the network policy action in policy if it is one
+// of actions, def otherwise.
+func getNetworkPolicy(policy, def string, actions ...string) string {
+	policy = strings.TrimSpace(policy)
+	for _, a := range actions {
+		if strings.EqualFold(policy, a) {
+			return a
+		}
+	}
+	return def
+}
+
+// GetTrustedNetworkPolicy returns the trusted network policy action in the
+// XML profile: Disconnect, Connect, Pause or DoNothing. It returns
+// Disconnect if the policy is not set or invalid.
+func (p *Profile) GetTrustedNetworkPolicy() string {
+	return getNetworkPolicy(p.AutomaticVPNPolicy.TrustedNetworkPolicy,
+		NetworkPolicyDisconnect, NetworkPolicyDisconnect,
+		NetworkPolicyConnect, NetworkPolicyPause, NetworkPolicyDoNothing)
+}
+
+// GetUntrustedNetworkPolicy returns the untrusted network policy action in
+// the XML profile: Connect or DoNothing. It returns DoNothing if the policy
+// is not set or invalid.
+func (p *Profile) GetUntrustedNetworkPolicy() string {
+	return getNetworkPolicy(p.AutomaticVPNPolicy.UntrustedNetworkPolicy,
+		NetworkPolicyDoNothing, NetworkPolicyConnect, NetworkPolicyDoNothing)
+}
+
 // GetAlwaysOn returns the always on flag in the XML profile.
 func (p *Profile) GetAlwaysOn() bool {
 	return p.AutomaticVPNPolicy.AlwaysOn.Flag
 }
 
+// GetAllowVPNDisconnect returns whether the user is allowed to disconnect
+// the VPN in the XML profile. Disconnecting is allowed unless it is
+// explicitly set to false.
+func (p *Profile) GetAllowVPNDisconnect() bool {
+	allow := strings.TrimSpace(p.AutomaticVPNPolicy.AlwaysOn.AllowVPNDisconnect)
+	return !strings.EqualFold(allow, "false")
+}
+
+// GetConnectFailurePolicyOpen returns whether the connect failure policy in
+// the XML profile is open. The policy is closed unless it is explicitly set
+// to Open.
+func (p *Profile) GetConnectFailurePolicyOpen() bool {
+	policy := strings.TrimSpace(p.AutomaticVPNPolicy.AlwaysOn.ConnectFailurePolicy.Flag)
+	return strings.EqualFold(policy, "open")
+}
+
+// GetAllowCaptivePortalRemediation returns whether captive portal
+// remediation is allowed in the XML profile. Remediation is allowed unless
+// it is explicitly set to false.
+func (p *Profile) GetAllowCaptivePortalRemediation() bool {
+	remediation := p.AutomaticVPNPolicy.AlwaysOn.ConnectFailurePolicy.AllowCaptivePortalRemediation
+	return !strings.EqualFold(strings.TrimSpace(remediation.Flag), "false")
+}
+
+// GetCaptivePortalRemediationTimeout returns the captive portal remediation
+// timeout in the XML profile. It returns 0 if the timeout is not set or
+// invalid.
+func (p *Profile) GetCaptivePortalRemediationTimeout() time.Duration {
+	remediation := p.AutomaticVPNPolicy.AlwaysOn.ConnectFailurePolicy.AllowCaptivePortalRemediation
+	minutes, err := strconv.Atoi(strings.TrimSpace(remediation.CaptivePortalRemediationTimeout))
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
 // Equal returns whether the profile and other are equal.
 func (p *Profile) Equal(other *Profile) bool {
 	return reflect.DeepEqual(p, other)
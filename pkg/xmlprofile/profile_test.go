@@ -5,6 +5,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // TestProfileGetAllowedHosts tests GetAllowedHosts of Profile.
@@ -143,6 +144,61 @@ func TestProfileGetTNDServers(t *testing.T) {
 	}
 }
 
+// TestProfileGetTrustedDNSDomains tests GetTrustedDNSDomains of Profile.
+func TestProfileGetTrustedDNSDomains(t *testing.T) {
+	p := NewProfile()
+
+	// test empty
+	var want []string
+	got := p.GetTrustedDNSDomains()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test filled
+	p.AutomaticVPNPolicy.TrustedDNSDomains = []string{
+		"*.mycompany.com, mycompany.com",
+		"",
+		"other.mycompany.com",
+	}
+	want = []string{
+		"*.mycompany.com",
+		"mycompany.com",
+		"other.mycompany.com",
+	}
+	got = p.GetTrustedDNSDomains()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestProfileGetTrustedDNSServers tests GetTrustedDNSServers of Profile.
+func TestProfileGetTrustedDNSServers(t *testing.T) {
+	p := NewProfile()
+
+	// test empty
+	var want []string
+	got := p.GetTrustedDNSServers()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// test filled
+	p.AutomaticVPNPolicy.TrustedDNSServers = []string{
+		"10.1.1.1,10.2.*.*",
+		"2001:db8::1",
+	}
+	want = []string{
+		"10.1.1.1",
+		"10.2.*.*",
+		"2001:db8::1",
+	}
+	got = p.GetTrustedDNSServers()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
 // TestProfileGetTNDHTTPSServers tests GetTNDHTTPSServers of Profile.
 func TestProfileGetTNDHTTPSServers(t *testing.T) {
 	p := NewProfile()
@@ -188,6 +244,125 @@ func TestProfileGetAlwaysOn(t *testing.T) {
 	}
 }
 
+// TestProfileGetAllowVPNDisconnect tests GetAllowVPNDisconnect of Profile.
+func TestProfileGetAllowVPNDisconnect(t *testing.T) {
+	p := NewProfile()
+	for s, want := range map[string]bool{
+		"":        true,
+		"true":    true,
+		"True":    true,
+		"false":   false,
+		"False":   false,
+		" false ": false,
+	} {
+		p.AutomaticVPNPolicy.AlwaysOn.AllowVPNDisconnect = s
+		got := p.GetAllowVPNDisconnect()
+		if got != want {
+			t.Errorf("%q: got %t, want %t", s, got, want)
+		}
+	}
+}
+
+// TestProfileGetConnectFailurePolicyOpen tests GetConnectFailurePolicyOpen
+// of Profile.
+func TestProfileGetConnectFailurePolicyOpen(t *testing.T) {
+	p := NewProfile()
+	for s, want := range map[string]bool{
+		"":             false,
+		"Closed":       false,
+		"Closed\n\t\t": false,
+		"Open":         true,
+		"open":         true,
+		"Open\n\t\t":   true,
+	} {
+		p.AutomaticVPNPolicy.AlwaysOn.ConnectFailurePolicy.Flag = s
+		got := p.GetConnectFailurePolicyOpen()
+		if got != want {
+			t.Errorf("%q: got %t, want %t", s, got, want)
+		}
+	}
+}
+
+// TestProfileGetTrustedNetworkPolicy tests GetTrustedNetworkPolicy of
+// Profile.
+func TestProfileGetTrustedNetworkPolicy(t *testing.T) {
+	p := NewProfile()
+	for s, want := range map[string]string{
+		"":                NetworkPolicyDisconnect,
+		"invalid":         NetworkPolicyDisconnect,
+		"Disconnect":      NetworkPolicyDisconnect,
+		"Connect":         NetworkPolicyConnect,
+		"pause":           NetworkPolicyPause,
+		"DoNothing\n\t\t": NetworkPolicyDoNothing,
+	} {
+		p.AutomaticVPNPolicy.TrustedNetworkPolicy = s
+		got := p.GetTrustedNetworkPolicy()
+		if got != want {
+			t.Errorf("%q: got %s, want %s", s, got, want)
+		}
+	}
+}
+
+// TestProfileGetUntrustedNetworkPolicy tests GetUntrustedNetworkPolicy of
+// Profile.
+func TestProfileGetUntrustedNetworkPolicy(t *testing.T) {
+	p := NewProfile()
+	for s, want := range map[string]string{
+		"":              NetworkPolicyDoNothing,
+		"invalid":       NetworkPolicyDoNothing,
+		"Disconnect":    NetworkPolicyDoNothing,
+		"Pause":         NetworkPolicyDoNothing,
+		"DoNothing":     NetworkPolicyDoNothing,
+		"Connect":       NetworkPolicyConnect,
+		"connect\n\t\t": NetworkPolicyConnect,
+	} {
+		p.AutomaticVPNPolicy.UntrustedNetworkPolicy = s
+		got := p.GetUntrustedNetworkPolicy()
+		if got != want {
+			t.Errorf("%q: got %s, want %s", s, got, want)
+		}
+	}
+}
+
+// TestProfileGetAllowCaptivePortalRemediation tests
+// GetAllowCaptivePortalRemediation of Profile.
+func TestProfileGetAllowCaptivePortalRemediation(t *testing.T) {
+	p := NewProfile()
+	for s, want := range map[string]bool{
+		"":           true,
+		"true":       true,
+		"true\n\t\t": true,
+		"false":      false,
+		"False":      false,
+	} {
+		p.AutomaticVPNPolicy.AlwaysOn.ConnectFailurePolicy.AllowCaptivePortalRemediation.Flag = s
+		got := p.GetAllowCaptivePortalRemediation()
+		if got != want {
+			t.Errorf("%q: got %t, want %t", s, got, want)
+		}
+	}
+}
+
+// TestProfileGetCaptivePortalRemediationTimeout tests
+// GetCaptivePortalRemediationTimeout of Profile.
+func TestProfileGetCaptivePortalRemediationTimeout(t *testing.T) {
+	p := NewProfile()
+	for s, want := range map[string]time.Duration{
+		"":     0,
+		"x":    0,
+		"-1":   0,
+		"0":    0,
+		"5":    5 * time.Minute,
+		" 10 ": 10 * time.Minute,
+	} {
+		p.AutomaticVPNPolicy.AlwaysOn.ConnectFailurePolicy.AllowCaptivePortalRemediation.CaptivePortalRemediationTimeout = s
+		got := p.GetCaptivePortalRemediationTimeout()
+		if got != want {
+			t.Errorf("%q: got %s, want %s", s, got, want)
+		}
+	}
+}
+
 // TestProfileEqual tests Equal of Profile.
 func TestProfileEqual(t *testing.T) {
 	// test new profiles